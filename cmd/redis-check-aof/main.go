@@ -0,0 +1,113 @@
+// Command redis-check-aof checks an append only file after a crash, and truncates it to
+// its last valid command with --fix:
+//
+//	redis-check-aof [--fix] <file.manifest|file.aof|file.rdb>
+//
+// Given a manifest, every file it lists is checked in order, and only the last one may be
+// fixed. It exits with status 1 when a file is not valid.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"redis-repo/internal/aof"
+	"redis-repo/internal/data_structure"
+	"redis-repo/internal/rdb"
+	"strings"
+)
+
+func main() {
+	args := os.Args[1:]
+	fix := len(args) == 2 && args[0] == "--fix"
+	if fix {
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: redis-check-aof [--fix] <file.manifest|file.aof|file.rdb>")
+		os.Exit(1)
+	}
+
+	path := args[0]
+	files := []string{path}
+	if strings.HasSuffix(path, ".manifest") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fail("Cannot read the manifest: %v", err)
+		}
+		m, err := aof.ParseManifest(data)
+		if err != nil {
+			fail("Invalid manifest %s: %v", path, err)
+		}
+		files = files[:0]
+		for _, f := range m.Files() {
+			files = append(files, filepath.Join(filepath.Dir(path), f.Name))
+		}
+		fmt.Println("Start checking Multi Part AOF")
+	}
+
+	for i, file := range files {
+		if !checkFile(file, fix && i == len(files)-1) {
+			os.Exit(1)
+		}
+	}
+	if len(files) > 1 {
+		fmt.Println("All AOF files and manifest are valid")
+	}
+}
+
+// checkFile checks a file, an RDB file when it has the extension of one, and truncates it
+// when fix is set. It reports whether the file is valid once done
+func checkFile(path string, fix bool) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fail("Cannot read %s: %v", path, err)
+	}
+	if strings.HasSuffix(path, ".rdb") {
+		d := rdb.NewDecoder(bytes.NewReader(data))
+		if err := d.Decode(func(int, data_structure.SnapshotEntry) error { return nil }); err != nil {
+			fmt.Printf("RDB file %s is not valid at offset %d: %v\n", path, d.Offset(), err)
+			return false
+		}
+		fmt.Printf("RDB file %s is valid\n", path)
+		return true
+	}
+
+	valid, err := aof.Check(data)
+	fmt.Printf("AOF analyzed: filename=%s, size=%d, ok_up_to=%d, ok_up_to_line=%d, diff=%d\n",
+		path, len(data), valid, bytes.Count(data[:valid], []byte("\n"))+1, len(data)-valid)
+	if err == nil {
+		fmt.Printf("AOF %s is valid\n", path)
+		return true
+	}
+	fmt.Printf("AOF %s is not valid at offset %d: %v\n", path, valid, err)
+	if errors.Is(err, aof.ErrPreamble) {
+		fmt.Println("The RDB preamble cannot be fixed")
+		return false
+	}
+	if !fix {
+		fmt.Println("Use the --fix option to try fixing it.")
+		return false
+	}
+
+	fmt.Printf("This will shrink the AOF %s from %d bytes, with %d bytes, to %d bytes\n", path, len(data), len(data)-valid, valid)
+	fmt.Print("Continue? [y/N]: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		fmt.Println("Aborting...")
+		return false
+	}
+	if err := os.Truncate(path, int64(valid)); err != nil {
+		fail("Failed to truncate AOF %s: %v", path, err)
+	}
+	fmt.Printf("Successfully truncated AOF %s\n", path)
+	return true
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
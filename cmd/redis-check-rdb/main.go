@@ -0,0 +1,76 @@
+// Command redis-check-rdb checks an RDB file, reporting the offset of the first error:
+//
+//	redis-check-rdb <file.rdb>
+//
+// It exits with status 1 when the file is not valid.
+package main
+
+import (
+	"fmt"
+	"os"
+	"redis-repo/internal/config"
+	"redis-repo/internal/data_structure"
+	"redis-repo/internal/rdb"
+	"sort"
+	"time"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: redis-check-rdb <file.rdb>")
+		os.Exit(1)
+	}
+	path := os.Args[1]
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	fmt.Printf("[offset 0] Checking RDB file %s\n", path)
+	now := uint64(time.Now().UnixMilli())
+	var keys, expires, expired int
+	dbs := map[int]int{}
+	d := rdb.NewDecoder(f)
+	err = d.Decode(func(db int, entry data_structure.SnapshotEntry) error {
+		keys++
+		dbs[db]++
+		if entry.ExpiryTimeMs != 0 {
+			expires++
+			if entry.ExpiryTimeMs < now {
+				expired++
+			}
+		}
+		return nil
+	})
+
+	names := make([]string, 0, len(d.Aux))
+	for name := range d.Aux {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if d.Version != 0 {
+		fmt.Printf("[info] RDB version %d\n", d.Version)
+	}
+	for _, name := range names {
+		fmt.Printf("[info] AUX FIELD %s = '%s'\n", name, d.Aux[name])
+	}
+	if err != nil {
+		fmt.Printf("--- RDB ERROR DETECTED ---\n")
+		fmt.Printf("[offset %d] %v\n", d.Offset(), err)
+		fmt.Printf("[additional info] While doing: reading key %d\n", keys+1)
+		os.Exit(1)
+	}
+
+	fmt.Printf("[offset %d] Checksum OK\n", d.Offset())
+	fmt.Printf("[offset %d] \\o/ RDB looks OK! \\o/\n", d.Offset())
+	for db := range config.Databases {
+		if dbs[db] > 0 {
+			fmt.Printf("[info] db %d: %d keys\n", db, dbs[db])
+		}
+	}
+	fmt.Printf("[info] %d keys read\n", keys)
+	fmt.Printf("[info] %d expires\n", expires)
+	fmt.Printf("[info] %d already expired\n", expired)
+}
@@ -1,7 +1,15 @@
 package main
 
-import "redis-repo/internal/server"
+import (
+	"log"
+	"os"
+	"redis-repo/internal/core/executor"
+	"redis-repo/internal/server"
+)
 
 func main() {
+	if err := executor.SetConfigArgs(os.Args[1:]); err != nil {
+		log.Fatal("Invalid arguments: ", err)
+	}
 	server.RunRedisServer()
 }
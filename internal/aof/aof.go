@@ -0,0 +1,88 @@
+// Package aof reads and writes the append only files of Redis 7: a directory holding a
+// base file, a snapshot of the databases either in the RDB format or as commands, the
+// incremental files of the commands written since, and a manifest listing them in order.
+//
+// Commands are written as RESP arrays of bulk strings, as clients send them. A file may
+// start with an RDB preamble, and Redis may insert annotations, lines starting with '#'.
+package aof
+
+import (
+	"errors"
+	"fmt"
+	"redis-repo/internal/core/resp"
+	"strconv"
+)
+
+// ErrTruncated reports a file ending in the middle of a command
+var ErrTruncated = errors.New("unexpected end of file")
+
+// AppendCommand appends argv to buf as a RESP array of bulk strings
+func AppendCommand(buf []byte, argv []string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(argv)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range argv {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// DecodeCommands calls fn on every command of data with its offset, skipping the
+// annotations. It returns
+// the offset following the last complete command, with ErrTruncated when data ends in the
+// middle of the next one, or another error when it is not a command
+func DecodeCommands(data []byte, fn func(argv []string, offset int) error) (int, error) {
+	offset := 0
+	for offset < len(data) {
+		if data[offset] == '#' {
+			end := offset
+			for end < len(data) && data[end] != '\n' {
+				end++
+			}
+			if end == len(data) {
+				return offset, ErrTruncated
+			}
+			offset = end + 1
+			continue
+		}
+		if data[offset] != '*' {
+			return offset, fmt.Errorf("expected a command, got %q", data[offset])
+		}
+
+		result, err := resp.DecodeNext(data[offset:])
+		if resp.IsIncomplete(err) {
+			return offset, ErrTruncated
+		}
+		if err != nil {
+			return offset, err
+		}
+		argv, err := commandArgv(result.Value)
+		if err != nil {
+			return offset, err
+		}
+		if err := fn(argv, offset); err != nil {
+			return offset, err
+		}
+		offset += result.Length
+	}
+	return offset, nil
+}
+
+// commandArgv converts a decoded RESP array of bulk strings to the arguments of a command
+func commandArgv(value any) ([]string, error) {
+	array, ok := value.([]any)
+	if !ok || len(array) == 0 {
+		return nil, errors.New("expected a non empty array")
+	}
+	argv := make([]string, len(array))
+	for i, arg := range array {
+		if argv[i], ok = arg.(string); !ok {
+			return nil, fmt.Errorf("expected a bulk string argument, got %T", arg)
+		}
+	}
+	return argv, nil
+}
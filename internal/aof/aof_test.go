@@ -0,0 +1,218 @@
+package aof
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"redis-repo/internal/data_structure"
+	"redis-repo/internal/rdb"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	data := "# comment\n" +
+		"file appendonly.aof.2.base.rdb seq 2 type b\n" +
+		"\n" +
+		"type h seq 1 file appendonly.aof.1.incr.aof\n" +
+		"file appendonly.aof.2.incr.aof seq 2 type i\n" +
+		"file appendonly.aof.3.incr.aof seq 3 type i\n"
+	m, err := ParseManifest([]byte(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []File{
+		{Name: "appendonly.aof.2.base.rdb", Seq: 2, Type: TypeBase},
+		{Name: "appendonly.aof.2.incr.aof", Seq: 2, Type: TypeIncr},
+		{Name: "appendonly.aof.3.incr.aof", Seq: 3, Type: TypeIncr},
+	}
+	if !reflect.DeepEqual(m.Files(), expected) || len(m.History) != 1 {
+		t.Errorf("Expected %v and 1 history file, got %v and %v", expected, m.Files(), m.History)
+	}
+	if m.NextSeq(TypeBase) != 3 || m.NextSeq(TypeIncr) != 4 {
+		t.Errorf("Expected the sequences 3 and 4, got %d and %d", m.NextSeq(TypeBase), m.NextSeq(TypeIncr))
+	}
+
+	formatted := "file appendonly.aof.2.base.rdb seq 2 type b\n" +
+		"file appendonly.aof.1.incr.aof seq 1 type h\n" +
+		"file appendonly.aof.2.incr.aof seq 2 type i\n" +
+		"file appendonly.aof.3.incr.aof seq 3 type i\n"
+	if string(m.Bytes()) != formatted {
+		t.Errorf("Expected %q, got %q", formatted, m.Bytes())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"file a seq 1\n",
+		"file a seq x type b\n",
+		"file a seq 1 type x\n",
+		"file a seq 1 type b\nfile b seq 2 type b\n",
+		"file a seq 2 type i\nfile b seq 1 type i\n",
+	} {
+		if _, err := ParseManifest([]byte(invalid)); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestDecodeCommands(t *testing.T) {
+	var data []byte
+	data = AppendCommand(data, []string{"SELECT", "0"})
+	data = append(data, "#TS:1700000000\r\n"...)
+	data = AppendCommand(data, []string{"SET", "key", "a\r\nb"})
+	complete := len(data)
+
+	var got [][]string
+	var offsets []int
+	decode := func(data []byte) (int, error) {
+		got, offsets = nil, nil
+		return DecodeCommands(data, func(argv []string, offset int) error {
+			got = append(got, argv)
+			offsets = append(offsets, offset)
+			return nil
+		})
+	}
+
+	n, err := decode(data)
+	expected := [][]string{{"SELECT", "0"}, {"SET", "key", "a\r\nb"}}
+	if err != nil || n != complete || !reflect.DeepEqual(got, expected) || !reflect.DeepEqual(offsets, []int{0, 23 + 16}) {
+		t.Errorf("Expected %q at 0 and 39, got %q at %v, %d, %v", expected, got, offsets, n, err)
+	}
+
+	n, err = decode(append(data, "*2\r\n$3\r\nDEL"...))
+	if !errors.Is(err, ErrTruncated) || n != complete || len(got) != 2 {
+		t.Errorf("Expected a truncated command at %d, got %d, %v", complete, n, err)
+	}
+
+	n, err = decode(append(data, "GET key\r\n"...))
+	if err == nil || errors.Is(err, ErrTruncated) || n != complete {
+		t.Errorf("Expected an invalid command at %d, got %d, %v", complete, n, err)
+	}
+
+	n, err = decode(append(data, "*1\r\n$9223372036854775806\r\nDEL\r\n"...))
+	if !errors.Is(err, ErrTruncated) || n != complete {
+		t.Errorf("Expected a truncated command at %d, got %d, %v", complete, n, err)
+	}
+
+	if _, err := decode([]byte("*0\r\n")); err == nil {
+		t.Errorf("Expected an error for an empty command")
+	}
+}
+
+func TestWriteDB(t *testing.T) {
+	list := data_structure.NewQuicklist(-2)
+	for i := range itemsPerCommand + 1 {
+		list.PushTail(strconv.Itoa(i))
+	}
+	zset := data_structure.NewZSet()
+	zset.Set("low", math.Inf(-1))
+	zset.Set("mid", 0.1)
+	hash := data_structure.NewHash()
+	hash.Set("kept", "1")
+	hash.Set("expiring", "2")
+	hash.SetExpiry("expiring", 2000)
+	hash.Set("expired", "3")
+	hash.SetExpiry("expired", 500)
+	expiredHash := data_structure.NewHash()
+	expiredHash.Set("expired", "1")
+	expiredHash.SetExpiry("expired", 500)
+
+	entries := []data_structure.SnapshotEntry{
+		{Key: "string", Value: data_structure.NewStringObject("value"), ExpiryTimeMs: 5000},
+		{Key: "expired", Value: data_structure.NewStringObject("value"), ExpiryTimeMs: 1000},
+		{Key: "int", Value: data_structure.NewIntObject(42)},
+		{Key: "list", Value: data_structure.NewListObject(list)},
+		{Key: "set", Value: data_structure.NewSetObject(data_structure.NewSet([]string{"a"}))},
+		{Key: "zset", Value: data_structure.NewZSetObject(zset)},
+		{Key: "hash", Value: data_structure.NewHashObject(hash)},
+		{Key: "expired-hash", Value: data_structure.NewHashObject(expiredHash)},
+	}
+	var buf bytes.Buffer
+	if err := WriteDB(&buf, 3, entries, 1000); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var commands [][]string
+	if _, err := DecodeCommands(buf.Bytes(), func(argv []string, _ int) error {
+		commands = append(commands, argv)
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	firstPush := []string{"RPUSH", "list"}
+	for i := range itemsPerCommand {
+		firstPush = append(firstPush, strconv.Itoa(i))
+	}
+	expected := [][]string{
+		{"SELECT", "3"},
+		{"SET", "string", "value"},
+		{"PEXPIREAT", "string", "5000"},
+		{"SET", "int", "42"},
+		firstPush,
+		{"RPUSH", "list", strconv.Itoa(itemsPerCommand)},
+		{"SADD", "set", "a"},
+		{"ZADD", "zset", "-inf", "low", "0.1", "mid"},
+	}
+	if !reflect.DeepEqual(commands[:len(expected)], expected) {
+		t.Errorf("Expected %q, got %q", expected, commands[:len(expected)])
+	}
+	// The fields of a hash are iterated in no particular order
+	hashCommands := commands[len(expected):]
+	if len(hashCommands) != 2 || hashCommands[0][0] != "HSET" || len(hashCommands[0]) != 6 ||
+		!reflect.DeepEqual(hashCommands[1], []string{"HPEXPIREAT", "hash", "2000", "FIELDS", "1", "expiring"}) {
+		t.Errorf("Expected HSET of 2 fields and HPEXPIREAT of 1, got %q", hashCommands)
+	}
+
+	buf.Reset()
+	if err := WriteDB(&buf, 0, nil, 1000); err != nil || buf.Len() != 0 {
+		t.Errorf("Expected nothing for an empty database, got %q, %v", buf.Bytes(), err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var preamble bytes.Buffer
+	e := rdb.NewEncoder(&preamble)
+	e.WriteHeader(rdb.Version)
+	e.WriteDB(0, []data_structure.SnapshotEntry{{Key: "key", Value: data_structure.NewStringObject("value")}})
+	e.WriteEOF()
+
+	var commands []byte
+	commands = AppendCommand(commands, []string{"SET", "a", "1"})
+	commands = AppendCommand(commands, []string{"MULTI"})
+	commands = AppendCommand(commands, []string{"INCR", "a"})
+	commands = AppendCommand(commands, []string{"EXEC"})
+	valid := len(commands)
+	commands = commands[:valid:valid] // Every test appends to its own copy
+	multi := AppendCommand(nil, []string{"MULTI"})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected int
+		err      error
+	}{
+		{"commands", commands, valid, nil},
+		{"preamble", append(preamble.Bytes(), commands...), preamble.Len() + valid, nil},
+		{"truncated command", append(commands, "*2\r\n$3\r\nDEL\r\n"...), valid, ErrTruncated},
+		{"transaction without EXEC", append(append(commands, multi...), AppendCommand(nil, []string{"DEL", "a"})...), valid, ErrTruncated},
+		{"nested MULTI", append(append(commands, multi...), multi...), valid, errors.New("unexpected MULTI")},
+		{"EXEC without MULTI", AppendCommand(commands, []string{"EXEC"}), valid, errors.New("unexpected EXEC")},
+		{"not a command", append(commands, "+OK\r\n"...), valid, errors.New("expected a command, got '+'")},
+		{"truncated preamble", preamble.Bytes()[:preamble.Len()-4], 0, ErrPreamble},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := Check(tt.data)
+			if offset != tt.expected {
+				t.Errorf("Expected the offset %d, got %d", tt.expected, offset)
+			}
+			switch {
+			case tt.err == nil && err != nil:
+				t.Errorf("Unexpected error: %v", err)
+			case tt.err != nil && !errors.Is(err, tt.err) && (err == nil || err.Error() != tt.err.Error()):
+				t.Errorf("Expected the error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
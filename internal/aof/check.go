@@ -0,0 +1,53 @@
+package aof
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"redis-repo/internal/data_structure"
+	"redis-repo/internal/rdb"
+	"strings"
+)
+
+// ErrPreamble reports an RDB preamble that cannot be read. Unlike the commands, the file
+// cannot be fixed by truncating it
+var ErrPreamble = errors.New("invalid RDB preamble")
+
+// Check validates a file of an append only file: an optional RDB preamble followed by
+// commands, MULTI and EXEC enclosing the commands of a transaction. It returns the offset
+// following the last valid command, outside of a transaction, with ErrTruncated when data
+// ends in the middle of a command or of a transaction, or the error found there
+func Check(data []byte) (int, error) {
+	offset := 0
+	if bytes.HasPrefix(data, []byte("REDIS")) {
+		d := rdb.NewDecoder(bytes.NewReader(data))
+		if err := d.Decode(func(int, data_structure.SnapshotEntry) error { return nil }); err != nil {
+			return 0, fmt.Errorf("%w at offset %d: %v", ErrPreamble, d.Offset(), err)
+		}
+		offset = int(d.Offset())
+	}
+
+	multi := -1 // Offset of the MULTI of the transaction, -1 outside of one
+	end, err := DecodeCommands(data[offset:], func(argv []string, at int) error {
+		switch strings.ToUpper(argv[0]) {
+		case "MULTI":
+			if multi >= 0 {
+				return errors.New("unexpected MULTI")
+			}
+			multi = offset + at
+		case "EXEC":
+			if multi < 0 {
+				return errors.New("unexpected EXEC")
+			}
+			multi = -1
+		}
+		return nil
+	})
+	if multi < 0 {
+		return offset + end, err
+	}
+	if err == nil {
+		err = ErrTruncated // The EXEC is missing
+	}
+	return multi, err
+}
@@ -0,0 +1,165 @@
+package aof
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Types of the files of a manifest
+const (
+	TypeBase    = 'b'
+	TypeHistory = 'h' // Replaced by a rewrite, to delete
+	TypeIncr    = 'i'
+)
+
+// File is a file of the append only directory, named after the appendfilename prefix,
+// its sequence number and its type, such as appendonly.aof.1.incr.aof
+type File struct {
+	Name string
+	Seq  int64
+	Type byte
+}
+
+// Manifest lists the files of the append only directory, one per line such as
+// "file appendonly.aof.1.base.rdb seq 1 type b"
+type Manifest struct {
+	Base    *File
+	History []File
+	Incrs   []File // In the order they are loaded
+}
+
+// BaseFileName returns the name of a base file, in the RDB format or made of commands
+func BaseFileName(prefix string, seq int64, rdb bool) string {
+	if rdb {
+		return fmt.Sprintf("%s.%d.base.rdb", prefix, seq)
+	}
+	return fmt.Sprintf("%s.%d.base.aof", prefix, seq)
+}
+
+// IncrFileName returns the name of an incremental file
+func IncrFileName(prefix string, seq int64) string {
+	return fmt.Sprintf("%s.%d.incr.aof", prefix, seq)
+}
+
+// ManifestFileName returns the name of the manifest
+func ManifestFileName(prefix string) string {
+	return prefix + ".manifest"
+}
+
+// ParseManifest parses a manifest. Like Redis, empty lines and lines starting with '#'
+// are skipped, and the fields of a line are key value pairs in any order
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields)%2 != 0 {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", line, text)
+		}
+		var f File
+		for i := 0; i < len(fields); i += 2 {
+			switch value := fields[i+1]; fields[i] {
+			case "file":
+				f.Name = value
+			case "seq":
+				seq, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || seq < 1 {
+					return nil, fmt.Errorf("invalid sequence on manifest line %d: %q", line, value)
+				}
+				f.Seq = seq
+			case "type":
+				if len(value) != 1 {
+					return nil, fmt.Errorf("invalid type on manifest line %d: %q", line, value)
+				}
+				f.Type = value[0]
+			}
+		}
+		if f.Name == "" || f.Seq == 0 || f.Type == 0 {
+			return nil, fmt.Errorf("incomplete manifest line %d: %q", line, text)
+		}
+
+		switch f.Type {
+		case TypeBase:
+			if m.Base != nil {
+				return nil, errors.New("found duplicate base file information")
+			}
+			m.Base = &f
+		case TypeHistory:
+			m.History = append(m.History, f)
+		case TypeIncr:
+			if len(m.Incrs) > 0 && f.Seq <= m.Incrs[len(m.Incrs)-1].Seq {
+				return nil, errors.New("found a non-monotonic sequence number")
+			}
+			m.Incrs = append(m.Incrs, f)
+		default:
+			return nil, fmt.Errorf("unknown file type on manifest line %d: %q", line, f.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m.Base == nil && len(m.Incrs) == 0 {
+		return nil, errors.New("found an empty manifest")
+	}
+	return m, nil
+}
+
+// Bytes formats the manifest as Redis writes it: the base file, the history files, then
+// the incremental files
+func (m *Manifest) Bytes() []byte {
+	var b bytes.Buffer
+	write := func(f File) {
+		fmt.Fprintf(&b, "file %s seq %d type %c\n", f.Name, f.Seq, f.Type)
+	}
+	if m.Base != nil {
+		write(*m.Base)
+	}
+	for _, f := range m.History {
+		write(f)
+	}
+	for _, f := range m.Incrs {
+		write(f)
+	}
+	return b.Bytes()
+}
+
+// Files returns the files holding the data, in the order they are loaded: the base file
+// then the incremental files
+func (m *Manifest) Files() []File {
+	var files []File
+	if m.Base != nil {
+		files = append(files, *m.Base)
+	}
+	return append(files, m.Incrs...)
+}
+
+// NextSeq returns the sequence number of the next file of type t, TypeBase or TypeIncr,
+// following the files of the manifest including the history ones not deleted yet
+func (m *Manifest) NextSeq(t byte) int64 {
+	suffix := ".incr.aof"
+	files := m.Incrs
+	if t == TypeBase {
+		suffix = ".base."
+		files = nil
+		if m.Base != nil {
+			files = []File{*m.Base}
+		}
+	}
+
+	seq := int64(0)
+	for _, f := range append(files, m.History...) {
+		if strings.Contains(f.Name, suffix) {
+			seq = max(seq, f.Seq)
+		}
+	}
+	return seq + 1
+}
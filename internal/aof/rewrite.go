@@ -0,0 +1,129 @@
+package aof
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"redis-repo/internal/data_structure"
+	"strconv"
+)
+
+// itemsPerCommand is the most elements added by a command of a rewritten file, as Redis's
+// AOF_REWRITE_ITEMS_PER_CMD, so replaying a large collection does not build huge commands
+const itemsPerCommand = 64
+
+// WriteDB writes the keys of database id as the commands creating them, preceded by a
+// SELECT, nothing when it has none. Expiry times are written as absolute PEXPIREAT and
+// HPEXPIREAT commands. The keys and the hash fields expired at nowMs are skipped
+func WriteDB(w io.Writer, id int, entries []data_structure.SnapshotEntry, nowMs uint64) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	buf := AppendCommand(nil, []string{"SELECT", strconv.Itoa(id)})
+	for _, entry := range entries {
+		if entry.ExpiryTimeMs != 0 && entry.ExpiryTimeMs <= nowMs {
+			continue
+		}
+		var err error
+		if buf, err = appendEntry(buf, entry, nowMs); err != nil {
+			return err
+		}
+		if len(buf) >= 64*1024 {
+			if _, err := w.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// appendEntry appends the commands creating a key with its value and expiry
+func appendEntry(buf []byte, entry data_structure.SnapshotEntry, nowMs uint64) ([]byte, error) {
+	key := entry.Key
+	switch v := entry.Value.Value.(type) {
+	case int64:
+		buf = AppendCommand(buf, []string{"SET", key, strconv.FormatInt(v, 10)})
+	case string:
+		buf = AppendCommand(buf, []string{"SET", key, v})
+	case *data_structure.Quicklist:
+		items := make([]string, 0, min(v.Len(), itemsPerCommand))
+		v.Iterate(0, func(_ int, value string) bool {
+			if items = append(items, value); len(items) == itemsPerCommand {
+				buf, items = appendItems(buf, "RPUSH", key, items), items[:0]
+			}
+			return true
+		})
+		buf = appendItems(buf, "RPUSH", key, items)
+	case *data_structure.Set:
+		items := make([]string, 0, min(v.Len(), itemsPerCommand))
+		v.SnapshotIterate(func(member string) bool {
+			if items = append(items, member); len(items) == itemsPerCommand {
+				buf, items = appendItems(buf, "SADD", key, items), items[:0]
+			}
+			return true
+		})
+		buf = appendItems(buf, "SADD", key, items)
+	case *data_structure.ZSet:
+		items := make([]string, 0, min(2*v.Len(), 2*itemsPerCommand))
+		v.Iterate(func(m data_structure.ZSetMember) bool {
+			if items = append(items, formatScore(m.Score), m.Member); len(items) == 2*itemsPerCommand {
+				buf, items = appendItems(buf, "ZADD", key, items), items[:0]
+			}
+			return true
+		})
+		buf = appendItems(buf, "ZADD", key, items)
+	case *data_structure.Hash:
+		var expiring []string
+		fields := 0
+		items := make([]string, 0, min(2*v.Len(), 2*itemsPerCommand))
+		v.SnapshotIterate(func(field, value string) bool {
+			if expiryTime, ok := v.GetExpiry(field); ok {
+				if expiryTime <= nowMs {
+					return true
+				}
+				expiring = append(expiring, field)
+			}
+			fields++
+			if items = append(items, field, value); len(items) == 2*itemsPerCommand {
+				buf, items = appendItems(buf, "HSET", key, items), items[:0]
+			}
+			return true
+		})
+		if fields == 0 {
+			return buf, nil // Every field expired
+		}
+		buf = appendItems(buf, "HSET", key, items)
+		for _, field := range expiring {
+			expiryTime, _ := v.GetExpiry(field)
+			buf = AppendCommand(buf, []string{"HPEXPIREAT", key, strconv.FormatUint(expiryTime, 10), "FIELDS", "1", field})
+		}
+	default:
+		return buf, fmt.Errorf("cannot rewrite a value of type %T", v)
+	}
+
+	if entry.ExpiryTimeMs != 0 {
+		buf = AppendCommand(buf, []string{"PEXPIREAT", key, strconv.FormatUint(entry.ExpiryTimeMs, 10)})
+	}
+	return buf, nil
+}
+
+// appendItems appends the command adding items to key, nothing without items
+func appendItems(buf []byte, cmd, key string, items []string) []byte {
+	if len(items) == 0 {
+		return buf
+	}
+	return AppendCommand(buf, append([]string{cmd, key}, items...))
+}
+
+// formatScore formats a score so that parsing it gives the same float64
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
@@ -1,5 +1,123 @@
 package config
 
 const Protocol = "tcp"
-const Port = ":3000"
 const MaxConnection = 20000
+
+// Port is the TCP port the server listens on, as Redis's port
+var Port = 3000
+
+// RedisVersion is the version of Redis whose commands and file formats the server follows
+const RedisVersion = "7.2.0"
+
+// Databases is the number of logical databases a client can SELECT, as Redis's databases
+const Databases = 16
+
+// Client query buffer
+const (
+	IOBufferSize        = 16 * 1024          // Bytes read from a socket per readable event
+	MaxQueryBufferBytes = 1024 * 1024 * 1024 // A client holding more unparsed bytes than this is disconnected
+	ProtoInlineMaxSize  = 64 * 1024          // Longest line of a multibulk header, as Redis's PROTO_INLINE_MAX_SIZE
+	ProtoMbulkBigArg    = 32 * 1024          // Bulk arguments from this length are read in one go, as Redis's PROTO_MBULK_BIG_ARG
+)
+
+// ProtoMaxBulkLen is the largest string a command such as APPEND or SETRANGE may build
+const ProtoMaxBulkLen = 512 * 1024 * 1024
+
+// ListMaxListpackSize limits the nodes of a list as Redis's list-max-listpack-size: a positive
+// value is a number of elements per node, -1 to -5 a node size of 4, 8, 16, 32 or 64 KB
+const ListMaxListpackSize = -2
+
+// Set encoding thresholds, as Redis's set-max-intset-entries, set-max-listpack-entries and
+// set-max-listpack-value: a set of integers stays an intset up to SetMaxIntsetEntries members,
+// other sets stay a listpack up to SetMaxListpackEntries members of SetMaxListpackValue bytes
+const (
+	SetMaxIntsetEntries   = 512
+	SetMaxListpackEntries = 128
+	SetMaxListpackValue   = 64
+)
+
+// Access frequency tracking, as Redis's lfu-log-factor and lfu-decay-time: the higher
+// LFULogFactor, the more accesses the 8 bit frequency counter needs to grow, and the
+// counter is decremented once every LFUDecayTime minutes without access, never if 0
+const (
+	LFULogFactor = 10
+	LFUDecayTime = 1
+)
+
+// Memory limit, as Redis's maxmemory, maxmemory-policy and maxmemory-samples: once the
+// estimated size of the databases exceeds MaxMemory bytes, keys are evicted following
+// MaxMemoryPolicy, sampling MaxMemorySamples keys per database to find the best ones.
+// A MaxMemory of 0 disables the limit. They can be changed at runtime with CONFIG SET
+var (
+	MaxMemory        int64 = 0
+	MaxMemoryPolicy        = "noeviction"
+	MaxMemorySamples       = 5
+)
+
+// Snapshots, as Redis's dir, dbfilename and save: the databases are saved to
+// Dir/DBFilename, loaded from it at startup, and saved in the background once a SaveParam
+// has seen at least Changes writes in Seconds seconds. No SaveParams disables the saves.
+// They can be changed at runtime with CONFIG SET
+var (
+	Dir        = "."
+	DBFilename = "dump.rdb"
+	SaveParams = []SaveParam{{Seconds: 3600, Changes: 1}, {Seconds: 300, Changes: 100}, {Seconds: 60, Changes: 10000}}
+)
+
+type SaveParam struct {
+	Seconds int64
+	Changes int64
+}
+
+// Append only file, as Redis's appendonly, appenddirname, appendfilename, appendfsync and
+// aof-load-truncated: when AppendOnly is set, every write is appended to the files of
+// Dir/AppendDirname, named after AppendFilename, and they are loaded at startup instead
+// of the snapshot. AppendFsync is always, everysec or no. A file ending in the middle of
+// a command is loaded up to it when AOFLoadTruncated is set
+var (
+	AppendOnly       = false
+	AppendDirname    = "appendonlydir"
+	AppendFilename   = "appendonly.aof"
+	AppendFsync      = "everysec"
+	AOFLoadTruncated = true
+)
+
+// Append only file rewrite, as Redis's aof-use-rdb-preamble, auto-aof-rewrite-percentage
+// and auto-aof-rewrite-min-size: the base file is written in the RDB format when
+// AOFUseRDBPreamble is set, as commands otherwise. The files are rewritten once they grew
+// by AutoAOFRewritePercentage percent since the last rewrite or the startup, and hold
+// more than AutoAOFRewriteMinSize bytes. A percentage of 0 disables it
+var (
+	AOFUseRDBPreamble        = true
+	AutoAOFRewritePercentage = 100
+	AutoAOFRewriteMinSize    = int64(64 * 1024 * 1024)
+)
+
+// Replication, as Redis's replica-read-only, repl-backlog-size, repl-timeout and
+// repl-ping-replica-period: a replica refuses the writes of its clients when
+// ReplicaReadOnly is set. The last ReplBacklogSize bytes of the stream sent to the replicas
+// are kept so a replica that reconnects gets what it missed. A replica or a master silent
+// for ReplTimeout seconds is disconnected, the master pings its replicas every
+// ReplPingReplicaPeriod seconds
+var (
+	ReplicaReadOnly       = true
+	ReplBacklogSize       = int64(1024 * 1024)
+	ReplTimeout           = 60
+	ReplPingReplicaPeriod = 10
+)
+
+// OutputBufferLimit bounds the pending reply bytes of a client, following Redis's
+// client-output-buffer-limit: reaching HardBytes disconnects the client immediately,
+// staying above SoftBytes for SoftSeconds disconnects it too. Zero disables a limit
+type OutputBufferLimit struct {
+	HardBytes   int
+	SoftBytes   int
+	SoftSeconds int
+}
+
+// Output buffer limits per client class, with the Redis defaults
+var (
+	NormalOutputBufferLimit  = OutputBufferLimit{HardBytes: 0, SoftBytes: 0, SoftSeconds: 0}
+	ReplicaOutputBufferLimit = OutputBufferLimit{HardBytes: 256 * 1024 * 1024, SoftBytes: 64 * 1024 * 1024, SoftSeconds: 60}
+	PubSubOutputBufferLimit  = OutputBufferLimit{HardBytes: 32 * 1024 * 1024, SoftBytes: 8 * 1024 * 1024, SoftSeconds: 60}
+)
@@ -2,8 +2,9 @@ package constant
 
 // RESP Protocol Response Constants
 const (
-	RespOk  = "+OK\r\n"
-	RespNil = "$-1\r\n"
+	RespOk       = "+OK\r\n"
+	RespNil      = "$-1\r\n"
+	RespNilArray = "*-1\r\n"
 )
 
 // TTL Response Constants
@@ -14,9 +15,35 @@ const (
 
 // Error Messages
 const (
-	ErrWrongArgCount = "-ERR wrong number of arguments for '%s' command\r\n"
-	ErrEmptyKey      = "-ERR empty key\r\n"
-	ErrInvalidTime   = "-ERR invalid time\r\n"
+	ErrWrongArgCount       = "-ERR wrong number of arguments for '%s' command\r\n"
+	ErrEmptyKey            = "-ERR empty key\r\n"
+	ErrInvalidTime         = "-ERR invalid time\r\n"
+	ErrProtocol            = "-ERR Protocol error: invalid multibulk request\r\n"
+	ErrWrongType           = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	ErrNoSuchKey           = "-ERR no such key\r\n"
+	ErrNotInteger          = "-ERR value is not an integer or out of range\r\n"
+	ErrNotFloat            = "-ERR value is not a valid float\r\n"
+	ErrNotPositive         = "-ERR value is out of range, must be positive\r\n"
+	ErrSyntax              = "-ERR syntax error\r\n"
+	ErrStringTooLong       = "-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n"
+	ErrDBOutOfRange        = "-ERR DB index is out of range\r\n"
+	ErrSameObject          = "-ERR source and destination objects are the same\r\n"
+	ErrOOM                 = "-OOM command not allowed when used memory > 'maxmemory'.\r\n"
+	ErrBgsaveInProgress    = "-ERR Background save already in progress\r\n"
+	ErrSaveFailed          = "-ERR Failed saving the DB, see the server logs for details\r\n"
+	ErrAOFRewriteActive    = "-ERR Background append only file rewriting already in progress\r\n"
+	ErrAOFRewriteFailed    = "-ERR Can't execute an AOF background rewriting. Please check the server logs for more information.\r\n"
+	ErrBgsaveAOFActive     = "-ERR Another child process is active (AOF?): can't BGSAVE right now. Use BGSAVE SCHEDULE in order to schedule a BGSAVE whenever possible.\r\n"
+	ErrReadOnly            = "-READONLY You can't write against a read only replica.\r\n"
+	ErrNoMasterLink        = "-NOMASTERLINK Can't SYNC while not connected with my master\r\n"
+	ErrReplicaConnection   = "-ERR Replica already connected or sending commands\r\n"
+	ErrInvalidMasterPort   = "-ERR Invalid master port\r\n"
+	ErrReplicaofFromMaster = "-ERR Command is not valid when client is a replica.\r\n"
+)
+
+// Eviction
+const (
+	EvictionPoolSize = 16 // Best candidates kept between evictions, as Redis's EVPOOL_SIZE
 )
 
 // Active Cleanup
@@ -26,3 +53,8 @@ const (
 	ActiveCleanupSampleSize                = 20
 	ActiveCleanupAcceptedExpiredProportion = 0.1 // The percentage of expired keys in the sample size is acceptable.
 )
+
+// Active Rehashing
+const (
+	ActiveRehashTimeLimit = 1 // 1ms per cleanup, spent moving the buckets of a resized keyspace
+)
@@ -0,0 +1,206 @@
+package connection
+
+import (
+	"io"
+	"os"
+	"redis-repo/internal/config"
+	"syscall"
+	"time"
+)
+
+// Class groups clients that share an output buffer limit
+type Class int
+
+const (
+	ClassNormal Class = iota
+	ClassReplica
+	ClassPubSub
+)
+
+// Connection holds the state of a single client connection between events
+type Connection struct {
+	Fd       int
+	Class    Class
+	QueryBuf []byte // Bytes read from the socket that have not been executed yet
+	DB       int    // Database selected with SELECT
+
+	// The multibulk request being parsed, resumed by the next read when it is partial
+	QueryPos     int      // Bytes of QueryBuf already parsed into Argv
+	MultibulkLen int      // Arguments of the request not parsed yet, 0 between requests
+	BulkLen      int      // Length of the bulk argument being read, -1 until its header is parsed
+	Argv         []string // Arguments of the request parsed so far
+
+	outBuf             []byte // Replies waiting to be written to the socket
+	outPos             int    // Bytes of outBuf already written
+	softLimitReachedAt int64  // Unix milliseconds when the soft limit was first exceeded, 0 if below it
+
+	// A file sent after the first fileAt bytes of outBuf, such as the RDB file of a full
+	// resynchronization. It is streamed from the disk, outside of the output buffer limit
+	file       *os.File
+	fileAt     int
+	fileOffset int64 // Bytes of the file already written
+	fileSize   int64
+
+	WantWrite bool // The fd is currently monitored for EPOLLOUT
+	CloseASAP bool // The client must be disconnected, e.g. it exceeded its output buffer limit
+	Master    bool // The link to the master of this replica, whose commands get no reply
+}
+
+// NewConnection creates the state for a newly accepted client file descriptor
+func NewConnection(fd int) *Connection {
+	return &Connection{
+		Fd:      fd,
+		Class:   ClassNormal,
+		BulkLen: -1,
+	}
+}
+
+// ConsumeQuery drops the first n bytes of the query buffer once they have been executed,
+// keeping any partial command for the next read
+func (c *Connection) ConsumeQuery(n int) {
+	if n <= 0 {
+		return
+	}
+	if n >= len(c.QueryBuf) {
+		c.QueryBuf = c.QueryBuf[:0]
+		c.QueryPos = 0
+		return
+	}
+	remaining := copy(c.QueryBuf, c.QueryBuf[n:])
+	c.QueryBuf = c.QueryBuf[:remaining]
+	c.QueryPos -= n
+}
+
+// AddReply queues a reply for the client and flags it for closing
+// when its output buffer goes over the limit of its class
+func (c *Connection) AddReply(reply []byte) {
+	if c.CloseASAP {
+		return // Nothing more is sent to a client about to be disconnected
+	}
+	c.outBuf = append(c.outBuf, reply...)
+	if c.outputLimitReached(time.Now().UnixMilli()) {
+		c.CloseASAP = true
+	}
+}
+
+// AddFile queues the size first bytes of f after the replies queued so far. Only the
+// replies count against the output buffer limit, the file is read as the socket accepts
+// it, and closed once sent or by Release
+func (c *Connection) AddFile(f *os.File, size int64) {
+	if c.CloseASAP {
+		f.Close()
+		return
+	}
+	c.file, c.fileAt, c.fileOffset, c.fileSize = f, len(c.outBuf), 0, size
+}
+
+// Release closes the file still being sent to a client being disconnected
+func (c *Connection) Release() {
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+	}
+}
+
+// PendingOutput returns the number of reply and file bytes not written yet
+func (c *Connection) PendingOutput() int {
+	pending := len(c.outBuf) - c.outPos
+	if c.file != nil {
+		pending += int(c.fileSize - c.fileOffset)
+	}
+	return pending
+}
+
+// WriteOutput writes as much of the pending output as the socket accepts without blocking
+func (c *Connection) WriteOutput() error {
+	for c.PendingOutput() > 0 {
+		if c.file != nil && c.outPos == c.fileAt {
+			if err := c.writeFile(); err != nil {
+				if err == syscall.EAGAIN {
+					break
+				}
+				return err
+			}
+			continue
+		}
+
+		end := len(c.outBuf)
+		if c.file != nil {
+			end = c.fileAt
+		}
+		n, err := syscall.Write(c.Fd, c.outBuf[c.outPos:end])
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			if err == syscall.EAGAIN {
+				break // Socket buffer is full, wait for EPOLLOUT
+			}
+			return err
+		}
+		c.outPos += n
+	}
+
+	if c.PendingOutput() == 0 {
+		c.outBuf = c.outBuf[:0]
+		c.outPos = 0
+		c.softLimitReachedAt = 0
+	} else if c.outPos > len(c.outBuf)/2 {
+		// Reclaim the written half so the buffer does not grow forever
+		remaining := copy(c.outBuf, c.outBuf[c.outPos:])
+		c.outBuf = c.outBuf[:remaining]
+		c.fileAt -= c.outPos
+		c.outPos = 0
+	}
+	return nil
+}
+
+// writeFile sends the rest of the queued file with sendfile, and closes it once sent
+func (c *Connection) writeFile() error {
+	for c.fileOffset < c.fileSize {
+		n, err := syscall.Sendfile(c.Fd, int(c.file.Fd()), &c.fileOffset, int(min(c.fileSize-c.fileOffset, 1<<30)))
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrUnexpectedEOF // The file is shorter than queued
+		}
+	}
+	c.Release()
+	return nil
+}
+
+func (c *Connection) outputBufferLimit() config.OutputBufferLimit {
+	switch c.Class {
+	case ClassReplica:
+		return config.ReplicaOutputBufferLimit
+	case ClassPubSub:
+		return config.PubSubOutputBufferLimit
+	default:
+		return config.NormalOutputBufferLimit
+	}
+}
+
+// outputLimitReached reports whether the pending output breaks the hard limit,
+// or has stayed above the soft limit for longer than allowed
+func (c *Connection) outputLimitReached(nowMs int64) bool {
+	limit := c.outputBufferLimit()
+	used := len(c.outBuf) - c.outPos
+
+	if limit.HardBytes > 0 && used >= limit.HardBytes {
+		return true
+	}
+
+	if limit.SoftBytes == 0 || used < limit.SoftBytes {
+		c.softLimitReachedAt = 0
+		return false
+	}
+	if c.softLimitReachedAt == 0 {
+		c.softLimitReachedAt = nowMs
+		return false
+	}
+	return nowMs-c.softLimitReachedAt >= int64(limit.SoftSeconds)*1000
+}
@@ -0,0 +1,160 @@
+package connection
+
+import (
+	"bytes"
+	"os"
+	"redis-repo/internal/config"
+	"syscall"
+	"testing"
+)
+
+func newSocketPair(t *testing.T) (int, int) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("socketpair failed: %v", err)
+	}
+	if err := syscall.SetNonblock(fds[0], true); err != nil {
+		t.Fatalf("set non-blocking failed: %v", err)
+	}
+	t.Cleanup(func() {
+		syscall.Close(fds[0])
+		syscall.Close(fds[1])
+	})
+	return fds[0], fds[1]
+}
+
+func TestWriteOutputKeepsUnsentBytes(t *testing.T) {
+	serverFd, peerFd := newSocketPair(t)
+	conn := NewConnection(serverFd)
+
+	reply := bytes.Repeat([]byte("x"), 4*1024*1024)
+	conn.AddReply(reply)
+	if err := conn.WriteOutput(); err != nil {
+		t.Fatalf("WriteOutput failed: %v", err)
+	}
+	if conn.PendingOutput() == 0 {
+		t.Fatalf("Expected a short write to leave pending output")
+	}
+
+	received := 0
+	buf := make([]byte, 64*1024)
+	for received < len(reply) {
+		n, err := syscall.Read(peerFd, buf)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		received += n
+		if err := conn.WriteOutput(); err != nil {
+			t.Fatalf("WriteOutput failed: %v", err)
+		}
+	}
+
+	if conn.PendingOutput() != 0 {
+		t.Errorf("Expected output to be drained, %d bytes pending", conn.PendingOutput())
+	}
+	if received != len(reply) {
+		t.Errorf("Expected %d bytes received, got %d", len(reply), received)
+	}
+}
+
+// Test that a queued file is sent between the replies queued before and after it, and does
+// not count against the output buffer limit
+func TestAddFile(t *testing.T) {
+	serverFd, peerFd := newSocketPair(t)
+	conn := NewConnection(serverFd)
+	conn.Class = ClassReplica
+
+	f, err := os.CreateTemp(t.TempDir(), "file")
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	content := bytes.Repeat([]byte("f"), 1024*1024)
+	f.Write(content)
+
+	conn.AddReply([]byte("before"))
+	conn.AddFile(f, int64(len(content)))
+	conn.AddReply([]byte("after"))
+	if conn.CloseASAP || conn.PendingOutput() != len(content)+11 {
+		t.Fatalf("Expected %d bytes pending within the limit, got %d", len(content)+11, conn.PendingOutput())
+	}
+
+	var received []byte
+	buf := make([]byte, 64*1024)
+	for conn.PendingOutput() > 0 || len(received) < len(content)+11 {
+		if err := conn.WriteOutput(); err != nil {
+			t.Fatalf("WriteOutput failed: %v", err)
+		}
+		n, err := syscall.Read(peerFd, buf)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		received = append(received, buf[:n]...)
+	}
+	expected := append(append([]byte("before"), content...), "after"...)
+	if !bytes.Equal(received, expected) || conn.file != nil {
+		t.Errorf("Expected the file between the replies and closed, got %d bytes", len(received))
+	}
+
+	big, err := os.CreateTemp(t.TempDir(), "big")
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	big.Truncate(int64(config.ReplicaOutputBufferLimit.HardBytes) * 2)
+	conn.AddFile(big, int64(config.ReplicaOutputBufferLimit.HardBytes)*2)
+	conn.AddReply([]byte("command"))
+	if conn.CloseASAP {
+		t.Errorf("Expected a file over the hard limit not to close the client")
+	}
+	conn.Release()
+}
+
+func TestOutputBufferLimits(t *testing.T) {
+	original := config.PubSubOutputBufferLimit
+	t.Cleanup(func() { config.PubSubOutputBufferLimit = original })
+	config.PubSubOutputBufferLimit = config.OutputBufferLimit{HardBytes: 100, SoftBytes: 10, SoftSeconds: 60}
+
+	t.Run("normal clients are unlimited by default", func(t *testing.T) {
+		conn := NewConnection(-1)
+		conn.AddReply(bytes.Repeat([]byte("x"), 1024*1024))
+		if conn.CloseASAP {
+			t.Errorf("Expected normal client to stay connected")
+		}
+	})
+
+	t.Run("hard limit closes immediately", func(t *testing.T) {
+		conn := NewConnection(-1)
+		conn.Class = ClassPubSub
+		conn.AddReply(bytes.Repeat([]byte("x"), 100))
+		if !conn.CloseASAP {
+			t.Errorf("Expected client over the hard limit to be closed")
+		}
+	})
+
+	t.Run("soft limit closes after the grace period", func(t *testing.T) {
+		conn := NewConnection(-1)
+		conn.Class = ClassPubSub
+		conn.outBuf = bytes.Repeat([]byte("x"), 20)
+
+		if conn.outputLimitReached(1000) {
+			t.Fatalf("Expected the first soft limit breach to be tolerated")
+		}
+		if conn.outputLimitReached(1000 + 59*1000) {
+			t.Fatalf("Expected the client to be tolerated within the grace period")
+		}
+		if !conn.outputLimitReached(1000 + 60*1000) {
+			t.Errorf("Expected the client to be closed after the grace period")
+		}
+	})
+
+	t.Run("dropping below the soft limit resets the timer", func(t *testing.T) {
+		conn := NewConnection(-1)
+		conn.Class = ClassPubSub
+		conn.outBuf = bytes.Repeat([]byte("x"), 20)
+		conn.outputLimitReached(1000)
+
+		conn.outPos = 15
+		if conn.outputLimitReached(2000) || conn.softLimitReachedAt != 0 {
+			t.Fatalf("Expected the soft limit timer to be reset")
+		}
+	})
+}
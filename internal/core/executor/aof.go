@@ -0,0 +1,476 @@
+package executor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"redis-repo/internal/aof"
+	"redis-repo/internal/config"
+	"redis-repo/internal/core/command"
+	"redis-repo/internal/rdb"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policies of appendfsync
+const (
+	fsyncAlways   = "always"   // Sync after every write, before replying
+	fsyncEverySec = "everysec" // Sync once a second in another goroutine
+	fsyncNo       = "no"       // Let the operating system sync
+)
+
+var fsyncPolicies = []string{fsyncAlways, fsyncEverySec, fsyncNo}
+
+// loading is set while the append only file is replayed, so the commands accept expiry
+// times that passed since they were written
+var loading bool
+
+// aofFile is the append only file being written, nil when appendonly is off
+var aofFile *appendOnlyFile
+
+// appendOnlyFile appends the commands propagated by propagate to the last incremental file
+// of the manifest. They are buffered until FlushAppendOnlyFile, as Redis's aof_buf
+type appendOnlyFile struct {
+	dir        string // Directory of the files, kept when dir changes
+	manifest   *aof.Manifest
+	incr       *os.File
+	buf        []byte
+	selectedDB int // Database of the last command appended, -1 to select it first
+	unsynced   bool
+	lastFsync  time.Time
+	fsyncing   chan error // Result of the background fsync in progress, nil for none
+	writeErr   error      // Error of the last write, the write commands are refused meanwhile
+	size       int64      // Bytes of the files of the manifest
+	baseSize   int64      // Value of size after the last rewrite or the startup, see auto-aof-rewrite-percentage
+}
+
+// newAppendOnlyFile appends the commands to incr, the last incremental file of m
+func newAppendOnlyFile(dir string, m *aof.Manifest, incr *os.File) *appendOnlyFile {
+	f := &appendOnlyFile{dir: dir, manifest: m, incr: incr, selectedDB: -1, lastFsync: time.Now()}
+	f.size = manifestSize(dir, m)
+	f.baseSize = f.size
+	return f
+}
+
+// aofDir returns the directory of the append only files
+func aofDir() string {
+	return filepath.Join(config.Dir, config.AppendDirname)
+}
+
+// A write command is appended to the AOF as it was sent, unless its handler calls
+// rewriteCommand, see call
+var (
+	commandRewritten  bool
+	rewrittenCommands [][]string
+)
+
+// rewriteCommand replaces the command being run by commands in the AOF, so replaying them
+// later gives the same result: relative expiry times become absolute ones, random choices
+// the elements chosen. Without commands, nothing is appended
+func rewriteCommand(commands ...[]string) {
+	commandRewritten = true
+	rewrittenCommands = commands
+}
+
+// propagate appends a write command run against database db to the append only file and
+// sends it to the replicas, selecting the database first when the previous command ran
+// against another one. The commands of the master are sent to the replicas as received,
+// see FeedMasterStream
+func propagate(db int, argv []string) {
+	if f := aofFile; f != nil {
+		if db != f.selectedDB {
+			f.buf = aof.AppendCommand(f.buf, []string{"SELECT", strconv.Itoa(db)})
+			f.selectedDB = db
+		}
+		f.buf = aof.AppendCommand(f.buf, argv)
+	}
+	if currentClient == nil || !currentClient.Master {
+		feedReplicas(db, argv)
+	}
+}
+
+// propagateExpired deletes a key found expired from the append only file and the
+// replicas, so a command run later against it has the same result everywhere. A replica
+// leaves it to the DEL of its master
+func propagateExpired(db int, key string) {
+	if masterHost == "" {
+		propagate(db, []string{"DEL", key})
+	}
+}
+
+// propagateExpiredFields deletes the fields of a hash found expired from the append only
+// file and the replicas, as propagateExpired does for keys. The HDEL of the last field
+// deletes the key
+func propagateExpiredFields(db int, key string, fields []string) {
+	if masterHost == "" {
+		propagate(db, append([]string{"HDEL", key}, fields...))
+	}
+}
+
+// FlushAppendOnlyFile writes the commands propagated since the last call, before the
+// replies of their clients are sent, and syncs the file following appendfsync. With
+// everysec, the file is synced at most once a second, so the cron calls it too
+func FlushAppendOnlyFile() {
+	if aofFile != nil {
+		aofFile.flush()
+	}
+}
+
+func (f *appendOnlyFile) flush() {
+	if f.fsyncing != nil {
+		select {
+		case err := <-f.fsyncing:
+			f.fsyncing = nil
+			if err != nil {
+				log.Println("Error syncing the AOF file:", err)
+			}
+		default:
+		}
+	}
+
+	if len(f.buf) > 0 {
+		n, err := f.incr.Write(f.buf)
+		f.buf = append(f.buf[:0], f.buf[n:]...)
+		f.unsynced = f.unsynced || n > 0
+		f.size += int64(n)
+		if err != nil {
+			if config.AppendFsync == fsyncAlways {
+				log.Fatal("Can't recover from AOF write error when the AOF fsync policy is 'always': ", err)
+			}
+			if f.writeErr == nil {
+				log.Println("Error writing to the AOF file:", err)
+			}
+			f.writeErr = err
+			return
+		}
+		if f.writeErr != nil {
+			log.Println("AOF write error looks solved, the write commands are accepted again")
+			f.writeErr = nil
+		}
+	}
+
+	if !f.unsynced {
+		return
+	}
+	switch config.AppendFsync {
+	case fsyncAlways:
+		if err := f.incr.Sync(); err != nil {
+			log.Fatal("Can't recover from AOF fsync error when the AOF fsync policy is 'always': ", err)
+		}
+		f.unsynced, f.lastFsync = false, time.Now()
+	case fsyncEverySec:
+		if f.fsyncing == nil && time.Since(f.lastFsync) >= time.Second {
+			done, file := make(chan error, 1), f.incr
+			go func() {
+				done <- file.Sync()
+			}()
+			f.fsyncing, f.unsynced, f.lastFsync = done, false, time.Now()
+		}
+	}
+}
+
+// close writes and syncs the pending commands, then closes the incremental file
+func (f *appendOnlyFile) close() error {
+	f.flush()
+	err := f.writeErr
+	if closeErr := f.closeIncr(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// closeIncr syncs and closes the incremental file, once the background fsync is done
+func (f *appendOnlyFile) closeIncr() error {
+	if f.fsyncing != nil {
+		<-f.fsyncing
+		f.fsyncing = nil
+	}
+	err := f.incr.Sync()
+	if closeErr := f.incr.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openNextIncr appends the commands to a new incremental file from now on, as the files
+// of the manifest are being rewritten. It returns the sequence number of the new file
+func (f *appendOnlyFile) openNextIncr() (int64, error) {
+	if f.flush(); f.writeErr != nil {
+		return 0, f.writeErr
+	}
+	seq := f.manifest.NextSeq(aof.TypeIncr)
+	file, err := openIncrFile(f.dir, f.manifest, seq)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.closeIncr(); err != nil {
+		log.Println("Error closing the AOF file:", err)
+	}
+	f.incr, f.selectedDB, f.unsynced = file, -1, false
+	return seq, nil
+}
+
+// startAppendOnly turns the append only file on: a base file is written with the
+// databases, in the RDB format, then the commands are appended to a new incremental file.
+// The files of a previous append only file are replaced
+func startAppendOnly() error {
+	waitBackgroundJobs() // A database has one snapshot at a time
+	dir := aofDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	previous, err := readManifest(dir)
+	if err != nil {
+		previous = &aof.Manifest{}
+	}
+
+	m := &aof.Manifest{}
+	seq := previous.NextSeq(aof.TypeBase)
+	m.Base = &aof.File{Name: aof.BaseFileName(config.AppendFilename, seq, true), Seq: seq, Type: aof.TypeBase}
+	dbs, snapshot := snapshotDatabases()
+	err = writeRDB(filepath.Join(dir, m.Base.Name), snapshot, usedMemory(), true)
+	releaseSnapshot(dbs)
+	if err != nil {
+		return err
+	}
+
+	incr, err := openIncrFile(dir, m, previous.NextSeq(aof.TypeIncr))
+	if err != nil {
+		return err
+	}
+	removeAppendOnlyFiles(dir, previous, m)
+	aofFile = newAppendOnlyFile(dir, m, incr)
+	log.Println("Append only file enabled in", dir)
+	return nil
+}
+
+// stopAppendOnly turns the append only file off, its files are kept
+func stopAppendOnly() error {
+	waitAppendOnlyRewrite()
+	f := aofFile
+	aofFile = nil
+	return f.close()
+}
+
+// openIncrFile creates the incremental file seq, adds it to the manifest and writes the
+// manifest, so the commands are appended to it from now on
+func openIncrFile(dir string, m *aof.Manifest, seq int64) (*os.File, error) {
+	incr := aof.File{Name: aof.IncrFileName(config.AppendFilename, seq), Seq: seq, Type: aof.TypeIncr}
+	file, err := os.OpenFile(filepath.Join(dir, incr.Name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	m.Incrs = append(m.Incrs, incr)
+	if err := writeManifest(dir, m); err != nil {
+		file.Close()
+		m.Incrs = m.Incrs[:len(m.Incrs)-1]
+		return nil, err
+	}
+	return file, nil
+}
+
+// removeAppendOnlyFiles removes the files of previous, and its history, that m does not
+// list anymore
+func removeAppendOnlyFiles(dir string, previous, m *aof.Manifest) {
+	kept := map[string]bool{}
+	for _, f := range m.Files() {
+		kept[f.Name] = true
+	}
+	for _, f := range append(previous.Files(), previous.History...) {
+		if !kept[f.Name] {
+			os.Remove(filepath.Join(dir, f.Name))
+		}
+	}
+}
+
+// manifestSize returns the bytes of the files of m
+func manifestSize(dir string, m *aof.Manifest) int64 {
+	var size int64
+	for _, f := range m.Files() {
+		if info, err := os.Stat(filepath.Join(dir, f.Name)); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// readManifest reads the manifest of dir, an error satisfying os.ErrNotExist without one
+func readManifest(dir string) (*aof.Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, aof.ManifestFileName(config.AppendFilename)))
+	if err != nil {
+		return nil, err
+	}
+	return aof.ParseManifest(data)
+}
+
+// writeManifest replaces the manifest of dir, through a temporary file so a crash
+// leaves either the previous or the new one
+func writeManifest(dir string, m *aof.Manifest) error {
+	path := filepath.Join(dir, aof.ManifestFileName(config.AppendFilename))
+	tmp := filepath.Join(dir, "temp-"+aof.ManifestFileName(config.AppendFilename))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp) // No-op once renamed
+
+	_, err = f.Write(m.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err == nil {
+		err = syncDir(dir)
+	}
+	return err
+}
+
+// syncDir syncs a directory, so the files renamed in it survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// LoadDataFromDisk loads the databases at startup: from the append only file when
+// appendonly is set, otherwise from the snapshot. Without an append only file yet, the
+// snapshot is loaded and used as its base
+func LoadDataFromDisk() error {
+	if !config.AppendOnly {
+		return loadRDB()
+	}
+
+	dir := aofDir()
+	m, err := readManifest(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := loadRDB(); err != nil {
+			return err
+		}
+		return startAppendOnly()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", aof.ManifestFileName(config.AppendFilename), err)
+	}
+
+	start := time.Now()
+	if err := loadAppendOnlyFiles(dir, m); err != nil {
+		return err
+	}
+	log.Printf("DB loaded from append only file: %.3f seconds", time.Since(start).Seconds())
+
+	// Like Redis, the commands are appended to the last incremental file
+	var incr *os.File
+	if len(m.Incrs) == 0 {
+		incr, err = openIncrFile(dir, m, m.NextSeq(aof.TypeIncr))
+	} else {
+		last := m.Incrs[len(m.Incrs)-1]
+		incr, err = os.OpenFile(filepath.Join(dir, last.Name), os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+	if err != nil {
+		return err
+	}
+	aofFile = newAppendOnlyFile(dir, m, incr)
+	return nil
+}
+
+// loadAppendOnlyFiles replays the files of the manifest in order
+func loadAppendOnlyFiles(dir string, m *aof.Manifest) error {
+	loading = true
+	defer func() {
+		loading = false
+		selectDB(0)
+		dirty = 0
+	}()
+
+	files := m.Files()
+	for i, f := range files {
+		if err := loadAppendOnlyFile(filepath.Join(dir, f.Name), i == len(files)-1); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// loadAppendOnlyFile replays a file: an RDB file, or commands following an optional RDB
+// preamble. The commands between MULTI and EXEC are run once EXEC is read. When the last
+// file ends in the middle of a command or of a transaction, it is loaded and truncated up
+// to there if aof-load-truncated is set
+func loadAppendOnlyFile(path string, last bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	if bytes.HasPrefix(data, []byte("REDIS")) {
+		d := rdb.NewDecoder(bytes.NewReader(data))
+		if err := d.Decode(loadSnapshotEntry); err != nil {
+			return fmt.Errorf("RDB at offset %d: %w", d.Offset(), err)
+		}
+		offset = int(d.Offset())
+	}
+
+	var transaction [][]string // Commands queued since MULTI, nil outside of a transaction
+	multiOffset := 0
+	valid, err := aof.DecodeCommands(data[offset:], func(argv []string, at int) error {
+		switch strings.ToUpper(argv[0]) {
+		case "MULTI":
+			transaction, multiOffset = [][]string{}, offset+at
+			return nil
+		case "EXEC":
+			for _, queued := range transaction {
+				if err := replayCommand(queued); err != nil {
+					return err
+				}
+			}
+			transaction = nil
+			return nil
+		}
+		if transaction != nil {
+			transaction = append(transaction, argv)
+			return nil
+		}
+		return replayCommand(argv)
+	})
+	valid += offset
+	if transaction != nil && (err == nil || errors.Is(err, aof.ErrTruncated)) {
+		valid, err = multiOffset, aof.ErrTruncated // The EXEC is missing, the transaction is discarded
+	}
+	if !errors.Is(err, aof.ErrTruncated) {
+		if err != nil {
+			return fmt.Errorf("bad file format at offset %d: %w", valid, err)
+		}
+		return nil
+	}
+
+	if !last || !config.AOFLoadTruncated {
+		return fmt.Errorf("unexpected end of file at offset %d, use redis-check-aof --fix or set aof-load-truncated to yes", valid)
+	}
+	log.Printf("!!! Warning: short read while loading the AOF file %s !!!", path)
+	log.Printf("AOF %s loaded anyway because aof-load-truncated is enabled, truncated to %d bytes", path, valid)
+	return os.Truncate(path, int64(valid))
+}
+
+// replayCommand runs a command read from the append only file. Its reply is dropped:
+// like Redis, a command failing is not an error, but an unknown command is
+func replayCommand(argv []string) error {
+	cmd := &command.Command{Cmd: strings.ToUpper(argv[0]), Args: argv[1:]}
+	spec, args, errReply := resolveCommand(cmd)
+	if errReply != nil {
+		return fmt.Errorf("invalid command '%s': %s", argv[0], strings.TrimSpace(string(errReply)))
+	}
+	call(spec, cmd, args)
+	return nil
+}
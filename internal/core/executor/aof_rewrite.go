@@ -0,0 +1,190 @@
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"redis-repo/internal/aof"
+	"redis-repo/internal/config"
+	"redis-repo/internal/data_structure"
+	"time"
+)
+
+// Rewrite state. A rewrite and a background save both hold a snapshot of the databases,
+// so one waits for the other, as Redis runs one child process at a time
+var (
+	aofRewriteInFlight  *appendOnlyRewrite
+	aofRewriteScheduled bool // BGREWRITEAOF was called during a background save
+	lastAOFRewriteTry   int64
+	lastAOFRewriteOK    = true
+)
+
+// appendOnlyRewrite is a base file being written by another goroutine from a snapshot of
+// the databases. The commands run meanwhile are appended to the incremental file opened
+// when it started, the first one kept once the new base file is in the manifest
+type appendOnlyRewrite struct {
+	dbs      []*data_structure.Dict
+	dir      string
+	base     aof.File
+	firstSeq int64 // Sequence number of the first incremental file kept, 0 with appendonly off
+	done     chan error
+}
+
+// rewriteAppendOnlyFileBackground starts rewriting the append only file: the databases are
+// written as a new base file in another goroutine, replacing the files of the manifest
+// once done. With appendonly off, only the base file is written
+func rewriteAppendOnlyFileBackground() error {
+	if aofRewriteInFlight != nil || bgsaveInFlight != nil {
+		return errors.New("background job already in progress")
+	}
+	lastAOFRewriteTry = time.Now().Unix()
+	aofRewriteScheduled = false
+
+	rw := &appendOnlyRewrite{done: make(chan error, 1)}
+	var m *aof.Manifest
+	if aofFile != nil {
+		seq, err := aofFile.openNextIncr()
+		if err != nil {
+			log.Println("Can't open a new AOF file for the rewrite:", err)
+			lastAOFRewriteOK = false
+			return err
+		}
+		rw.dir, m, rw.firstSeq = aofFile.dir, aofFile.manifest, seq
+	} else {
+		rw.dir = aofDir()
+		if err := os.MkdirAll(rw.dir, 0o755); err != nil {
+			lastAOFRewriteOK = false
+			return err
+		}
+		var err error
+		if m, err = readManifest(rw.dir); err != nil {
+			m = &aof.Manifest{}
+		}
+	}
+	seq := m.NextSeq(aof.TypeBase)
+	rw.base = aof.File{Name: aof.BaseFileName(config.AppendFilename, seq, config.AOFUseRDBPreamble), Seq: seq, Type: aof.TypeBase}
+
+	dbs, snapshot := snapshotDatabases()
+	rw.dbs = dbs
+	path, usedMem, preamble := filepath.Join(rw.dir, rw.base.Name), usedMemory(), config.AOFUseRDBPreamble
+	go func() {
+		if preamble {
+			rw.done <- writeRDB(path, snapshot, usedMem, true)
+		} else {
+			rw.done <- writeAppendOnlyCommands(path, snapshot)
+		}
+	}()
+	aofRewriteInFlight = rw
+	log.Println("Background append only file rewriting started")
+	return nil
+}
+
+// writeAppendOnlyCommands writes the snapshot to path as the commands creating its keys
+func writeAppendOnlyCommands(path string, snapshot [][]data_structure.SnapshotEntry) error {
+	now := uint64(time.Now().UnixMilli())
+	return writeFileAtomically(path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		for id, entries := range snapshot {
+			if err := aof.WriteDB(bw, id, entries, now); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	})
+}
+
+// waitAppendOnlyRewrite blocks until the rewrite in progress, if any, is done
+func waitAppendOnlyRewrite() {
+	if aofRewriteInFlight != nil {
+		finishAppendOnlyRewrite(<-aofRewriteInFlight.done)
+	}
+}
+
+// waitBackgroundJobs blocks until the background save and the rewrite are done
+func waitBackgroundJobs() {
+	waitBackgroundSave()
+	waitAppendOnlyRewrite()
+}
+
+// finishAppendOnlyRewrite puts the new base file in the manifest, followed by the
+// incremental files opened since the rewrite started, and removes the previous files
+func finishAppendOnlyRewrite(err error) {
+	rw := aofRewriteInFlight
+	aofRewriteInFlight = nil
+	releaseSnapshot(rw.dbs)
+	if err == nil {
+		err = installRewrittenBase(rw)
+	}
+	lastAOFRewriteOK = err == nil
+	if err != nil {
+		log.Println("Background AOF rewrite failed:", err)
+		return
+	}
+	log.Println("Background AOF rewrite finished successfully")
+}
+
+func installRewrittenBase(rw *appendOnlyRewrite) error {
+	var previous *aof.Manifest
+	if aofFile != nil {
+		previous = aofFile.manifest
+	} else if m, err := readManifest(rw.dir); err == nil {
+		previous = m
+	} else {
+		previous = &aof.Manifest{}
+	}
+
+	m := &aof.Manifest{Base: &rw.base}
+	for _, incr := range previous.Incrs {
+		if rw.firstSeq != 0 && incr.Seq >= rw.firstSeq {
+			m.Incrs = append(m.Incrs, incr)
+		}
+	}
+	if err := writeManifest(rw.dir, m); err != nil {
+		os.Remove(filepath.Join(rw.dir, rw.base.Name))
+		return err
+	}
+	removeAppendOnlyFiles(rw.dir, previous, m)
+	if aofFile != nil {
+		aofFile.manifest = m
+		aofFile.size = manifestSize(rw.dir, m)
+		aofFile.baseSize = aofFile.size
+	}
+	return nil
+}
+
+// CheckAppendOnlyRewrite completes the rewrite once its goroutine is done, and starts one
+// when it was scheduled or when the files grew by auto-aof-rewrite-percentage, as
+// Redis's serverCron
+func CheckAppendOnlyRewrite() {
+	if aofRewriteInFlight != nil {
+		select {
+		case err := <-aofRewriteInFlight.done:
+			finishAppendOnlyRewrite(err)
+		default:
+		}
+		return
+	}
+	if bgsaveInFlight != nil {
+		return
+	}
+	if aofRewriteScheduled {
+		rewriteAppendOnlyFileBackground()
+		return
+	}
+
+	f := aofFile
+	if f == nil || config.AutoAOFRewritePercentage == 0 || f.size < config.AutoAOFRewriteMinSize {
+		return
+	}
+	if !lastAOFRewriteOK && time.Now().Unix()-lastAOFRewriteTry <= bgsaveRetryDelay {
+		return
+	}
+	base := max(f.baseSize, 1)
+	if growth := (f.size*100)/base - 100; growth >= int64(config.AutoAOFRewritePercentage) {
+		log.Printf("Starting automatic rewriting of AOF on %d%% growth", growth)
+		rewriteAppendOnlyFileBackground()
+	}
+}
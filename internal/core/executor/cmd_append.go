@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"redis-repo/internal/config"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+)
+
+// cmdAPPEND handles APPEND key value, creating the key when it does not exist
+func cmdAPPEND(args []string) []byte {
+	key, suffix := args[0], args[1]
+
+	obj := lookupKeyWrite(key)
+	if obj == nil {
+		dict.Set(key, suffix, 0)
+		return resp.Encode(len(suffix))
+	}
+	if obj.Type != data_structure.ObjString {
+		return []byte(constant.ErrWrongType)
+	}
+
+	value := obj.StringValue()
+	if len(value)+len(suffix) > config.ProtoMaxBulkLen {
+		return []byte(constant.ErrStringTooLong)
+	}
+	value += suffix
+	dict.SetDictStore(key, value)
+	return resp.Encode(len(value))
+}
+
+// cmdSTRLEN handles STRLEN key, 0 when the key does not exist
+func cmdSTRLEN(args []string) []byte {
+	value, _, ok := getString(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	return resp.Encode(len(value))
+}
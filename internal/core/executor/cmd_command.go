@@ -0,0 +1,192 @@
+package executor
+
+import (
+	"errors"
+	"redis-repo/internal/core/resp"
+	"sort"
+	"strings"
+)
+
+// cmdCOMMAND handles COMMAND, returning the details of every registered command
+func cmdCOMMAND(args []string) []byte {
+	specs := sortedCommandSpecs()
+	res := make([]any, 0, len(specs))
+	for _, spec := range specs {
+		res = append(res, commandInfo(spec))
+	}
+	return resp.Encode(res)
+}
+
+// cmdCOMMANDCOUNT handles COMMAND COUNT
+func cmdCOMMANDCOUNT(args []string) []byte {
+	return resp.Encode(len(commandTable))
+}
+
+// cmdCOMMANDINFO handles COMMAND INFO [command-name ...]
+func cmdCOMMANDINFO(args []string) []byte {
+	if len(args) == 0 {
+		return cmdCOMMAND(args)
+	}
+
+	res := make([]any, len(args))
+	for i, name := range args {
+		if spec := findCommandSpec(name); spec != nil {
+			res[i] = commandInfo(spec)
+		} // Unknown commands are reported as nil
+	}
+	return resp.Encode(res)
+}
+
+// cmdCOMMANDDOCS handles COMMAND DOCS [command-name ...]
+func cmdCOMMANDDOCS(args []string) []byte {
+	var specs []*commandSpec
+	if len(args) == 0 {
+		specs = sortedCommandSpecs()
+	} else {
+		for _, name := range args {
+			if spec := findCommandSpec(name); spec != nil {
+				specs = append(specs, spec) // Unknown commands are skipped
+			}
+		}
+	}
+
+	res := make([]any, 0, 2*len(specs))
+	for _, spec := range specs {
+		res = append(res, spec.Name, commandDocs(spec))
+	}
+	return resp.Encode(res)
+}
+
+// cmdCOMMANDGETKEYS handles COMMAND GETKEYS command [arg ...]
+func cmdCOMMANDGETKEYS(args []string) []byte {
+	spec, errReply := lookupCommand(args[0], args[1:])
+	if errReply != nil {
+		return resp.Encode(errors.New("ERR Invalid command specified"))
+	}
+	if !spec.arityMatches(len(args)) {
+		return resp.Encode(errors.New("ERR Invalid number of arguments specified for command"))
+	}
+
+	positions := spec.getKeyPositions(args)
+	if len(positions) == 0 {
+		return resp.Encode(errors.New("ERR The command has no key arguments"))
+	}
+
+	keys := make([]any, len(positions))
+	for i, pos := range positions {
+		keys[i] = args[pos]
+	}
+	return resp.Encode(keys)
+}
+
+// findCommandSpec finds a command by name, subcommands are named like "command|info"
+func findCommandSpec(name string) *commandSpec {
+	containerName, _, isSub := strings.Cut(name, "|")
+	spec, ok := commandTable[strings.ToUpper(containerName)]
+	if !ok {
+		return nil
+	}
+	if !isSub {
+		return spec
+	}
+	for _, sub := range spec.Subcommands {
+		if strings.EqualFold(sub.Name, name) {
+			return sub
+		}
+	}
+	return nil
+}
+
+func sortedCommandSpecs() []*commandSpec {
+	specs := make([]*commandSpec, 0, len(commandTable))
+	for _, spec := range commandTable {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+// commandInfo builds the Redis 7 COMMAND INFO entry of a command: name, arity, flags,
+// first key, last key, step, ACL categories, tips, key specifications and subcommands
+func commandInfo(spec *commandSpec) []any {
+	flags := make([]any, 0)
+	for _, f := range flagNames {
+		if spec.Flags&f.flag != 0 {
+			flags = append(flags, resp.SimpleString(f.name))
+		}
+	}
+
+	subcommands := make([]any, 0, len(spec.Subcommands))
+	for _, sub := range spec.Subcommands {
+		subcommands = append(subcommands, commandInfo(sub))
+	}
+
+	return []any{
+		spec.Name,
+		spec.Arity,
+		flags,
+		spec.FirstKey,
+		spec.LastKey,
+		spec.Step,
+		aclCategories(spec),
+		[]any{},
+		[]any{},
+		subcommands,
+	}
+}
+
+// aclCategories derives the ACL categories of a command from its flags and group
+func aclCategories(spec *commandSpec) []any {
+	categories := make([]any, 0)
+	add := func(category string) {
+		categories = append(categories, resp.SimpleString("@"+category))
+	}
+
+	if spec.Flags&flagWrite != 0 {
+		add("write")
+	}
+	if spec.Flags&flagReadonly != 0 {
+		add("read")
+	}
+	if spec.Flags&flagAdmin != 0 {
+		add("admin")
+		add("dangerous")
+	}
+	if spec.Flags&flagPubSub != 0 {
+		add("pubsub")
+	}
+	if spec.Flags&flagFast != 0 {
+		add("fast")
+	} else {
+		add("slow")
+	}
+
+	switch spec.Group {
+	case groupGeneric:
+		add("keyspace")
+	case groupString, groupList, groupSet, groupHash, groupConnection:
+		add(spec.Group)
+	case groupSortedSet:
+		add("sortedset")
+	}
+	return categories
+}
+
+// commandDocs builds the COMMAND DOCS map of a command, flattened for RESP2
+func commandDocs(spec *commandSpec) []any {
+	docs := []any{
+		"summary", spec.Summary,
+		"since", spec.Since,
+		"group", spec.Group,
+	}
+	if len(spec.Subcommands) > 0 {
+		subcommands := make([]any, 0, 2*len(spec.Subcommands))
+		for _, sub := range spec.Subcommands {
+			subcommands = append(subcommands, sub.Name, commandDocs(sub))
+		}
+		docs = append(docs, "subcommands", subcommands)
+	}
+	return docs
+}
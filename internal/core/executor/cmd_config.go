@@ -0,0 +1,477 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"redis-repo/internal/config"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/glob"
+	"strconv"
+	"strings"
+)
+
+// configParam is a parameter read by CONFIG GET and changed by CONFIG SET, backed by a
+// variable of the config package. set returns why a value is rejected. apply, when not
+// nil, puts the new value in effect once CONFIG SET set every parameter. An immutable
+// parameter is only set on the command line, before the server starts
+type configParam struct {
+	name      string
+	immutable bool
+	get       func() string
+	set       func(value string) error
+	apply     func() error
+}
+
+var configParams = []*configParam{
+	{
+		name: "maxmemory",
+		get:  func() string { return strconv.FormatInt(config.MaxMemory, 10) },
+		set: func(value string) error {
+			n, ok := parseMemory(value)
+			if !ok {
+				return errors.New("argument must be a memory value")
+			}
+			config.MaxMemory = n
+			return nil
+		},
+	},
+	{
+		name: "maxmemory-policy",
+		get:  func() string { return config.MaxMemoryPolicy },
+		set: func(value string) error {
+			for _, policy := range evictionPolicies {
+				if strings.EqualFold(value, policy) {
+					if policy != config.MaxMemoryPolicy {
+						evictionPool = evictionPool[:0] // The idle scores of another policy do not compare
+					}
+					config.MaxMemoryPolicy = policy
+					return nil
+				}
+			}
+			return fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(evictionPolicies, ", "))
+		},
+	},
+	{
+		name: "maxmemory-samples",
+		get:  func() string { return strconv.Itoa(config.MaxMemorySamples) },
+		set: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 64 {
+				return errors.New("argument must be between 1 and 64 inclusive")
+			}
+			config.MaxMemorySamples = n
+			return nil
+		},
+	},
+	{
+		name: "save",
+		get:  formatSaveParams,
+		set: func(value string) error {
+			params, ok := parseSaveParams(value)
+			if !ok {
+				return errors.New("Invalid save parameters")
+			}
+			config.SaveParams = params
+			return nil
+		},
+	},
+	{
+		name: "dir",
+		get: func() string {
+			if dir, err := filepath.Abs(config.Dir); err == nil {
+				return dir
+			}
+			return config.Dir
+		},
+		set: func(value string) error {
+			if info, err := os.Stat(value); err != nil {
+				return err
+			} else if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", value)
+			}
+			config.Dir = value
+			return nil
+		},
+	},
+	{
+		name: "dbfilename",
+		get:  func() string { return config.DBFilename },
+		set: func(value string) error {
+			if value == "" || filepath.Base(value) != value {
+				return errors.New("dbfilename can't be a path, just a filename")
+			}
+			config.DBFilename = value
+			return nil
+		},
+	},
+	{
+		name: "appendonly",
+		get:  func() string { return formatYesNo(config.AppendOnly) },
+		set: func(value string) (err error) {
+			config.AppendOnly, err = parseYesNo(value)
+			return err
+		},
+		apply: func() error {
+			if config.AppendOnly && aofFile == nil {
+				if err := startAppendOnly(); err != nil {
+					log.Println("Unable to turn on AOF:", err)
+					return errors.New("Unable to turn on AOF. Check server logs.")
+				}
+			} else if !config.AppendOnly && aofFile != nil {
+				if err := stopAppendOnly(); err != nil {
+					log.Println("Error closing the AOF file:", err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		name:      "appenddirname",
+		immutable: true,
+		get:       func() string { return config.AppendDirname },
+		set: func(value string) error {
+			if value == "" || filepath.Base(value) != value {
+				return errors.New("appenddirname can't be a path, just a dirname")
+			}
+			config.AppendDirname = value
+			return nil
+		},
+	},
+	{
+		name:      "appendfilename",
+		immutable: true,
+		get:       func() string { return config.AppendFilename },
+		set: func(value string) error {
+			if value == "" || filepath.Base(value) != value {
+				return errors.New("appendfilename can't be a path, just a filename")
+			}
+			config.AppendFilename = value
+			return nil
+		},
+	},
+	{
+		name: "appendfsync",
+		get:  func() string { return config.AppendFsync },
+		set: func(value string) error {
+			for _, policy := range fsyncPolicies {
+				if strings.EqualFold(value, policy) {
+					config.AppendFsync = policy
+					return nil
+				}
+			}
+			return fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(fsyncPolicies, ", "))
+		},
+	},
+	{
+		name: "aof-use-rdb-preamble",
+		get:  func() string { return formatYesNo(config.AOFUseRDBPreamble) },
+		set: func(value string) (err error) {
+			config.AOFUseRDBPreamble, err = parseYesNo(value)
+			return err
+		},
+	},
+	{
+		name: "auto-aof-rewrite-percentage",
+		get:  func() string { return strconv.Itoa(config.AutoAOFRewritePercentage) },
+		set: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return errors.New("argument must be between 0 and 2147483647 inclusive")
+			}
+			config.AutoAOFRewritePercentage = n
+			return nil
+		},
+	},
+	{
+		name: "auto-aof-rewrite-min-size",
+		get:  func() string { return strconv.FormatInt(config.AutoAOFRewriteMinSize, 10) },
+		set: func(value string) error {
+			n, ok := parseMemory(value)
+			if !ok {
+				return errors.New("argument must be a memory value")
+			}
+			config.AutoAOFRewriteMinSize = n
+			return nil
+		},
+	},
+	{
+		name: "aof-load-truncated",
+		get:  func() string { return formatYesNo(config.AOFLoadTruncated) },
+		set: func(value string) (err error) {
+			config.AOFLoadTruncated, err = parseYesNo(value)
+			return err
+		},
+	},
+	{
+		name:      "port",
+		immutable: true,
+		get:       func() string { return strconv.Itoa(config.Port) },
+		set: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > 65535 {
+				return errors.New("argument must be between 0 and 65535 inclusive")
+			}
+			config.Port = n
+			return nil
+		},
+	},
+	{
+		// Set at startup as "host port", REPLICAOF changes it afterwards
+		name:      "replicaof",
+		immutable: true,
+		get: func() string {
+			if masterHost == "" {
+				return ""
+			}
+			return masterHost + " " + strconv.Itoa(masterPort)
+		},
+		set: func(value string) error {
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				return errors.New("argument must be 'host port'")
+			}
+			port, err := strconv.Atoi(fields[1])
+			if err != nil || port < 0 || port > 65535 {
+				return errors.New("Invalid master port")
+			}
+			replicationSetMaster(fields[0], port)
+			return nil
+		},
+	},
+	{
+		name: "replica-read-only",
+		get:  func() string { return formatYesNo(config.ReplicaReadOnly) },
+		set: func(value string) (err error) {
+			config.ReplicaReadOnly, err = parseYesNo(value)
+			return err
+		},
+	},
+	{
+		name: "repl-backlog-size",
+		get:  func() string { return strconv.FormatInt(config.ReplBacklogSize, 10) },
+		set: func(value string) error {
+			n, ok := parseMemory(value)
+			if !ok || n < 1 {
+				return errors.New("argument must be a memory value")
+			}
+			config.ReplBacklogSize = n
+			return nil
+		},
+		apply: func() error {
+			if backlog != nil && int64(len(backlog.buf)) != config.ReplBacklogSize {
+				backlog.resize(config.ReplBacklogSize)
+			}
+			return nil
+		},
+	},
+	{
+		name: "repl-timeout",
+		get:  func() string { return strconv.Itoa(config.ReplTimeout) },
+		set: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return errors.New("argument must be between 1 and 2147483647 inclusive")
+			}
+			config.ReplTimeout = n
+			return nil
+		},
+	},
+	{
+		name: "repl-ping-replica-period",
+		get:  func() string { return strconv.Itoa(config.ReplPingReplicaPeriod) },
+		set: func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return errors.New("argument must be between 1 and 2147483647 inclusive")
+			}
+			config.ReplPingReplicaPeriod = n
+			return nil
+		},
+	},
+}
+
+func lookupConfigParam(name string) *configParam {
+	for _, param := range configParams {
+		if strings.EqualFold(param.name, name) {
+			return param
+		}
+	}
+	return nil
+}
+
+func formatYesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, errors.New("argument must be 'yes' or 'no'")
+}
+
+// formatSaveParams formats the save rules as Redis does: "seconds changes" pairs separated
+// by spaces, such as "3600 1 300 100"
+func formatSaveParams() string {
+	fields := make([]string, 0, 2*len(config.SaveParams))
+	for _, param := range config.SaveParams {
+		fields = append(fields, strconv.FormatInt(param.Seconds, 10), strconv.FormatInt(param.Changes, 10))
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseSaveParams parses save rules formatted as formatSaveParams, an empty string
+// disabling the saves
+func parseSaveParams(s string) ([]config.SaveParam, bool) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, false
+	}
+	params := []config.SaveParam{}
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err1 := strconv.ParseInt(fields[i], 10, 64)
+		changes, err2 := strconv.ParseInt(fields[i+1], 10, 64)
+		if err1 != nil || err2 != nil || seconds < 1 || changes < 0 {
+			return nil, false
+		}
+		params = append(params, config.SaveParam{Seconds: seconds, Changes: changes})
+	}
+	return params, true
+}
+
+// memoryUnits are the multipliers of the units accepted by parseMemory
+var memoryUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1000, "kb": 1024,
+	"m": 1000 * 1000, "mb": 1024 * 1024,
+	"g": 1000 * 1000 * 1000, "gb": 1024 * 1024 * 1024,
+}
+
+// parseMemory parses a number of bytes with an optional unit like Redis's memtoull:
+// 1k is 1000 bytes and 1kb 1024 bytes, the same for m, mb, g and gb
+func parseMemory(s string) (int64, bool) {
+	digits := strings.TrimRightFunc(s, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	mul, ok := memoryUnits[strings.ToLower(s[len(digits):])]
+	if !ok || digits == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n > (1<<63-1)/mul {
+		return 0, false
+	}
+	return n * mul, true
+}
+
+// cmdCONFIGGET handles CONFIG GET parameter [parameter ...], returning the name and value
+// of every parameter matching one of the glob-style patterns
+func cmdCONFIGGET(args []string) []byte {
+	reply := []any{}
+	for _, param := range configParams {
+		for _, pattern := range args {
+			if glob.MatchFold(pattern, param.name) {
+				reply = append(reply, param.name, param.get())
+				break
+			}
+		}
+	}
+	return resp.Encode(reply)
+}
+
+// cmdCONFIGSET handles CONFIG SET parameter value [parameter value ...]. Like Redis, the
+// parameters are set atomically: when a value is rejected, the previous values are restored
+func cmdCONFIGSET(args []string) []byte {
+	if len(args)%2 != 0 {
+		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "CONFIG|SET"))
+	}
+
+	params := make([]*configParam, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		param := lookupConfigParam(args[i])
+		if param == nil {
+			return resp.Encode(fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", args[i]))
+		}
+		if param.immutable {
+			return configSetError(args[i], errors.New("can't set immutable config"))
+		}
+		for _, seen := range params {
+			if seen == param {
+				return configSetError(args[i], errors.New("duplicate parameter"))
+			}
+		}
+		params = append(params, param)
+	}
+
+	previous := make([]string, len(params))
+	for i, param := range params {
+		previous[i] = param.get()
+	}
+	restore := func() {
+		for j := len(params) - 1; j >= 0; j-- {
+			params[j].set(previous[j])
+		}
+	}
+	for i, param := range params {
+		if err := param.set(args[2*i+1]); err != nil {
+			restore()
+			return configSetError(args[2*i], err)
+		}
+	}
+
+	// Like Redis, the previous values are applied again when a new one cannot be
+	for i, param := range params {
+		if param.apply == nil {
+			continue
+		}
+		if err := param.apply(); err != nil {
+			restore()
+			for _, param := range params[:i+1] {
+				if param.apply != nil {
+					param.apply()
+				}
+			}
+			return configSetError(args[2*i], err)
+		}
+	}
+	return []byte(constant.RespOk)
+}
+
+// SetConfigArgs sets the parameters given on the command line of the server, such as
+// "--appendonly yes --save 900 1 300 10": every argument following a name is part of its
+// value. Immutable parameters can be set, and the values are used as the server starts
+func SetConfigArgs(args []string) error {
+	for i := 0; i < len(args); {
+		if !strings.HasPrefix(args[i], "--") {
+			return fmt.Errorf("expected a parameter name, got '%s'", args[i])
+		}
+		param := lookupConfigParam(args[i][2:])
+		if param == nil {
+			return fmt.Errorf("unknown parameter '%s'", args[i][2:])
+		}
+
+		j := i + 1
+		for j < len(args) && !strings.HasPrefix(args[j], "--") {
+			j++
+		}
+		if err := param.set(strings.Join(args[i+1:j], " ")); err != nil {
+			return fmt.Errorf("invalid value for '%s': %v", param.name, err)
+		}
+		i = j
+	}
+	return nil
+}
+
+func configSetError(name string, err error) []byte {
+	return resp.Encode(fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %v", name, err))
+}
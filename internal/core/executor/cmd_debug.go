@@ -0,0 +1,24 @@
+package executor
+
+import (
+	"errors"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+)
+
+// cmdDEBUGHTSTATS handles DEBUG HTSTATS dbid [FULL], describing the hash tables of the
+// keyspace and of the expires: their sizes while being resized and, with FULL, the
+// chain lengths of their buckets
+func cmdDEBUGHTSTATS(args []string) []byte {
+	dbid, err := strconv.Atoi(args[0])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	if dbid < 0 || dbid >= len(databases) {
+		return resp.Encode(errors.New("ERR Out of range database"))
+	}
+	full := len(args) > 1 && strings.EqualFold(args[1], "FULL")
+	return resp.Encode(databases[dbid].Stats(full))
+}
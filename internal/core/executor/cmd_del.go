@@ -13,3 +13,17 @@ func cmdDEL(args []string) []byte {
 	}
 	return resp.Encode(count)
 }
+
+// cmdUNLINK handles UNLINK key [key ...]. The keys are removed right away like DEL, and
+// their large values are freed by another goroutine so the server does not walk them
+func cmdUNLINK(args []string) []byte {
+	count := 0
+	for _, key := range args {
+		obj, exist := dict.Unlink(key)
+		if exist {
+			freeObjectAsync(obj)
+			count++
+		}
+	}
+	return resp.Encode(count)
+}
@@ -0,0 +1,16 @@
+package executor
+
+import (
+	"redis-repo/internal/core/resp"
+)
+
+// cmdEXISTS handles EXISTS key [key ...], a key given several times is counted several times
+func cmdEXISTS(args []string) []byte {
+	count := 0
+	for _, key := range args {
+		if lookupKeyRead(key) != nil {
+			count++
+		}
+	}
+	return resp.Encode(count)
+}
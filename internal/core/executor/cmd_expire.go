@@ -0,0 +1,164 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Conditions of the Redis 7 EXPIRE family
+const (
+	expireNX = 1 << iota // Set only when the key has no expiry
+	expireXX             // Set only when the key already has an expiry
+	expireGT             // Set only when the new expiry is greater than the current one
+	expireLT             // Set only when the new expiry is less than the current one
+)
+
+// cmdEXPIRE handles EXPIRE key seconds [NX | XX | GT | LT]
+func cmdEXPIRE(args []string) []byte {
+	return expireGeneric("expire", args, time.Now().UnixMilli(), true)
+}
+
+// cmdPEXPIRE handles PEXPIRE key milliseconds [NX | XX | GT | LT]
+func cmdPEXPIRE(args []string) []byte {
+	return expireGeneric("pexpire", args, time.Now().UnixMilli(), false)
+}
+
+// cmdEXPIREAT handles EXPIREAT key unix-time-seconds [NX | XX | GT | LT]
+func cmdEXPIREAT(args []string) []byte {
+	return expireGeneric("expireat", args, 0, true)
+}
+
+// cmdPEXPIREAT handles PEXPIREAT key unix-time-milliseconds [NX | XX | GT | LT]
+func cmdPEXPIREAT(args []string) []byte {
+	return expireGeneric("pexpireat", args, 0, false)
+}
+
+// expireGeneric sets the expiry of a key to baseTimeMs + the given time.
+// baseTimeMs is now for relative commands and 0 for absolute ones
+func expireGeneric(name string, args []string, baseTimeMs int64, inSeconds bool) []byte {
+	key := args[0]
+	when, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	flags, errReply := parseExpireFlags(args[2:])
+	if errReply != nil {
+		return errReply
+	}
+
+	invalidExpire := resp.Encode(fmt.Errorf("ERR invalid expire time in '%s' command", name))
+	if inSeconds {
+		if when > math.MaxInt64/1000 || when < math.MinInt64/1000 {
+			return invalidExpire
+		}
+		when *= 1000
+	}
+	if when > math.MaxInt64-baseTimeMs {
+		return invalidExpire
+	}
+	when += baseTimeMs
+
+	rewriteCommand() // Unless the expiry is set
+	if lookupKeyWrite(key) == nil {
+		return resp.Encode(0)
+	}
+
+	if flags != 0 {
+		current, hasExpiry := dict.GetExpiryTime(key)
+		switch {
+		case flags&expireNX != 0 && hasExpiry:
+			return resp.Encode(0)
+		case flags&expireXX != 0 && !hasExpiry:
+			return resp.Encode(0)
+		case flags&expireGT != 0 && (!hasExpiry || when <= int64(current)):
+			// A key without expiry has an infinite TTL, nothing is greater
+			return resp.Encode(0)
+		case flags&expireLT != 0 && hasExpiry && when >= int64(current):
+			return resp.Encode(0)
+		}
+	}
+
+	if when <= 0 || (when <= time.Now().UnixMilli() && !loading) {
+		// An expiry in the past deletes the key right away, the AOF may hold passed times
+		dict.Delete(key)
+		rewriteCommand([]string{"DEL", key})
+		return resp.Encode(1)
+	}
+
+	dict.SetExpiry(key, uint64(when))
+	rewriteCommand([]string{"PEXPIREAT", key, strconv.FormatInt(when, 10)})
+	return resp.Encode(1)
+}
+
+func parseExpireFlags(options []string) (int, []byte) {
+	flags := 0
+	for _, option := range options {
+		switch strings.ToUpper(option) {
+		case "NX":
+			flags |= expireNX
+		case "XX":
+			flags |= expireXX
+		case "GT":
+			flags |= expireGT
+		case "LT":
+			flags |= expireLT
+		default:
+			return 0, resp.Encode(fmt.Errorf("ERR Unsupported option %s", option))
+		}
+	}
+
+	if flags&expireNX != 0 && flags&(expireXX|expireGT|expireLT) != 0 {
+		return 0, resp.Encode(errors.New("ERR NX and XX, GT or LT options at the same time are not compatible"))
+	}
+	if flags&expireGT != 0 && flags&expireLT != 0 {
+		return 0, resp.Encode(errors.New("ERR GT and LT options at the same time are not compatible"))
+	}
+	return flags, nil
+}
+
+// cmdPERSIST handles PERSIST key, removing its expiry
+func cmdPERSIST(args []string) []byte {
+	key := args[0]
+	if lookupKeyWrite(key) == nil {
+		return resp.Encode(0)
+	}
+	if _, hasExpiry := dict.GetExpiryTime(key); !hasExpiry {
+		return resp.Encode(0)
+	}
+
+	dict.DeleteExpiry(key)
+	return resp.Encode(1)
+}
+
+// cmdEXPIRETIME handles EXPIRETIME key, returning the absolute expiry as a Unix timestamp in seconds
+func cmdEXPIRETIME(args []string) []byte {
+	return expireTimeGeneric(args[0], true)
+}
+
+// cmdPEXPIRETIME handles PEXPIRETIME key, returning the absolute expiry as a Unix timestamp in milliseconds
+func cmdPEXPIRETIME(args []string) []byte {
+	return expireTimeGeneric(args[0], false)
+}
+
+func expireTimeGeneric(key string, inSeconds bool) []byte {
+	if lookupKeyRead(key) == nil {
+		return []byte(constant.TtlKeyNotExist)
+	}
+
+	expiryTime, hasExpiry := dict.GetExpiryTime(key)
+	if !hasExpiry {
+		return []byte(constant.TtlKeyExistNoExpire)
+	}
+
+	if inSeconds {
+		return resp.Encode(expiryTime / 1000)
+	}
+	return resp.Encode(expiryTime)
+}
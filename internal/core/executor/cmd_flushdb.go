@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strings"
+)
+
+// cmdFLUSHDB handles FLUSHDB [ASYNC|SYNC], removing every key of the selected database
+func cmdFLUSHDB(args []string) []byte {
+	async, ok := parseFlushMode(args)
+	if !ok {
+		return []byte(constant.ErrSyntax)
+	}
+	emptyDB([]int{selectedDB}, async)
+	return []byte(constant.RespOk)
+}
+
+// cmdFLUSHALL handles FLUSHALL [ASYNC|SYNC], removing every key of every database
+func cmdFLUSHALL(args []string) []byte {
+	async, ok := parseFlushMode(args)
+	if !ok {
+		return []byte(constant.ErrSyntax)
+	}
+	ids := make([]int, len(databases))
+	for i := range ids {
+		ids[i] = i
+	}
+	emptyDB(ids, async)
+	return []byte(constant.RespOk)
+}
+
+// emptyDB replaces the databases ids by empty ones, as Redis's emptyData. With async, the
+// keys removed are freed by another goroutine, else they are left to the garbage collector
+func emptyDB(ids []int, async bool) {
+	old := make([]*data_structure.Dict, len(ids))
+	for i, id := range ids {
+		old[i] = databases[id]
+		databases[id] = newDatabase()
+	}
+	selectDB(selectedDB)
+	if async {
+		emptyDBAsync(old)
+	}
+}
+
+// parseFlushMode parses the optional ASYNC or SYNC argument of FLUSHDB and FLUSHALL, and
+// reports whether the keys are freed asynchronously
+func parseFlushMode(args []string) (async bool, ok bool) {
+	if len(args) == 0 {
+		return false, true
+	}
+	if len(args) > 1 {
+		return false, false
+	}
+	switch strings.ToUpper(args[0]) {
+	case "ASYNC":
+		return true, true
+	case "SYNC":
+		return false, true
+	}
+	return false, false
+}
+
+// cmdDBSIZE handles DBSIZE, returning the number of keys of the selected database. Like
+// Redis, it counts the expired keys not deleted yet
+func cmdDBSIZE(args []string) []byte {
+	return resp.Encode(dict.Len())
+}
@@ -1,24 +1,72 @@
 package executor
 
 import (
-	"fmt"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
+	"strconv"
 )
 
 func cmdGET(args []string) []byte {
-	if len(args) != 1 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "GET"))
-	}
 	key := args[0]
 	if key == "" {
 		return []byte(constant.ErrEmptyKey)
 	}
 
-	vObject := dict.Get(key)
-	if vObject == nil {
+	value, exists, ok := getString(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if !exists {
 		return []byte(constant.RespNil)
 	}
 
-	return resp.Encode(vObject.Value)
+	return resp.Encode(value)
+}
+
+// cmdGETSET handles GETSET key value, setting the value and returning the previous one
+func cmdGETSET(args []string) []byte {
+	return setGeneric(args[0], args[1], stringOptions{flags: setGet})
+}
+
+// cmdGETDEL handles GETDEL key, returning the value and deleting the key
+func cmdGETDEL(args []string) []byte {
+	value, exists, ok := getString(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if !exists {
+		return []byte(constant.RespNil)
+	}
+
+	dict.Delete(args[0])
+	return resp.Encode(value)
+}
+
+// Support GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+func cmdGETEX(args []string) []byte {
+	key := args[0]
+	opts, errReply := parseStringOptions(args[1:], false)
+	if errReply != nil {
+		return errReply
+	}
+
+	value, exists, ok := getString(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if !exists {
+		return []byte(constant.RespNil)
+	}
+
+	if opts.flags&setExpiryFlags != 0 {
+		dict.SetExpiry(key, opts.expiryTimeMs)
+		rewriteCommand([]string{"PEXPIREAT", key, strconv.FormatUint(opts.expiryTimeMs, 10)})
+	} else if opts.flags&setPersist != 0 {
+		dict.DeleteExpiry(key)
+		rewriteCommand([]string{"PERSIST", key})
+	} else {
+		rewriteCommand() // Nothing changed
+	}
+
+	return resp.Encode(value)
 }
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"errors"
+	"redis-repo/internal/config"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// cmdGETRANGE handles GETRANGE key start end. Negative offsets count from the end
+// of the string and the range is clamped to the string, both ends included
+func cmdGETRANGE(args []string) []byte {
+	start, err := strconv.Atoi(args[1])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	end, err := strconv.Atoi(args[2])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	value, _, ok := getString(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	length := len(value)
+	if start < 0 && end < 0 && start > end {
+		return resp.Encode("")
+	}
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	start = max(start, 0)
+	end = max(end, 0)
+	end = min(end, length-1)
+	if length == 0 || start > end {
+		return resp.Encode("")
+	}
+	return resp.Encode(value[start : end+1])
+}
+
+// cmdSETRANGE handles SETRANGE key offset value, overwriting part of the string at
+// offset. The string is padded with zero bytes when offset is past its end
+func cmdSETRANGE(args []string) []byte {
+	key, patch := args[0], args[2]
+	offset, err := strconv.Atoi(args[1])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	if offset < 0 {
+		return resp.Encode(errors.New("ERR offset is out of range"))
+	}
+
+	obj := lookupKeyWrite(key)
+	var value string
+	if obj != nil {
+		if obj.Type != data_structure.ObjString {
+			return []byte(constant.ErrWrongType)
+		}
+		value = obj.StringValue()
+	}
+
+	// An empty patch changes nothing, and does not create the key
+	if len(patch) == 0 {
+		return resp.Encode(len(value))
+	}
+	if offset > config.ProtoMaxBulkLen-len(patch) { // Written so that a huge offset cannot overflow
+		return []byte(constant.ErrStringTooLong)
+	}
+
+	var sb strings.Builder
+	sb.Grow(max(len(value), offset+len(patch)))
+	if offset <= len(value) {
+		sb.WriteString(value[:offset])
+	} else {
+		sb.WriteString(value)
+		sb.WriteString(strings.Repeat("\x00", offset-len(value)))
+	}
+	sb.WriteString(patch)
+	if offset+len(patch) < len(value) {
+		sb.WriteString(value[offset+len(patch):])
+	}
+	value = sb.String()
+
+	if obj == nil {
+		dict.Set(key, value, 0)
+	} else {
+		dict.SetDictStore(key, value)
+	}
+	return resp.Encode(len(value))
+}
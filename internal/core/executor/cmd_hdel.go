@@ -0,0 +1,27 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdHDEL handles HDEL key field [field ...], deleting the key with its last field
+func cmdHDEL(args []string) []byte {
+	key := args[0]
+	hash, ok := getHashForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if hash == nil {
+		return resp.Encode(0)
+	}
+
+	deleted := 0
+	for _, field := range args[1:] {
+		if hash.Delete(field) {
+			deleted++
+		}
+	}
+	deleteIfEmpty(key, hash.Len())
+	return resp.Encode(deleted)
+}
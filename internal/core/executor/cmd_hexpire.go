@@ -0,0 +1,220 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replies of the hash field expiry commands, one per field
+const (
+	fieldNotFound     = -2 // The field, or the whole key, does not exist
+	fieldNoExpiry     = -1 // HTTL and HPERSIST on a field without expiry
+	fieldNotUpdated   = 0  // The NX, XX, GT or LT condition is not met
+	fieldUpdated      = 1
+	fieldExpiredByCmd = 2 // The expiry time is in the past, the field is deleted
+)
+
+// maxFieldExpiryMs is the largest expiry time of a hash field, as in Redis
+const maxFieldExpiryMs = 1<<48 - 1
+
+// cmdHEXPIRE handles HEXPIRE key seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHEXPIRE(args []string) []byte {
+	return hexpireGeneric("hexpire", args, time.Now().UnixMilli(), true)
+}
+
+// cmdHPEXPIRE handles HPEXPIRE key milliseconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHPEXPIRE(args []string) []byte {
+	return hexpireGeneric("hpexpire", args, time.Now().UnixMilli(), false)
+}
+
+// cmdHEXPIREAT handles HEXPIREAT key unix-time-seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHEXPIREAT(args []string) []byte {
+	return hexpireGeneric("hexpireat", args, 0, true)
+}
+
+// cmdHPEXPIREAT handles HPEXPIREAT key unix-time-milliseconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func cmdHPEXPIREAT(args []string) []byte {
+	return hexpireGeneric("hpexpireat", args, 0, false)
+}
+
+// hexpireGeneric sets the expiry of hash fields to baseTimeMs + the given time.
+// baseTimeMs is now for relative commands and 0 for absolute ones
+func hexpireGeneric(name string, args []string, baseTimeMs int64, inSeconds bool) []byte {
+	key := args[0]
+	when, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	if when < 0 {
+		return resp.Encode(errors.New("ERR invalid expire time, must be >= 0"))
+	}
+
+	flags := 0
+	fieldsAt := 2
+	switch strings.ToUpper(args[2]) {
+	case "NX", "XX", "GT", "LT":
+		flags, _ = parseExpireFlags(args[2:3])
+		fieldsAt = 3
+	}
+	fields, errReply := parseFieldsArgument(args[fieldsAt:])
+	if errReply != nil {
+		return errReply
+	}
+
+	if inSeconds {
+		if when > math.MaxInt64/1000 {
+			return resp.Encode(fmt.Errorf("ERR invalid expire time in '%s' command", name))
+		}
+		when *= 1000
+	}
+	if when > maxFieldExpiryMs-baseTimeMs {
+		return resp.Encode(fmt.Errorf("ERR invalid expire time in '%s' command", name))
+	}
+	when += baseTimeMs
+
+	hash, ok := getHashForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	// The AOF sets the expiry time of the fields updated, and deletes the expired ones
+	updated := []string{"HPEXPIREAT", key, strconv.FormatInt(when, 10), "FIELDS", ""}
+	deleted := []string{"HDEL", key}
+	res := make([]any, len(fields))
+	for i, field := range fields {
+		if hash == nil {
+			res[i] = fieldNotFound
+			continue
+		}
+		if _, exists := hash.Get(field); !exists {
+			res[i] = fieldNotFound
+			continue
+		}
+
+		current, hasExpiry := hash.GetExpiry(field)
+		if (flags&expireNX != 0 && hasExpiry) ||
+			(flags&expireXX != 0 && !hasExpiry) ||
+			(flags&expireGT != 0 && (!hasExpiry || when <= int64(current))) ||
+			(flags&expireLT != 0 && hasExpiry && when >= int64(current)) {
+			res[i] = fieldNotUpdated
+			continue
+		}
+
+		if when <= time.Now().UnixMilli() && !loading {
+			hash.Delete(field)
+			deleted = append(deleted, field)
+			res[i] = fieldExpiredByCmd
+			continue
+		}
+		hash.SetExpiry(field, uint64(when))
+		updated = append(updated, field)
+		res[i] = fieldUpdated
+	}
+
+	var rewritten [][]string
+	if len(updated) > 5 {
+		updated[4] = strconv.Itoa(len(updated) - 5)
+		rewritten = append(rewritten, updated)
+	}
+	if len(deleted) > 2 {
+		rewritten = append(rewritten, deleted)
+	}
+	rewriteCommand(rewritten...)
+
+	if hash != nil {
+		if hash.HasFieldExpiry() {
+			dict.TrackFieldExpiry(key)
+		}
+		deleteIfEmpty(key, hash.Len())
+	}
+	return resp.Encode(res)
+}
+
+// cmdHTTL handles HTTL key FIELDS numfields field [field ...], replying the remaining
+// time to live in seconds of every field
+func cmdHTTL(args []string) []byte {
+	fields, errReply := parseFieldsArgument(args[1:])
+	if errReply != nil {
+		return errReply
+	}
+
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	now := time.Now().UnixMilli()
+	res := make([]any, len(fields))
+	for i, field := range fields {
+		if hash == nil {
+			res[i] = fieldNotFound
+			continue
+		}
+		if _, exists := hash.Get(field); !exists {
+			res[i] = fieldNotFound
+			continue
+		}
+
+		expiryTimeMs, hasExpiry := hash.GetExpiry(field)
+		if !hasExpiry {
+			res[i] = fieldNoExpiry
+			continue
+		}
+		res[i] = (int64(expiryTimeMs) - now + 500) / 1000 // Rounded like TTL
+	}
+	return resp.Encode(res)
+}
+
+// cmdHPERSIST handles HPERSIST key FIELDS numfields field [field ...]
+func cmdHPERSIST(args []string) []byte {
+	fields, errReply := parseFieldsArgument(args[1:])
+	if errReply != nil {
+		return errReply
+	}
+
+	hash, ok := getHashForWrite(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	res := make([]any, len(fields))
+	for i, field := range fields {
+		if hash == nil {
+			res[i] = fieldNotFound
+			continue
+		}
+		if _, exists := hash.Get(field); !exists {
+			res[i] = fieldNotFound
+			continue
+		}
+
+		if hash.Persist(field) {
+			res[i] = fieldUpdated
+		} else {
+			res[i] = fieldNoExpiry
+		}
+	}
+	return resp.Encode(res)
+}
+
+// parseFieldsArgument parses FIELDS numfields field [field ...] and returns the fields
+func parseFieldsArgument(args []string) ([]string, []byte) {
+	if len(args) < 2 || strings.ToUpper(args[0]) != "FIELDS" {
+		return nil, resp.Encode(errors.New("ERR Mandatory argument FIELDS is missing or not at the right position"))
+	}
+
+	numFields, err := strconv.Atoi(args[1])
+	if err != nil || numFields <= 0 {
+		return nil, resp.Encode(errors.New("ERR Parameter `numFields` should be greater than 0"))
+	}
+	if numFields != len(args)-2 {
+		return nil, resp.Encode(errors.New("ERR The `numfields` parameter must match the number of arguments"))
+	}
+	return args[2:], nil
+}
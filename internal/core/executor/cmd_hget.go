@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdHGET handles HGET key field
+func cmdHGET(args []string) []byte {
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if hash == nil {
+		return []byte(constant.RespNil)
+	}
+
+	value, exists := hash.Get(args[1])
+	if !exists {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(value)
+}
+
+// cmdHMGET handles HMGET key field [field ...], missing fields are nil
+func cmdHMGET(args []string) []byte {
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	res := make([]any, len(args)-1)
+	if hash != nil {
+		for i, field := range args[1:] {
+			if value, exists := hash.Get(field); exists {
+				res[i] = value
+			}
+		}
+	}
+	return resp.Encode(res)
+}
+
+// cmdHEXISTS handles HEXISTS key field
+func cmdHEXISTS(args []string) []byte {
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if hash == nil {
+		return resp.Encode(0)
+	}
+
+	if _, exists := hash.Get(args[1]); exists {
+		return resp.Encode(1)
+	}
+	return resp.Encode(0)
+}
+
+// cmdHSTRLEN handles HSTRLEN key field, 0 when the field does not exist
+func cmdHSTRLEN(args []string) []byte {
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if hash == nil {
+		return resp.Encode(0)
+	}
+
+	value, _ := hash.Get(args[1])
+	return resp.Encode(len(value))
+}
+
+// cmdHLEN handles HLEN key
+func cmdHLEN(args []string) []byte {
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if hash == nil {
+		return resp.Encode(0)
+	}
+	return resp.Encode(hash.Len())
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdHKEYS handles HKEYS key
+func cmdHKEYS(args []string) []byte {
+	return hashGetAll(args[0], true, false)
+}
+
+// cmdHVALS handles HVALS key
+func cmdHVALS(args []string) []byte {
+	return hashGetAll(args[0], false, true)
+}
+
+// cmdHGETALL handles HGETALL key, replying fields and values interleaved
+func cmdHGETALL(args []string) []byte {
+	return hashGetAll(args[0], true, true)
+}
+
+func hashGetAll(key string, withFields, withValues bool) []byte {
+	hash, ok := getHash(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if hash == nil {
+		return resp.Encode([]any{})
+	}
+
+	res := make([]any, 0, 2*hash.Len())
+	hash.Iterate(func(field, value string) bool {
+		if withFields {
+			res = append(res, field)
+		}
+		if withValues {
+			res = append(res, value)
+		}
+		return true
+	})
+	return resp.Encode(res)
+}
@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+)
+
+// cmdHINCRBY handles HINCRBY key field increment, a missing field counts as 0
+func cmdHINCRBY(args []string) []byte {
+	increment, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	hash, ok := getOrCreateHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	var current int64
+	if value, exists := hash.Get(args[1]); exists {
+		if current, ok = data_structure.ParseCanonicalInt(value); !ok {
+			return resp.Encode(errors.New("ERR hash value is not an integer"))
+		}
+	}
+
+	if (increment < 0 && current < math.MinInt64-increment) || (increment > 0 && current > math.MaxInt64-increment) {
+		return resp.Encode(errors.New("ERR increment or decrement would overflow"))
+	}
+	current += increment
+
+	hash.Update(args[1], strconv.FormatInt(current, 10)) // The field keeps its expiry
+	return resp.Encode(current)
+}
+
+// cmdHINCRBYFLOAT handles HINCRBYFLOAT key field increment, a missing field counts as 0
+func cmdHINCRBYFLOAT(args []string) []byte {
+	increment, ok := parseFloatArg(args[2])
+	if !ok {
+		return []byte(constant.ErrNotFloat)
+	}
+
+	hash, ok := getOrCreateHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	var current float64
+	if value, exists := hash.Get(args[1]); exists {
+		if current, ok = parseFloatArg(value); !ok {
+			return resp.Encode(errors.New("ERR hash value is not a float"))
+		}
+	}
+
+	current += increment
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return resp.Encode(errors.New("ERR increment would produce NaN or Infinity"))
+	}
+
+	value := strconv.FormatFloat(current, 'f', -1, 64)
+	hash.Update(args[1], value)
+	return resp.Encode(value)
+}
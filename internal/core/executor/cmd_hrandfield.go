@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+)
+
+// Support HRANDFIELD key [count [WITHVALUES]]. A positive count returns distinct fields,
+// a negative one returns -count fields that may repeat
+func cmdHRANDFIELD(args []string) []byte {
+	if len(args) > 3 || (len(args) == 3 && strings.ToUpper(args[2]) != "WITHVALUES") {
+		return []byte(constant.ErrSyntax)
+	}
+	withValues := len(args) == 3
+
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	if len(args) == 1 {
+		if hash == nil {
+			return []byte(constant.RespNil)
+		}
+		field, _ := hash.RandomField()
+		return resp.Encode(field)
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count == math.MinInt {
+		return []byte(constant.ErrNotInteger)
+	}
+	if hash == nil || count == 0 {
+		return resp.Encode([]any{})
+	}
+
+	var picked []string
+	if count > 0 {
+		randomField := func() string {
+			field, _ := hash.RandomField()
+			return field
+		}
+		picked = randomDistinct(hash.Len(), count, randomField, hash.Fields)
+	} else {
+		for i := 0; i < -count; i++ {
+			field, _ := hash.RandomField()
+			picked = append(picked, field)
+		}
+	}
+
+	res := make([]any, 0, 2*len(picked))
+	for _, field := range picked {
+		res = append(res, field)
+		if withValues {
+			value, _ := hash.Get(field)
+			res = append(res, value)
+		}
+	}
+	return resp.Encode(res)
+}
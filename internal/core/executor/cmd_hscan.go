@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+)
+
+// Support HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func cmdHSCAN(args []string) []byte {
+	cursor, errReply := parseScanCursor(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[2:], scanAllowNoValues)
+	if errReply != nil {
+		return errReply
+	}
+
+	hash, ok := getHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if hash == nil {
+		return scanReply(0, []any{})
+	}
+
+	var fields, values []string
+	cursor = scanSteps(cursor, opts.count, func(cursor uint64) uint64 {
+		return hash.Scan(cursor, func(field, value string) {
+			fields = append(fields, field)
+			values = append(values, value)
+		})
+	}, func() int { return len(fields) })
+
+	elements := make([]any, 0)
+	for i, field := range fields {
+		if opts.matches(field) {
+			elements = append(elements, field)
+			if !opts.noValues {
+				elements = append(elements, values[i])
+			}
+		}
+	}
+	return scanReply(cursor, elements)
+}
@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"fmt"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+)
+
+// cmdHSET handles HSET key field value [field value ...], returning the number of new fields
+func cmdHSET(args []string) []byte {
+	if len(args)%2 != 1 {
+		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "HSET"))
+	}
+
+	hash, ok := getOrCreateHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	added := 0
+	for i := 1; i < len(args); i += 2 {
+		if hash.Set(args[i], args[i+1]) {
+			added++
+		}
+	}
+	return resp.Encode(added)
+}
+
+// cmdHSETNX handles HSETNX key field value, setting the field only when it does not exist
+func cmdHSETNX(args []string) []byte {
+	hash, ok := getOrCreateHash(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	if _, exists := hash.Get(args[1]); exists {
+		return resp.Encode(0)
+	}
+	hash.Set(args[1], args[2])
+	return resp.Encode(1)
+}
+
+// getOrCreateHash returns the hash stored at key for a write, creating it when the key
+// does not exist. ok is false when the key holds another type
+func getOrCreateHash(key string) (hash *data_structure.Hash, ok bool) {
+	hash, ok = getHashForWrite(key)
+	if ok && hash == nil {
+		hash = data_structure.NewHash()
+		dict.Set(key, hash, 0)
+	}
+	return hash, ok
+}
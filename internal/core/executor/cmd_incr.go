@@ -0,0 +1,119 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// cmdINCR handles INCR key
+func cmdINCR(args []string) []byte {
+	return incrDecr(args[0], 1)
+}
+
+// cmdDECR handles DECR key
+func cmdDECR(args []string) []byte {
+	return incrDecr(args[0], -1)
+}
+
+// cmdINCRBY handles INCRBY key increment
+func cmdINCRBY(args []string) []byte {
+	increment, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	return incrDecr(args[0], increment)
+}
+
+// cmdDECRBY handles DECRBY key decrement
+func cmdDECRBY(args []string) []byte {
+	decrement, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	if decrement == math.MinInt64 {
+		return resp.Encode(errors.New("ERR decrement would overflow"))
+	}
+	return incrDecr(args[0], -decrement)
+}
+
+// incrDecr adds delta to the integer stored at key, a missing key counts as 0
+func incrDecr(key string, delta int64) []byte {
+	obj := lookupKeyWrite(key)
+	var current int64
+	if obj != nil {
+		if obj.Type != data_structure.ObjString {
+			return []byte(constant.ErrWrongType)
+		}
+		var ok bool
+		if current, ok = intFromObject(obj); !ok {
+			return []byte(constant.ErrNotInteger)
+		}
+	}
+
+	if (delta < 0 && current < math.MinInt64-delta) || (delta > 0 && current > math.MaxInt64-delta) {
+		return resp.Encode(errors.New("ERR increment or decrement would overflow"))
+	}
+	current += delta
+
+	if obj != nil && obj.Encoding == data_structure.EncodingInt {
+		obj.Value = current // Update the counter in place
+	} else {
+		dict.SetDictStore(key, data_structure.NewIntObject(current))
+	}
+	return resp.Encode(current)
+}
+
+// intFromObject reads a string object as an integer, without parsing when it is int encoded
+func intFromObject(obj *data_structure.ValueObject) (int64, bool) {
+	if obj.Encoding == data_structure.EncodingInt {
+		return obj.Value.(int64), true
+	}
+	return data_structure.ParseCanonicalInt(obj.Value.(string))
+}
+
+// cmdINCRBYFLOAT handles INCRBYFLOAT key increment
+func cmdINCRBYFLOAT(args []string) []byte {
+	key := args[0]
+	increment, ok := parseFloatArg(args[1])
+	if !ok {
+		return []byte(constant.ErrNotFloat)
+	}
+
+	obj := lookupKeyWrite(key)
+	var current float64
+	if obj != nil {
+		if obj.Type != data_structure.ObjString {
+			return []byte(constant.ErrWrongType)
+		}
+		if current, ok = parseFloatArg(obj.StringValue()); !ok {
+			return []byte(constant.ErrNotFloat)
+		}
+	}
+
+	current += increment
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return resp.Encode(errors.New("ERR increment would produce NaN or Infinity"))
+	}
+
+	// Stored as a plain decimal string, never in exponent notation
+	value := strconv.FormatFloat(current, 'f', -1, 64)
+	dict.SetDictStore(key, value)
+	return resp.Encode(value)
+}
+
+// parseFloatArg parses a finite float argument, rejecting spaces, NaN and infinities
+func parseFloatArg(s string) (float64, bool) {
+	if len(s) == 0 || strings.ContainsAny(s, " \t\r\n") {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,23 @@
+package executor
+
+import (
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"redis-repo/internal/glob"
+)
+
+// cmdKEYS handles KEYS pattern, returning every key matching the glob pattern. It walks
+// the whole keyspace at once, SCAN is the way to do it without blocking the server
+func cmdKEYS(args []string) []byte {
+	pattern := args[0]
+	allKeys := pattern == "*"
+
+	keys := make([]any, 0)
+	dict.Iterate(func(key string, _ *data_structure.ValueObject) bool {
+		if allKeys || glob.Match(pattern, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return resp.Encode(keys)
+}
@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"errors"
+	"redis-repo/internal/config"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+)
+
+// Support LCS key1 key2 [LEN] [IDX] [MINMATCHLEN min-match-len] [WITHMATCHLEN]
+func cmdLCS(args []string) []byte {
+	var getLen, getIdx, withMatchLen bool
+	minMatchLen := 0
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(args[i])
+		switch {
+		case option == "LEN":
+			getLen = true
+		case option == "IDX":
+			getIdx = true
+		case option == "WITHMATCHLEN":
+			withMatchLen = true
+		case option == "MINMATCHLEN" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return []byte(constant.ErrNotInteger)
+			}
+			minMatchLen = max(n, 0)
+			i++
+		default:
+			return []byte(constant.ErrSyntax)
+		}
+	}
+	if getLen && getIdx {
+		return resp.Encode(errors.New("ERR If you want both the length and indexes, please just use IDX."))
+	}
+
+	// Missing keys are compared as empty strings
+	a, _, okA := getString(args[0])
+	b, _, okB := getString(args[1])
+	if !okA || !okB {
+		return resp.Encode(errors.New("ERR The specified keys must contain string values"))
+	}
+
+	if (uint64(len(a))+1)*(uint64(len(b))+1)*4 > config.ProtoMaxBulkLen {
+		return resp.Encode(errors.New("ERR Insufficient memory, transient memory for LCS exceeds proto-max-bulk-len"))
+	}
+
+	// lcs[i*(len(b)+1)+j] is the length of the LCS of a[:i] and b[:j]
+	stride := len(b) + 1
+	lcs := make([]uint32, (len(a)+1)*stride)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				lcs[i*stride+j] = lcs[(i-1)*stride+j-1] + 1
+			} else {
+				lcs[i*stride+j] = max(lcs[(i-1)*stride+j], lcs[i*stride+j-1])
+			}
+		}
+	}
+
+	length := int(lcs[len(a)*stride+len(b)])
+	if getLen {
+		return resp.Encode(length)
+	}
+
+	// Walk the table back from the end of both strings, collecting the LCS and, for IDX,
+	// the ranges of contiguous matches from the last one to the first one
+	result := make([]byte, length)
+	matches := make([]any, 0)
+	idx := length
+	aStart, aEnd, bStart, bEnd := len(a), 0, 0, 0 // aStart == len(a) means no range in progress
+	for i, j := len(a), len(b); i > 0 && j > 0; {
+		emitRange := false
+		if a[i-1] == b[j-1] {
+			result[idx-1] = a[i-1]
+
+			if aStart == len(a) {
+				aStart, aEnd = i-1, i-1
+				bStart, bEnd = j-1, j-1
+			} else if aStart == i && bStart == j {
+				aStart-- // Extend the range backward since it is contiguous
+				bStart--
+			} else {
+				emitRange = true
+			}
+			// Emit the range once it reaches the first byte of either string, as the loop ends
+			if aStart == 0 || bStart == 0 {
+				emitRange = true
+			}
+			idx--
+			i--
+			j--
+		} else {
+			if lcs[(i-1)*stride+j] > lcs[i*stride+j-1] {
+				i--
+			} else {
+				j--
+			}
+			if aStart != len(a) {
+				emitRange = true
+			}
+		}
+
+		if emitRange {
+			matchLen := aEnd - aStart + 1
+			if getIdx && (minMatchLen == 0 || matchLen >= minMatchLen) {
+				match := []any{[]any{aStart, aEnd}, []any{bStart, bEnd}}
+				if withMatchLen {
+					match = append(match, matchLen)
+				}
+				matches = append(matches, match)
+			}
+			aStart = len(a) // Restart at the next match
+		}
+	}
+
+	if getIdx {
+		return resp.Encode([]any{"matches", matches, "len", length})
+	}
+	return resp.Encode(string(result))
+}
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strings"
+)
+
+// Support LMOVE source destination LEFT | RIGHT LEFT | RIGHT
+func cmdLMOVE(args []string) []byte {
+	source, destination := args[0], args[1]
+	fromLeft, ok := parseListSide(args[2])
+	if !ok {
+		return []byte(constant.ErrSyntax)
+	}
+	toLeft, ok := parseListSide(args[3])
+	if !ok {
+		return []byte(constant.ErrSyntax)
+	}
+
+	srcList, ok := getListForWrite(source)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if srcList == nil {
+		return []byte(constant.RespNil)
+	}
+	dstList, ok := getListForWrite(destination)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	var value string
+	if fromLeft {
+		value, _ = srcList.PopHead()
+	} else {
+		value, _ = srcList.PopTail()
+	}
+
+	if dstList == nil {
+		dstList = newList()
+		dict.Set(destination, dstList, 0)
+	}
+	if toLeft {
+		dstList.PushHead(value)
+	} else {
+		dstList.PushTail(value)
+	}
+
+	// Checked after the push, as source and destination may be the same list
+	deleteIfEmpty(source, srcList.Len())
+	return resp.Encode(value)
+}
+
+// parseListSide parses LEFT or RIGHT, left is true for LEFT
+func parseListSide(side string) (left bool, ok bool) {
+	switch strings.ToUpper(side) {
+	case "LEFT":
+		return true, true
+	case "RIGHT":
+		return false, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"fmt"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+)
+
+// Support LPOP key [count]
+func cmdLPOP(args []string) []byte {
+	return popGeneric("LPOP", args, true)
+}
+
+// Support RPOP key [count]
+func cmdRPOP(args []string) []byte {
+	return popGeneric("RPOP", args, false)
+}
+
+// popGeneric pops from the head or the tail of the list. Without a count it replies
+// the popped element, with a count an array of up to count elements
+func popGeneric(name string, args []string, head bool) []byte {
+	if len(args) > 2 {
+		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, name))
+	}
+
+	key := args[0]
+	hasCount := len(args) == 2
+	count := 1
+	if hasCount {
+		var err error
+		count, err = strconv.Atoi(args[1])
+		if err != nil || count < 0 {
+			return []byte(constant.ErrNotPositive)
+		}
+	}
+
+	list, ok := getListForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		if hasCount {
+			return []byte(constant.RespNilArray)
+		}
+		return []byte(constant.RespNil)
+	}
+
+	popped := make([]any, 0, min(count, list.Len()))
+	for len(popped) < count {
+		var value string
+		if head {
+			value, ok = list.PopHead()
+		} else {
+			value, ok = list.PopTail()
+		}
+		if !ok {
+			break
+		}
+		popped = append(popped, value)
+	}
+	deleteIfEmpty(key, list.Len())
+
+	if !hasCount {
+		return resp.Encode(popped[0])
+	}
+	return resp.Encode(popped)
+}
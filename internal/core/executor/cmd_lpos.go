@@ -0,0 +1,89 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+)
+
+// Support LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func cmdLPOS(args []string) []byte {
+	key, element := args[0], args[1]
+	rank, count, maxLen := 1, -1, 0 // count is -1 when the COUNT option is not given
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return []byte(constant.ErrSyntax)
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			return []byte(constant.ErrNotInteger)
+		}
+
+		switch strings.ToUpper(args[i]) {
+		case "RANK":
+			if n == 0 {
+				return resp.Encode(errors.New("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list"))
+			}
+			if n == math.MinInt {
+				return resp.Encode(errors.New("ERR value is out of range, value must between -9223372036854775807 and 9223372036854775807"))
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				return resp.Encode(errors.New("ERR COUNT can't be negative"))
+			}
+			count = n
+		case "MAXLEN":
+			if n < 0 {
+				return resp.Encode(errors.New("ERR MAXLEN can't be negative"))
+			}
+			maxLen = n
+		default:
+			return []byte(constant.ErrSyntax)
+		}
+	}
+
+	list, ok := getList(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	// Skip the first rank-1 matches, then collect up to count matches (all of them when
+	// count is 0), comparing at most maxLen elements
+	matches := make([]any, 0)
+	if list != nil {
+		skip := max(rank, -rank) - 1
+		compared := 0
+		visit := func(i int, value string) bool {
+			if maxLen > 0 && compared == maxLen {
+				return false
+			}
+			compared++
+			if value != element {
+				return true
+			}
+			if skip > 0 {
+				skip--
+				return true
+			}
+			matches = append(matches, i)
+			return count == 0 || len(matches) < max(count, 1)
+		}
+		if rank > 0 {
+			list.Iterate(0, visit)
+		} else {
+			list.IterateReverse(list.Len()-1, visit)
+		}
+	}
+
+	if count < 0 {
+		if len(matches) == 0 {
+			return []byte(constant.RespNil)
+		}
+		return resp.Encode(matches[0])
+	}
+	return resp.Encode(matches)
+}
@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdLPUSH handles LPUSH key element [element ...]
+func cmdLPUSH(args []string) []byte {
+	return pushGeneric(args[0], args[1:], true, false)
+}
+
+// cmdRPUSH handles RPUSH key element [element ...]
+func cmdRPUSH(args []string) []byte {
+	return pushGeneric(args[0], args[1:], false, false)
+}
+
+// cmdLPUSHX handles LPUSHX key element [element ...], only pushing to an existing list
+func cmdLPUSHX(args []string) []byte {
+	return pushGeneric(args[0], args[1:], true, true)
+}
+
+// cmdRPUSHX handles RPUSHX key element [element ...], only pushing to an existing list
+func cmdRPUSHX(args []string) []byte {
+	return pushGeneric(args[0], args[1:], false, true)
+}
+
+// pushGeneric pushes the elements one after the other at the head or the tail of the list
+// and returns its new length. The list is created unless onlyExisting is set
+func pushGeneric(key string, elements []string, head bool, onlyExisting bool) []byte {
+	list, ok := getListForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		if onlyExisting {
+			return resp.Encode(0)
+		}
+		list = newList()
+		dict.Set(key, list, 0)
+	}
+
+	for _, element := range elements {
+		if head {
+			list.PushHead(element)
+		} else {
+			list.PushTail(element)
+		}
+	}
+	return resp.Encode(list.Len())
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+)
+
+// cmdLRANGE handles LRANGE key start stop. Negative offsets count from the tail
+// and the range is clamped to the list, both ends included
+func cmdLRANGE(args []string) []byte {
+	start, err := strconv.Atoi(args[1])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	stop, err := strconv.Atoi(args[2])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	list, ok := getList(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		return resp.Encode([]any{})
+	}
+
+	length := list.Len()
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	start = max(start, 0)
+	if start > stop || start >= length {
+		return resp.Encode([]any{})
+	}
+	stop = min(stop, length-1)
+
+	res := make([]any, 0, stop-start+1)
+	list.Iterate(start, func(i int, value string) bool {
+		res = append(res, value)
+		return i < stop
+	})
+	return resp.Encode(res)
+}
+
+// cmdLINDEX handles LINDEX key index, negative indexes count from the tail
+func cmdLINDEX(args []string) []byte {
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	list, ok := getList(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		return []byte(constant.RespNil)
+	}
+
+	value, found := list.Index(index)
+	if !found {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(value)
+}
+
+// cmdLLEN handles LLEN key
+func cmdLLEN(args []string) []byte {
+	list, ok := getList(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		return resp.Encode(0)
+	}
+	return resp.Encode(list.Len())
+}
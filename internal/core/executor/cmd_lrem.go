@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+)
+
+// cmdLREM handles LREM key count element. A positive count removes the first count
+// occurrences from the head, a negative one the last ones from the tail, 0 removes all
+func cmdLREM(args []string) []byte {
+	key := args[0]
+	count, err := strconv.Atoi(args[1])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	list, ok := getListForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		return resp.Encode(0)
+	}
+
+	removed := list.Remove(args[2], count)
+	deleteIfEmpty(key, list.Len())
+	return resp.Encode(removed)
+}
+
+// cmdLTRIM handles LTRIM key start stop, keeping only the elements in the range
+func cmdLTRIM(args []string) []byte {
+	key := args[0]
+	start, err := strconv.Atoi(args[1])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+	stop, err := strconv.Atoi(args[2])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	list, ok := getListForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		return []byte(constant.RespOk)
+	}
+
+	length := list.Len()
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	start = max(start, 0)
+
+	// Number of elements to remove from the head and from the tail
+	var trimHead, trimTail int
+	if start > stop || start >= length {
+		trimHead = length // Out of range, the whole list is removed
+	} else {
+		stop = min(stop, length-1)
+		trimHead = start
+		trimTail = length - stop - 1
+	}
+
+	if trimTail > 0 {
+		list.DeleteRange(length-trimTail, trimTail)
+	}
+	if trimHead > 0 {
+		list.DeleteRange(0, trimHead)
+	}
+	deleteIfEmpty(key, list.Len())
+	return []byte(constant.RespOk)
+}
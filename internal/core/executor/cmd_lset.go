@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"errors"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+)
+
+// cmdLSET handles LSET key index element, negative indexes count from the tail
+func cmdLSET(args []string) []byte {
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	list, ok := getListForWrite(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		return []byte(constant.ErrNoSuchKey)
+	}
+
+	if !list.Replace(index, args[2]) {
+		return resp.Encode(errors.New("ERR index out of range"))
+	}
+	return []byte(constant.RespOk)
+}
+
+// Support LINSERT key BEFORE | AFTER pivot element
+func cmdLINSERT(args []string) []byte {
+	key, pivot, element := args[0], args[2], args[3]
+	var after bool
+	switch strings.ToUpper(args[1]) {
+	case "BEFORE":
+		after = false
+	case "AFTER":
+		after = true
+	default:
+		return []byte(constant.ErrSyntax)
+	}
+
+	list, ok := getListForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if list == nil {
+		return resp.Encode(0)
+	}
+
+	index := -1
+	list.Iterate(0, func(i int, value string) bool {
+		if value == pivot {
+			index = i
+			return false
+		}
+		return true
+	})
+	if index < 0 {
+		return resp.Encode(-1) // The pivot was not found
+	}
+
+	if after {
+		index++
+	}
+	list.Insert(index, element)
+	return resp.Encode(list.Len())
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"fmt"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// cmdMEMORYUSAGE handles MEMORY USAGE key [SAMPLES count], estimating the bytes used by
+// the key and its value from count elements of a collection, 5 by default, all of them if 0
+func cmdMEMORYUSAGE(args []string) []byte {
+	samples := data_structure.DefaultMemorySamples
+	for i := 1; i < len(args); i += 2 {
+		if !strings.EqualFold(args[i], "SAMPLES") || i+1 >= len(args) {
+			return []byte(constant.ErrSyntax)
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			return []byte(constant.ErrNotInteger)
+		}
+		if n < 0 {
+			return []byte(constant.ErrSyntax)
+		}
+		samples = n
+	}
+
+	obj := lookupKeyNoTouch(args[0])
+	if obj == nil {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(data_structure.KeyMemoryUsage(args[0], obj, samples))
+}
+
+// cmdMEMORYSTATS handles MEMORY STATS, reporting the memory compared with maxmemory:
+// the overhead of the hash tables of each database and the estimated size of the keys
+func cmdMEMORYSTATS(args []string) []byte {
+	var total, overhead int64
+	keys := 0
+	var dbStats []any
+	for id, db := range databases {
+		main, expires := db.MemoryOverhead()
+		total += db.UsedMemory()
+		overhead += main + expires
+		keys += db.Len()
+		if db.Len() > 0 {
+			dbStats = append(dbStats, fmt.Sprintf("db.%d", id), []any{
+				"overhead.hashtable.main", main,
+				"overhead.hashtable.expires", expires,
+			})
+		}
+	}
+
+	bytesPerKey := int64(0)
+	if keys > 0 {
+		bytesPerKey = total / int64(keys)
+	}
+	reply := []any{
+		"total.allocated", total,
+		"overhead.total", overhead,
+	}
+	reply = append(reply, dbStats...)
+	reply = append(reply,
+		"keys.count", keys,
+		"keys.bytes-per-key", bytesPerKey,
+		"dataset.bytes", total-overhead,
+	)
+	return resp.Encode(reply)
+}
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strings"
+)
+
+// cmdMOVE handles MOVE key db, moving the key with its expiry unless db already holds it
+func cmdMOVE(args []string) []byte {
+	key := args[0]
+	dst, errReply := parseDBIndex(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	if dst == selectedDB {
+		return []byte(constant.ErrSameObject)
+	}
+
+	obj := lookupKeyWrite(key)
+	if obj == nil || databases[dst].Get(key) != nil {
+		return resp.Encode(0)
+	}
+	expiryTime, _ := dict.GetExpiryTime(key)
+	storeObject(databases[dst], key, obj, expiryTime)
+	dict.Delete(key)
+	return resp.Encode(1)
+}
+
+// cmdCOPY handles COPY source destination [DB destination-db] [REPLACE]. The destination
+// gets its own copy of the value and the expiry of the source
+func cmdCOPY(args []string) []byte {
+	src, dst := args[0], args[1]
+	dstDB := selectedDB
+	replace := false
+	for i := 2; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "REPLACE":
+			replace = true
+		case option == "DB" && i+1 < len(args):
+			var errReply []byte
+			if dstDB, errReply = parseDBIndex(args[i+1]); errReply != nil {
+				return errReply
+			}
+			i++
+		default:
+			return []byte(constant.ErrSyntax)
+		}
+	}
+	if src == dst && dstDB == selectedDB {
+		return []byte(constant.ErrSameObject)
+	}
+
+	obj := lookupKeyRead(src)
+	if obj == nil {
+		return resp.Encode(0)
+	}
+	db := databases[dstDB]
+	if db.Get(dst) != nil {
+		if !replace {
+			return resp.Encode(0)
+		}
+		db.Delete(dst)
+	}
+	expiryTime, _ := dict.GetExpiryTime(src)
+	storeObject(db, dst, obj.Dup(), expiryTime)
+	return resp.Encode(1)
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"fmt"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdMSET handles MSET key value [key value ...]
+func cmdMSET(args []string) []byte {
+	if len(args)%2 != 0 {
+		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "MSET"))
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		dict.Set(args[i], args[i+1], 0)
+	}
+	return []byte(constant.RespOk)
+}
+
+// cmdMSETNX handles MSETNX key value [key value ...], setting nothing when any key exists
+func cmdMSETNX(args []string) []byte {
+	if len(args)%2 != 0 {
+		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "MSETNX"))
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		if lookupKeyWrite(args[i]) != nil {
+			return resp.Encode(0)
+		}
+	}
+	for i := 0; i < len(args); i += 2 {
+		dict.Set(args[i], args[i+1], 0)
+	}
+	return resp.Encode(1)
+}
+
+// cmdMGET handles MGET key [key ...]. Missing keys and keys holding another type are nil
+func cmdMGET(args []string) []byte {
+	res := make([]any, len(args))
+	for i, key := range args {
+		if value, exists, ok := getString(key); exists && ok {
+			res[i] = value
+		}
+	}
+	return resp.Encode(res)
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdOBJECTENCODING handles OBJECT ENCODING key
+func cmdOBJECTENCODING(args []string) []byte {
+	obj := lookupKeyNoTouch(args[0])
+	if obj == nil {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(obj.CurrentEncoding().String())
+}
+
+// cmdOBJECTIDLETIME handles OBJECT IDLETIME key, returning the seconds since the last access
+func cmdOBJECTIDLETIME(args []string) []byte {
+	obj := lookupKeyNoTouch(args[0])
+	if obj == nil {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(obj.IdleTime())
+}
+
+// cmdOBJECTFREQ handles OBJECT FREQ key, returning the logarithmic access frequency counter
+func cmdOBJECTFREQ(args []string) []byte {
+	obj := lookupKeyNoTouch(args[0])
+	if obj == nil {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(obj.Freq())
+}
+
+// cmdOBJECTREFCOUNT handles OBJECT REFCOUNT key. Values are never shared between keys,
+// so an existing key always has one reference
+func cmdOBJECTREFCOUNT(args []string) []byte {
+	if lookupKeyNoTouch(args[0]) == nil {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(1)
+}
+
+// objectHelp is the reply of OBJECT HELP
+var objectHelp = []string{
+	"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"ENCODING <key>",
+	"    Return the kind of internal representation used in order to store the value",
+	"    associated with a <key>.",
+	"FREQ <key>",
+	"    Return the access frequency index of the <key>. The returned integer is",
+	"    proportional to the logarithm of the recent access frequency of the key.",
+	"IDLETIME <key>",
+	"    Return the idle time of the <key>, that is the approximated number of",
+	"    seconds elapsed since the last access to the key.",
+	"REFCOUNT <key>",
+	"    Return the number of references of the value associated with the specified",
+	"    <key>.",
+	"HELP",
+	"    Print this help.",
+}
+
+// cmdOBJECTHELP handles OBJECT HELP
+func cmdOBJECTHELP(args []string) []byte {
+	lines := make([]any, len(objectHelp))
+	for i, line := range objectHelp {
+		lines[i] = resp.SimpleString(line)
+	}
+	return resp.Encode(lines)
+}
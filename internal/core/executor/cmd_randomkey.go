@@ -0,0 +1,20 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdRANDOMKEY handles RANDOMKEY, returning a random key of the selected database or nil
+// when it is empty. Expired keys found on the way are deleted and another key is picked
+func cmdRANDOMKEY(args []string) []byte {
+	for {
+		key, ok := dict.RandomKey()
+		if !ok {
+			return []byte(constant.RespNil)
+		}
+		if lookupKeyRead(key) != nil {
+			return resp.Encode(key)
+		}
+	}
+}
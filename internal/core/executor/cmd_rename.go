@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdRENAME handles RENAME key newkey, overwriting newkey
+func cmdRENAME(args []string) []byte {
+	return renameGeneric(args[0], args[1], false)
+}
+
+// cmdRENAMENX handles RENAMENX key newkey, renaming only when newkey does not exist
+func cmdRENAMENX(args []string) []byte {
+	return renameGeneric(args[0], args[1], true)
+}
+
+// renameGeneric moves the value of key to newkey with its expiry, replying OK for
+// RENAME and 1 or 0 for RENAMENX
+func renameGeneric(key, newKey string, nx bool) []byte {
+	obj := lookupKeyWrite(key)
+	if obj == nil {
+		return []byte(constant.ErrNoSuchKey)
+	}
+	if key == newKey {
+		if nx {
+			return resp.Encode(0)
+		}
+		return []byte(constant.RespOk)
+	}
+
+	if lookupKeyWrite(newKey) != nil {
+		if nx {
+			return resp.Encode(0)
+		}
+		dict.Delete(newKey)
+	}
+	expiryTime, _ := dict.GetExpiryTime(key)
+	storeObject(dict, newKey, obj, expiryTime)
+	dict.Delete(key)
+
+	if nx {
+		return resp.Encode(1)
+	}
+	return []byte(constant.RespOk)
+}
@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/connection"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cmdREPLICAOF handles REPLICAOF host port, replicating another server, and REPLICAOF NO
+// ONE, turning the replica into a master
+func cmdREPLICAOF(args []string) []byte {
+	if strings.EqualFold(args[0], "no") && strings.EqualFold(args[1], "one") {
+		if masterHost != "" {
+			replicationUnsetMaster()
+			log.Println("MASTER MODE enabled")
+		}
+		return []byte(constant.RespOk)
+	}
+	if currentClient != nil && currentClient.Master {
+		return []byte(constant.ErrReplicaofFromMaster)
+	}
+
+	port, err := strconv.Atoi(args[1])
+	if err != nil || port < 0 || port > 65535 {
+		return []byte(constant.ErrInvalidMasterPort)
+	}
+	if strings.EqualFold(masterHost, args[0]) && masterPort == port {
+		return resp.Encode(resp.SimpleString("OK Already connected to specified master"))
+	}
+	replicationSetMaster(args[0], port)
+	log.Printf("REPLICAOF %s:%d enabled", args[0], port)
+	return []byte(constant.RespOk)
+}
+
+// cmdPSYNC handles PSYNC replicationid offset. The replica continues from offset when it
+// is in the backlog of the same history, otherwise it gets an RDB file once a background
+// save is done, followed by the commands run since
+func cmdPSYNC(args []string) []byte {
+	conn := currentClient
+	if conn == nil || conn.Master || findReplica(conn) != nil {
+		return []byte(constant.ErrReplicaConnection)
+	}
+	if masterHost != "" && masterLinkState != linkConnected {
+		return []byte(constant.ErrNoMasterLink)
+	}
+	offset, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return []byte(constant.ErrNotInteger)
+	}
+
+	if backlog != nil && (args[0] == replID || (args[0] == replID2 && offset <= secondReplOffset)) &&
+		offset >= backlog.offset && offset <= masterReplOffset+1 {
+		addReplica(replicaOnline)
+		conn.AddReply([]byte("+CONTINUE " + replID + "\r\n"))
+		conn.AddReply(backlog.since(offset))
+		log.Printf("Partial resynchronization request accepted, sending %d bytes of backlog", masterReplOffset+1-offset)
+		return nil
+	}
+
+	addReplica(replicaWaitBgsaveStart)
+	log.Println("Full resynchronization requested by a replica")
+	if bgsaveInFlight == nil && aofRewriteInFlight == nil {
+		rdbSaveBackground()
+	}
+	return nil
+}
+
+// cmdREPLCONF handles REPLCONF option value [option value ...], sent by a replica to its
+// master: listening-port and capa during the handshake, then ACK offset every second,
+// which gets no reply. GETACK, sent by a master, asks for an ACK right away
+func cmdREPLCONF(args []string) []byte {
+	if len(args)%2 != 0 {
+		return []byte(constant.ErrSyntax)
+	}
+	conn := currentClient
+	for i := 0; i < len(args); i += 2 {
+		switch option, value := strings.ToLower(args[i]), args[i+1]; option {
+		case "listening-port":
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				return []byte(constant.ErrNotInteger)
+			}
+			if conn != nil {
+				replicaPorts[conn] = port
+			}
+		case "capa":
+		case "ack":
+			if r := findReplica(conn); r != nil {
+				if offset, err := strconv.ParseInt(value, 10, 64); err == nil {
+					r.ackOffset, r.ackTime = offset, time.Now()
+				}
+			}
+			return nil
+		case "getack":
+			if conn != nil && conn.Master {
+				conn.AddReply(replconfAck())
+			}
+			return nil
+		default:
+			return resp.Encode(fmt.Errorf("ERR Unrecognized REPLCONF option: %s", args[i]))
+		}
+	}
+	return []byte(constant.RespOk)
+}
+
+// cmdROLE handles ROLE. A master replies its offset and, for every replica, its address
+// and acknowledged offset. A replica replies the address of its
+// master, the state of the link and its offset
+func cmdROLE(args []string) []byte {
+	if masterHost != "" {
+		offset := int64(-1)
+		if masterLinkState == linkConnected {
+			offset = masterReplOffset
+		}
+		return resp.Encode([]any{"slave", masterHost, int64(masterPort), masterLinkState, offset})
+	}
+
+	entries := []any{}
+	for _, r := range replicas {
+		if r.state != replicaOnline {
+			continue
+		}
+		entries = append(entries, []any{replicaIP(r.conn), strconv.Itoa(r.port), strconv.FormatInt(r.ackOffset, 10)})
+	}
+	return resp.Encode([]any{"master", masterReplOffset, entries})
+}
+
+// replicaIP returns the IP address of the peer of conn
+func replicaIP(conn *connection.Connection) string {
+	switch a := peerName(conn).(type) {
+	case *syscall.SockaddrInet4:
+		return net.IP(a.Addr[:]).String()
+	case *syscall.SockaddrInet6:
+		return net.IP(a.Addr[:]).String()
+	}
+	return "?"
+}
+
+func peerName(conn *connection.Connection) syscall.Sockaddr {
+	sa, err := syscall.Getpeername(conn.Fd)
+	if err != nil {
+		return nil
+	}
+	return sa
+}
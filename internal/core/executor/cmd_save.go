@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strings"
+)
+
+// cmdSAVE handles SAVE, writing the snapshot of the databases before replying
+func cmdSAVE(args []string) []byte {
+	if bgsaveInFlight != nil {
+		return []byte(constant.ErrBgsaveInProgress)
+	}
+	if err := rdbSave(); err != nil {
+		return []byte(constant.ErrSaveFailed)
+	}
+	return []byte(constant.RespOk)
+}
+
+// cmdBGSAVE handles BGSAVE [SCHEDULE], writing the snapshot in the background. During an
+// append only file rewrite, SCHEDULE starts it once the rewrite is done
+func cmdBGSAVE(args []string) []byte {
+	schedule := len(args) == 1 && strings.EqualFold(args[0], "SCHEDULE")
+	if len(args) > 1 || (len(args) == 1 && !schedule) {
+		return []byte(constant.ErrSyntax)
+	}
+	if bgsaveInFlight != nil {
+		return []byte(constant.ErrBgsaveInProgress)
+	}
+	if aofRewriteInFlight != nil {
+		if !schedule {
+			return []byte(constant.ErrBgsaveAOFActive)
+		}
+		bgsaveScheduled = true
+		return resp.Encode(resp.SimpleString("Background saving scheduled"))
+	}
+	rdbSaveBackground()
+	return resp.Encode(resp.SimpleString("Background saving started"))
+}
+
+// cmdBGREWRITEAOF handles BGREWRITEAOF, rewriting the append only file in the background.
+// During a background save, the rewrite starts once the save is done
+func cmdBGREWRITEAOF(args []string) []byte {
+	if aofRewriteInFlight != nil {
+		return []byte(constant.ErrAOFRewriteActive)
+	}
+	if bgsaveInFlight != nil {
+		aofRewriteScheduled = true
+		return resp.Encode(resp.SimpleString("Background append only file rewriting scheduled"))
+	}
+	if err := rewriteAppendOnlyFileBackground(); err != nil {
+		return []byte(constant.ErrAOFRewriteFailed)
+	}
+	return resp.Encode(resp.SimpleString("Background append only file rewriting started"))
+}
+
+// cmdLASTSAVE handles LASTSAVE, returning the Unix time of the last successful save
+func cmdLASTSAVE(args []string) []byte {
+	return resp.Encode(lastSave)
+}
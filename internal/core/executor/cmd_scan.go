@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"redis-repo/internal/glob"
+	"strconv"
+	"strings"
+)
+
+// scanOptions holds the options shared by the SCAN family of commands
+type scanOptions struct {
+	pattern  string // Empty when every element matches
+	count    int
+	noValues bool
+	typeName string // Empty when keys of every type match
+}
+
+// Options accepted by parseScanOptions besides MATCH and COUNT
+const (
+	scanAllowNoValues = 1 << iota
+	scanAllowType
+)
+
+// parseScanOptions parses [MATCH pattern] [COUNT count], with [NOVALUES] and [TYPE type]
+// when allowed by the allow bits
+func parseScanOptions(options []string, allow int) (scanOptions, []byte) {
+	opts := scanOptions{count: 10}
+	for i := 0; i < len(options); i++ {
+		option := strings.ToUpper(options[i])
+		hasNext := i+1 < len(options)
+
+		switch {
+		case option == "MATCH" && hasNext:
+			opts.pattern = options[i+1]
+			if opts.pattern == "*" {
+				opts.pattern = "" // Matches everything, the empty element included
+			}
+			i++
+		case option == "COUNT" && hasNext:
+			count, err := strconv.Atoi(options[i+1])
+			if err != nil {
+				return opts, []byte(constant.ErrNotInteger)
+			}
+			if count < 1 {
+				return opts, []byte(constant.ErrSyntax)
+			}
+			opts.count = count
+			i++
+		case option == "NOVALUES" && allow&scanAllowNoValues != 0:
+			opts.noValues = true
+		case option == "TYPE" && hasNext && allow&scanAllowType != 0:
+			opts.typeName = strings.ToLower(options[i+1])
+			if !isTypeName(opts.typeName) {
+				return opts, resp.Encode(fmt.Errorf("ERR unknown type name '%s'", options[i+1]))
+			}
+			i++
+		default:
+			return opts, []byte(constant.ErrSyntax)
+		}
+	}
+	return opts, nil
+}
+
+// isTypeName reports whether name is a type reported by TYPE
+func isTypeName(name string) bool {
+	for t := data_structure.ObjString; t <= data_structure.ObjHash; t++ {
+		if t.String() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScanCursor parses a cursor, an unsigned 64 bit integer
+func parseScanCursor(cursor string) (uint64, []byte) {
+	n, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, resp.Encode(errors.New("ERR invalid cursor"))
+	}
+	return n, nil
+}
+
+// matches reports whether s matches the MATCH pattern of the options
+func (opts scanOptions) matches(s string) bool {
+	if opts.pattern == "" {
+		return true
+	}
+	return glob.Match(opts.pattern, s)
+}
+
+// scanSteps calls step, which visits one bucket and returns the next cursor, until the
+// scan completes or at least count elements were visited. Like Redis it gives up after
+// 10 * count empty buckets, so a sparse table does not block the server
+func scanSteps(cursor uint64, count int, step func(cursor uint64) uint64, visited func() int) uint64 {
+	for maxSteps := 10 * count; ; maxSteps-- {
+		cursor = step(cursor)
+		if cursor == 0 || maxSteps <= 0 || visited() >= count {
+			return cursor
+		}
+	}
+}
+
+// scanReply builds the reply of the SCAN family: the next cursor and the elements
+func scanReply(cursor uint64, elements []any) []byte {
+	return resp.Encode([]any{strconv.FormatUint(cursor, 10), elements})
+}
+
+// Support SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+func cmdSCAN(args []string) []byte {
+	cursor, errReply := parseScanCursor(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[1:], scanAllowType)
+	if errReply != nil {
+		return errReply
+	}
+
+	type visitedKey struct {
+		key string
+		obj *data_structure.ValueObject
+	}
+	var keys []visitedKey
+	cursor = scanSteps(cursor, opts.count, func(cursor uint64) uint64 {
+		return dict.Scan(cursor, func(key string, obj *data_structure.ValueObject) {
+			keys = append(keys, visitedKey{key, obj})
+		})
+	}, func() int { return len(keys) })
+
+	elements := make([]any, 0, len(keys))
+	for _, k := range keys {
+		if opts.matches(k.key) && (opts.typeName == "" || k.obj.Type.String() == opts.typeName) {
+			elements = append(elements, k.key)
+		}
+	}
+	return scanReply(cursor, elements)
+}
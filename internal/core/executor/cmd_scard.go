@@ -1,21 +1,16 @@
 package executor
 
 import (
-	"fmt"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
 )
 
 func cmdSCARD(args []string) []byte {
-	if len(args) != 1 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "SCARD"))
-	}
-
 	keySet := args[0]
-	set, exists := setStore[keySet]
-	if !exists {
-		return resp.Encode(0) // Return 0 for non-existing set
+	set, ok := getSet(keySet)
+	if !ok {
+		return []byte(constant.ErrWrongType)
 	}
 
-	return resp.Encode(len(set))
+	return resp.Encode(set.Len()) // A non-existing set has 0 members
 }
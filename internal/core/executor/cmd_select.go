@@ -0,0 +1,38 @@
+package executor
+
+import (
+	"errors"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+)
+
+// cmdSELECT handles SELECT index, changing the database of the client
+func cmdSELECT(args []string) []byte {
+	id, errReply := parseDBIndex(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	selectDB(id)
+	return []byte(constant.RespOk)
+}
+
+// cmdSWAPDB handles SWAPDB index1 index2. The clients keep their selected index, so they
+// see the data of the other database from their next command on
+func cmdSWAPDB(args []string) []byte {
+	id1, err := strconv.Atoi(args[0])
+	if err != nil {
+		return resp.Encode(errors.New("ERR invalid first DB index"))
+	}
+	id2, err := strconv.Atoi(args[1])
+	if err != nil {
+		return resp.Encode(errors.New("ERR invalid second DB index"))
+	}
+	if id1 < 0 || id1 >= len(databases) || id2 < 0 || id2 >= len(databases) {
+		return []byte(constant.ErrDBOutOfRange)
+	}
+
+	databases[id1], databases[id2] = databases[id2], databases[id1]
+	selectDB(selectedDB)
+	return []byte(constant.RespOk)
+}
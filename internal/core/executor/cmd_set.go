@@ -1,9 +1,9 @@
 package executor
 
 import (
-	"errors"
 	"fmt"
 	"log"
+	"math"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
 	"strconv"
@@ -11,43 +11,159 @@ import (
 	"time"
 )
 
-// Support SET key value [EX seconds|PX milliseconds|EXAT timestamp|PXAT milliseconds-timestamp]
-func cmdSET(args []string) []byte {
-	if len(args) != 2 && len(args) != 4 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "SET"))
+// Flags of the extended string arguments shared by SET and GETEX
+const (
+	setNX = 1 << iota
+	setXX
+	setGet
+	setKeepTTL
+	setPersist
+	setEX
+	setPX
+	setEXAT
+	setPXAT
+)
+
+const setExpiryFlags = setEX | setPX | setEXAT | setPXAT
+
+// stringOptions holds the parsed options of SET or GETEX
+type stringOptions struct {
+	flags        int
+	expiryTimeMs uint64
+}
+
+// parseStringOptions parses the options of SET (forSet) or GETEX in any order.
+// Conflicting or unknown options are a syntax error
+func parseStringOptions(options []string, forSet bool) (stringOptions, []byte) {
+	var opts stringOptions
+	for i := 0; i < len(options); i++ {
+		option := strings.ToUpper(options[i])
+		hasNext := i+1 < len(options)
+
+		switch {
+		case forSet && option == "NX" && opts.flags&setXX == 0:
+			opts.flags |= setNX
+		case forSet && option == "XX" && opts.flags&setNX == 0:
+			opts.flags |= setXX
+		case forSet && option == "GET":
+			opts.flags |= setGet
+		case forSet && option == "KEEPTTL" && opts.flags&(setExpiryFlags|setPersist) == 0:
+			opts.flags |= setKeepTTL
+		case !forSet && option == "PERSIST" && opts.flags&(setExpiryFlags|setKeepTTL) == 0:
+			opts.flags |= setPersist
+		case hasNext && opts.flags&(setExpiryFlags|setKeepTTL|setPersist) == 0 &&
+			(option == "EX" || option == "PX" || option == "EXAT" || option == "PXAT"):
+			var err error
+			timeStr := options[i+1]
+			i++
+
+			switch option {
+			case "EX": // TimeStr in seconds
+				opts.flags |= setEX
+				opts.expiryTimeMs, err = expiryTimeMsFromEX(timeStr)
+			case "PX": // TimeStr in milliseconds
+				opts.flags |= setPX
+				opts.expiryTimeMs, err = expiryTimeMsFromPX(timeStr)
+			case "EXAT": // TimeStr in timestamp
+				opts.flags |= setEXAT
+				opts.expiryTimeMs, err = expiryTimeMsFromEXAT(timeStr)
+			case "PXAT": // TimeStr in milliseconds-timestamp
+				opts.flags |= setPXAT
+				opts.expiryTimeMs, err = expiryTimeMsFromPXAT(timeStr)
+			}
+
+			if err != nil {
+				log.Println(err)
+				return opts, []byte(constant.ErrInvalidTime)
+			}
+		default:
+			return opts, []byte(constant.ErrSyntax)
+		}
 	}
+	return opts, nil
+}
 
+// Support SET key value [NX | XX] [GET] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+func cmdSET(args []string) []byte {
 	if args[0] == "" {
 		return []byte(constant.ErrEmptyKey)
 	}
 
-	var expiryTimeMs uint64
-	var err error
-	// Has expiry time
-	if len(args) == 4 {
-		typeExp, timeStr := args[2], args[3]
-
-		switch strings.ToUpper(typeExp) {
-		case "EX": // TimeStr in seconds
-			expiryTimeMs, err = expiryTimeMsFromEX(timeStr)
-		case "PX": // TimeStr in milliseconds
-			expiryTimeMs, err = expiryTimeMsFromPX(timeStr)
-		case "EXAT": // TimeStr in timestamp
-			expiryTimeMs, err = expiryTimeMsFromEXAT(timeStr)
-		case "PXAT": // TimeStr in milliseconds-timestamp
-			expiryTimeMs, err = expiryTimeMsFromPXAT(timeStr)
-		default:
-			return resp.Encode(errors.New("ERR invalid type of expiry time"))
+	opts, errReply := parseStringOptions(args[2:], true)
+	if errReply != nil {
+		return errReply
+	}
+
+	return setGeneric(args[0], args[1], opts)
+}
+
+// setGeneric stores a string value following the parsed SET options
+func setGeneric(key string, value string, opts stringOptions) []byte {
+	var oldValue any // Reply of the GET option, nil when the key did not exist
+	if opts.flags&setGet != 0 {
+		old, exists, ok := getString(key)
+		if !ok {
+			return []byte(constant.ErrWrongType)
 		}
+		if exists {
+			oldValue = old
+		}
+	}
 
-		if err != nil {
-			log.Println(err)
-			return []byte(constant.ErrInvalidTime)
+	exists := lookupKeyWrite(key) != nil
+	if (opts.flags&setNX != 0 && exists) || (opts.flags&setXX != 0 && !exists) {
+		rewriteCommand()
+		if opts.flags&setGet != 0 {
+			return resp.Encode(oldValue)
 		}
+		return []byte(constant.RespNil)
 	}
 
-	dict.Set(args[0], args[1], expiryTimeMs)
+	if opts.flags&setKeepTTL != 0 {
+		dict.SetDictStore(key, value)
+	} else {
+		dict.Set(key, value, opts.expiryTimeMs)
+	}
+	if opts.flags&setExpiryFlags != 0 {
+		rewriteCommand([]string{"SET", key, value, "PXAT", strconv.FormatUint(opts.expiryTimeMs, 10)})
+	}
 
+	if opts.flags&setGet != 0 {
+		return resp.Encode(oldValue)
+	}
+	return []byte(constant.RespOk)
+}
+
+// cmdSETNX handles SETNX key value
+func cmdSETNX(args []string) []byte {
+	if lookupKeyWrite(args[0]) != nil {
+		return resp.Encode(0)
+	}
+	dict.Set(args[0], args[1], 0)
+	return resp.Encode(1)
+}
+
+// cmdSETEX handles SETEX key seconds value
+func cmdSETEX(args []string) []byte {
+	expiryTimeMs, err := expiryTimeMsFromEX(args[1])
+	if err != nil {
+		log.Println(err)
+		return []byte(constant.ErrInvalidTime)
+	}
+	dict.Set(args[0], args[2], expiryTimeMs)
+	rewriteCommand([]string{"SET", args[0], args[2], "PXAT", strconv.FormatUint(expiryTimeMs, 10)})
+	return []byte(constant.RespOk)
+}
+
+// cmdPSETEX handles PSETEX key milliseconds value
+func cmdPSETEX(args []string) []byte {
+	expiryTimeMs, err := expiryTimeMsFromPX(args[1])
+	if err != nil {
+		log.Println(err)
+		return []byte(constant.ErrInvalidTime)
+	}
+	dict.Set(args[0], args[2], expiryTimeMs)
+	rewriteCommand([]string{"SET", args[0], args[2], "PXAT", strconv.FormatUint(expiryTimeMs, 10)})
 	return []byte(constant.RespOk)
 }
 
@@ -60,7 +176,11 @@ func expiryTimeMsFromEX(timeStr string) (uint64, error) {
 		return 0, fmt.Errorf("expiryTimeMsFromEX: invalid ttl %q, must be >= 0", timeStr)
 	}
 
-	expiryTimeMs := uint64(time.Now().Unix()+ttlSec) * 1000 // to milliseconds
+	if ttlSec > (math.MaxInt64-time.Now().UnixMilli())/1000 {
+		return 0, fmt.Errorf("expiryTimeMsFromEX: invalid ttl %q, overflows", timeStr)
+	}
+
+	expiryTimeMs := uint64(time.Now().UnixMilli() + ttlSec*1000) // to milliseconds
 
 	return expiryTimeMs, nil
 }
@@ -74,6 +194,10 @@ func expiryTimeMsFromPX(timeStr string) (uint64, error) {
 		return 0, fmt.Errorf("expiryTimeMsFromPX: invalid ttl %q, must be >= 0", timeStr)
 	}
 
+	if ttlMs > math.MaxInt64-time.Now().UnixMilli() {
+		return 0, fmt.Errorf("expiryTimeMsFromPX: invalid ttl %q, overflows", timeStr)
+	}
+
 	expiryTimeMs := uint64(time.Now().UnixMilli() + ttlMs)
 
 	return expiryTimeMs, nil
@@ -84,10 +208,14 @@ func expiryTimeMsFromEXAT(timeStr string) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	if expiryTimeSec <= time.Now().Unix() {
+	if expiryTimeSec <= 0 || (!loading && expiryTimeSec <= time.Now().Unix()) { // The AOF may hold passed times
 		return 0, fmt.Errorf("expiryTimeMsFromEXAT: invalid timestamp %q, must be >= now", timeStr)
 	}
 
+	if expiryTimeSec > math.MaxInt64/1000 {
+		return 0, fmt.Errorf("expiryTimeMsFromEXAT: invalid timestamp %q, overflows", timeStr)
+	}
+
 	expiryTimeMs := uint64(expiryTimeSec) * 1000
 
 	return expiryTimeMs, nil
@@ -98,7 +226,7 @@ func expiryTimeMsFromPXAT(timeStr string) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	if expiryTimeMs <= time.Now().UnixMilli() {
+	if expiryTimeMs <= 0 || (!loading && expiryTimeMs <= time.Now().UnixMilli()) { // The AOF may hold passed times
 		return 0, fmt.Errorf("expiryTimeMsFromEXAT: invalid timestamp (milliseconds) %q, must be >= now", timeStr)
 	}
 
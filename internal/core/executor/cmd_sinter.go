@@ -1,46 +1,50 @@
 package executor
 
 import (
-	"fmt"
+	"errors"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
 )
 
+// Support SINTER key [key ...]
 func cmdSINTER(args []string) []byte {
-	if len(args) == 0 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "SINTER"))
-	}
-
-	smallestKey := args[0]
-	for i := 1; i < len(args); i++ {
-		if _, exists := setStore[args[i]]; !exists {
-			return resp.Encode([]any{})
-		}
-		if len(setStore[args[i]]) < len(setStore[smallestKey]) {
-			smallestKey = args[i]
-		}
-	}
+	return setOpGeneric("", args, setOpInter)
+}
 
-	result := make([]any, 0)
+// Support SINTERSTORE destination key [key ...]
+func cmdSINTERSTORE(args []string) []byte {
+	return setOpGeneric(args[0], args[1:], setOpInter)
+}
 
-	// Check each member of the smallest set against all other sets
-	for member := range setStore[smallestKey] {
-		validMember := true
-		for _, key := range args {
-			if key == smallestKey {
-				continue
-			}
+// Support SINTERCARD numkeys key [key ...] [LIMIT limit]
+func cmdSINTERCARD(args []string) []byte {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys <= 0 {
+		return resp.Encode(errors.New("ERR numkeys should be greater than 0"))
+	}
+	if numKeys > len(args)-1 {
+		return resp.Encode(errors.New("ERR Number of keys can't be greater than number of args"))
+	}
 
-			if setStore[key].IsMember(member) == 0 { // Member not found
-				validMember = false
-				break
-			}
+	limit := 0
+	options := args[1+numKeys:]
+	for i := 0; i < len(options); i++ {
+		if strings.ToUpper(options[i]) != "LIMIT" || i+1 >= len(options) {
+			return []byte(constant.ErrSyntax)
 		}
-
-		if validMember {
-			result = append(result, member)
+		limit, err = strconv.Atoi(options[i+1])
+		if err != nil || limit < 0 {
+			return resp.Encode(errors.New("ERR LIMIT can't be negative"))
 		}
+		i++
 	}
 
-	return resp.Encode(result)
+	sets, errReply := loadSets(args[1 : 1+numKeys])
+	if errReply != nil {
+		return errReply
+	}
+	return resp.Encode(data_structure.SetInterCard(sets, limit))
 }
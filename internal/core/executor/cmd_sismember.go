@@ -1,31 +1,30 @@
 package executor
 
 import (
-	"fmt"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
 )
 
-func cmdSMISMEMBER(args []string) []byte {
-	if len(args) < 2 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "SMISMEMBER"))
+func cmdSISMEMBER(args []string) []byte {
+	set, ok := getSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
 	}
+	return resp.Encode(set.IsMember(args[1])) // A non-existing set has no members
+}
 
+func cmdSMISMEMBER(args []string) []byte {
 	keySet := args[0]
 	members := args[1:]
 	ans := make([]any, len(members))
 
-	set, exists := setStore[keySet]
-	if !exists {
-		// Initialize all elements to 0 (member not found)
-		for i := range ans {
-			ans[i] = 0
-		}
-		return resp.Encode(ans)
+	set, ok := getSet(keySet)
+	if !ok {
+		return []byte(constant.ErrWrongType)
 	}
 
 	for i, member := range members {
-		ans[i] = set.IsMember(member)
+		ans[i] = set.IsMember(member) // Members of a non-existing set are reported as 0
 	}
 
 	return resp.Encode(ans)
@@ -1,26 +1,22 @@
 package executor
 
 import (
-	"fmt"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
 )
 
 func cmdSMEMBERS(args []string) []byte {
-	if len(args) != 1 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "SMEMBERS"))
-	}
-
 	keySet := args[0]
-	set, exists := setStore[keySet]
-	if !exists {
-		return resp.Encode([]any{}) // Return empty array
+	set, ok := getSet(keySet)
+	if !ok {
+		return []byte(constant.ErrWrongType)
 	}
 
-	ans := make([]any, 0, len(set))
-	for member := range set {
+	ans := make([]any, 0, set.Len())
+	set.Iterate(func(member string) bool {
 		ans = append(ans, member)
-	}
+		return true
+	})
 
-	return resp.Encode(ans)
+	return resp.Encode(ans) // Empty array for a non-existing set
 }
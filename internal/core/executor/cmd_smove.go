@@ -0,0 +1,36 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+)
+
+// Support SMOVE source destination member
+func cmdSMOVE(args []string) []byte {
+	srcKey, dstKey, member := args[0], args[1], args[2]
+
+	src, ok := getSetForWrite(srcKey)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	dst, ok := getSetForWrite(dstKey)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if src.IsMember(member) == 0 {
+		return resp.Encode(0)
+	}
+	if srcKey == dstKey {
+		return resp.Encode(1) // Moving a member to its own set changes nothing
+	}
+
+	src.Remove([]string{member})
+	deleteIfEmpty(srcKey, src.Len())
+	if dst == nil {
+		dict.Set(dstKey, data_structure.NewSet([]string{member}), 0)
+	} else {
+		dst.Add([]string{member})
+	}
+	return resp.Encode(1)
+}
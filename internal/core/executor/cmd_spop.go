@@ -0,0 +1,79 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+)
+
+// spopMoveStrategyMul is Redis's SPOP_MOVE_STRATEGY_MUL: when fewer than count*5 members
+// would be left, picking the members kept costs less than popping count of them
+const spopMoveStrategyMul = 5
+
+// Support SPOP key [count]
+func cmdSPOP(args []string) []byte {
+	if len(args) > 2 {
+		return []byte(constant.ErrSyntax)
+	}
+
+	key := args[0]
+	set, ok := getSetForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	if len(args) == 1 {
+		if set == nil {
+			return []byte(constant.RespNil)
+		}
+		member := set.RandomMember()
+		set.Remove([]string{member})
+		deleteIfEmpty(key, set.Len())
+		rewriteCommand([]string{"SREM", key, member}) // The AOF removes the member chosen
+		return resp.Encode(member)
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count < 0 {
+		return []byte(constant.ErrNotPositive)
+	}
+	if set == nil || count == 0 {
+		return resp.Encode([]any{})
+	}
+
+	// Like Redis, the members are popped one at a time unless few are left, then the
+	// members kept are picked instead
+	var popped []string
+	if remaining := set.Len() - count; remaining <= 0 {
+		popped = set.Members()
+		set.Remove(popped)
+	} else if remaining*spopMoveStrategyMul > count {
+		popped = make([]string, 0, count)
+		for range count {
+			member := set.RandomMember()
+			set.Remove([]string{member})
+			popped = append(popped, member)
+		}
+	} else {
+		kept := make(map[string]struct{}, remaining)
+		for _, member := range randomDistinct(set.Len(), remaining, set.RandomMember, set.Members) {
+			kept[member] = struct{}{}
+		}
+		set.Iterate(func(member string) bool {
+			if _, ok := kept[member]; !ok {
+				popped = append(popped, member)
+			}
+			return true
+		})
+		set.Remove(popped)
+	}
+
+	res := make([]any, 0, len(popped))
+	for _, member := range popped {
+		res = append(res, member)
+	}
+	deleteIfEmpty(key, set.Len())
+	srem := append([]string{"SREM", key}, popped...)
+	rewriteCommand(srem)
+	return resp.Encode(res)
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"math"
+	"math/rand"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strconv"
+)
+
+// randomSubStrategyMul is Redis's SRANDMEMBER_SUB_STRATEGY_MUL: past size/3 distinct
+// elements, copying every element and picking from the copy beats sampling, which keeps
+// drawing elements already picked
+const randomSubStrategyMul = 3
+
+// randomDistinct returns count distinct random elements of a collection of size
+// elements, picked with random. Like Redis, a count close to size picks from all the
+// elements instead, so both cost in proportion to count
+func randomDistinct(size, count int, random func() string, all func() []string) []string {
+	if count >= size {
+		return all()
+	}
+	if count*randomSubStrategyMul > size {
+		elements := all()
+		for i := 0; i < count; i++ {
+			j := i + rand.Intn(len(elements)-i)
+			elements[i], elements[j] = elements[j], elements[i]
+		}
+		return elements[:count]
+	}
+
+	picked := make([]string, 0, count)
+	seen := make(map[string]struct{}, count)
+	for len(picked) < count {
+		element := random()
+		if _, ok := seen[element]; !ok {
+			seen[element] = struct{}{}
+			picked = append(picked, element)
+		}
+	}
+	return picked
+}
+
+// Support SRANDMEMBER key [count]. A positive count returns distinct members, a negative
+// one returns -count members that may repeat
+func cmdSRANDMEMBER(args []string) []byte {
+	if len(args) > 2 {
+		return []byte(constant.ErrSyntax)
+	}
+
+	set, ok := getSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	if len(args) == 1 {
+		if set == nil {
+			return []byte(constant.RespNil)
+		}
+		return resp.Encode(set.RandomMember())
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count == math.MinInt {
+		return []byte(constant.ErrNotInteger)
+	}
+	if set == nil || count == 0 {
+		return resp.Encode([]any{})
+	}
+
+	var res []any
+	if count > 0 {
+		for _, member := range randomDistinct(set.Len(), count, set.RandomMember, set.Members) {
+			res = append(res, member)
+		}
+	} else {
+		for i := 0; i < -count; i++ {
+			res = append(res, set.RandomMember())
+		}
+	}
+	return resp.Encode(res)
+}
@@ -1,23 +1,23 @@
 package executor
 
 import (
-	"fmt"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
 )
 
 func cmdSREM(args []string) []byte {
-	if len(args) < 2 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "SREM"))
-	}
 	keySet := args[0]
 	members := args[1:]
 
-	set, exists := setStore[keySet]
-	if !exists {
+	set, ok := getSetForWrite(keySet)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if set == nil {
 		return resp.Encode(0) // Nothing to remove
 	}
 
 	removed := set.Remove(members)
+	deleteIfEmpty(keySet, set.Len())
 	return resp.Encode(removed)
 }
@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+)
+
+// Support SSCAN key cursor [MATCH pattern] [COUNT count]
+func cmdSSCAN(args []string) []byte {
+	cursor, errReply := parseScanCursor(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[2:], 0)
+	if errReply != nil {
+		return errReply
+	}
+
+	set, ok := getSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if set == nil {
+		return scanReply(0, []any{})
+	}
+
+	var members []string
+	cursor = scanSteps(cursor, opts.count, func(cursor uint64) uint64 {
+		return set.Scan(cursor, func(member string) {
+			members = append(members, member)
+		})
+	}, func() int { return len(members) })
+
+	elements := make([]any, 0, len(members))
+	for _, member := range members {
+		if opts.matches(member) {
+			elements = append(elements, member)
+		}
+	}
+	return scanReply(cursor, elements)
+}
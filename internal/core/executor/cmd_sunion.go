@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+)
+
+// Operations of setOpGeneric
+const (
+	setOpUnion = iota
+	setOpInter
+	setOpDiff
+)
+
+// Support SUNION key [key ...]
+func cmdSUNION(args []string) []byte {
+	return setOpGeneric("", args, setOpUnion)
+}
+
+// Support SUNIONSTORE destination key [key ...]
+func cmdSUNIONSTORE(args []string) []byte {
+	return setOpGeneric(args[0], args[1:], setOpUnion)
+}
+
+// Support SDIFF key [key ...]
+func cmdSDIFF(args []string) []byte {
+	return setOpGeneric("", args, setOpDiff)
+}
+
+// Support SDIFFSTORE destination key [key ...]
+func cmdSDIFFSTORE(args []string) []byte {
+	return setOpGeneric(args[0], args[1:], setOpDiff)
+}
+
+// setOpGeneric computes the union, intersection or difference of the sets at keys. It
+// replies the members, or stores them at dst and replies their number when dst is set
+func setOpGeneric(dst string, keys []string, op int) []byte {
+	sets, errReply := loadSets(keys)
+	if errReply != nil {
+		return errReply
+	}
+
+	var result *data_structure.Set
+	switch op {
+	case setOpUnion:
+		result = data_structure.SetUnion(sets)
+	case setOpInter:
+		result = data_structure.SetInter(sets)
+	case setOpDiff:
+		result = data_structure.SetDiff(sets)
+	}
+
+	if dst != "" {
+		if result.Len() == 0 {
+			dict.Delete(dst)
+			return resp.Encode(0)
+		}
+		dict.Set(dst, result, 0)
+		return resp.Encode(result.Len())
+	}
+
+	members := make([]any, 0, result.Len())
+	result.Iterate(func(member string) bool {
+		members = append(members, member)
+		return true
+	})
+	return resp.Encode(members)
+}
+
+// loadSets returns the sets at keys, a missing key being an empty set, or an error
+// reply when a key holds another type
+func loadSets(keys []string) ([]*data_structure.Set, []byte) {
+	sets := make([]*data_structure.Set, len(keys))
+	for i, key := range keys {
+		set, ok := getSet(key)
+		if !ok {
+			return nil, []byte(constant.ErrWrongType)
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
@@ -0,0 +1,17 @@
+package executor
+
+import (
+	"redis-repo/internal/core/resp"
+)
+
+// cmdTOUCH handles TOUCH key [key ...], recording an access to the keys and returning how
+// many of them exist
+func cmdTOUCH(args []string) []byte {
+	count := 0
+	for _, key := range args {
+		if lookupKeyRead(key) != nil {
+			count++
+		}
+	}
+	return resp.Encode(count)
+}
@@ -1,22 +1,27 @@
 package executor
 
 import (
-	"fmt"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/core/resp"
 	"time"
 )
 
+// cmdTTL handles TTL key, returning the remaining time to live in seconds
 func cmdTTL(args []string) []byte {
-	if len(args) != 1 {
-		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, "TTL"))
-	}
-	key := args[0]
+	return ttlGeneric(args[0], false)
+}
+
+// cmdPTTL handles PTTL key, returning the remaining time to live in milliseconds
+func cmdPTTL(args []string) []byte {
+	return ttlGeneric(args[0], true)
+}
+
+func ttlGeneric(key string, outputMs bool) []byte {
 	if key == "" {
 		return []byte(constant.ErrEmptyKey)
 	}
 
-	vObj := dict.Get(key)
+	vObj := lookupKeyRead(key)
 	if vObj == nil {
 		return []byte(constant.TtlKeyNotExist)
 	}
@@ -34,5 +39,8 @@ func cmdTTL(args []string) []byte {
 	}
 
 	remainMs := expiryTime - now
-	return resp.Encode(remainMs / 1000)
+	if outputMs {
+		return resp.Encode(remainMs)
+	}
+	return resp.Encode((remainMs + 500) / 1000)
 }
@@ -0,0 +1,14 @@
+package executor
+
+import (
+	"redis-repo/internal/core/resp"
+)
+
+// cmdTYPE handles TYPE key, returning the type of the value or none for a missing key
+func cmdTYPE(args []string) []byte {
+	obj := lookupKeyNoTouch(args[0])
+	if obj == nil {
+		return resp.EncodeSimpleString("none")
+	}
+	return resp.EncodeSimpleString(obj.Type.String())
+}
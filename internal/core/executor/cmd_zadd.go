@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// Flags of ZADD
+const (
+	zaddNX   = 1 << iota // Only add new members
+	zaddXX               // Only update existing members
+	zaddGT               // Only update when the new score is greater than the current one
+	zaddLT               // Only update when the new score is less than the current one
+	zaddCH               // Reply the number of added and updated members
+	zaddINCR             // Increment the score like ZINCRBY
+)
+
+// Support ZADD key [NX | XX] [GT | LT] [CH] [INCR] score member [score member ...]
+func cmdZADD(args []string) []byte {
+	flags := 0
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			flags |= zaddNX
+		case "XX":
+			flags |= zaddXX
+		case "GT":
+			flags |= zaddGT
+		case "LT":
+			flags |= zaddLT
+		case "CH":
+			flags |= zaddCH
+		case "INCR":
+			flags |= zaddINCR
+		default:
+			break options
+		}
+	}
+
+	elements := args[i:]
+	if len(elements) == 0 || len(elements)%2 != 0 {
+		return []byte(constant.ErrSyntax)
+	}
+	if flags&zaddNX != 0 && flags&zaddXX != 0 {
+		return resp.Encode(errors.New("ERR XX and NX options at the same time are not compatible"))
+	}
+	if (flags&zaddGT != 0 && flags&zaddNX != 0) || (flags&zaddLT != 0 && flags&zaddNX != 0) ||
+		(flags&zaddGT != 0 && flags&zaddLT != 0) {
+		return resp.Encode(errors.New("ERR GT, LT, and/or NX options at the same time are not compatible"))
+	}
+	if flags&zaddINCR != 0 && len(elements) > 2 {
+		return resp.Encode(errors.New("ERR INCR option supports a single increment-element pair"))
+	}
+
+	return zaddGeneric(args[0], flags, elements)
+}
+
+// cmdZINCRBY handles ZINCRBY key increment member
+func cmdZINCRBY(args []string) []byte {
+	return zaddGeneric(args[0], zaddINCR, args[1:])
+}
+
+// zaddGeneric adds the score and member pairs of elements following the ZADD flags
+func zaddGeneric(key string, flags int, elements []string) []byte {
+	// Parse every score before changing anything
+	scores := make([]float64, len(elements)/2)
+	for i := range scores {
+		score, ok := parseScore(elements[2*i])
+		if !ok {
+			return []byte(constant.ErrNotFloat)
+		}
+		scores[i] = score
+	}
+
+	zset, ok := getZSetForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		if flags&zaddXX != 0 {
+			if flags&zaddINCR != 0 {
+				return []byte(constant.RespNil)
+			}
+			return resp.Encode(0)
+		}
+		zset = data_structure.NewZSet()
+		dict.Set(key, zset, 0)
+	}
+
+	added, updated := 0, 0
+	var incrScore any // Reply of INCR, nil when the condition is not met
+	for i, score := range scores {
+		member := elements[2*i+1]
+		current, exists := zset.Score(member)
+
+		if !exists {
+			if flags&zaddXX != 0 {
+				continue
+			}
+			zset.Set(member, score)
+			added++
+			incrScore = formatScore(score)
+			continue
+		}
+
+		if flags&zaddNX != 0 {
+			continue
+		}
+		if flags&zaddINCR != 0 {
+			score += current
+			if math.IsNaN(score) {
+				return resp.Encode(errors.New("ERR resulting score is not a number (NaN)"))
+			}
+		}
+		if (flags&zaddLT != 0 && score >= current) || (flags&zaddGT != 0 && score <= current) {
+			continue
+		}
+		if score != current {
+			zset.Set(member, score)
+			updated++
+		}
+		incrScore = formatScore(score)
+	}
+
+	if flags&zaddINCR != 0 {
+		return resp.Encode(incrScore)
+	}
+	if flags&zaddCH != 0 {
+		return resp.Encode(added + updated)
+	}
+	return resp.Encode(added)
+}
+
+// parseScore parses a sorted set score, accepting "inf", "+inf" and "-inf" but not NaN
+func parseScore(s string) (float64, bool) {
+	if len(s) == 0 || strings.ContainsAny(s, " \t\r\n") {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// formatScore formats a score as Redis does: the shortest representation, switching
+// to the exponent notation for very small and very large numbers
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+
+	s := strconv.FormatFloat(score, 'e', -1, 64)
+	exp, _ := strconv.Atoi(s[strings.IndexByte(s, 'e')+1:])
+	if exp < -4 || exp >= 17 {
+		return s
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
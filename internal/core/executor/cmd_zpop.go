@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"fmt"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+)
+
+// Support ZPOPMIN key [count]
+func cmdZPOPMIN(args []string) []byte {
+	return zpopGeneric("ZPOPMIN", args, false)
+}
+
+// Support ZPOPMAX key [count]
+func cmdZPOPMAX(args []string) []byte {
+	return zpopGeneric("ZPOPMAX", args, true)
+}
+
+// zpopGeneric pops up to count members with the lowest or the highest scores, replying
+// members and scores interleaved
+func zpopGeneric(name string, args []string, highest bool) []byte {
+	if len(args) > 2 {
+		return []byte(fmt.Sprintf(constant.ErrWrongArgCount, name))
+	}
+
+	key := args[0]
+	count := 1
+	if len(args) == 2 {
+		var err error
+		count, err = strconv.Atoi(args[1])
+		if err != nil || count < 0 {
+			return []byte(constant.ErrNotPositive)
+		}
+	}
+
+	zset, ok := getZSetForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		return resp.Encode([]any{})
+	}
+
+	res := make([]any, 0, 2*min(count, zset.Len()))
+	for i := 0; i < count; i++ {
+		var m data_structure.ZSetMember
+		if highest {
+			m, ok = zset.PopMax()
+		} else {
+			m, ok = zset.PopMin()
+		}
+		if !ok {
+			break
+		}
+		res = append(res, m.Member, formatScore(m.Score))
+	}
+	deleteIfEmpty(key, zset.Len())
+	return resp.Encode(res)
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"math"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// Support ZRANDMEMBER key [count [WITHSCORES]]. A positive count returns distinct members,
+// a negative one returns -count members that may repeat
+func cmdZRANDMEMBER(args []string) []byte {
+	if len(args) > 3 || (len(args) == 3 && strings.ToUpper(args[2]) != "WITHSCORES") {
+		return []byte(constant.ErrSyntax)
+	}
+	withScores := len(args) == 3
+
+	zset, ok := getZSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	if len(args) == 1 {
+		if zset == nil {
+			return []byte(constant.RespNil)
+		}
+		return resp.Encode(zset.RandomMember().Member)
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil || count == math.MinInt {
+		return []byte(constant.ErrNotInteger)
+	}
+	if zset == nil || count == 0 {
+		return resp.Encode([]any{})
+	}
+
+	var picked []data_structure.ZSetMember
+	if count > 0 {
+		randomMember := func() string {
+			return zset.RandomMember().Member
+		}
+		allMembers := func() []string {
+			members := make([]string, 0, zset.Len())
+			zset.Iterate(func(m data_structure.ZSetMember) bool {
+				members = append(members, m.Member)
+				return true
+			})
+			return members
+		}
+		for _, member := range randomDistinct(zset.Len(), count, randomMember, allMembers) {
+			score, _ := zset.Score(member)
+			picked = append(picked, data_structure.ZSetMember{Member: member, Score: score})
+		}
+	} else {
+		for i := 0; i < -count; i++ {
+			picked = append(picked, zset.RandomMember())
+		}
+	}
+
+	res := make([]any, 0, 2*len(picked))
+	for _, m := range picked {
+		res = append(res, m.Member)
+		if withScores {
+			res = append(res, formatScore(m.Score))
+		}
+	}
+	return resp.Encode(res)
+}
@@ -0,0 +1,195 @@
+package executor
+
+import (
+	"errors"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// How the start and stop arguments of ZRANGE are interpreted
+const (
+	zrangeByRank = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// Support ZRANGE key start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func cmdZRANGE(args []string) []byte {
+	return zrangeGeneric(args, "", false)
+}
+
+// Support ZRANGESTORE dst src min max [BYSCORE | BYLEX] [REV] [LIMIT offset count]
+func cmdZRANGESTORE(args []string) []byte {
+	return zrangeGeneric(args[1:], args[0], true)
+}
+
+// zrangeGeneric selects a range of the sorted set at args[0] and replies it, or stores
+// it at dst and replies its length when store is set
+func zrangeGeneric(args []string, dst string, store bool) []byte {
+	key, startArg, stopArg := args[0], args[1], args[2]
+
+	rangeType := zrangeByRank
+	var reverse, withScores, hasLimit bool
+	offset, limit := 0, -1 // A negative limit returns every member after offset
+	options := args[3:]
+	for i := 0; i < len(options); i++ {
+		switch option := strings.ToUpper(options[i]); {
+		case option == "BYSCORE" && rangeType == zrangeByRank:
+			rangeType = zrangeByScore
+		case option == "BYLEX" && rangeType == zrangeByRank:
+			rangeType = zrangeByLex
+		case option == "REV":
+			reverse = true
+		case option == "WITHSCORES" && !store:
+			withScores = true
+		case option == "LIMIT" && i+2 < len(options):
+			var err error
+			if offset, err = strconv.Atoi(options[i+1]); err != nil {
+				return []byte(constant.ErrNotInteger)
+			}
+			if limit, err = strconv.Atoi(options[i+2]); err != nil {
+				return []byte(constant.ErrNotInteger)
+			}
+			hasLimit = true
+			i += 2
+		default:
+			return []byte(constant.ErrSyntax)
+		}
+	}
+	if hasLimit && rangeType == zrangeByRank {
+		return resp.Encode(errors.New("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX"))
+	}
+	if withScores && rangeType == zrangeByLex {
+		return resp.Encode(errors.New("ERR syntax error, WITHSCORES not supported in combination with BYLEX"))
+	}
+
+	// Reversed score and lex ranges are given from max to min
+	if reverse && rangeType != zrangeByRank {
+		startArg, stopArg = stopArg, startArg
+	}
+
+	var members []data_structure.ZSetMember
+	collect := func(m data_structure.ZSetMember) bool {
+		members = append(members, m)
+		return limit < 0 || len(members) < limit
+	}
+
+	var start, stop int
+	var scoreRange data_structure.ScoreRange
+	var lexRange data_structure.LexRange
+	var errReply []byte
+	switch rangeType {
+	case zrangeByRank:
+		var err1, err2 error
+		start, err1 = strconv.Atoi(startArg)
+		stop, err2 = strconv.Atoi(stopArg)
+		if err1 != nil || err2 != nil {
+			return []byte(constant.ErrNotInteger)
+		}
+	case zrangeByScore:
+		scoreRange, errReply = parseScoreRange(startArg, stopArg)
+	case zrangeByLex:
+		lexRange, errReply = parseLexRange(startArg, stopArg)
+	}
+	if errReply != nil {
+		return errReply
+	}
+
+	zset, ok := getZSet(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset != nil && offset >= 0 && limit != 0 {
+		switch rangeType {
+		case zrangeByRank:
+			length := zset.Len()
+			if start < 0 {
+				start += length
+			}
+			if stop < 0 {
+				stop += length
+			}
+			start = max(start, 0)
+			stop = min(stop, length-1)
+			zset.RangeByRank(start, stop, reverse, collect)
+		case zrangeByScore:
+			zset.RangeByScore(scoreRange, reverse, offset, collect)
+		case zrangeByLex:
+			zset.RangeByLex(lexRange, reverse, offset, collect)
+		}
+	}
+
+	if store {
+		if len(members) == 0 {
+			dict.Delete(dst)
+			return resp.Encode(0)
+		}
+		result := data_structure.NewZSet()
+		for _, m := range members {
+			result.Set(m.Member, m.Score)
+		}
+		dict.Set(dst, result, 0)
+		return resp.Encode(result.Len())
+	}
+
+	res := make([]any, 0, 2*len(members))
+	for _, m := range members {
+		res = append(res, m.Member)
+		if withScores {
+			res = append(res, formatScore(m.Score))
+		}
+	}
+	return resp.Encode(res)
+}
+
+// parseScoreRange parses the min and max of a score range, "(" marking an exclusive end
+func parseScoreRange(minArg, maxArg string) (data_structure.ScoreRange, []byte) {
+	var r data_structure.ScoreRange
+	var okMin, okMax bool
+	r.Min, r.MinEx, okMin = parseScoreBound(minArg)
+	r.Max, r.MaxEx, okMax = parseScoreBound(maxArg)
+	if !okMin || !okMax {
+		return r, resp.Encode(errors.New("ERR min or max is not a float"))
+	}
+	return r, nil
+}
+
+func parseScoreBound(s string) (score float64, exclusive bool, ok bool) {
+	if strings.HasPrefix(s, "(") {
+		exclusive = true
+		s = s[1:]
+	}
+	score, ok = parseScore(s)
+	return score, exclusive, ok
+}
+
+// parseLexRange parses the min and max of a lexicographic range: "-" and "+" are the
+// infinities, other ends start with "[" when inclusive or "(" when exclusive
+func parseLexRange(minArg, maxArg string) (data_structure.LexRange, []byte) {
+	var r data_structure.LexRange
+	var okMin, okMax bool
+	r.Min, okMin = parseLexBound(minArg)
+	r.Max, okMax = parseLexBound(maxArg)
+	if !okMin || !okMax {
+		return r, resp.Encode(errors.New("ERR min or max not valid string range item"))
+	}
+	return r, nil
+}
+
+func parseLexBound(s string) (data_structure.LexBound, bool) {
+	switch {
+	case s == "-":
+		return data_structure.LexBound{Inf: -1}, true
+	case s == "+":
+		return data_structure.LexBound{Inf: 1}, true
+	case strings.HasPrefix(s, "["):
+		return data_structure.LexBound{Value: s[1:]}, true
+	case strings.HasPrefix(s, "("):
+		return data_structure.LexBound{Value: s[1:], Exclusive: true}, true
+	default:
+		return data_structure.LexBound{}, false
+	}
+}
@@ -0,0 +1,27 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+)
+
+// cmdZREM handles ZREM key member [member ...], deleting the key with its last member
+func cmdZREM(args []string) []byte {
+	key := args[0]
+	zset, ok := getZSetForWrite(key)
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		return resp.Encode(0)
+	}
+
+	removed := 0
+	for _, member := range args[1:] {
+		if zset.Remove(member) {
+			removed++
+		}
+	}
+	deleteIfEmpty(key, zset.Len())
+	return resp.Encode(removed)
+}
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/data_structure"
+)
+
+// Support ZSCAN key cursor [MATCH pattern] [COUNT count], replying members and scores interleaved
+func cmdZSCAN(args []string) []byte {
+	cursor, errReply := parseScanCursor(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[2:], 0)
+	if errReply != nil {
+		return errReply
+	}
+
+	zset, ok := getZSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		return scanReply(0, []any{})
+	}
+
+	var members []data_structure.ZSetMember
+	cursor = scanSteps(cursor, opts.count, func(cursor uint64) uint64 {
+		return zset.Scan(cursor, func(m data_structure.ZSetMember) {
+			members = append(members, m)
+		})
+	}, func() int { return len(members) })
+
+	elements := make([]any, 0, 2*len(members))
+	for _, m := range members {
+		if opts.matches(m.Member) {
+			elements = append(elements, m.Member, formatScore(m.Score))
+		}
+	}
+	return scanReply(cursor, elements)
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"strings"
+)
+
+// cmdZSCORE handles ZSCORE key member
+func cmdZSCORE(args []string) []byte {
+	zset, ok := getZSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		return []byte(constant.RespNil)
+	}
+
+	score, exists := zset.Score(args[1])
+	if !exists {
+		return []byte(constant.RespNil)
+	}
+	return resp.Encode(formatScore(score))
+}
+
+// cmdZMSCORE handles ZMSCORE key member [member ...], missing members are nil
+func cmdZMSCORE(args []string) []byte {
+	zset, ok := getZSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+
+	res := make([]any, len(args)-1)
+	if zset != nil {
+		for i, member := range args[1:] {
+			if score, exists := zset.Score(member); exists {
+				res[i] = formatScore(score)
+			}
+		}
+	}
+	return resp.Encode(res)
+}
+
+// cmdZCARD handles ZCARD key
+func cmdZCARD(args []string) []byte {
+	zset, ok := getZSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		return resp.Encode(0)
+	}
+	return resp.Encode(zset.Len())
+}
+
+// cmdZCOUNT handles ZCOUNT key min max
+func cmdZCOUNT(args []string) []byte {
+	r, errReply := parseScoreRange(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+
+	zset, ok := getZSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		return resp.Encode(0)
+	}
+	return resp.Encode(zset.CountInRange(r))
+}
+
+// Support ZRANK key member [WITHSCORE]
+func cmdZRANK(args []string) []byte {
+	return zrankGeneric(args, false)
+}
+
+// Support ZREVRANK key member [WITHSCORE]
+func cmdZREVRANK(args []string) []byte {
+	return zrankGeneric(args, true)
+}
+
+func zrankGeneric(args []string, reverse bool) []byte {
+	if len(args) > 3 || (len(args) == 3 && strings.ToUpper(args[2]) != "WITHSCORE") {
+		return []byte(constant.ErrSyntax)
+	}
+	withScore := len(args) == 3
+	notFound := []byte(constant.RespNil)
+	if withScore {
+		notFound = []byte(constant.RespNilArray)
+	}
+
+	zset, ok := getZSet(args[0])
+	if !ok {
+		return []byte(constant.ErrWrongType)
+	}
+	if zset == nil {
+		return notFound
+	}
+
+	rank, exists := zset.Rank(args[1], reverse)
+	if !exists {
+		return notFound
+	}
+	if withScore {
+		score, _ := zset.Score(args[1])
+		return resp.Encode([]any{rank, formatScore(score)})
+	}
+	return resp.Encode(rank)
+}
@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/resp"
+	"redis-repo/internal/data_structure"
+	"strconv"
+	"strings"
+)
+
+// Operations of zsetOpGeneric
+const (
+	zsetOpUnion = iota
+	zsetOpInter
+	zsetOpDiff
+)
+
+// Support ZUNION numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX] [WITHSCORES]
+func cmdZUNION(args []string) []byte {
+	return zsetOpGeneric("zunion", "", args, zsetOpUnion)
+}
+
+// Support ZINTER numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX] [WITHSCORES]
+func cmdZINTER(args []string) []byte {
+	return zsetOpGeneric("zinter", "", args, zsetOpInter)
+}
+
+// Support ZDIFF numkeys key [key ...] [WITHSCORES]
+func cmdZDIFF(args []string) []byte {
+	return zsetOpGeneric("zdiff", "", args, zsetOpDiff)
+}
+
+// Support ZUNIONSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX]
+func cmdZUNIONSTORE(args []string) []byte {
+	return zsetOpGeneric("zunionstore", args[0], args[1:], zsetOpUnion)
+}
+
+// Support ZINTERSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX]
+func cmdZINTERSTORE(args []string) []byte {
+	return zsetOpGeneric("zinterstore", args[0], args[1:], zsetOpInter)
+}
+
+// Support ZDIFFSTORE destination numkeys key [key ...]
+func cmdZDIFFSTORE(args []string) []byte {
+	return zsetOpGeneric("zdiffstore", args[0], args[1:], zsetOpDiff)
+}
+
+// Support ZINTERCARD numkeys key [key ...] [LIMIT limit]
+func cmdZINTERCARD(args []string) []byte {
+	inputs, options, errReply := parseZSetOpInputs("zintercard", args)
+	if errReply != nil {
+		return errReply
+	}
+
+	limit := 0
+	for i := 0; i < len(options); i++ {
+		if strings.ToUpper(options[i]) != "LIMIT" || i+1 >= len(options) {
+			return []byte(constant.ErrSyntax)
+		}
+		var err error
+		limit, err = strconv.Atoi(options[i+1])
+		if err != nil || limit < 0 {
+			return resp.Encode(errors.New("ERR LIMIT can't be negative"))
+		}
+		i++
+	}
+
+	return resp.Encode(data_structure.ZInterCard(inputs, limit))
+}
+
+// zsetOpGeneric computes the union, intersection or difference of the sorted sets or
+// plain sets given by args, which starts at numkeys. It replies the result, or stores
+// it at dst and replies its length when dst is set
+func zsetOpGeneric(name, dst string, args []string, op int) []byte {
+	inputs, options, errReply := parseZSetOpInputs(name, args)
+	if errReply != nil {
+		return errReply
+	}
+
+	weights := make([]float64, len(inputs))
+	for i := range weights {
+		weights[i] = 1
+	}
+	agg := data_structure.AggregateSum
+	withScores := false
+	for i := 0; i < len(options); i++ {
+		remaining := len(options) - i - 1
+		switch option := strings.ToUpper(options[i]); {
+		case option == "WEIGHTS" && op != zsetOpDiff && remaining >= len(inputs):
+			for k := range weights {
+				weight, ok := parseScore(options[i+1+k])
+				if !ok {
+					return resp.Encode(errors.New("ERR weight value is not a float"))
+				}
+				weights[k] = weight
+			}
+			i += len(inputs)
+		case option == "AGGREGATE" && op != zsetOpDiff && remaining >= 1:
+			switch strings.ToUpper(options[i+1]) {
+			case "SUM":
+				agg = data_structure.AggregateSum
+			case "MIN":
+				agg = data_structure.AggregateMin
+			case "MAX":
+				agg = data_structure.AggregateMax
+			default:
+				return []byte(constant.ErrSyntax)
+			}
+			i++
+		case option == "WITHSCORES" && dst == "":
+			withScores = true
+		default:
+			return []byte(constant.ErrSyntax)
+		}
+	}
+
+	var result *data_structure.ZSet
+	if op == zsetOpDiff {
+		result = data_structure.ZDiff(inputs)
+	} else {
+		weighted := make([]data_structure.ZSetInput, len(inputs))
+		for i, input := range inputs {
+			weighted[i] = data_structure.ZSetInput{Members: input, Weight: weights[i]}
+		}
+		if op == zsetOpUnion {
+			result = data_structure.ZUnion(weighted, agg)
+		} else {
+			result = data_structure.ZInter(weighted, agg)
+		}
+	}
+
+	if dst != "" {
+		if result.Len() == 0 {
+			dict.Delete(dst)
+			return resp.Encode(0)
+		}
+		dict.Set(dst, result, 0)
+		return resp.Encode(result.Len())
+	}
+
+	res := make([]any, 0, 2*result.Len())
+	result.Iterate(func(m data_structure.ZSetMember) bool {
+		res = append(res, m.Member)
+		if withScores {
+			res = append(res, formatScore(m.Score))
+		}
+		return true
+	})
+	return resp.Encode(res)
+}
+
+// parseZSetOpInputs reads numkeys and the keys following it, returning the inputs and
+// the options after the keys, or an error reply
+func parseZSetOpInputs(name string, args []string) ([]data_structure.ScoredMembers, []string, []byte) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, nil, []byte(constant.ErrNotInteger)
+	}
+	if numKeys < 1 {
+		return nil, nil, resp.Encode(fmt.Errorf("ERR at least 1 input key is needed for '%s' command", name))
+	}
+	if numKeys > len(args)-1 {
+		return nil, nil, []byte(constant.ErrSyntax)
+	}
+
+	inputs := make([]data_structure.ScoredMembers, numKeys)
+	for i, key := range args[1 : 1+numKeys] {
+		members, ok := getScoredMembers(key)
+		if !ok {
+			return nil, nil, []byte(constant.ErrWrongType)
+		}
+		inputs[i] = members
+	}
+	return inputs, args[1+numKeys:], nil
+}
@@ -0,0 +1,1015 @@
+package executor
+
+import (
+	"fmt"
+	"redis-repo/internal/config"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/core/command"
+	"redis-repo/internal/core/resp"
+	"strconv"
+	"strings"
+)
+
+// commandFlag describes how a command behaves, as reported by COMMAND INFO
+type commandFlag uint32
+
+const (
+	flagWrite commandFlag = 1 << iota
+	flagReadonly
+	flagDenyOOM
+	flagAdmin
+	flagPubSub
+	flagNoScript
+	flagLoading
+	flagStale
+	flagFast
+	flagMovableKeys
+)
+
+// flagNames lists the flags in the order COMMAND INFO reports them
+var flagNames = []struct {
+	flag commandFlag
+	name string
+}{
+	{flagWrite, "write"},
+	{flagReadonly, "readonly"},
+	{flagDenyOOM, "denyoom"},
+	{flagAdmin, "admin"},
+	{flagPubSub, "pubsub"},
+	{flagNoScript, "noscript"},
+	{flagLoading, "loading"},
+	{flagStale, "stale"},
+	{flagFast, "fast"},
+	{flagMovableKeys, "movablekeys"},
+}
+
+// Command groups, as reported by COMMAND DOCS
+const (
+	groupConnection = "connection"
+	groupGeneric    = "generic"
+	groupHash       = "hash"
+	groupList       = "list"
+	groupServer     = "server"
+	groupSet        = "set"
+	groupSortedSet  = "sorted-set"
+	groupString     = "string"
+)
+
+// commandSpec is the registry entry of a command: its metadata and handler
+type commandSpec struct {
+	Name        string
+	Arity       int // Number of arguments including the command name, -N means at least N
+	Flags       commandFlag
+	FirstKey    int // Position of the first key in argv, 0 when the command has no keys
+	LastKey     int // Position of the last key, negative values count from the end of argv
+	Step        int // Distance between two keys
+	Group       string
+	Since       string
+	Summary     string
+	Handler     func(args []string) []byte
+	Subcommands []*commandSpec
+
+	// GetKeys finds the key positions of commands whose keys depend on their arguments,
+	// it replaces FirstKey, LastKey and Step when set
+	GetKeys func(argv []string) []int
+}
+
+// commandTable maps an upper case command name to its spec
+var commandTable map[string]*commandSpec
+
+func init() {
+	registerCommands(
+		&commandSpec{
+			Name: "ping", Arity: -1, Flags: flagFast,
+			Group: groupConnection, Since: "1.0.0", Summary: "Returns the server's liveliness response.",
+			Handler: cmdPING,
+		},
+		&commandSpec{
+			Name: "get", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Returns the string value of a key.",
+			Handler: cmdGET,
+		},
+		&commandSpec{
+			Name: "set", Arity: -3, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+			Handler: cmdSET,
+		},
+		&commandSpec{
+			Name: "setnx", Arity: 3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Set the string value of a key only when the key doesn't exist.",
+			Handler: cmdSETNX,
+		},
+		&commandSpec{
+			Name: "setex", Arity: 4, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "2.0.0", Summary: "Sets the string value and expiration time of a key. Creates the key if it doesn't exist.",
+			Handler: cmdSETEX,
+		},
+		&commandSpec{
+			Name: "psetex", Arity: 4, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "2.6.0", Summary: "Sets both string value and expiration time in milliseconds of a key. The key is created if it doesn't exist.",
+			Handler: cmdPSETEX,
+		},
+		&commandSpec{
+			Name: "getset", Arity: 3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Returns the previous string value of a key after setting it to a new value.",
+			Handler: cmdGETSET,
+		},
+		&commandSpec{
+			Name: "getdel", Arity: 2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "6.2.0", Summary: "Returns the string value of a key after deleting the key.",
+			Handler: cmdGETDEL,
+		},
+		&commandSpec{
+			Name: "getex", Arity: -2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "6.2.0", Summary: "Returns the string value of a key after setting its expiration time.",
+			Handler: cmdGETEX,
+		},
+		&commandSpec{
+			Name: "mset", Arity: -3, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: -1, Step: 2,
+			Group: groupString, Since: "1.0.1", Summary: "Atomically creates or modifies the string values of one or more keys.",
+			Handler: cmdMSET,
+		},
+		&commandSpec{
+			Name: "msetnx", Arity: -3, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: -1, Step: 2,
+			Group: groupString, Since: "1.0.1", Summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.",
+			Handler: cmdMSETNX,
+		},
+		&commandSpec{
+			Name: "mget", Arity: -2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Atomically returns the string values of one or more keys.",
+			Handler: cmdMGET,
+		},
+		&commandSpec{
+			Name: "append", Arity: 3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "2.0.0", Summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.",
+			Handler: cmdAPPEND,
+		},
+		&commandSpec{
+			Name: "strlen", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "2.2.0", Summary: "Returns the length of a string value.",
+			Handler: cmdSTRLEN,
+		},
+		&commandSpec{
+			Name: "getrange", Arity: 4, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "2.4.0", Summary: "Returns a substring of the string stored at a key.",
+			Handler: cmdGETRANGE,
+		},
+		&commandSpec{
+			Name: "setrange", Arity: 4, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "2.2.0", Summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.",
+			Handler: cmdSETRANGE,
+		},
+		&commandSpec{
+			Name: "lcs", Arity: -3, Flags: flagReadonly, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: groupString, Since: "7.0.0", Summary: "Finds the longest common substring.",
+			Handler: cmdLCS,
+		},
+		&commandSpec{
+			Name: "incr", Arity: 2, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+			Handler: cmdINCR,
+		},
+		&commandSpec{
+			Name: "decr", Arity: 2, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+			Handler: cmdDECR,
+		},
+		&commandSpec{
+			Name: "incrby", Arity: 3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+			Handler: cmdINCRBY,
+		},
+		&commandSpec{
+			Name: "decrby", Arity: 3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "1.0.0", Summary: "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+			Handler: cmdDECRBY,
+		},
+		&commandSpec{
+			Name: "incrbyfloat", Arity: 3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupString, Since: "2.6.0", Summary: "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+			Handler: cmdINCRBYFLOAT,
+		},
+		&commandSpec{
+			Name: "ttl", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Returns the expiration time in seconds of a key.",
+			Handler: cmdTTL,
+		},
+		&commandSpec{
+			Name: "pttl", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "2.6.0", Summary: "Returns the expiration time in milliseconds of a key.",
+			Handler: cmdPTTL,
+		},
+		&commandSpec{
+			Name: "expiretime", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "7.0.0", Summary: "Returns the expiration time of a key as a Unix timestamp.",
+			Handler: cmdEXPIRETIME,
+		},
+		&commandSpec{
+			Name: "pexpiretime", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "7.0.0", Summary: "Returns the expiration time of a key as a Unix milliseconds timestamp.",
+			Handler: cmdPEXPIRETIME,
+		},
+		&commandSpec{
+			Name: "expire", Arity: -3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Sets the expiration time of a key in seconds.",
+			Handler: cmdEXPIRE,
+		},
+		&commandSpec{
+			Name: "pexpire", Arity: -3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "2.6.0", Summary: "Sets the expiration time of a key in milliseconds.",
+			Handler: cmdPEXPIRE,
+		},
+		&commandSpec{
+			Name: "expireat", Arity: -3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "1.2.0", Summary: "Sets the expiration time of a key to a Unix timestamp.",
+			Handler: cmdEXPIREAT,
+		},
+		&commandSpec{
+			Name: "pexpireat", Arity: -3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "2.6.0", Summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.",
+			Handler: cmdPEXPIREAT,
+		},
+		&commandSpec{
+			Name: "persist", Arity: 2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "2.2.0", Summary: "Removes the expiration time of a key.",
+			Handler: cmdPERSIST,
+		},
+		&commandSpec{
+			Name: "del", Arity: -2, Flags: flagWrite, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Deletes one or more keys.",
+			Handler: cmdDEL,
+		},
+		&commandSpec{
+			Name: "type", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Determines the type of value stored at a key.",
+			Handler: cmdTYPE,
+		},
+		&commandSpec{
+			Name: "unlink", Arity: -2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupGeneric, Since: "4.0.0", Summary: "Asynchronously deletes one or more keys.",
+			Handler: cmdUNLINK,
+		},
+		&commandSpec{
+			Name: "touch", Arity: -2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupGeneric, Since: "3.2.1", Summary: "Returns the number of existing keys out of those specified after updating the time they were last accessed.",
+			Handler: cmdTOUCH,
+		},
+		&commandSpec{
+			Name: "rename", Arity: 3, Flags: flagWrite, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Renames a key and overwrites the destination.",
+			Handler: cmdRENAME,
+		},
+		&commandSpec{
+			Name: "renamenx", Arity: 3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Renames a key only when the target key name doesn't exist.",
+			Handler: cmdRENAMENX,
+		},
+		&commandSpec{
+			Name: "randomkey", Arity: 1, Flags: flagReadonly,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Returns a random key name from the database.",
+			Handler: cmdRANDOMKEY,
+		},
+		&commandSpec{
+			Name: "move", Arity: 3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Moves a key to another database.",
+			Handler: cmdMOVE,
+		},
+		&commandSpec{
+			Name: "copy", Arity: -3, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: groupGeneric, Since: "6.2.0", Summary: "Copies the value of a key to a new key.",
+			Handler: cmdCOPY,
+		},
+		&commandSpec{
+			Name: "keys", Arity: 2, Flags: flagReadonly,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Returns all key names that match a pattern.",
+			Handler: cmdKEYS,
+		},
+		&commandSpec{
+			Name: "scan", Arity: -2, Flags: flagReadonly,
+			Group: groupGeneric, Since: "2.8.0", Summary: "Iterates over the key names in the database.",
+			Handler: cmdSCAN,
+		},
+		&commandSpec{
+			Name: "exists", Arity: -2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupGeneric, Since: "1.0.0", Summary: "Determines whether one or more keys exist.",
+			Handler: cmdEXISTS,
+		},
+		&commandSpec{
+			Name: "object", Arity: -2,
+			Group: groupGeneric, Since: "2.2.3", Summary: "A container for object introspection commands.",
+			Subcommands: []*commandSpec{
+				{
+					Name: "encoding", Arity: 3, Flags: flagReadonly, FirstKey: 2, LastKey: 2, Step: 1,
+					Group: groupGeneric, Since: "2.2.3", Summary: "Returns the internal encoding of a Redis object.",
+					Handler: cmdOBJECTENCODING,
+				},
+				{
+					Name: "freq", Arity: 3, Flags: flagReadonly, FirstKey: 2, LastKey: 2, Step: 1,
+					Group: groupGeneric, Since: "4.0.0", Summary: "Returns the logarithmic access frequency counter of a Redis object.",
+					Handler: cmdOBJECTFREQ,
+				},
+				{
+					Name: "help", Arity: 2, Flags: flagLoading | flagStale,
+					Group: groupGeneric, Since: "6.2.0", Summary: "Returns helpful text about the different subcommands.",
+					Handler: cmdOBJECTHELP,
+				},
+				{
+					Name: "idletime", Arity: 3, Flags: flagReadonly, FirstKey: 2, LastKey: 2, Step: 1,
+					Group: groupGeneric, Since: "2.2.3", Summary: "Returns the time since the last access to a Redis object.",
+					Handler: cmdOBJECTIDLETIME,
+				},
+				{
+					Name: "refcount", Arity: 3, Flags: flagReadonly, FirstKey: 2, LastKey: 2, Step: 1,
+					Group: groupGeneric, Since: "2.2.3", Summary: "Returns the reference count of a value of a key.",
+					Handler: cmdOBJECTREFCOUNT,
+				},
+			},
+		},
+		&commandSpec{
+			Name: "lpush", Arity: -3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.",
+			Handler: cmdLPUSH,
+		},
+		&commandSpec{
+			Name: "rpush", Arity: -3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.",
+			Handler: cmdRPUSH,
+		},
+		&commandSpec{
+			Name: "lpushx", Arity: -3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "2.2.0", Summary: "Prepends one or more elements to a list only when the list exists.",
+			Handler: cmdLPUSHX,
+		},
+		&commandSpec{
+			Name: "rpushx", Arity: -3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "2.2.0", Summary: "Appends an element to a list only when the list exists.",
+			Handler: cmdRPUSHX,
+		},
+		&commandSpec{
+			Name: "lpop", Arity: -2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.",
+			Handler: cmdLPOP,
+		},
+		&commandSpec{
+			Name: "rpop", Arity: -2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Returns and removes the last elements of the list. Deletes the list if the last element was popped.",
+			Handler: cmdRPOP,
+		},
+		&commandSpec{
+			Name: "lrange", Arity: 4, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Returns a range of elements from a list.",
+			Handler: cmdLRANGE,
+		},
+		&commandSpec{
+			Name: "lindex", Arity: 3, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Returns an element from a list by its index.",
+			Handler: cmdLINDEX,
+		},
+		&commandSpec{
+			Name: "lset", Arity: 4, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Sets the value of an element in a list by its index.",
+			Handler: cmdLSET,
+		},
+		&commandSpec{
+			Name: "linsert", Arity: 5, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "2.2.0", Summary: "Inserts an element before or after another element in a list.",
+			Handler: cmdLINSERT,
+		},
+		&commandSpec{
+			Name: "lrem", Arity: 4, Flags: flagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Removes elements from a list. Deletes the list if the last element was removed.",
+			Handler: cmdLREM,
+		},
+		&commandSpec{
+			Name: "ltrim", Arity: 4, Flags: flagWrite, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Removes elements from both ends a list. Deletes the list if all elements were trimmed.",
+			Handler: cmdLTRIM,
+		},
+		&commandSpec{
+			Name: "llen", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "1.0.0", Summary: "Returns the length of a list.",
+			Handler: cmdLLEN,
+		},
+		&commandSpec{
+			Name: "lpos", Arity: -3, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupList, Since: "6.0.6", Summary: "Returns the index of matching elements in a list.",
+			Handler: cmdLPOS,
+		},
+		&commandSpec{
+			Name: "lmove", Arity: 5, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: groupList, Since: "6.2.0", Summary: "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.",
+			Handler: cmdLMOVE,
+		},
+		&commandSpec{
+			Name: "sadd", Arity: -3, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Adds one or more members to a set. Creates the key if it doesn't exist.",
+			Handler: cmdSADD,
+		},
+		&commandSpec{
+			Name: "srem", Arity: -3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Removes one or more members from a set. Deletes the set if the last member was removed.",
+			Handler: cmdSREM,
+		},
+		&commandSpec{
+			Name: "sismember", Arity: 3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Determines whether a member belongs to a set.",
+			Handler: cmdSISMEMBER,
+		},
+		&commandSpec{
+			Name: "smismember", Arity: -3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "6.2.0", Summary: "Determines whether multiple members belong to a set.",
+			Handler: cmdSMISMEMBER,
+		},
+		&commandSpec{
+			Name: "smembers", Arity: 2, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Returns all members of a set.",
+			Handler: cmdSMEMBERS,
+		},
+		&commandSpec{
+			Name: "scard", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Returns the number of members in a set.",
+			Handler: cmdSCARD,
+		},
+		&commandSpec{
+			Name: "sinter", Arity: -2, Flags: flagReadonly, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Returns the intersect of multiple sets.",
+			Handler: cmdSINTER,
+		},
+		&commandSpec{
+			Name: "sinterstore", Arity: -3, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Stores the intersect of multiple sets in a key.",
+			Handler: cmdSINTERSTORE,
+		},
+		&commandSpec{
+			Name: "sintercard", Arity: -3, Flags: flagReadonly, GetKeys: numkeysKeys(1),
+			Group: groupSet, Since: "7.0.0", Summary: "Returns the number of members of the intersect of multiple sets.",
+			Handler: cmdSINTERCARD,
+		},
+		&commandSpec{
+			Name: "sunion", Arity: -2, Flags: flagReadonly, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Returns the union of multiple sets.",
+			Handler: cmdSUNION,
+		},
+		&commandSpec{
+			Name: "sunionstore", Arity: -3, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Stores the union of multiple sets in a key.",
+			Handler: cmdSUNIONSTORE,
+		},
+		&commandSpec{
+			Name: "sdiff", Arity: -2, Flags: flagReadonly, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Returns the difference of multiple sets.",
+			Handler: cmdSDIFF,
+		},
+		&commandSpec{
+			Name: "sdiffstore", Arity: -3, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Stores the difference of multiple sets in a key.",
+			Handler: cmdSDIFFSTORE,
+		},
+		&commandSpec{
+			Name: "smove", Arity: 4, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Moves a member from one set to another.",
+			Handler: cmdSMOVE,
+		},
+		&commandSpec{
+			Name: "spop", Arity: -2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped.",
+			Handler: cmdSPOP,
+		},
+		&commandSpec{
+			Name: "srandmember", Arity: -2, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "1.0.0", Summary: "Get one or multiple random members from a set.",
+			Handler: cmdSRANDMEMBER,
+		},
+		&commandSpec{
+			Name: "sscan", Arity: -3, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSet, Since: "2.8.0", Summary: "Iterates over members of a set.",
+			Handler: cmdSSCAN,
+		},
+		&commandSpec{
+			Name: "zadd", Arity: -4, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "1.2.0", Summary: "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.",
+			Handler: cmdZADD,
+		},
+		&commandSpec{
+			Name: "zincrby", Arity: 4, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "1.2.0", Summary: "Increments the score of a member in a sorted set.",
+			Handler: cmdZINCRBY,
+		},
+		&commandSpec{
+			Name: "zrem", Arity: -3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "1.2.0", Summary: "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.",
+			Handler: cmdZREM,
+		},
+		&commandSpec{
+			Name: "zscore", Arity: 3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "1.2.0", Summary: "Returns the score of a member in a sorted set.",
+			Handler: cmdZSCORE,
+		},
+		&commandSpec{
+			Name: "zmscore", Arity: -3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "6.2.0", Summary: "Returns the score of one or more members in a sorted set.",
+			Handler: cmdZMSCORE,
+		},
+		&commandSpec{
+			Name: "zcard", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "1.2.0", Summary: "Returns the number of members in a sorted set.",
+			Handler: cmdZCARD,
+		},
+		&commandSpec{
+			Name: "zcount", Arity: 4, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "2.0.0", Summary: "Returns the count of members in a sorted set that have scores within a range.",
+			Handler: cmdZCOUNT,
+		},
+		&commandSpec{
+			Name: "zrank", Arity: -3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "2.0.0", Summary: "Returns the index of a member in a sorted set ordered by ascending scores.",
+			Handler: cmdZRANK,
+		},
+		&commandSpec{
+			Name: "zrevrank", Arity: -3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "2.0.0", Summary: "Returns the index of a member in a sorted set ordered by descending scores.",
+			Handler: cmdZREVRANK,
+		},
+		&commandSpec{
+			Name: "zrange", Arity: -4, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "1.2.0", Summary: "Returns members in a sorted set within a range of indexes.",
+			Handler: cmdZRANGE,
+		},
+		&commandSpec{
+			Name: "zrangestore", Arity: -5, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: groupSortedSet, Since: "6.2.0", Summary: "Stores a range of members from sorted set in a key.",
+			Handler: cmdZRANGESTORE,
+		},
+		&commandSpec{
+			Name: "zpopmin", Arity: -2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "5.0.0", Summary: "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+			Handler: cmdZPOPMIN,
+		},
+		&commandSpec{
+			Name: "zpopmax", Arity: -2, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "5.0.0", Summary: "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+			Handler: cmdZPOPMAX,
+		},
+		&commandSpec{
+			Name: "zrandmember", Arity: -2, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "6.2.0", Summary: "Returns one or more random members from a sorted set.",
+			Handler: cmdZRANDMEMBER,
+		},
+		&commandSpec{
+			Name: "zunion", Arity: -3, Flags: flagReadonly, GetKeys: numkeysKeys(1),
+			Group: groupSortedSet, Since: "6.2.0", Summary: "Returns the union of multiple sorted sets.",
+			Handler: cmdZUNION,
+		},
+		&commandSpec{
+			Name: "zinter", Arity: -3, Flags: flagReadonly, GetKeys: numkeysKeys(1),
+			Group: groupSortedSet, Since: "6.2.0", Summary: "Returns the intersect of multiple sorted sets.",
+			Handler: cmdZINTER,
+		},
+		&commandSpec{
+			Name: "zintercard", Arity: -3, Flags: flagReadonly, GetKeys: numkeysKeys(1),
+			Group: groupSortedSet, Since: "7.0.0", Summary: "Returns the number of members of the intersect of multiple sorted sets.",
+			Handler: cmdZINTERCARD,
+		},
+		&commandSpec{
+			Name: "zdiff", Arity: -3, Flags: flagReadonly, GetKeys: numkeysKeys(1),
+			Group: groupSortedSet, Since: "6.2.0", Summary: "Returns the difference between multiple sorted sets.",
+			Handler: cmdZDIFF,
+		},
+		&commandSpec{
+			Name: "zunionstore", Arity: -4, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1, GetKeys: numkeysKeys(2),
+			Group: groupSortedSet, Since: "2.0.0", Summary: "Stores the union of multiple sorted sets in a key.",
+			Handler: cmdZUNIONSTORE,
+		},
+		&commandSpec{
+			Name: "zinterstore", Arity: -4, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1, GetKeys: numkeysKeys(2),
+			Group: groupSortedSet, Since: "2.0.0", Summary: "Stores the intersect of multiple sorted sets in a key.",
+			Handler: cmdZINTERSTORE,
+		},
+		&commandSpec{
+			Name: "zdiffstore", Arity: -4, Flags: flagWrite | flagDenyOOM, FirstKey: 1, LastKey: 1, Step: 1, GetKeys: numkeysKeys(2),
+			Group: groupSortedSet, Since: "6.2.0", Summary: "Stores the difference of multiple sorted sets in a key.",
+			Handler: cmdZDIFFSTORE,
+		},
+		&commandSpec{
+			Name: "zscan", Arity: -3, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupSortedSet, Since: "2.8.0", Summary: "Iterates over members and scores of a sorted set.",
+			Handler: cmdZSCAN,
+		},
+		&commandSpec{
+			Name: "hset", Arity: -4, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Creates or modifies the value of a field in a hash.",
+			Handler: cmdHSET,
+		},
+		&commandSpec{
+			Name: "hsetnx", Arity: 4, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Sets the value of a field in a hash only when the field doesn't exist.",
+			Handler: cmdHSETNX,
+		},
+		&commandSpec{
+			Name: "hget", Arity: 3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Returns the value of a field in a hash.",
+			Handler: cmdHGET,
+		},
+		&commandSpec{
+			Name: "hmget", Arity: -3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Returns the values of all fields in a hash.",
+			Handler: cmdHMGET,
+		},
+		&commandSpec{
+			Name: "hdel", Arity: -3, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.",
+			Handler: cmdHDEL,
+		},
+		&commandSpec{
+			Name: "hexists", Arity: 3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Determines whether a field exists in a hash.",
+			Handler: cmdHEXISTS,
+		},
+		&commandSpec{
+			Name: "hlen", Arity: 2, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Returns the number of fields in a hash.",
+			Handler: cmdHLEN,
+		},
+		&commandSpec{
+			Name: "hstrlen", Arity: 3, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "3.2.0", Summary: "Returns the length of the value of a field.",
+			Handler: cmdHSTRLEN,
+		},
+		&commandSpec{
+			Name: "hkeys", Arity: 2, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Returns all fields in a hash.",
+			Handler: cmdHKEYS,
+		},
+		&commandSpec{
+			Name: "hvals", Arity: 2, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Returns all values in a hash.",
+			Handler: cmdHVALS,
+		},
+		&commandSpec{
+			Name: "hgetall", Arity: 2, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Returns all fields and values in a hash.",
+			Handler: cmdHGETALL,
+		},
+		&commandSpec{
+			Name: "hincrby", Arity: 4, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.0.0", Summary: "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.",
+			Handler: cmdHINCRBY,
+		},
+		&commandSpec{
+			Name: "hincrbyfloat", Arity: 4, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.6.0", Summary: "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.",
+			Handler: cmdHINCRBYFLOAT,
+		},
+		&commandSpec{
+			Name: "hrandfield", Arity: -2, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "6.2.0", Summary: "Returns one or more random fields from a hash.",
+			Handler: cmdHRANDFIELD,
+		},
+		&commandSpec{
+			Name: "hscan", Arity: -3, Flags: flagReadonly, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "2.8.0", Summary: "Iterates over fields and values of a hash.",
+			Handler: cmdHSCAN,
+		},
+		&commandSpec{
+			Name: "hexpire", Arity: -6, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "7.4.0", Summary: "Set expiry for hash field using relative time to expire (seconds)",
+			Handler: cmdHEXPIRE,
+		},
+		&commandSpec{
+			Name: "hpexpire", Arity: -6, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "7.4.0", Summary: "Set expiry for hash field using relative time to expire (milliseconds)",
+			Handler: cmdHPEXPIRE,
+		},
+		&commandSpec{
+			Name: "hexpireat", Arity: -6, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "7.4.0", Summary: "Set expiry for hash field using an absolute Unix timestamp (seconds)",
+			Handler: cmdHEXPIREAT,
+		},
+		&commandSpec{
+			Name: "hpexpireat", Arity: -6, Flags: flagWrite | flagDenyOOM | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "7.4.0", Summary: "Set expiry for hash field using an absolute Unix timestamp (milliseconds)",
+			Handler: cmdHPEXPIREAT,
+		},
+		&commandSpec{
+			Name: "httl", Arity: -5, Flags: flagReadonly | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "7.4.0", Summary: "Returns the TTL in seconds of a hash field.",
+			Handler: cmdHTTL,
+		},
+		&commandSpec{
+			Name: "hpersist", Arity: -5, Flags: flagWrite | flagFast, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: groupHash, Since: "7.4.0", Summary: "Removes the expiration time for each specified field",
+			Handler: cmdHPERSIST,
+		},
+		&commandSpec{
+			Name: "select", Arity: 2, Flags: flagLoading | flagStale | flagFast,
+			Group: groupConnection, Since: "1.0.0", Summary: "Changes the selected database.",
+			Handler: cmdSELECT,
+		},
+		&commandSpec{
+			Name: "swapdb", Arity: 3, Flags: flagWrite | flagFast,
+			Group: groupServer, Since: "4.0.0", Summary: "Swaps two Redis databases.",
+			Handler: cmdSWAPDB,
+		},
+		&commandSpec{
+			Name: "dbsize", Arity: 1, Flags: flagReadonly | flagFast,
+			Group: groupServer, Since: "1.0.0", Summary: "Returns the number of keys in the database.",
+			Handler: cmdDBSIZE,
+		},
+		&commandSpec{
+			Name: "flushdb", Arity: -1, Flags: flagWrite,
+			Group: groupServer, Since: "1.0.0", Summary: "Remove all keys from the current database.",
+			Handler: cmdFLUSHDB,
+		},
+		&commandSpec{
+			Name: "flushall", Arity: -1, Flags: flagWrite,
+			Group: groupServer, Since: "1.0.0", Summary: "Removes all keys from all databases.",
+			Handler: cmdFLUSHALL,
+		},
+		&commandSpec{
+			Name: "save", Arity: 1, Flags: flagAdmin | flagNoScript,
+			Group: groupServer, Since: "1.0.0", Summary: "Synchronously saves the database(s) to disk.",
+			Handler: cmdSAVE,
+		},
+		&commandSpec{
+			Name: "bgsave", Arity: -1, Flags: flagAdmin | flagNoScript,
+			Group: groupServer, Since: "1.0.0", Summary: "Asynchronously saves the database(s) to disk.",
+			Handler: cmdBGSAVE,
+		},
+		&commandSpec{
+			Name: "bgrewriteaof", Arity: 1, Flags: flagAdmin | flagNoScript,
+			Group: groupServer, Since: "1.0.0", Summary: "Asynchronously rewrites the append-only file to disk.",
+			Handler: cmdBGREWRITEAOF,
+		},
+		&commandSpec{
+			Name: "replicaof", Arity: 3, Flags: flagAdmin | flagNoScript | flagStale,
+			Group: groupServer, Since: "5.0.0", Summary: "Configures a server as replica of another, or promotes it to a master.",
+			Handler: cmdREPLICAOF,
+		},
+		&commandSpec{
+			Name: "slaveof", Arity: 3, Flags: flagAdmin | flagNoScript | flagStale,
+			Group: groupServer, Since: "1.0.0", Summary: "Sets a Redis server as a replica of another, or promotes it to being a master.",
+			Handler: cmdREPLICAOF,
+		},
+		&commandSpec{
+			Name: "role", Arity: 1, Flags: flagNoScript | flagLoading | flagStale | flagFast,
+			Group: groupServer, Since: "2.8.12", Summary: "Returns the replication role.",
+			Handler: cmdROLE,
+		},
+		&commandSpec{
+			Name: "psync", Arity: -3, Flags: flagAdmin | flagNoScript,
+			Group: groupServer, Since: "2.8.0", Summary: "An internal command used in replication.",
+			Handler: cmdPSYNC,
+		},
+		&commandSpec{
+			Name: "replconf", Arity: -1, Flags: flagAdmin | flagNoScript | flagLoading | flagStale,
+			Group: groupServer, Since: "3.0.0", Summary: "An internal command for configuring the replication stream.",
+			Handler: cmdREPLCONF,
+		},
+		&commandSpec{
+			Name: "lastsave", Arity: 1, Flags: flagLoading | flagStale | flagFast,
+			Group: groupServer, Since: "1.0.0", Summary: "Returns the Unix timestamp of the last successful save to disk.",
+			Handler: cmdLASTSAVE,
+		},
+		&commandSpec{
+			Name: "config", Arity: -2, Flags: flagAdmin | flagNoScript | flagLoading | flagStale,
+			Group: groupServer, Since: "2.0.0", Summary: "A container for server configuration commands.",
+			Subcommands: []*commandSpec{
+				{
+					Name: "get", Arity: -3, Flags: flagAdmin | flagNoScript | flagLoading | flagStale,
+					Group: groupServer, Since: "2.0.0", Summary: "Returns the effective values of configuration parameters.",
+					Handler: cmdCONFIGGET,
+				},
+				{
+					Name: "set", Arity: -4, Flags: flagAdmin | flagNoScript | flagLoading | flagStale,
+					Group: groupServer, Since: "2.0.0", Summary: "Sets configuration parameters in-flight.",
+					Handler: cmdCONFIGSET,
+				},
+			},
+		},
+		&commandSpec{
+			Name: "memory", Arity: -2,
+			Group: groupServer, Since: "4.0.0", Summary: "A container for memory diagnostics commands.",
+			Subcommands: []*commandSpec{
+				{
+					Name: "stats", Arity: 2,
+					Group: groupServer, Since: "4.0.0", Summary: "Returns details about memory usage.",
+					Handler: cmdMEMORYSTATS,
+				},
+				{
+					Name: "usage", Arity: -3, Flags: flagReadonly, FirstKey: 2, LastKey: 2, Step: 1,
+					Group: groupServer, Since: "4.0.0", Summary: "Estimates the memory usage of a key.",
+					Handler: cmdMEMORYUSAGE,
+				},
+			},
+		},
+		&commandSpec{
+			Name: "debug", Arity: -2, Flags: flagAdmin | flagNoScript | flagLoading | flagStale,
+			Group: groupServer, Since: "1.0.0", Summary: "A container for debugging commands.",
+			Subcommands: []*commandSpec{
+				{
+					Name: "htstats", Arity: -3, Flags: flagAdmin | flagNoScript | flagLoading | flagStale,
+					Group: groupServer, Since: "1.0.0", Summary: "Returns statistics about the hash tables of a database.",
+					Handler: cmdDEBUGHTSTATS,
+				},
+			},
+		},
+		&commandSpec{
+			Name: "command", Arity: -1, Flags: flagLoading | flagStale,
+			Group: groupServer, Since: "2.8.13", Summary: "Returns detailed information about all commands.",
+			Handler: cmdCOMMAND,
+			Subcommands: []*commandSpec{
+				{
+					Name: "count", Arity: 2, Flags: flagLoading | flagStale,
+					Group: groupServer, Since: "2.8.13", Summary: "Returns a count of commands.",
+					Handler: cmdCOMMANDCOUNT,
+				},
+				{
+					Name: "docs", Arity: -2, Flags: flagLoading | flagStale,
+					Group: groupServer, Since: "7.0.0", Summary: "Returns documentary information about one, multiple or all commands.",
+					Handler: cmdCOMMANDDOCS,
+				},
+				{
+					Name: "getkeys", Arity: -3, Flags: flagLoading | flagStale,
+					Group: groupServer, Since: "2.8.13", Summary: "Extracts the key names from an arbitrary command.",
+					Handler: cmdCOMMANDGETKEYS,
+				},
+				{
+					Name: "info", Arity: -2, Flags: flagLoading | flagStale,
+					Group: groupServer, Since: "2.8.13", Summary: "Returns information about one, multiple or all commands.",
+					Handler: cmdCOMMANDINFO,
+				},
+			},
+		},
+	)
+}
+
+func registerCommands(specs ...*commandSpec) {
+	if commandTable == nil {
+		commandTable = make(map[string]*commandSpec)
+	}
+	for _, spec := range specs {
+		if spec.GetKeys != nil {
+			spec.Flags |= flagMovableKeys
+		}
+		for _, sub := range spec.Subcommands {
+			sub.Name = spec.Name + "|" + sub.Name
+		}
+		commandTable[strings.ToUpper(spec.Name)] = spec
+	}
+}
+
+// lookupCommand finds the spec of a command, or of its subcommand when it has any.
+// The returned error reply is nil when the command exists
+func lookupCommand(name string, args []string) (*commandSpec, []byte) {
+	spec, ok := commandTable[strings.ToUpper(name)]
+	if !ok {
+		return nil, unknownCommandError(name, args)
+	}
+	if len(spec.Subcommands) == 0 || len(args) == 0 {
+		return spec, nil
+	}
+
+	subName := strings.ToLower(spec.Name + "|" + args[0])
+	for _, sub := range spec.Subcommands {
+		if sub.Name == subName {
+			return sub, nil
+		}
+	}
+	return nil, resp.Encode(fmt.Errorf("ERR unknown subcommand '%s'. Try %s HELP.", args[0], strings.ToUpper(spec.Name)))
+}
+
+// arityMatches checks the number of arguments, argc counts the command name too
+func (spec *commandSpec) arityMatches(argc int) bool {
+	if spec.Arity >= 0 {
+		return argc == spec.Arity
+	}
+	return argc >= -spec.Arity
+}
+
+// isSubcommand reports whether the spec belongs to a container command like COMMAND
+func (spec *commandSpec) isSubcommand() bool {
+	return strings.Contains(spec.Name, "|")
+}
+
+// execute validates a command against the registry and runs its handler
+func execute(cmd *command.Command) []byte {
+	spec, args, errReply := resolveCommand(cmd)
+	if errReply != nil {
+		return errReply
+	}
+
+	// A replica only takes the writes of its master, which it applies whatever happens
+	fromMaster := currentClient != nil && currentClient.Master
+	if spec.Flags&flagWrite != 0 && masterHost != "" && config.ReplicaReadOnly && !fromMaster {
+		return []byte(constant.ErrReadOnly)
+	}
+
+	// Like Redis, the writes are refused while they cannot be appended to the AOF
+	if spec.Flags&flagWrite != 0 && aofFile != nil && aofFile.writeErr != nil && !fromMaster {
+		return resp.Encode(fmt.Errorf("MISCONF Errors writing to the AOF file: %v", aofFile.writeErr))
+	}
+
+	// Like Redis, keys are evicted before any command runs, and the commands that may
+	// use more memory are refused when the limit cannot be enforced. A replica leaves
+	// the evictions to its master
+	if masterHost == "" && !performEvictions() && spec.Flags&flagDenyOOM != 0 {
+		return []byte(constant.ErrOOM)
+	}
+
+	return call(spec, cmd, args)
+}
+
+// resolveCommand looks up the spec of a command and checks its number of arguments,
+// returning the arguments of its handler or the error reply
+func resolveCommand(cmd *command.Command) (*commandSpec, []string, []byte) {
+	spec, errReply := lookupCommand(cmd.Cmd, cmd.Args)
+	if errReply != nil {
+		return nil, nil, errReply
+	}
+
+	argc := len(cmd.Args) + 1
+	args := cmd.Args
+	if spec.isSubcommand() {
+		args = cmd.Args[1:] // The subcommand name is part of argc, but not of the handler arguments
+	}
+	if !spec.arityMatches(argc) {
+		return nil, nil, []byte(fmt.Sprintf(constant.ErrWrongArgCount, strings.ToUpper(spec.Name)))
+	}
+	return spec, args, nil
+}
+
+// call runs the handler of a command and records what a write changed: the size of its
+// keys, the number of changes for the save rules, and the command for the AOF
+func call(spec *commandSpec, cmd *command.Command, args []string) []byte {
+	commandRewritten, rewrittenCommands = false, nil
+	reply := spec.Handler(args)
+	if spec.Flags&flagWrite == 0 {
+		return reply
+	}
+
+	// Values are modified in place, so their size is estimated again once written
+	argv := append([]string{cmd.Cmd}, cmd.Args...)
+	for _, pos := range spec.getKeyPositions(argv) {
+		dict.UpdateMemory(argv[pos])
+	}
+
+	if reply[0] != '-' {
+		dirty++ // Counted per command rather than per modified key, for the save rules
+		if !commandRewritten {
+			propagate(selectedDB, argv)
+		}
+		for _, rewritten := range rewrittenCommands {
+			propagate(selectedDB, rewritten)
+		}
+	}
+	return reply
+}
+
+// getKeyPositions returns the indexes of the keys in argv, where argv[0] is the command name
+func (spec *commandSpec) getKeyPositions(argv []string) []int {
+	if spec.GetKeys != nil {
+		return spec.GetKeys(argv)
+	}
+	if spec.FirstKey == 0 {
+		return nil
+	}
+
+	last := spec.LastKey
+	if last < 0 {
+		last = len(argv) + last
+	}
+
+	var positions []int
+	for i := spec.FirstKey; i <= last && i < len(argv); i += spec.Step {
+		positions = append(positions, i)
+	}
+	return positions
+}
+
+// numkeysKeys returns the GetKeys of commands taking argv[numkeysPos] keys right after
+// it, like ZUNION numkeys key [key ...]. The arguments before numkeys, like the
+// destination of ZUNIONSTORE, are keys too
+func numkeysKeys(numkeysPos int) func(argv []string) []int {
+	return func(argv []string) []int {
+		var positions []int
+		for i := 1; i < numkeysPos; i++ {
+			positions = append(positions, i)
+		}
+		numkeys, err := strconv.Atoi(argv[numkeysPos])
+		if err != nil {
+			return positions
+		}
+		for i := numkeysPos + 1; i <= numkeysPos+numkeys && i < len(argv); i++ {
+			positions = append(positions, i)
+		}
+		return positions
+	}
+}
+
+func unknownCommandError(name string, args []string) []byte {
+	var quoted strings.Builder
+	for _, arg := range args {
+		fmt.Fprintf(&quoted, "'%s' ", arg)
+	}
+	return resp.Encode(fmt.Errorf("ERR unknown command '%s', with args beginning with: %s", name, quoted.String()))
+}
@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"math"
+	"redis-repo/internal/config"
+	"redis-repo/internal/constant"
+	"redis-repo/internal/data_structure"
+	"sort"
+)
+
+// Eviction policies, as Redis's maxmemory-policy values
+const (
+	policyNoEviction     = "noeviction"
+	policyAllKeysLRU     = "allkeys-lru"
+	policyVolatileLRU    = "volatile-lru"
+	policyAllKeysLFU     = "allkeys-lfu"
+	policyVolatileLFU    = "volatile-lfu"
+	policyAllKeysRandom  = "allkeys-random"
+	policyVolatileRandom = "volatile-random"
+	policyVolatileTTL    = "volatile-ttl"
+)
+
+// evictionPolicies lists the valid values of config.MaxMemoryPolicy, in Redis's order
+var evictionPolicies = []string{
+	policyVolatileLRU, policyVolatileLFU, policyVolatileRandom, policyVolatileTTL,
+	policyAllKeysLRU, policyAllKeysLFU, policyAllKeysRandom, policyNoEviction,
+}
+
+// evictionCandidate is a key sampled for eviction, the higher idle the better the candidate
+type evictionCandidate struct {
+	idle uint64
+	key  string
+	db   int
+}
+
+// evictionPool keeps the best candidates sampled so far sorted by ascending idle, so the
+// next key evicted is the last one. Keeping them across evictions makes the sampled LRU
+// and LFU closer to the exact ones, as in Redis
+var evictionPool = make([]evictionCandidate, 0, constant.EvictionPoolSize)
+
+// nextEvictionDB is the database the random policies evict from next, so every database
+// loses keys in turn
+var nextEvictionDB int
+
+// usedMemory returns the estimated bytes used by all the databases, compared with
+// config.MaxMemory
+func usedMemory() int64 {
+	var used int64
+	for _, db := range databases {
+		used += db.UsedMemory()
+	}
+	return used
+}
+
+// performEvictions evicts keys following config.MaxMemoryPolicy until the used memory is
+// within config.MaxMemory. It returns false when it could not get there, because of the
+// noeviction policy or because no key is left that the policy can evict
+func performEvictions() bool {
+	if config.MaxMemory <= 0 {
+		return true
+	}
+	for usedMemory() > config.MaxMemory {
+		if !evictKey() {
+			return false
+		}
+	}
+	return true
+}
+
+// evictKey evicts one key chosen by the eviction policy, it returns false when no key
+// could be evicted
+func evictKey() bool {
+	switch config.MaxMemoryPolicy {
+	case policyNoEviction:
+		return false
+	case policyAllKeysRandom, policyVolatileRandom:
+		return evictRandomKey(config.MaxMemoryPolicy == policyVolatileRandom)
+	}
+
+	volatile := isVolatilePolicy(config.MaxMemoryPolicy)
+	for id, db := range databases {
+		populateEvictionPool(id, db, volatile)
+	}
+	for len(evictionPool) > 0 {
+		best := evictionPool[len(evictionPool)-1]
+		evictionPool = evictionPool[:len(evictionPool)-1]
+
+		// The candidate may have been deleted, or lost its expiry, since it was sampled
+		db := databases[best.db]
+		if volatile {
+			if _, ok := db.GetExpiryTime(best.key); !ok {
+				continue
+			}
+		}
+		if db.Delete(best.key) {
+			propagate(best.db, []string{"DEL", best.key}) // Or the key comes back when the AOF is loaded
+			return true
+		}
+	}
+	return false
+}
+
+// evictRandomKey evicts a random key of the next database having one
+func evictRandomKey(volatile bool) bool {
+	for range databases {
+		id, db := nextEvictionDB, databases[nextEvictionDB]
+		nextEvictionDB = (nextEvictionDB + 1) % len(databases)
+
+		var key string
+		var ok bool
+		if volatile {
+			key, _, ok = db.RandomExpiringKey()
+		} else {
+			key, ok = db.RandomKey()
+		}
+		if ok && db.Delete(key) {
+			propagate(id, []string{"DEL", key})
+			return true
+		}
+	}
+	return false
+}
+
+func isVolatilePolicy(policy string) bool {
+	return policy == policyVolatileLRU || policy == policyVolatileLFU || policy == policyVolatileTTL
+}
+
+// populateEvictionPool samples config.MaxMemorySamples keys of database id, or of its keys
+// with an expiry for the volatile policies, and adds the better ones to the pool
+func populateEvictionPool(id int, db *data_structure.Dict, volatile bool) {
+	for i := 0; i < config.MaxMemorySamples; i++ {
+		var key string
+		var expiryTime uint64
+		var ok bool
+		if volatile {
+			key, expiryTime, ok = db.RandomExpiringKey()
+		} else {
+			key, ok = db.RandomKey()
+		}
+		if !ok {
+			return
+		}
+
+		var idle uint64
+		switch config.MaxMemoryPolicy {
+		case policyVolatileTTL:
+			idle = math.MaxUint64 - expiryTime // The sooner it expires, the better
+		default:
+			obj := db.Peek(key) // Only its access statistics are read
+			if obj == nil {
+				continue
+			}
+			if config.MaxMemoryPolicy == policyAllKeysLFU || config.MaxMemoryPolicy == policyVolatileLFU {
+				idle = uint64(255 - obj.Freq())
+			} else {
+				idle = uint64(obj.IdleTime())
+			}
+		}
+		insertEvictionCandidate(evictionCandidate{idle: idle, key: key, db: id})
+	}
+}
+
+// insertEvictionCandidate adds c to the pool at its rank. A full pool drops its worst
+// candidate to make room, unless c is worse than all of them
+func insertEvictionCandidate(c evictionCandidate) {
+	for _, e := range evictionPool {
+		if e.key == c.key && e.db == c.db {
+			return // Sampled again
+		}
+	}
+
+	i := sort.Search(len(evictionPool), func(i int) bool {
+		return evictionPool[i].idle >= c.idle
+	})
+	if len(evictionPool) < constant.EvictionPoolSize {
+		evictionPool = append(evictionPool, evictionCandidate{})
+		copy(evictionPool[i+1:], evictionPool[i:])
+		evictionPool[i] = c
+		return
+	}
+	if i == 0 {
+		return
+	}
+	copy(evictionPool[:i-1], evictionPool[1:i])
+	evictionPool[i-1] = c
+}
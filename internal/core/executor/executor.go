@@ -2,39 +2,19 @@ package executor
 
 import (
 	"redis-repo/internal/core/command"
-	"syscall"
+	"redis-repo/internal/core/connection"
 )
 
-func ExecuteAndRespond(cmd *command.Command, clientFd int) error {
-	var res []byte
+// ExecuteAndRespond executes the command against the database selected by the client and
+// queues its reply in the client's output buffer. The commands of the master get no reply
+func ExecuteAndRespond(cmd *command.Command, conn *connection.Connection) {
+	currentClient = conn
+	defer func() { currentClient = nil }()
 
-	switch cmd.Cmd {
-	case "PING":
-		res = cmdPING(cmd.Args)
-	case "GET":
-		res = cmdGET(cmd.Args)
-	case "SET":
-		res = cmdSET(cmd.Args)
-	case "TTL":
-		res = cmdTTL(cmd.Args)
-	case "DEL":
-		res = cmdDEL(cmd.Args)
-	case "SADD":
-		res = cmdSADD(cmd.Args)
-	case "SREM":
-		res = cmdSREM(cmd.Args)
-	case "SMISMEMBER":
-		res = cmdSMISMEMBER(cmd.Args)
-	case "SMEMBERS":
-		res = cmdSMEMBERS(cmd.Args)
-	case "SCARD":
-		res = cmdSCARD(cmd.Args)
-	case "SINTER":
-		res = cmdSINTER(cmd.Args)
-	default:
-		res = []byte("-CMD NOT FOUND\r\n")
+	selectDB(conn.DB)
+	reply := execute(cmd)
+	if !conn.Master {
+		conn.AddReply(reply)
 	}
-
-	_, err := syscall.Write(clientFd, res)
-	return err
+	conn.DB = selectedDB // SELECT changes the database of the client
 }
@@ -1,19 +1,40 @@
 package executor
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"redis-repo/internal/aof"
+	"redis-repo/internal/config"
 	"redis-repo/internal/constant"
+	"redis-repo/internal/core/command"
+	"redis-repo/internal/core/connection"
+	"redis-repo/internal/core/resp"
 	"redis-repo/internal/data_structure"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
 
 func resetGlobalDict() {
-	dict = data_structure.NewDict()
+	for i := range databases {
+		databases[i] = newDatabase()
+	}
+	selectDB(0)
 }
 
-func resetGlobalSetStore() {
-	setStore = make(map[string]data_structure.Set)
+// executeCommand runs a command through the registry, as a client request would
+func executeCommand(name string, args []string) []byte {
+	return execute(&command.Command{Cmd: name, Args: args})
 }
 
 func assertResponse(t *testing.T, got []byte, expected string) {
@@ -49,7 +70,7 @@ func TestExecutePing(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := cmdPING(tt.args)
+			result := executeCommand("PING", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -112,7 +133,7 @@ func TestExecuteGet(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			resetGlobalDict()
 			tt.setup()
-			result := cmdGET(tt.args)
+			result := executeCommand("GET", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -175,7 +196,7 @@ func TestExecuteSet(t *testing.T) {
 		{
 			name:     "SET with invalid expiry type",
 			args:     []string{"key", "value", "INVALID", "60"},
-			expected: "-ERR invalid type of expiry time\r\n",
+			expected: constant.ErrSyntax,
 		},
 		{
 			name:     "SET with EXAT in the past",
@@ -187,7 +208,7 @@ func TestExecuteSet(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			resetGlobalDict()
-			result := cmdSET(tt.args)
+			result := executeCommand("SET", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -259,7 +280,7 @@ func TestExecuteTTL(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			resetGlobalDict()
 			tt.setup()
-			result := cmdTTL(tt.args)
+			result := executeCommand("TTL", tt.args)
 
 			// For TTL with future expiry, just check it's a positive integer
 			if tt.name == "TTL for key with future expiry" {
@@ -323,7 +344,7 @@ func TestExecuteDel(t *testing.T) {
 				// No setup needed
 			},
 			args:     []string{},
-			expected: ":0\r\n",
+			expected: "-ERR wrong number of arguments for 'DEL' command\r\n",
 		},
 	}
 
@@ -331,7 +352,7 @@ func TestExecuteDel(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			resetGlobalDict()
 			tt.setup()
-			result := cmdDEL(tt.args)
+			result := executeCommand("DEL", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -387,7 +408,7 @@ func TestCommandIntegration(t *testing.T) {
 
 // Test SADD command
 func TestExecuteSadd(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	tests := []struct {
 		name     string
@@ -428,12 +449,12 @@ func TestExecuteSadd(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resetGlobalSetStore()
+			resetGlobalDict()
 			// Pre-populate set for some tests
 			if tt.name == "SADD existing set with new members" || tt.name == "SADD existing set with duplicate members" {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			}
-			result := cmdSADD(tt.args)
+			result := executeCommand("SADD", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -441,7 +462,7 @@ func TestExecuteSadd(t *testing.T) {
 
 // Test SMEMBERS command
 func TestExecuteSmembers(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	tests := []struct {
 		name     string
@@ -452,7 +473,7 @@ func TestExecuteSmembers(t *testing.T) {
 		{
 			name: "SMEMBERS existing set",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset"},
 			expected: "*3\r\n", // Just check array length, order is not guaranteed
@@ -460,7 +481,7 @@ func TestExecuteSmembers(t *testing.T) {
 		{
 			name: "SMEMBERS empty set",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{})
+				dict.Set("myset", data_structure.NewSet([]string{}), 0)
 			},
 			args:     []string{"myset"},
 			expected: "*0\r\n",
@@ -468,7 +489,7 @@ func TestExecuteSmembers(t *testing.T) {
 		{
 			name: "SMEMBERS non-existing set",
 			setup: func() {
-				// No setup - empty setStore
+				// No setup - empty dict
 			},
 			args:     []string{"nonexistent"},
 			expected: "*0\r\n",
@@ -493,9 +514,9 @@ func TestExecuteSmembers(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resetGlobalSetStore()
+			resetGlobalDict()
 			tt.setup()
-			result := cmdSMEMBERS(tt.args)
+			result := executeCommand("SMEMBERS", tt.args)
 
 			// For existing set test, just check array length since order is not guaranteed
 			if tt.name == "SMEMBERS existing set" {
@@ -512,7 +533,7 @@ func TestExecuteSmembers(t *testing.T) {
 
 // Test SMISMEMBER command
 func TestExecuteSismember(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	tests := []struct {
 		name     string
@@ -523,7 +544,7 @@ func TestExecuteSismember(t *testing.T) {
 		{
 			name: "SMISMEMBER existing member",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset", "member1"},
 			expected: "*1\r\n:1\r\n",
@@ -531,7 +552,7 @@ func TestExecuteSismember(t *testing.T) {
 		{
 			name: "SMISMEMBER non-existing member",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset", "member4"},
 			expected: "*1\r\n:0\r\n",
@@ -539,7 +560,7 @@ func TestExecuteSismember(t *testing.T) {
 		{
 			name: "SMISMEMBER non-existing set",
 			setup: func() {
-				// No setup - empty setStore
+				// No setup - empty dict
 			},
 			args:     []string{"nonexistent", "member1"},
 			expected: "*1\r\n:0\r\n",
@@ -547,7 +568,7 @@ func TestExecuteSismember(t *testing.T) {
 		{
 			name: "SMISMEMBER multiple members - mixed results",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset", "member1", "member4", "member2"},
 			expected: "*3\r\n:1\r\n:0\r\n:1\r\n",
@@ -572,9 +593,9 @@ func TestExecuteSismember(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resetGlobalSetStore()
+			resetGlobalDict()
 			tt.setup()
-			result := cmdSMISMEMBER(tt.args)
+			result := executeCommand("SMISMEMBER", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -582,7 +603,7 @@ func TestExecuteSismember(t *testing.T) {
 
 // Test SREM command
 func TestExecuteSrem(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	tests := []struct {
 		name     string
@@ -593,7 +614,7 @@ func TestExecuteSrem(t *testing.T) {
 		{
 			name: "SREM existing member",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset", "member1"},
 			expected: ":1\r\n",
@@ -601,7 +622,7 @@ func TestExecuteSrem(t *testing.T) {
 		{
 			name: "SREM non-existing member",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset", "member4"},
 			expected: ":0\r\n",
@@ -609,7 +630,7 @@ func TestExecuteSrem(t *testing.T) {
 		{
 			name: "SREM non-existing set",
 			setup: func() {
-				// No setup - empty setStore
+				// No setup - empty dict
 			},
 			args:     []string{"nonexistent", "member1"},
 			expected: ":0\r\n",
@@ -617,7 +638,7 @@ func TestExecuteSrem(t *testing.T) {
 		{
 			name: "SREM multiple members - some exist",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset", "member1", "member4", "member2"},
 			expected: ":2\r\n", // Only member1 and member2 were removed
@@ -625,7 +646,7 @@ func TestExecuteSrem(t *testing.T) {
 		{
 			name: "SREM multiple members - none exist",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset", "member4", "member5"},
 			expected: ":0\r\n",
@@ -650,9 +671,9 @@ func TestExecuteSrem(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resetGlobalSetStore()
+			resetGlobalDict()
 			tt.setup()
-			result := cmdSREM(tt.args)
+			result := executeCommand("SREM", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -660,7 +681,7 @@ func TestExecuteSrem(t *testing.T) {
 
 // Integration tests for set commands
 func TestSetCommandIntegration(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	t.Run("SADD-SMEMBERS-SMISMEMBER-SREM workflow", func(t *testing.T) {
 		// SADD members to a new set
@@ -719,7 +740,7 @@ func TestSetCommandIntegration(t *testing.T) {
 
 // Test SCARD command
 func TestExecuteScard(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	tests := []struct {
 		name     string
@@ -730,7 +751,7 @@ func TestExecuteScard(t *testing.T) {
 		{
 			name: "SCARD existing set with members",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{"member1", "member2", "member3"})
+				dict.Set("myset", data_structure.NewSet([]string{"member1", "member2", "member3"}), 0)
 			},
 			args:     []string{"myset"},
 			expected: ":3\r\n",
@@ -738,7 +759,7 @@ func TestExecuteScard(t *testing.T) {
 		{
 			name: "SCARD empty set",
 			setup: func() {
-				setStore["myset"] = data_structure.NewSet([]string{})
+				dict.Set("myset", data_structure.NewSet([]string{}), 0)
 			},
 			args:     []string{"myset"},
 			expected: ":0\r\n",
@@ -746,7 +767,7 @@ func TestExecuteScard(t *testing.T) {
 		{
 			name: "SCARD non-existing set",
 			setup: func() {
-				// No setup - empty setStore
+				// No setup - empty dict
 			},
 			args:     []string{"nonexistent"},
 			expected: ":0\r\n",
@@ -771,9 +792,9 @@ func TestExecuteScard(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resetGlobalSetStore()
+			resetGlobalDict()
 			tt.setup()
-			result := cmdSCARD(tt.args)
+			result := executeCommand("SCARD", tt.args)
 			assertResponse(t, result, tt.expected)
 		})
 	}
@@ -781,7 +802,7 @@ func TestExecuteScard(t *testing.T) {
 
 // Test SINTER command
 func TestExecuteSinter(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	tests := []struct {
 		name     string
@@ -792,8 +813,8 @@ func TestExecuteSinter(t *testing.T) {
 		{
 			name: "SINTER two sets with common members",
 			setup: func() {
-				setStore["set1"] = data_structure.NewSet([]string{"a", "b", "c"})
-				setStore["set2"] = data_structure.NewSet([]string{"b", "c", "d"})
+				dict.Set("set1", data_structure.NewSet([]string{"a", "b", "c"}), 0)
+				dict.Set("set2", data_structure.NewSet([]string{"b", "c", "d"}), 0)
 			},
 			args:     []string{"set1", "set2"},
 			expected: "*2\r\n", // Should have 2 common members (b, c)
@@ -801,9 +822,9 @@ func TestExecuteSinter(t *testing.T) {
 		{
 			name: "SINTER three sets with common members",
 			setup: func() {
-				setStore["set1"] = data_structure.NewSet([]string{"a", "b", "c", "d"})
-				setStore["set2"] = data_structure.NewSet([]string{"b", "c", "d", "e"})
-				setStore["set3"] = data_structure.NewSet([]string{"c", "d", "e", "f"})
+				dict.Set("set1", data_structure.NewSet([]string{"a", "b", "c", "d"}), 0)
+				dict.Set("set2", data_structure.NewSet([]string{"b", "c", "d", "e"}), 0)
+				dict.Set("set3", data_structure.NewSet([]string{"c", "d", "e", "f"}), 0)
 			},
 			args:     []string{"set1", "set2", "set3"},
 			expected: "*2\r\n", // Should have 2 common members (c, d)
@@ -811,8 +832,8 @@ func TestExecuteSinter(t *testing.T) {
 		{
 			name: "SINTER sets with no common members",
 			setup: func() {
-				setStore["set1"] = data_structure.NewSet([]string{"a", "b"})
-				setStore["set2"] = data_structure.NewSet([]string{"c", "d"})
+				dict.Set("set1", data_structure.NewSet([]string{"a", "b"}), 0)
+				dict.Set("set2", data_structure.NewSet([]string{"c", "d"}), 0)
 			},
 			args:     []string{"set1", "set2"},
 			expected: "*0\r\n",
@@ -820,8 +841,8 @@ func TestExecuteSinter(t *testing.T) {
 		{
 			name: "SINTER identical sets",
 			setup: func() {
-				setStore["set1"] = data_structure.NewSet([]string{"a", "b", "c"})
-				setStore["set2"] = data_structure.NewSet([]string{"a", "b", "c"})
+				dict.Set("set1", data_structure.NewSet([]string{"a", "b", "c"}), 0)
+				dict.Set("set2", data_structure.NewSet([]string{"a", "b", "c"}), 0)
 			},
 			args:     []string{"set1", "set2"},
 			expected: "*3\r\n", // Should have 3 common members
@@ -829,7 +850,7 @@ func TestExecuteSinter(t *testing.T) {
 		{
 			name: "SINTER with non-existing set",
 			setup: func() {
-				setStore["set1"] = data_structure.NewSet([]string{"a", "b", "c"})
+				dict.Set("set1", data_structure.NewSet([]string{"a", "b", "c"}), 0)
 				// set2 doesn't exist
 			},
 			args:     []string{"set1", "nonexistent"},
@@ -838,8 +859,8 @@ func TestExecuteSinter(t *testing.T) {
 		{
 			name: "SINTER with empty set",
 			setup: func() {
-				setStore["set1"] = data_structure.NewSet([]string{"a", "b", "c"})
-				setStore["set2"] = data_structure.NewSet([]string{})
+				dict.Set("set1", data_structure.NewSet([]string{"a", "b", "c"}), 0)
+				dict.Set("set2", data_structure.NewSet([]string{}), 0)
 			},
 			args:     []string{"set1", "set2"},
 			expected: "*0\r\n",
@@ -847,7 +868,7 @@ func TestExecuteSinter(t *testing.T) {
 		{
 			name: "SINTER single set",
 			setup: func() {
-				setStore["set1"] = data_structure.NewSet([]string{"a", "b", "c"})
+				dict.Set("set1", data_structure.NewSet([]string{"a", "b", "c"}), 0)
 			},
 			args:     []string{"set1"},
 			expected: "*3\r\n", // Should return all members of the single set
@@ -864,9 +885,9 @@ func TestExecuteSinter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resetGlobalSetStore()
+			resetGlobalDict()
 			tt.setup()
-			result := cmdSINTER(tt.args)
+			result := executeCommand("SINTER", tt.args)
 
 			// For SINTER tests, we check array length since order is not guaranteed
 			if strings.HasPrefix(tt.expected, "*") {
@@ -883,7 +904,7 @@ func TestExecuteSinter(t *testing.T) {
 
 // Integration tests for SCARD and SINTER commands
 func TestScardSinterIntegration(t *testing.T) {
-	resetGlobalSetStore()
+	resetGlobalDict()
 
 	t.Run("SCARD-SINTER workflow", func(t *testing.T) {
 		// Create sets with some overlap
@@ -950,3 +971,4069 @@ func TestScardSinterIntegration(t *testing.T) {
 		assertResponse(t, sinterMixedResult, "*0\r\n")
 	})
 }
+
+// Test command registry dispatch and the COMMAND introspection family
+func TestCommandRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "unknown command",
+			cmd:      "FOO",
+			args:     []string{"a", "b"},
+			expected: "-ERR unknown command 'FOO', with args beginning with: 'a' 'b' \r\n",
+		},
+		{
+			name:     "COMMAND COUNT",
+			cmd:      "COMMAND",
+			args:     []string{"COUNT"},
+			expected: fmt.Sprintf(":%d\r\n", len(commandTable)),
+		},
+		{
+			name:     "COMMAND INFO known and unknown command",
+			cmd:      "COMMAND",
+			args:     []string{"INFO", "get", "nosuchcommand"},
+			expected: "*2\r\n*10\r\n$3\r\nget\r\n:2\r\n*2\r\n+readonly\r\n+fast\r\n:1\r\n:1\r\n:1\r\n*3\r\n+@read\r\n+@fast\r\n+@string\r\n*0\r\n*0\r\n*0\r\n$-1\r\n",
+		},
+		{
+			name:     "COMMAND DOCS",
+			cmd:      "COMMAND",
+			args:     []string{"DOCS", "ttl"},
+			expected: "*2\r\n$3\r\nttl\r\n*6\r\n$7\r\nsummary\r\n$48\r\nReturns the expiration time in seconds of a key.\r\n$5\r\nsince\r\n$5\r\n1.0.0\r\n$5\r\ngroup\r\n$7\r\ngeneric\r\n",
+		},
+		{
+			name:     "COMMAND GETKEYS with multiple keys",
+			cmd:      "COMMAND",
+			args:     []string{"GETKEYS", "DEL", "k1", "k2"},
+			expected: "*2\r\n$2\r\nk1\r\n$2\r\nk2\r\n",
+		},
+		{
+			name:     "COMMAND GETKEYS skips values",
+			cmd:      "COMMAND",
+			args:     []string{"GETKEYS", "SET", "k", "v", "EX", "10"},
+			expected: "*1\r\n$1\r\nk\r\n",
+		},
+		{
+			name:     "COMMAND GETKEYS with a keyless command",
+			cmd:      "COMMAND",
+			args:     []string{"GETKEYS", "PING", "hello"},
+			expected: "-ERR The command has no key arguments\r\n",
+		},
+		{
+			name:     "COMMAND GETKEYS with wrong arity",
+			cmd:      "COMMAND",
+			args:     []string{"GETKEYS", "GET"},
+			expected: "-ERR Invalid number of arguments specified for command\r\n",
+		},
+		{
+			name:     "COMMAND GETKEYS with unknown command",
+			cmd:      "COMMAND",
+			args:     []string{"GETKEYS", "FOO", "k"},
+			expected: "-ERR Invalid command specified\r\n",
+		},
+		{
+			name:     "unknown COMMAND subcommand",
+			cmd:      "COMMAND",
+			args:     []string{"FOO"},
+			expected: "-ERR unknown subcommand 'FOO'. Try COMMAND HELP.\r\n",
+		},
+		{
+			name:     "subcommand with wrong arity",
+			cmd:      "COMMAND",
+			args:     []string{"COUNT", "extra"},
+			expected: "-ERR wrong number of arguments for 'COMMAND|COUNT' command\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+		})
+	}
+
+	t.Run("COMMAND lists every command", func(t *testing.T) {
+		result := string(executeCommand("COMMAND", []string{}))
+		if !strings.HasPrefix(result, fmt.Sprintf("*%d\r\n", len(commandTable))) {
+			t.Errorf("Expected %d entries, got %q", len(commandTable), result)
+		}
+	})
+}
+
+// Test that strings and sets share one keyspace
+func TestTypedKeyspace(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+	}{
+		{
+			name: "GET against a set",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "GET",
+			args:     []string{"myset"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name: "SADD against a string",
+			setup: func() {
+				dict.Set("mystr", "value", 0)
+			},
+			cmd:      "SADD",
+			args:     []string{"mystr", "a"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name: "SINTER with a string key",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+				dict.Set("mystr", "value", 0)
+			},
+			cmd:      "SINTER",
+			args:     []string{"myset", "mystr"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name: "SET overwrites a set",
+			setup: func() {
+				dict.Set("key", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "SET",
+			args:     []string{"key", "value"},
+			expected: constant.RespOk,
+		},
+		{
+			name: "DEL deletes a set",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "DEL",
+			args:     []string{"myset"},
+			expected: ":1\r\n",
+		},
+		{
+			name: "TTL of a set without expiry",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "TTL",
+			args:     []string{"myset"},
+			expected: constant.TtlKeyExistNoExpire,
+		},
+		{
+			name: "SCARD of an expired set",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), uint64(time.Now().UnixMilli()-1000))
+			},
+			cmd:      "SCARD",
+			args:     []string{"myset"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "TYPE of a string",
+			setup: func() {
+				dict.Set("mystr", "value", 0)
+			},
+			cmd:      "TYPE",
+			args:     []string{"mystr"},
+			expected: "+string\r\n",
+		},
+		{
+			name: "TYPE of a set",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "TYPE",
+			args:     []string{"myset"},
+			expected: "+set\r\n",
+		},
+		{
+			name:     "TYPE of a missing key",
+			setup:    func() {},
+			cmd:      "TYPE",
+			args:     []string{"nonexistent"},
+			expected: "+none\r\n",
+		},
+		{
+			name: "EXISTS counts repeated keys",
+			setup: func() {
+				dict.Set("mystr", "value", 0)
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "EXISTS",
+			args:     []string{"mystr", "myset", "mystr", "nonexistent"},
+			expected: ":3\r\n",
+		},
+		{
+			name: "OBJECT ENCODING of a short string",
+			setup: func() {
+				dict.Set("mystr", "value", 0)
+			},
+			cmd:      "OBJECT",
+			args:     []string{"ENCODING", "mystr"},
+			expected: "$6\r\nembstr\r\n",
+		},
+		{
+			name: "OBJECT ENCODING of a long string",
+			setup: func() {
+				dict.Set("mystr", strings.Repeat("x", 45), 0)
+			},
+			cmd:      "OBJECT",
+			args:     []string{"ENCODING", "mystr"},
+			expected: "$3\r\nraw\r\n",
+		},
+		{
+			name: "OBJECT ENCODING of a set",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "OBJECT",
+			args:     []string{"ENCODING", "myset"},
+			expected: "$8\r\nlistpack\r\n",
+		},
+		{
+			name: "OBJECT ENCODING of a set of integers",
+			setup: func() {
+				executeCommand("SADD", []string{"myset", "1", "2", "-3"})
+			},
+			cmd:      "OBJECT",
+			args:     []string{"ENCODING", "myset"},
+			expected: "$6\r\nintset\r\n",
+		},
+		{
+			name: "OBJECT ENCODING of a set converted by a long member",
+			setup: func() {
+				executeCommand("SADD", []string{"myset", "1", "2"})
+				executeCommand("SADD", []string{"myset", strings.Repeat("x", 65)})
+			},
+			cmd:      "OBJECT",
+			args:     []string{"ENCODING", "myset"},
+			expected: "$9\r\nhashtable\r\n",
+		},
+		{
+			name:     "OBJECT ENCODING of a missing key",
+			setup:    func() {},
+			cmd:      "OBJECT",
+			args:     []string{"ENCODING", "nonexistent"},
+			expected: constant.RespNil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+		})
+	}
+}
+
+// Test the EXPIRE family
+func TestExecuteExpire(t *testing.T) {
+	future := uint64(time.Now().UnixMilli() + 3600*1000)
+
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "EXPIRE missing key",
+			setup:    func() {},
+			cmd:      "EXPIRE",
+			args:     []string{"nonexistent", "10"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "EXPIRE existing key",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10"},
+			expected: ":1\r\n",
+		},
+		{
+			name: "EXPIRE on a set",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "PEXPIRE",
+			args:     []string{"myset", "10000"},
+			expected: ":1\r\n",
+		},
+		{
+			name: "EXPIRE with non integer time",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "ten"},
+			expected: constant.ErrNotInteger,
+		},
+		{
+			name: "EXPIRE overflowing milliseconds",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "9223372036854775807"},
+			expected: "-ERR invalid expire time in 'expire' command\r\n",
+		},
+		{
+			name: "EXPIRE NX on key without expiry",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "NX"},
+			expected: ":1\r\n",
+		},
+		{
+			name: "EXPIRE NX on key with expiry",
+			setup: func() {
+				dict.Set("key", "value", future)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "nx"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "EXPIRE XX on key without expiry",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "XX"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "EXPIRE GT on key without expiry",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "GT"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "EXPIRE GT with a smaller time",
+			setup: func() {
+				dict.Set("key", "value", future)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "GT"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "EXPIRE LT on key without expiry",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "LT"},
+			expected: ":1\r\n",
+		},
+		{
+			name: "EXPIRE XX GT with a greater time",
+			setup: func() {
+				dict.Set("key", "value", future)
+			},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "7200", "XX", "GT"},
+			expected: ":1\r\n",
+		},
+		{
+			name:     "EXPIRE NX and XX together",
+			setup:    func() {},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "NX", "XX"},
+			expected: "-ERR NX and XX, GT or LT options at the same time are not compatible\r\n",
+		},
+		{
+			name:     "EXPIRE GT and LT together",
+			setup:    func() {},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "GT", "LT"},
+			expected: "-ERR GT and LT options at the same time are not compatible\r\n",
+		},
+		{
+			name:     "EXPIRE unknown option",
+			setup:    func() {},
+			cmd:      "EXPIRE",
+			args:     []string{"key", "10", "FOO"},
+			expected: "-ERR Unsupported option FOO\r\n",
+		},
+		{
+			name: "EXPIREAT sets an absolute time",
+			setup: func() {
+				dict.Set("key", "value", 0)
+				executeCommand("EXPIREAT", []string{"key", "33177117420"})
+			},
+			cmd:      "EXPIRETIME",
+			args:     []string{"key"},
+			expected: ":33177117420\r\n",
+		},
+		{
+			name: "PEXPIREAT sets an absolute time",
+			setup: func() {
+				dict.Set("key", "value", 0)
+				executeCommand("PEXPIREAT", []string{"key", "33177117420123"})
+			},
+			cmd:      "PEXPIRETIME",
+			args:     []string{"key"},
+			expected: ":33177117420123\r\n",
+		},
+		{
+			name: "EXPIRETIME rounds down",
+			setup: func() {
+				dict.Set("key", "value", 0)
+				executeCommand("PEXPIREAT", []string{"key", "33177117420600"})
+			},
+			cmd:      "EXPIRETIME",
+			args:     []string{"key"},
+			expected: ":33177117420\r\n",
+		},
+		{
+			name: "A negative time deletes the key while loading",
+			setup: func() {
+				dict.Set("key", "value", 0)
+				loading = true
+				executeCommand("PEXPIREAT", []string{"key", "-5"})
+				loading = false
+			},
+			cmd:      "EXISTS",
+			args:     []string{"key"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "EXPIRETIME of key without expiry",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "EXPIRETIME",
+			args:     []string{"key"},
+			expected: constant.TtlKeyExistNoExpire,
+		},
+		{
+			name:     "PEXPIRETIME of missing key",
+			setup:    func() {},
+			cmd:      "PEXPIRETIME",
+			args:     []string{"nonexistent"},
+			expected: constant.TtlKeyNotExist,
+		},
+		{
+			name: "EXPIRE in the past deletes the key",
+			setup: func() {
+				dict.Set("key", "value", 0)
+				executeCommand("EXPIRE", []string{"key", "-1"})
+			},
+			cmd:      "EXISTS",
+			args:     []string{"key"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "PERSIST key with expiry",
+			setup: func() {
+				dict.Set("key", "value", future)
+			},
+			cmd:      "PERSIST",
+			args:     []string{"key"},
+			expected: ":1\r\n",
+		},
+		{
+			name: "PERSIST key without expiry",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "PERSIST",
+			args:     []string{"key"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "TTL after PERSIST",
+			setup: func() {
+				dict.Set("key", "value", future)
+				executeCommand("PERSIST", []string{"key"})
+			},
+			cmd:      "TTL",
+			args:     []string{"key"},
+			expected: constant.TtlKeyExistNoExpire,
+		},
+		{
+			name:     "PTTL of missing key",
+			setup:    func() {},
+			cmd:      "PTTL",
+			args:     []string{"nonexistent"},
+			expected: constant.TtlKeyNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+		})
+	}
+
+	t.Run("PTTL after PEXPIRE", func(t *testing.T) {
+		resetGlobalDict()
+		dict.Set("key", "value", 0)
+		executeCommand("PEXPIRE", []string{"key", "5000"})
+
+		result, err := strconv.Atoi(strings.Trim(string(executeCommand("PTTL", []string{"key"})), ":\r\n"))
+		if err != nil || result <= 4000 || result > 5000 {
+			t.Errorf("Expected PTTL close to 5000, got %d (%v)", result, err)
+		}
+		assertResponse(t, executeCommand("TTL", []string{"key"}), ":5\r\n")
+	})
+}
+
+// Test SET options and the related string commands
+func TestExecuteSetOptions(t *testing.T) {
+	future := uint64(time.Now().UnixMilli() + 3600*1000)
+
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "SET NX on missing key",
+			setup:    func() {},
+			cmd:      "SET",
+			args:     []string{"lock", "owner", "NX", "EX", "10"},
+			expected: constant.RespOk,
+			check:    []string{"GET", "lock"},
+			checkRes: "$5\r\nowner\r\n",
+		},
+		{
+			name: "SET NX on existing key",
+			setup: func() {
+				dict.Set("lock", "other", 0)
+			},
+			cmd:      "SET",
+			args:     []string{"lock", "owner", "EX", "10", "NX"},
+			expected: constant.RespNil,
+			check:    []string{"GET", "lock"},
+			checkRes: "$5\r\nother\r\n",
+		},
+		{
+			name:     "SET XX on missing key",
+			setup:    func() {},
+			cmd:      "SET",
+			args:     []string{"key", "value", "XX"},
+			expected: constant.RespNil,
+			check:    []string{"EXISTS", "key"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name: "SET GET returns the old value",
+			setup: func() {
+				dict.Set("key", "old", 0)
+			},
+			cmd:      "SET",
+			args:     []string{"key", "new", "GET"},
+			expected: "$3\r\nold\r\n",
+			check:    []string{"GET", "key"},
+			checkRes: "$3\r\nnew\r\n",
+		},
+		{
+			name:     "SET GET on missing key",
+			setup:    func() {},
+			cmd:      "SET",
+			args:     []string{"key", "new", "GET"},
+			expected: constant.RespNil,
+		},
+		{
+			name: "SET NX GET on existing key",
+			setup: func() {
+				dict.Set("key", "old", 0)
+			},
+			cmd:      "SET",
+			args:     []string{"key", "new", "NX", "GET"},
+			expected: "$3\r\nold\r\n",
+			check:    []string{"GET", "key"},
+			checkRes: "$3\r\nold\r\n",
+		},
+		{
+			name: "SET GET against a set",
+			setup: func() {
+				dict.Set("key", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "SET",
+			args:     []string{"key", "new", "GET"},
+			expected: constant.ErrWrongType,
+			check:    []string{"TYPE", "key"},
+			checkRes: "+set\r\n",
+		},
+		{
+			name: "SET KEEPTTL keeps the expiry",
+			setup: func() {
+				dict.Set("key", "old", future)
+			},
+			cmd:      "SET",
+			args:     []string{"key", "new", "KEEPTTL"},
+			expected: constant.RespOk,
+			check:    []string{"PEXPIRETIME", "key"},
+			checkRes: fmt.Sprintf(":%d\r\n", future),
+		},
+		{
+			name: "SET without KEEPTTL clears the expiry",
+			setup: func() {
+				dict.Set("key", "old", future)
+			},
+			cmd:      "SET",
+			args:     []string{"key", "new"},
+			expected: constant.RespOk,
+			check:    []string{"TTL", "key"},
+			checkRes: constant.TtlKeyExistNoExpire,
+		},
+		{
+			name:     "SET NX and XX together",
+			setup:    func() {},
+			cmd:      "SET",
+			args:     []string{"key", "value", "NX", "XX"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name:     "SET with two expiry options",
+			setup:    func() {},
+			cmd:      "SET",
+			args:     []string{"key", "value", "EX", "10", "PX", "100"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name:     "SET with KEEPTTL and EX",
+			setup:    func() {},
+			cmd:      "SET",
+			args:     []string{"key", "value", "KEEPTTL", "EX", "10"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name:     "SET with EX missing its value",
+			setup:    func() {},
+			cmd:      "SET",
+			args:     []string{"key", "value", "EX"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name:     "SETNX on missing key",
+			setup:    func() {},
+			cmd:      "SETNX",
+			args:     []string{"key", "value"},
+			expected: ":1\r\n",
+		},
+		{
+			name: "SETNX on existing key",
+			setup: func() {
+				dict.Set("key", "old", 0)
+			},
+			cmd:      "SETNX",
+			args:     []string{"key", "value"},
+			expected: ":0\r\n",
+		},
+		{
+			name:     "SETEX sets value and expiry",
+			setup:    func() {},
+			cmd:      "SETEX",
+			args:     []string{"key", "100", "value"},
+			expected: constant.RespOk,
+			check:    []string{"TTL", "key"},
+			checkRes: ":100\r\n",
+		},
+		{
+			name:     "SETEX with invalid time",
+			setup:    func() {},
+			cmd:      "SETEX",
+			args:     []string{"key", "0", "value"},
+			expected: constant.ErrInvalidTime,
+		},
+		{
+			name:     "PSETEX sets value and expiry",
+			setup:    func() {},
+			cmd:      "PSETEX",
+			args:     []string{"key", "100000", "value"},
+			expected: constant.RespOk,
+			check:    []string{"TTL", "key"},
+			checkRes: ":100\r\n",
+		},
+		{
+			name: "GETSET returns old value and clears expiry",
+			setup: func() {
+				dict.Set("key", "old", future)
+			},
+			cmd:      "GETSET",
+			args:     []string{"key", "new"},
+			expected: "$3\r\nold\r\n",
+			check:    []string{"TTL", "key"},
+			checkRes: constant.TtlKeyExistNoExpire,
+		},
+		{
+			name: "GETDEL returns value and deletes key",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "GETDEL",
+			args:     []string{"key"},
+			expected: "$5\r\nvalue\r\n",
+			check:    []string{"EXISTS", "key"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name: "GETDEL against a set",
+			setup: func() {
+				dict.Set("key", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "GETDEL",
+			args:     []string{"key"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name: "GETEX sets expiry",
+			setup: func() {
+				dict.Set("key", "value", 0)
+			},
+			cmd:      "GETEX",
+			args:     []string{"key", "EX", "100"},
+			expected: "$5\r\nvalue\r\n",
+			check:    []string{"TTL", "key"},
+			checkRes: ":100\r\n",
+		},
+		{
+			name: "GETEX PERSIST removes expiry",
+			setup: func() {
+				dict.Set("key", "value", future)
+			},
+			cmd:      "GETEX",
+			args:     []string{"key", "PERSIST"},
+			expected: "$5\r\nvalue\r\n",
+			check:    []string{"TTL", "key"},
+			checkRes: constant.TtlKeyExistNoExpire,
+		},
+		{
+			name:     "GETEX rejects SET only options",
+			setup:    func() {},
+			cmd:      "GETEX",
+			args:     []string{"key", "NX"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name:     "GETEX on missing key",
+			setup:    func() {},
+			cmd:      "GETEX",
+			args:     []string{"key", "PX", "100"},
+			expected: constant.RespNil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+}
+
+// Test integer and float counters
+func TestExecuteCounters(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "INCR missing key",
+			setup:    func() {},
+			cmd:      "INCR",
+			args:     []string{"counter"},
+			expected: ":1\r\n",
+			check:    []string{"OBJECT", "ENCODING", "counter"},
+			checkRes: "$3\r\nint\r\n",
+		},
+		{
+			name: "INCR value set with SET",
+			setup: func() {
+				executeCommand("SET", []string{"counter", "41"})
+			},
+			cmd:      "INCR",
+			args:     []string{"counter"},
+			expected: ":42\r\n",
+			check:    []string{"GET", "counter"},
+			checkRes: "$2\r\n42\r\n",
+		},
+		{
+			name: "INCR keeps the expiry",
+			setup: func() {
+				executeCommand("SET", []string{"counter", "1", "EX", "100"})
+			},
+			cmd:      "INCR",
+			args:     []string{"counter"},
+			expected: ":2\r\n",
+			check:    []string{"TTL", "counter"},
+			checkRes: ":100\r\n",
+		},
+		{
+			name: "INCR non integer string",
+			setup: func() {
+				dict.Set("counter", "abc", 0)
+			},
+			cmd:      "INCR",
+			args:     []string{"counter"},
+			expected: constant.ErrNotInteger,
+		},
+		{
+			name: "INCR integer with leading zero",
+			setup: func() {
+				dict.Set("counter", "007", 0)
+			},
+			cmd:      "INCR",
+			args:     []string{"counter"},
+			expected: constant.ErrNotInteger,
+		},
+		{
+			name: "INCR against a set",
+			setup: func() {
+				dict.Set("counter", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "INCR",
+			args:     []string{"counter"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name: "INCR overflow",
+			setup: func() {
+				dict.Set("counter", "9223372036854775807", 0)
+			},
+			cmd:      "INCR",
+			args:     []string{"counter"},
+			expected: "-ERR increment or decrement would overflow\r\n",
+		},
+		{
+			name: "DECR underflow",
+			setup: func() {
+				dict.Set("counter", "-9223372036854775808", 0)
+			},
+			cmd:      "DECR",
+			args:     []string{"counter"},
+			expected: "-ERR increment or decrement would overflow\r\n",
+		},
+		{
+			name: "INCRBY",
+			setup: func() {
+				dict.Set("counter", "10", 0)
+			},
+			cmd:      "INCRBY",
+			args:     []string{"counter", "-25"},
+			expected: ":-15\r\n",
+		},
+		{
+			name:     "INCRBY with invalid increment",
+			setup:    func() {},
+			cmd:      "INCRBY",
+			args:     []string{"counter", "1.5"},
+			expected: constant.ErrNotInteger,
+		},
+		{
+			name:     "DECRBY",
+			setup:    func() {},
+			cmd:      "DECRBY",
+			args:     []string{"counter", "5"},
+			expected: ":-5\r\n",
+		},
+		{
+			name:     "DECRBY minimum integer",
+			setup:    func() {},
+			cmd:      "DECRBY",
+			args:     []string{"counter", "-9223372036854775808"},
+			expected: "-ERR decrement would overflow\r\n",
+		},
+		{
+			name: "INCRBYFLOAT",
+			setup: func() {
+				dict.Set("price", "10.50", 0)
+			},
+			cmd:      "INCRBYFLOAT",
+			args:     []string{"price", "0.1"},
+			expected: "$4\r\n10.6\r\n",
+		},
+		{
+			name: "INCRBYFLOAT on an integer",
+			setup: func() {
+				executeCommand("INCRBY", []string{"price", "5"})
+			},
+			cmd:      "INCRBYFLOAT",
+			args:     []string{"price", "5.0e3"},
+			expected: "$4\r\n5005\r\n",
+		},
+		{
+			name:     "INCRBYFLOAT with invalid increment",
+			setup:    func() {},
+			cmd:      "INCRBYFLOAT",
+			args:     []string{"price", "abc"},
+			expected: constant.ErrNotFloat,
+		},
+		{
+			name:     "INCRBYFLOAT with infinity",
+			setup:    func() {},
+			cmd:      "INCRBYFLOAT",
+			args:     []string{"price", "inf"},
+			expected: constant.ErrNotFloat,
+		},
+		{
+			name: "INCRBYFLOAT producing infinity",
+			setup: func() {
+				dict.Set("price", "1.7e308", 0)
+			},
+			cmd:      "INCRBYFLOAT",
+			args:     []string{"price", "1.7e308"},
+			expected: "-ERR increment would produce NaN or Infinity\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+}
+
+// Test the multi-key and range string commands
+func TestExecuteStringCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "MSET then MGET",
+			setup:    func() {},
+			cmd:      "MSET",
+			args:     []string{"k1", "v1", "k2", "v2"},
+			expected: constant.RespOk,
+			check:    []string{"MGET", "k1", "missing", "k2"},
+			checkRes: "*3\r\n$2\r\nv1\r\n$-1\r\n$2\r\nv2\r\n",
+		},
+		{
+			name:     "MSET with odd number of arguments",
+			setup:    func() {},
+			cmd:      "MSET",
+			args:     []string{"k1", "v1", "k2"},
+			expected: "-ERR wrong number of arguments for 'MSET' command\r\n",
+		},
+		{
+			name: "MSET removes the expiry",
+			setup: func() {
+				executeCommand("SET", []string{"k1", "old", "EX", "100"})
+			},
+			cmd:      "MSET",
+			args:     []string{"k1", "v1"},
+			expected: constant.RespOk,
+			check:    []string{"TTL", "k1"},
+			checkRes: constant.TtlKeyExistNoExpire,
+		},
+		{
+			name: "MGET returns nil for other types",
+			setup: func() {
+				dict.Set("myset", data_structure.NewSet([]string{"a"}), 0)
+				dict.Set("k1", "v1", 0)
+			},
+			cmd:      "MGET",
+			args:     []string{"myset", "k1"},
+			expected: "*2\r\n$-1\r\n$2\r\nv1\r\n",
+		},
+		{
+			name:     "MSETNX with new keys",
+			setup:    func() {},
+			cmd:      "MSETNX",
+			args:     []string{"k1", "v1", "k2", "v2"},
+			expected: ":1\r\n",
+			check:    []string{"GET", "k2"},
+			checkRes: "$2\r\nv2\r\n",
+		},
+		{
+			name: "MSETNX with an existing key",
+			setup: func() {
+				dict.Set("k2", "old", 0)
+			},
+			cmd:      "MSETNX",
+			args:     []string{"k1", "v1", "k2", "v2"},
+			expected: ":0\r\n",
+			check:    []string{"EXISTS", "k1"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "APPEND creates the key",
+			setup:    func() {},
+			cmd:      "APPEND",
+			args:     []string{"k1", "Hello"},
+			expected: ":5\r\n",
+		},
+		{
+			name: "APPEND to an integer",
+			setup: func() {
+				executeCommand("SET", []string{"k1", "12"})
+			},
+			cmd:      "APPEND",
+			args:     []string{"k1", "a"},
+			expected: ":3\r\n",
+			check:    []string{"GET", "k1"},
+			checkRes: "$3\r\n12a\r\n",
+		},
+		{
+			name: "APPEND against a set",
+			setup: func() {
+				dict.Set("k1", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "APPEND",
+			args:     []string{"k1", "a"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name: "STRLEN",
+			setup: func() {
+				dict.Set("k1", "Hello World", 0)
+			},
+			cmd:      "STRLEN",
+			args:     []string{"k1"},
+			expected: ":11\r\n",
+		},
+		{
+			name:     "STRLEN missing key",
+			setup:    func() {},
+			cmd:      "STRLEN",
+			args:     []string{"k1"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "GETRANGE",
+			setup: func() {
+				dict.Set("k1", "This is a string", 0)
+			},
+			cmd:      "GETRANGE",
+			args:     []string{"k1", "0", "3"},
+			expected: "$4\r\nThis\r\n",
+		},
+		{
+			name: "GETRANGE negative offsets",
+			setup: func() {
+				dict.Set("k1", "This is a string", 0)
+			},
+			cmd:      "GETRANGE",
+			args:     []string{"k1", "-3", "-1"},
+			expected: "$3\r\ning\r\n",
+		},
+		{
+			name: "GETRANGE past the end",
+			setup: func() {
+				dict.Set("k1", "This is a string", 0)
+			},
+			cmd:      "GETRANGE",
+			args:     []string{"k1", "10", "100"},
+			expected: "$6\r\nstring\r\n",
+		},
+		{
+			name: "GETRANGE start after end",
+			setup: func() {
+				dict.Set("k1", "This is a string", 0)
+			},
+			cmd:      "GETRANGE",
+			args:     []string{"k1", "5", "3"},
+			expected: "$0\r\n\r\n",
+		},
+		{
+			name:     "GETRANGE missing key",
+			setup:    func() {},
+			cmd:      "GETRANGE",
+			args:     []string{"k1", "0", "-1"},
+			expected: "$0\r\n\r\n",
+		},
+		{
+			name: "SETRANGE",
+			setup: func() {
+				dict.Set("k1", "Hello World", 0)
+			},
+			cmd:      "SETRANGE",
+			args:     []string{"k1", "6", "Redis"},
+			expected: ":11\r\n",
+			check:    []string{"GET", "k1"},
+			checkRes: "$11\r\nHello Redis\r\n",
+		},
+		{
+			name:     "SETRANGE pads with zero bytes",
+			setup:    func() {},
+			cmd:      "SETRANGE",
+			args:     []string{"k1", "3", "a\x00b"},
+			expected: ":6\r\n",
+			check:    []string{"GET", "k1"},
+			checkRes: "$6\r\n\x00\x00\x00a\x00b\r\n",
+		},
+		{
+			name:     "SETRANGE with empty value does not create the key",
+			setup:    func() {},
+			cmd:      "SETRANGE",
+			args:     []string{"k1", "10", ""},
+			expected: ":0\r\n",
+			check:    []string{"EXISTS", "k1"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "SETRANGE negative offset",
+			setup:    func() {},
+			cmd:      "SETRANGE",
+			args:     []string{"k1", "-1", "a"},
+			expected: "-ERR offset is out of range\r\n",
+		},
+		{
+			name:     "SETRANGE past the maximum size",
+			setup:    func() {},
+			cmd:      "SETRANGE",
+			args:     []string{"k1", "536870911", "ab"},
+			expected: constant.ErrStringTooLong,
+		},
+		{
+			name:     "SETRANGE with the largest offset",
+			setup:    func() {},
+			cmd:      "SETRANGE",
+			args:     []string{"k1", strconv.FormatInt(math.MaxInt64, 10), "ab"},
+			expected: constant.ErrStringTooLong,
+		},
+		{
+			name: "LCS",
+			setup: func() {
+				executeCommand("MSET", []string{"key1", "ohmytext", "key2", "mynewtext"})
+			},
+			cmd:      "LCS",
+			args:     []string{"key1", "key2"},
+			expected: "$6\r\nmytext\r\n",
+		},
+		{
+			name: "LCS LEN",
+			setup: func() {
+				executeCommand("MSET", []string{"key1", "ohmytext", "key2", "mynewtext"})
+			},
+			cmd:      "LCS",
+			args:     []string{"key1", "key2", "LEN"},
+			expected: ":6\r\n",
+		},
+		{
+			name: "LCS IDX",
+			setup: func() {
+				executeCommand("MSET", []string{"key1", "ohmytext", "key2", "mynewtext"})
+			},
+			cmd:  "LCS",
+			args: []string{"key1", "key2", "IDX"},
+			expected: "*4\r\n$7\r\nmatches\r\n*2\r\n" +
+				"*2\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n" +
+				"*2\r\n*2\r\n:2\r\n:3\r\n*2\r\n:0\r\n:1\r\n" +
+				"$3\r\nlen\r\n:6\r\n",
+		},
+		{
+			name: "LCS IDX MINMATCHLEN WITHMATCHLEN",
+			setup: func() {
+				executeCommand("MSET", []string{"key1", "ohmytext", "key2", "mynewtext"})
+			},
+			cmd:  "LCS",
+			args: []string{"key1", "key2", "IDX", "MINMATCHLEN", "4", "WITHMATCHLEN"},
+			expected: "*4\r\n$7\r\nmatches\r\n*1\r\n" +
+				"*3\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n:4\r\n" +
+				"$3\r\nlen\r\n:6\r\n",
+		},
+		{
+			name:     "LCS LEN and IDX",
+			setup:    func() {},
+			cmd:      "LCS",
+			args:     []string{"key1", "key2", "LEN", "IDX"},
+			expected: "-ERR If you want both the length and indexes, please just use IDX.\r\n",
+		},
+		{
+			name: "LCS against a set",
+			setup: func() {
+				dict.Set("key1", data_structure.NewSet([]string{"a"}), 0)
+			},
+			cmd:      "LCS",
+			args:     []string{"key1", "key2"},
+			expected: "-ERR The specified keys must contain string values\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+}
+
+// Test the list commands
+func TestExecuteListCommands(t *testing.T) {
+	pushList := func(elements ...string) func() {
+		return func() {
+			executeCommand("RPUSH", append([]string{"mylist"}, elements...))
+		}
+	}
+
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "LPUSH creates the list",
+			setup:    func() {},
+			cmd:      "LPUSH",
+			args:     []string{"mylist", "a", "b", "c"},
+			expected: ":3\r\n",
+			check:    []string{"LRANGE", "mylist", "0", "-1"},
+			checkRes: "*3\r\n$1\r\nc\r\n$1\r\nb\r\n$1\r\na\r\n",
+		},
+		{
+			name:     "RPUSH creates the list",
+			setup:    func() {},
+			cmd:      "RPUSH",
+			args:     []string{"mylist", "a", "b"},
+			expected: ":2\r\n",
+			check:    []string{"TYPE", "mylist"},
+			checkRes: "+list\r\n",
+		},
+		{
+			name: "RPUSH against a string",
+			setup: func() {
+				dict.Set("mylist", "value", 0)
+			},
+			cmd:      "RPUSH",
+			args:     []string{"mylist", "a"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name:     "LPUSHX missing list",
+			setup:    func() {},
+			cmd:      "LPUSHX",
+			args:     []string{"mylist", "a"},
+			expected: ":0\r\n",
+			check:    []string{"EXISTS", "mylist"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "RPUSHX existing list",
+			setup:    pushList("a"),
+			cmd:      "RPUSHX",
+			args:     []string{"mylist", "b"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "LPOP",
+			setup:    pushList("a", "b", "c"),
+			cmd:      "LPOP",
+			args:     []string{"mylist"},
+			expected: "$1\r\na\r\n",
+		},
+		{
+			name:     "RPOP with count",
+			setup:    pushList("a", "b", "c"),
+			cmd:      "RPOP",
+			args:     []string{"mylist", "2"},
+			expected: "*2\r\n$1\r\nc\r\n$1\r\nb\r\n",
+		},
+		{
+			name:     "LPOP count larger than the list deletes the key",
+			setup:    pushList("a", "b"),
+			cmd:      "LPOP",
+			args:     []string{"mylist", "5"},
+			expected: "*2\r\n$1\r\na\r\n$1\r\nb\r\n",
+			check:    []string{"EXISTS", "mylist"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "LPOP missing list",
+			setup:    func() {},
+			cmd:      "LPOP",
+			args:     []string{"mylist"},
+			expected: constant.RespNil,
+		},
+		{
+			name:     "LPOP with count missing list",
+			setup:    func() {},
+			cmd:      "LPOP",
+			args:     []string{"mylist", "2"},
+			expected: constant.RespNilArray,
+		},
+		{
+			name:     "LPOP with zero count",
+			setup:    pushList("a"),
+			cmd:      "LPOP",
+			args:     []string{"mylist", "0"},
+			expected: "*0\r\n",
+		},
+		{
+			name:     "LPOP with negative count",
+			setup:    pushList("a"),
+			cmd:      "LPOP",
+			args:     []string{"mylist", "-1"},
+			expected: constant.ErrNotPositive,
+		},
+		{
+			name:     "LRANGE negative offsets",
+			setup:    pushList("a", "b", "c", "d"),
+			cmd:      "LRANGE",
+			args:     []string{"mylist", "-3", "-2"},
+			expected: "*2\r\n$1\r\nb\r\n$1\r\nc\r\n",
+		},
+		{
+			name:     "LRANGE out of range",
+			setup:    pushList("a", "b"),
+			cmd:      "LRANGE",
+			args:     []string{"mylist", "5", "10"},
+			expected: "*0\r\n",
+		},
+		{
+			name:     "LRANGE stop past the end",
+			setup:    pushList("a", "b"),
+			cmd:      "LRANGE",
+			args:     []string{"mylist", "-100", "100"},
+			expected: "*2\r\n$1\r\na\r\n$1\r\nb\r\n",
+		},
+		{
+			name:     "LINDEX",
+			setup:    pushList("a", "b", "c"),
+			cmd:      "LINDEX",
+			args:     []string{"mylist", "-1"},
+			expected: "$1\r\nc\r\n",
+		},
+		{
+			name:     "LINDEX out of range",
+			setup:    pushList("a"),
+			cmd:      "LINDEX",
+			args:     []string{"mylist", "3"},
+			expected: constant.RespNil,
+		},
+		{
+			name:     "LSET",
+			setup:    pushList("a", "b", "c"),
+			cmd:      "LSET",
+			args:     []string{"mylist", "1", "x"},
+			expected: constant.RespOk,
+			check:    []string{"LRANGE", "mylist", "0", "-1"},
+			checkRes: "*3\r\n$1\r\na\r\n$1\r\nx\r\n$1\r\nc\r\n",
+		},
+		{
+			name:     "LSET out of range",
+			setup:    pushList("a"),
+			cmd:      "LSET",
+			args:     []string{"mylist", "1", "x"},
+			expected: "-ERR index out of range\r\n",
+		},
+		{
+			name:     "LSET missing list",
+			setup:    func() {},
+			cmd:      "LSET",
+			args:     []string{"mylist", "0", "x"},
+			expected: constant.ErrNoSuchKey,
+		},
+		{
+			name:     "LINSERT AFTER",
+			setup:    pushList("a", "b"),
+			cmd:      "LINSERT",
+			args:     []string{"mylist", "AFTER", "a", "x"},
+			expected: ":3\r\n",
+			check:    []string{"LRANGE", "mylist", "0", "-1"},
+			checkRes: "*3\r\n$1\r\na\r\n$1\r\nx\r\n$1\r\nb\r\n",
+		},
+		{
+			name:     "LINSERT pivot not found",
+			setup:    pushList("a", "b"),
+			cmd:      "LINSERT",
+			args:     []string{"mylist", "BEFORE", "z", "x"},
+			expected: ":-1\r\n",
+		},
+		{
+			name:     "LINSERT invalid position",
+			setup:    pushList("a"),
+			cmd:      "LINSERT",
+			args:     []string{"mylist", "MIDDLE", "a", "x"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name:     "LREM from the tail",
+			setup:    pushList("a", "b", "a", "c", "a"),
+			cmd:      "LREM",
+			args:     []string{"mylist", "-2", "a"},
+			expected: ":2\r\n",
+			check:    []string{"LRANGE", "mylist", "0", "-1"},
+			checkRes: "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n",
+		},
+		{
+			name:     "LREM all occurrences deletes the key",
+			setup:    pushList("a", "a"),
+			cmd:      "LREM",
+			args:     []string{"mylist", "0", "a"},
+			expected: ":2\r\n",
+			check:    []string{"EXISTS", "mylist"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "LTRIM",
+			setup:    pushList("a", "b", "c", "d"),
+			cmd:      "LTRIM",
+			args:     []string{"mylist", "1", "-2"},
+			expected: constant.RespOk,
+			check:    []string{"LRANGE", "mylist", "0", "-1"},
+			checkRes: "*2\r\n$1\r\nb\r\n$1\r\nc\r\n",
+		},
+		{
+			name:     "LTRIM out of range deletes the key",
+			setup:    pushList("a", "b"),
+			cmd:      "LTRIM",
+			args:     []string{"mylist", "5", "10"},
+			expected: constant.RespOk,
+			check:    []string{"EXISTS", "mylist"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "LLEN",
+			setup:    pushList("a", "b", "c"),
+			cmd:      "LLEN",
+			args:     []string{"mylist"},
+			expected: ":3\r\n",
+		},
+		{
+			name:     "LPOS",
+			setup:    pushList("a", "b", "c", "1", "2", "3", "c", "c"),
+			cmd:      "LPOS",
+			args:     []string{"mylist", "c"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "LPOS RANK",
+			setup:    pushList("a", "b", "c", "1", "2", "3", "c", "c"),
+			cmd:      "LPOS",
+			args:     []string{"mylist", "c", "RANK", "-1"},
+			expected: ":7\r\n",
+		},
+		{
+			name:     "LPOS COUNT",
+			setup:    pushList("a", "b", "c", "1", "2", "3", "c", "c"),
+			cmd:      "LPOS",
+			args:     []string{"mylist", "c", "COUNT", "2"},
+			expected: "*2\r\n:2\r\n:6\r\n",
+		},
+		{
+			name:     "LPOS RANK COUNT MAXLEN",
+			setup:    pushList("a", "b", "c", "1", "2", "3", "c", "c"),
+			cmd:      "LPOS",
+			args:     []string{"mylist", "c", "RANK", "-1", "COUNT", "0", "MAXLEN", "2"},
+			expected: "*2\r\n:7\r\n:6\r\n",
+		},
+		{
+			name:     "LPOS no match",
+			setup:    pushList("a"),
+			cmd:      "LPOS",
+			args:     []string{"mylist", "z"},
+			expected: constant.RespNil,
+		},
+		{
+			name:     "LPOS zero rank",
+			setup:    pushList("a"),
+			cmd:      "LPOS",
+			args:     []string{"mylist", "a", "RANK", "0"},
+			expected: "-ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list\r\n",
+		},
+		{
+			name:     "LMOVE",
+			setup:    pushList("a", "b", "c"),
+			cmd:      "LMOVE",
+			args:     []string{"mylist", "other", "RIGHT", "LEFT"},
+			expected: "$1\r\nc\r\n",
+			check:    []string{"LRANGE", "other", "0", "-1"},
+			checkRes: "*1\r\n$1\r\nc\r\n",
+		},
+		{
+			name:     "LMOVE rotates the same list",
+			setup:    pushList("a", "b", "c"),
+			cmd:      "LMOVE",
+			args:     []string{"mylist", "mylist", "LEFT", "RIGHT"},
+			expected: "$1\r\na\r\n",
+			check:    []string{"LRANGE", "mylist", "0", "-1"},
+			checkRes: "*3\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\na\r\n",
+		},
+		{
+			name:     "LMOVE last element deletes the source",
+			setup:    pushList("a"),
+			cmd:      "LMOVE",
+			args:     []string{"mylist", "other", "LEFT", "LEFT"},
+			expected: "$1\r\na\r\n",
+			check:    []string{"EXISTS", "mylist"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name: "LMOVE to a string",
+			setup: func() {
+				executeCommand("RPUSH", []string{"mylist", "a"})
+				dict.Set("other", "value", 0)
+			},
+			cmd:      "LMOVE",
+			args:     []string{"mylist", "other", "LEFT", "LEFT"},
+			expected: constant.ErrWrongType,
+			check:    []string{"LLEN", "mylist"},
+			checkRes: ":1\r\n",
+		},
+		{
+			name:     "LMOVE missing source",
+			setup:    func() {},
+			cmd:      "LMOVE",
+			args:     []string{"mylist", "other", "LEFT", "LEFT"},
+			expected: constant.RespNil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+}
+
+// Test the hash commands
+func TestExecuteHashCommands(t *testing.T) {
+	setHash := func(fieldValues ...string) func() {
+		return func() {
+			executeCommand("HSET", append([]string{"myhash"}, fieldValues...))
+		}
+	}
+
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "HSET creates the hash",
+			setup:    func() {},
+			cmd:      "HSET",
+			args:     []string{"myhash", "f1", "v1", "f2", "v2"},
+			expected: ":2\r\n",
+			check:    []string{"TYPE", "myhash"},
+			checkRes: "+hash\r\n",
+		},
+		{
+			name:     "HSET updates a field",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HSET",
+			args:     []string{"myhash", "f1", "new", "f2", "v2"},
+			expected: ":1\r\n",
+			check:    []string{"HGET", "myhash", "f1"},
+			checkRes: "$3\r\nnew\r\n",
+		},
+		{
+			name:     "HSET with a field without value",
+			setup:    func() {},
+			cmd:      "HSET",
+			args:     []string{"myhash", "f1", "v1", "f2"},
+			expected: "-ERR wrong number of arguments for 'HSET' command\r\n",
+		},
+		{
+			name: "HSET against a string",
+			setup: func() {
+				dict.Set("myhash", "value", 0)
+			},
+			cmd:      "HSET",
+			args:     []string{"myhash", "f1", "v1"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name:     "HSETNX existing field",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HSETNX",
+			args:     []string{"myhash", "f1", "new"},
+			expected: ":0\r\n",
+			check:    []string{"HGET", "myhash", "f1"},
+			checkRes: "$2\r\nv1\r\n",
+		},
+		{
+			name:     "HGET missing field",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HGET",
+			args:     []string{"myhash", "f2"},
+			expected: constant.RespNil,
+		},
+		{
+			name:     "HMGET",
+			setup:    setHash("f1", "v1", "f2", "v2"),
+			cmd:      "HMGET",
+			args:     []string{"myhash", "f2", "missing", "f1"},
+			expected: "*3\r\n$2\r\nv2\r\n$-1\r\n$2\r\nv1\r\n",
+		},
+		{
+			name:     "HMGET missing hash",
+			setup:    func() {},
+			cmd:      "HMGET",
+			args:     []string{"myhash", "f1"},
+			expected: "*1\r\n$-1\r\n",
+		},
+		{
+			name:     "HDEL",
+			setup:    setHash("f1", "v1", "f2", "v2"),
+			cmd:      "HDEL",
+			args:     []string{"myhash", "f1", "missing"},
+			expected: ":1\r\n",
+			check:    []string{"HLEN", "myhash"},
+			checkRes: ":1\r\n",
+		},
+		{
+			name:     "HDEL last field deletes the key",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HDEL",
+			args:     []string{"myhash", "f1"},
+			expected: ":1\r\n",
+			check:    []string{"EXISTS", "myhash"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "HEXISTS",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HEXISTS",
+			args:     []string{"myhash", "f1"},
+			expected: ":1\r\n",
+		},
+		{
+			name:     "HSTRLEN",
+			setup:    setHash("f1", "Hello"),
+			cmd:      "HSTRLEN",
+			args:     []string{"myhash", "f1"},
+			expected: ":5\r\n",
+		},
+		{
+			name:     "HKEYS",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HKEYS",
+			args:     []string{"myhash"},
+			expected: "*1\r\n$2\r\nf1\r\n",
+		},
+		{
+			name:     "HVALS",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HVALS",
+			args:     []string{"myhash"},
+			expected: "*1\r\n$2\r\nv1\r\n",
+		},
+		{
+			name:     "HGETALL",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HGETALL",
+			args:     []string{"myhash"},
+			expected: "*2\r\n$2\r\nf1\r\n$2\r\nv1\r\n",
+		},
+		{
+			name:     "HGETALL missing hash",
+			setup:    func() {},
+			cmd:      "HGETALL",
+			args:     []string{"myhash"},
+			expected: "*0\r\n",
+		},
+		{
+			name:     "HINCRBY",
+			setup:    setHash("f1", "10"),
+			cmd:      "HINCRBY",
+			args:     []string{"myhash", "f1", "-15"},
+			expected: ":-5\r\n",
+		},
+		{
+			name:     "HINCRBY missing field",
+			setup:    func() {},
+			cmd:      "HINCRBY",
+			args:     []string{"myhash", "f1", "3"},
+			expected: ":3\r\n",
+		},
+		{
+			name:     "HINCRBY non integer value",
+			setup:    setHash("f1", "abc"),
+			cmd:      "HINCRBY",
+			args:     []string{"myhash", "f1", "1"},
+			expected: "-ERR hash value is not an integer\r\n",
+		},
+		{
+			name:     "HINCRBY overflow",
+			setup:    setHash("f1", "9223372036854775807"),
+			cmd:      "HINCRBY",
+			args:     []string{"myhash", "f1", "1"},
+			expected: "-ERR increment or decrement would overflow\r\n",
+		},
+		{
+			name:     "HINCRBYFLOAT",
+			setup:    setHash("f1", "10.50"),
+			cmd:      "HINCRBYFLOAT",
+			args:     []string{"myhash", "f1", "0.1"},
+			expected: "$4\r\n10.6\r\n",
+		},
+		{
+			name:     "HINCRBYFLOAT non float value",
+			setup:    setHash("f1", "abc"),
+			cmd:      "HINCRBYFLOAT",
+			args:     []string{"myhash", "f1", "1"},
+			expected: "-ERR hash value is not a float\r\n",
+		},
+		{
+			name:     "HRANDFIELD",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HRANDFIELD",
+			args:     []string{"myhash"},
+			expected: "$2\r\nf1\r\n",
+		},
+		{
+			name:     "HRANDFIELD count larger than the hash",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HRANDFIELD",
+			args:     []string{"myhash", "5", "WITHVALUES"},
+			expected: "*2\r\n$2\r\nf1\r\n$2\r\nv1\r\n",
+		},
+		{
+			name:     "HRANDFIELD negative count repeats fields",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HRANDFIELD",
+			args:     []string{"myhash", "-3"},
+			expected: "*3\r\n$2\r\nf1\r\n$2\r\nf1\r\n$2\r\nf1\r\n",
+		},
+		{
+			name:     "HRANDFIELD missing hash",
+			setup:    func() {},
+			cmd:      "HRANDFIELD",
+			args:     []string{"myhash"},
+			expected: constant.RespNil,
+		},
+		{
+			name:     "HSCAN with MATCH",
+			setup:    setHash("name", "Jack", "age", "33"),
+			cmd:      "HSCAN",
+			args:     []string{"myhash", "0", "MATCH", "n*"},
+			expected: "*2\r\n$1\r\n0\r\n*2\r\n$4\r\nname\r\n$4\r\nJack\r\n",
+		},
+		{
+			name:     "HSCAN with NOVALUES",
+			setup:    setHash("name", "Jack"),
+			cmd:      "HSCAN",
+			args:     []string{"myhash", "0", "NOVALUES"},
+			expected: "*2\r\n$1\r\n0\r\n*1\r\n$4\r\nname\r\n",
+		},
+		{
+			name:     "HSCAN invalid cursor",
+			setup:    func() {},
+			cmd:      "HSCAN",
+			args:     []string{"myhash", "abc"},
+			expected: "-ERR invalid cursor\r\n",
+		},
+		{
+			name:     "HEXPIRE",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HEXPIRE",
+			args:     []string{"myhash", "100", "FIELDS", "2", "f1", "missing"},
+			expected: "*2\r\n:1\r\n:-2\r\n",
+			check:    []string{"HTTL", "myhash", "FIELDS", "1", "f1"},
+			checkRes: "*1\r\n:100\r\n",
+		},
+		{
+			name: "HEXPIRE NX on a field with expiry",
+			setup: func() {
+				executeCommand("HSET", []string{"myhash", "f1", "v1"})
+				executeCommand("HEXPIRE", []string{"myhash", "100", "FIELDS", "1", "f1"})
+			},
+			cmd:      "HEXPIRE",
+			args:     []string{"myhash", "200", "NX", "FIELDS", "1", "f1"},
+			expected: "*1\r\n:0\r\n",
+		},
+		{
+			name:     "HEXPIRE GT on a field without expiry",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HEXPIRE",
+			args:     []string{"myhash", "200", "GT", "FIELDS", "1", "f1"},
+			expected: "*1\r\n:0\r\n",
+		},
+		{
+			name:     "HEXPIRE zero deletes the field",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HEXPIRE",
+			args:     []string{"myhash", "0", "FIELDS", "1", "f1"},
+			expected: "*1\r\n:2\r\n",
+			check:    []string{"EXISTS", "myhash"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "HEXPIRE missing hash",
+			setup:    func() {},
+			cmd:      "HEXPIRE",
+			args:     []string{"myhash", "100", "FIELDS", "1", "f1"},
+			expected: "*1\r\n:-2\r\n",
+		},
+		{
+			name:     "HEXPIRE numfields mismatch",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HEXPIRE",
+			args:     []string{"myhash", "100", "FIELDS", "2", "f1"},
+			expected: "-ERR The `numfields` parameter must match the number of arguments\r\n",
+		},
+		{
+			name:     "HEXPIRE without FIELDS",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HEXPIRE",
+			args:     []string{"myhash", "100", "NX", "1", "f1"},
+			expected: "-ERR Mandatory argument FIELDS is missing or not at the right position\r\n",
+		},
+		{
+			name:     "HPEXPIRE",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HPEXPIRE",
+			args:     []string{"myhash", "100000", "FIELDS", "1", "f1"},
+			expected: "*1\r\n:1\r\n",
+			check:    []string{"HTTL", "myhash", "FIELDS", "1", "f1"},
+			checkRes: "*1\r\n:100\r\n",
+		},
+		{
+			name:     "HTTL field without expiry",
+			setup:    setHash("f1", "v1"),
+			cmd:      "HTTL",
+			args:     []string{"myhash", "FIELDS", "2", "f1", "missing"},
+			expected: "*2\r\n:-1\r\n:-2\r\n",
+		},
+		{
+			name: "HSET removes the field expiry",
+			setup: func() {
+				executeCommand("HSET", []string{"myhash", "f1", "v1"})
+				executeCommand("HEXPIRE", []string{"myhash", "100", "FIELDS", "1", "f1"})
+			},
+			cmd:      "HSET",
+			args:     []string{"myhash", "f1", "v2"},
+			expected: ":0\r\n",
+			check:    []string{"HTTL", "myhash", "FIELDS", "1", "f1"},
+			checkRes: "*1\r\n:-1\r\n",
+		},
+		{
+			name: "HPERSIST",
+			setup: func() {
+				executeCommand("HSET", []string{"myhash", "f1", "v1", "f2", "v2"})
+				executeCommand("HEXPIRE", []string{"myhash", "100", "FIELDS", "1", "f1"})
+			},
+			cmd:      "HPERSIST",
+			args:     []string{"myhash", "FIELDS", "3", "f1", "f2", "missing"},
+			expected: "*3\r\n:1\r\n:-1\r\n:-2\r\n",
+			check:    []string{"HTTL", "myhash", "FIELDS", "1", "f1"},
+			checkRes: "*1\r\n:-1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+}
+
+// Test that expired hash fields disappear, lazily and in the active expiry cycle
+func TestHashFieldExpiry(t *testing.T) {
+	useTempDir(t)
+	resetGlobalDict()
+	executeCommand("HSET", []string{"myhash", "f1", "v1", "f2", "v2"})
+	executeCommand("HSET", []string{"other", "f1", "v1"})
+	executeCommand("HPEXPIRE", []string{"myhash", "10", "FIELDS", "1", "f1"})
+	executeCommand("HPEXPIRE", []string{"other", "10", "FIELDS", "1", "f1"})
+	time.Sleep(20 * time.Millisecond)
+	assertResponse(t, executeCommand("CONFIG", []string{"SET", "appendonly", "yes"}), constant.RespOk)
+	defer executeCommand("CONFIG", []string{"SET", "appendonly", "no"})
+
+	assertResponse(t, executeCommand("HGETALL", []string{"myhash"}), "*2\r\n$2\r\nf2\r\n$2\r\nv2\r\n")
+
+	// Nothing reads the other hash, only the active expiry cycle deletes it
+	CleanupExpiredKeys()
+	if dict.Get("other") != nil {
+		t.Errorf("Expected the hash without fields left to be deleted")
+	}
+	assertResponse(t, executeCommand("HLEN", []string{"myhash"}), ":1\r\n")
+
+	// The expired fields are deleted from the AOF and the replicas too
+	expected := [][]string{{"SELECT", "0"}, {"HDEL", "myhash", "f1"}, {"HDEL", "other", "f1"}}
+	if commands := readAppendOnlyCommands(t); !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected the commands %q, got %q", expected, commands)
+	}
+}
+
+func TestExecuteSortedSetCommands(t *testing.T) {
+	setZSet := func(scoreMembers ...string) func() {
+		return func() {
+			executeCommand("ZADD", append([]string{"myzset"}, scoreMembers...))
+		}
+	}
+
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "ZADD creates the sorted set",
+			setup:    func() {},
+			cmd:      "ZADD",
+			args:     []string{"myzset", "1", "a", "2", "b"},
+			expected: ":2\r\n",
+			check:    []string{"TYPE", "myzset"},
+			checkRes: "+zset\r\n",
+		},
+		{
+			name:     "ZADD updates a score",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZADD",
+			args:     []string{"myzset", "5", "a", "2", "b"},
+			expected: ":1\r\n",
+			check:    []string{"ZSCORE", "myzset", "a"},
+			checkRes: "$1\r\n5\r\n",
+		},
+		{
+			name:     "ZADD CH counts changed members",
+			setup:    setZSet("1", "a", "2", "b"),
+			cmd:      "ZADD",
+			args:     []string{"myzset", "CH", "1", "a", "3", "b", "4", "c"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "ZADD NX does not update",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZADD",
+			args:     []string{"myzset", "NX", "5", "a"},
+			expected: ":0\r\n",
+			check:    []string{"ZSCORE", "myzset", "a"},
+			checkRes: "$1\r\n1\r\n",
+		},
+		{
+			name:     "ZADD XX does not add",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZADD",
+			args:     []string{"myzset", "XX", "5", "b"},
+			expected: ":0\r\n",
+			check:    []string{"ZCARD", "myzset"},
+			checkRes: ":1\r\n",
+		},
+		{
+			name:     "ZADD GT only raises scores",
+			setup:    setZSet("3", "a", "3", "b"),
+			cmd:      "ZADD",
+			args:     []string{"myzset", "GT", "CH", "1", "a", "5", "b"},
+			expected: ":1\r\n",
+			check:    []string{"ZRANGE", "myzset", "0", "-1", "WITHSCORES"},
+			checkRes: "*4\r\n$1\r\na\r\n$1\r\n3\r\n$1\r\nb\r\n$1\r\n5\r\n",
+		},
+		{
+			name:     "ZADD INCR",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZADD",
+			args:     []string{"myzset", "INCR", "2.5", "a"},
+			expected: "$3\r\n3.5\r\n",
+		},
+		{
+			name:     "ZADD INCR aborted by XX",
+			setup:    func() {},
+			cmd:      "ZADD",
+			args:     []string{"myzset", "XX", "INCR", "1", "a"},
+			expected: constant.RespNil,
+		},
+		{
+			name:     "ZADD NX and XX",
+			setup:    func() {},
+			cmd:      "ZADD",
+			args:     []string{"myzset", "NX", "XX", "1", "a"},
+			expected: "-ERR XX and NX options at the same time are not compatible\r\n",
+		},
+		{
+			name:     "ZADD with an invalid score",
+			setup:    func() {},
+			cmd:      "ZADD",
+			args:     []string{"myzset", "nan", "a"},
+			expected: "-ERR value is not a valid float\r\n",
+		},
+		{
+			name: "ZADD against a string",
+			setup: func() {
+				dict.Set("myzset", "value", 0)
+			},
+			cmd:      "ZADD",
+			args:     []string{"myzset", "1", "a"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name:     "ZINCRBY a new member",
+			setup:    func() {},
+			cmd:      "ZINCRBY",
+			args:     []string{"myzset", "1.5", "a"},
+			expected: "$3\r\n1.5\r\n",
+		},
+		{
+			name:     "ZREM deletes the empty sorted set",
+			setup:    setZSet("1", "a", "2", "b"),
+			cmd:      "ZREM",
+			args:     []string{"myzset", "a", "b", "c"},
+			expected: ":2\r\n",
+			check:    []string{"EXISTS", "myzset"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "ZMSCORE",
+			setup:    setZSet("1", "a", "-inf", "b"),
+			cmd:      "ZMSCORE",
+			args:     []string{"myzset", "a", "b", "c"},
+			expected: "*3\r\n$1\r\n1\r\n$4\r\n-inf\r\n$-1\r\n",
+		},
+		{
+			name:     "ZCOUNT with exclusive bounds",
+			setup:    setZSet("1", "a", "2", "b", "3", "c"),
+			cmd:      "ZCOUNT",
+			args:     []string{"myzset", "(1", "+inf"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "ZCOUNT with an invalid bound",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZCOUNT",
+			args:     []string{"myzset", "x", "2"},
+			expected: "-ERR min or max is not a float\r\n",
+		},
+		{
+			name:     "ZRANK orders equal scores by member",
+			setup:    setZSet("1", "b", "1", "a", "0", "c"),
+			cmd:      "ZRANK",
+			args:     []string{"myzset", "b"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "ZREVRANK WITHSCORE",
+			setup:    setZSet("1", "a", "2", "b", "3", "c"),
+			cmd:      "ZREVRANK",
+			args:     []string{"myzset", "a", "WITHSCORE"},
+			expected: "*2\r\n:2\r\n$1\r\n1\r\n",
+		},
+		{
+			name:     "ZRANK WITHSCORE of a missing member",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZRANK",
+			args:     []string{"myzset", "b", "WITHSCORE"},
+			expected: constant.RespNilArray,
+		},
+		{
+			name:     "ZRANGE by rank",
+			setup:    setZSet("1", "a", "2", "b", "3", "c"),
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "1", "-1"},
+			expected: "*2\r\n$1\r\nb\r\n$1\r\nc\r\n",
+		},
+		{
+			name:     "ZRANGE REV",
+			setup:    setZSet("1", "a", "2", "b", "3", "c"),
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "0", "0", "REV", "WITHSCORES"},
+			expected: "*2\r\n$1\r\nc\r\n$1\r\n3\r\n",
+		},
+		{
+			name:     "ZRANGE BYSCORE with LIMIT",
+			setup:    setZSet("1", "a", "2", "b", "3", "c", "4", "d"),
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "(1", "+inf", "BYSCORE", "LIMIT", "1", "2"},
+			expected: "*2\r\n$1\r\nc\r\n$1\r\nd\r\n",
+		},
+		{
+			name:     "ZRANGE BYSCORE REV takes max first",
+			setup:    setZSet("1", "a", "2", "b", "3", "c"),
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "2", "-inf", "BYSCORE", "REV"},
+			expected: "*2\r\n$1\r\nb\r\n$1\r\na\r\n",
+		},
+		{
+			name:     "ZRANGE BYLEX",
+			setup:    setZSet("0", "a", "0", "b", "0", "c", "0", "d"),
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "[b", "(d", "BYLEX"},
+			expected: "*2\r\n$1\r\nb\r\n$1\r\nc\r\n",
+		},
+		{
+			name:     "ZRANGE BYLEX with an invalid bound",
+			setup:    setZSet("0", "a"),
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "a", "+", "BYLEX"},
+			expected: "-ERR min or max not valid string range item\r\n",
+		},
+		{
+			name:     "ZRANGE LIMIT without BYSCORE or BYLEX",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "0", "-1", "LIMIT", "0", "1"},
+			expected: "-ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX\r\n",
+		},
+		{
+			name:     "ZRANGE of a missing key",
+			setup:    func() {},
+			cmd:      "ZRANGE",
+			args:     []string{"myzset", "0", "-1"},
+			expected: "*0\r\n",
+		},
+		{
+			name:     "ZRANGESTORE",
+			setup:    setZSet("1", "a", "2", "b", "3", "c"),
+			cmd:      "ZRANGESTORE",
+			args:     []string{"dst", "myzset", "2", "3", "BYSCORE"},
+			expected: ":2\r\n",
+			check:    []string{"ZRANGE", "dst", "0", "-1", "WITHSCORES"},
+			checkRes: "*4\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\nc\r\n$1\r\n3\r\n",
+		},
+		{
+			name: "ZRANGESTORE of an empty range deletes the destination",
+			setup: func() {
+				setZSet("1", "a")()
+				dict.Set("dst", "value", 0)
+			},
+			cmd:      "ZRANGESTORE",
+			args:     []string{"dst", "myzset", "5", "10"},
+			expected: ":0\r\n",
+			check:    []string{"EXISTS", "dst"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "ZPOPMIN",
+			setup:    setZSet("1", "a", "2", "b", "3", "c"),
+			cmd:      "ZPOPMIN",
+			args:     []string{"myzset"},
+			expected: "*2\r\n$1\r\na\r\n$1\r\n1\r\n",
+			check:    []string{"ZCARD", "myzset"},
+			checkRes: ":2\r\n",
+		},
+		{
+			name:     "ZPOPMAX with count deletes the empty sorted set",
+			setup:    setZSet("1", "a", "2", "b"),
+			cmd:      "ZPOPMAX",
+			args:     []string{"myzset", "5"},
+			expected: "*4\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\na\r\n$1\r\n1\r\n",
+			check:    []string{"EXISTS", "myzset"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "ZRANDMEMBER with a count larger than the set",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZRANDMEMBER",
+			args:     []string{"myzset", "3", "WITHSCORES"},
+			expected: "*2\r\n$1\r\na\r\n$1\r\n1\r\n",
+		},
+		{
+			name:     "ZRANDMEMBER with a negative count repeats members",
+			setup:    setZSet("1", "a"),
+			cmd:      "ZRANDMEMBER",
+			args:     []string{"myzset", "-2"},
+			expected: "*2\r\n$1\r\na\r\n$1\r\na\r\n",
+		},
+		{
+			name: "ZUNION with WEIGHTS and a plain set",
+			setup: func() {
+				setZSet("1", "a", "2", "b")()
+				executeCommand("SADD", []string{"myset", "a", "c"})
+			},
+			cmd:      "ZUNION",
+			args:     []string{"2", "myzset", "myset", "WEIGHTS", "2", "3", "WITHSCORES"},
+			expected: "*6\r\n$1\r\nc\r\n$1\r\n3\r\n$1\r\nb\r\n$1\r\n4\r\n$1\r\na\r\n$1\r\n5\r\n",
+		},
+		{
+			name: "ZINTER with AGGREGATE MAX",
+			setup: func() {
+				setZSet("1", "a", "5", "b")()
+				executeCommand("ZADD", []string{"other", "3", "a", "4", "b", "5", "c"})
+			},
+			cmd:      "ZINTER",
+			args:     []string{"2", "myzset", "other", "AGGREGATE", "MAX", "WITHSCORES"},
+			expected: "*4\r\n$1\r\na\r\n$1\r\n3\r\n$1\r\nb\r\n$1\r\n5\r\n",
+		},
+		{
+			name: "ZDIFF",
+			setup: func() {
+				setZSet("1", "a", "2", "b", "3", "c")()
+				executeCommand("ZADD", []string{"other", "0", "b"})
+			},
+			cmd:      "ZDIFF",
+			args:     []string{"2", "myzset", "other"},
+			expected: "*2\r\n$1\r\na\r\n$1\r\nc\r\n",
+		},
+		{
+			name: "ZINTERSTORE into one of its inputs",
+			setup: func() {
+				setZSet("1", "a", "2", "b")()
+				executeCommand("ZADD", []string{"other", "10", "b"})
+			},
+			cmd:      "ZINTERSTORE",
+			args:     []string{"myzset", "2", "myzset", "other"},
+			expected: ":1\r\n",
+			check:    []string{"ZRANGE", "myzset", "0", "-1", "WITHSCORES"},
+			checkRes: "*2\r\n$1\r\nb\r\n$2\r\n12\r\n",
+		},
+		{
+			name: "ZDIFFSTORE of an empty result deletes the destination",
+			setup: func() {
+				setZSet("1", "a")()
+				dict.Set("dst", "value", 0)
+			},
+			cmd:      "ZDIFFSTORE",
+			args:     []string{"dst", "2", "myzset", "myzset"},
+			expected: ":0\r\n",
+			check:    []string{"EXISTS", "dst"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name: "ZINTERCARD with LIMIT",
+			setup: func() {
+				setZSet("1", "a", "2", "b", "3", "c")()
+				executeCommand("ZADD", []string{"other", "1", "a", "2", "b", "3", "c"})
+			},
+			cmd:      "ZINTERCARD",
+			args:     []string{"2", "myzset", "other", "LIMIT", "2"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "ZUNION without input keys",
+			setup:    func() {},
+			cmd:      "ZUNION",
+			args:     []string{"0", "myzset"},
+			expected: "-ERR at least 1 input key is needed for 'zunion' command\r\n",
+		},
+		{
+			name:     "ZUNION with fewer keys than numkeys",
+			setup:    func() {},
+			cmd:      "ZUNION",
+			args:     []string{"3", "a", "b"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name:     "ZUNIONSTORE with WITHSCORES",
+			setup:    func() {},
+			cmd:      "ZUNIONSTORE",
+			args:     []string{"dst", "1", "myzset", "WITHSCORES"},
+			expected: constant.ErrSyntax,
+		},
+		{
+			name: "ZINTER against a string",
+			setup: func() {
+				dict.Set("str", "value", 0)
+			},
+			cmd:      "ZINTER",
+			args:     []string{"2", "myzset", "str"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name:     "COMMAND GETKEYS of ZUNIONSTORE",
+			setup:    func() {},
+			cmd:      "COMMAND",
+			args:     []string{"GETKEYS", "ZUNIONSTORE", "dst", "2", "k1", "k2", "WEIGHTS", "1", "2"},
+			expected: "*3\r\n$3\r\ndst\r\n$2\r\nk1\r\n$2\r\nk2\r\n",
+		},
+		{
+			name:     "OBJECT ENCODING of a sorted set",
+			setup:    setZSet("1", "a"),
+			cmd:      "OBJECT",
+			args:     []string{"ENCODING", "myzset"},
+			expected: "$8\r\nskiplist\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			assertResponse(t, result, tt.expected)
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+}
+
+func TestExecuteSetCommands(t *testing.T) {
+	setSets := func() {
+		executeCommand("SADD", []string{"s1", "a", "b", "c"})
+		executeCommand("SADD", []string{"s2", "b", "c", "d"})
+	}
+
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "SISMEMBER",
+			setup:    setSets,
+			cmd:      "SISMEMBER",
+			args:     []string{"s1", "a"},
+			expected: ":1\r\n",
+		},
+		{
+			name:     "SISMEMBER of a missing set",
+			setup:    func() {},
+			cmd:      "SISMEMBER",
+			args:     []string{"s1", "a"},
+			expected: ":0\r\n",
+		},
+		{
+			name:     "SREM deletes the empty set",
+			setup:    setSets,
+			cmd:      "SREM",
+			args:     []string{"s1", "a", "b", "c"},
+			expected: ":3\r\n",
+			check:    []string{"EXISTS", "s1"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "SUNIONSTORE",
+			setup:    setSets,
+			cmd:      "SUNIONSTORE",
+			args:     []string{"dst", "s1", "s2", "missing"},
+			expected: ":4\r\n",
+			check:    []string{"SMISMEMBER", "dst", "a", "d", "e"},
+			checkRes: "*3\r\n:1\r\n:1\r\n:0\r\n",
+		},
+		{
+			name:     "SDIFF",
+			setup:    setSets,
+			cmd:      "SDIFF",
+			args:     []string{"s1", "s2"},
+			expected: "*1\r\n$1\r\na\r\n",
+		},
+		{
+			name:     "SINTERSTORE into one of its inputs",
+			setup:    setSets,
+			cmd:      "SINTERSTORE",
+			args:     []string{"s1", "s1", "s2"},
+			expected: ":2\r\n",
+			check:    []string{"SMISMEMBER", "s1", "a", "b", "c"},
+			checkRes: "*3\r\n:0\r\n:1\r\n:1\r\n",
+		},
+		{
+			name: "SDIFFSTORE of an empty result deletes the destination",
+			setup: func() {
+				setSets()
+				dict.Set("dst", "value", 0)
+			},
+			cmd:      "SDIFFSTORE",
+			args:     []string{"dst", "s1", "s1"},
+			expected: ":0\r\n",
+			check:    []string{"EXISTS", "dst"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "SUNION against a string",
+			setup:    func() { dict.Set("str", "value", 0) },
+			cmd:      "SUNION",
+			args:     []string{"s1", "str"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name:     "SINTERCARD",
+			setup:    setSets,
+			cmd:      "SINTERCARD",
+			args:     []string{"2", "s1", "s2"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "SINTERCARD with LIMIT",
+			setup:    setSets,
+			cmd:      "SINTERCARD",
+			args:     []string{"2", "s1", "s2", "LIMIT", "1"},
+			expected: ":1\r\n",
+		},
+		{
+			name:     "SINTERCARD with a negative LIMIT",
+			setup:    setSets,
+			cmd:      "SINTERCARD",
+			args:     []string{"2", "s1", "s2", "LIMIT", "-1"},
+			expected: "-ERR LIMIT can't be negative\r\n",
+		},
+		{
+			name:     "SINTERCARD with more keys than arguments",
+			setup:    setSets,
+			cmd:      "SINTERCARD",
+			args:     []string{"3", "s1", "s2"},
+			expected: "-ERR Number of keys can't be greater than number of args\r\n",
+		},
+		{
+			name:     "SMOVE",
+			setup:    setSets,
+			cmd:      "SMOVE",
+			args:     []string{"s1", "s2", "a"},
+			expected: ":1\r\n",
+			check:    []string{"SCARD", "s2"},
+			checkRes: ":4\r\n",
+		},
+		{
+			name:     "SMOVE a missing member",
+			setup:    setSets,
+			cmd:      "SMOVE",
+			args:     []string{"s1", "s2", "d"},
+			expected: ":0\r\n",
+		},
+		{
+			name: "SMOVE the last member deletes the source",
+			setup: func() {
+				executeCommand("SADD", []string{"s1", "a"})
+			},
+			cmd:      "SMOVE",
+			args:     []string{"s1", "s2", "a"},
+			expected: ":1\r\n",
+			check:    []string{"EXISTS", "s1"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name: "SMOVE to a string",
+			setup: func() {
+				setSets()
+				dict.Set("str", "value", 0)
+			},
+			cmd:      "SMOVE",
+			args:     []string{"s1", "str", "a"},
+			expected: constant.ErrWrongType,
+		},
+		{
+			name: "SPOP the last member deletes the set",
+			setup: func() {
+				executeCommand("SADD", []string{"s1", "a"})
+			},
+			cmd:      "SPOP",
+			args:     []string{"s1"},
+			expected: "$1\r\na\r\n",
+			check:    []string{"EXISTS", "s1"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "SPOP with count",
+			setup:    setSets,
+			cmd:      "SPOP",
+			args:     []string{"s1", "2"},
+			expected: "*2\r\n",
+			check:    []string{"SCARD", "s1"},
+			checkRes: ":1\r\n",
+		},
+		{
+			name: "SPOP most of a set picks the members kept",
+			setup: func() {
+				executeCommand("SADD", []string{"s1", "a", "b", "c", "d", "e", "f", "g"})
+			},
+			cmd:      "SPOP",
+			args:     []string{"s1", "6"},
+			expected: "*6\r\n",
+			check:    []string{"SCARD", "s1"},
+			checkRes: ":1\r\n",
+		},
+		{
+			name:     "SPOP of a missing set",
+			setup:    func() {},
+			cmd:      "SPOP",
+			args:     []string{"s1"},
+			expected: constant.RespNil,
+		},
+		{
+			name:     "SPOP with a negative count",
+			setup:    setSets,
+			cmd:      "SPOP",
+			args:     []string{"s1", "-1"},
+			expected: constant.ErrNotPositive,
+		},
+		{
+			name:     "SRANDMEMBER with a count larger than the set",
+			setup:    setSets,
+			cmd:      "SRANDMEMBER",
+			args:     []string{"s1", "10"},
+			expected: "*3\r\n",
+			check:    []string{"SCARD", "s1"},
+			checkRes: ":3\r\n",
+		},
+		{
+			name: "SRANDMEMBER with a negative count repeats members",
+			setup: func() {
+				executeCommand("SADD", []string{"s1", "a"})
+			},
+			cmd:      "SRANDMEMBER",
+			args:     []string{"s1", "-3"},
+			expected: "*3\r\n$1\r\na\r\n$1\r\na\r\n$1\r\na\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			result := executeCommand(tt.cmd, tt.args)
+			// Members come in no particular order, so an array header alone only checks their count
+			if strings.HasPrefix(tt.expected, "*") && strings.Count(tt.expected, "\r\n") == 1 {
+				if !strings.HasPrefix(string(result), tt.expected) {
+					t.Errorf("Expected %s, got %q", tt.expected, result)
+				}
+			} else {
+				assertResponse(t, result, tt.expected)
+			}
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+}
+
+// Test that randomDistinct picks distinct elements with both of its strategies
+func TestRandomDistinct(t *testing.T) {
+	elements := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	random := func() string { return elements[rand.Intn(len(elements))] }
+	all := func() []string { return append([]string(nil), elements...) }
+	for _, count := range []int{1, 3, 4, 9, 10, 20} {
+		picked := randomDistinct(len(elements), count, random, all)
+		seen := make(map[string]bool)
+		for _, element := range picked {
+			seen[element] = true
+		}
+		if len(picked) != min(count, len(elements)) || len(seen) != len(picked) {
+			t.Errorf("Expected %d distinct elements, got %v", min(count, len(elements)), picked)
+		}
+	}
+}
+
+// scanAll runs a SCAN family command from cursor 0 until it returns 0 and collects the elements
+func scanAll(t *testing.T, cmd string, args []string) []string {
+	t.Helper()
+	var elements []string
+	cursor := "0"
+	for i := 0; ; i++ {
+		if i > 1000 {
+			t.Fatalf("%s did not complete", cmd)
+		}
+		var cmdArgs []string
+		if cmd == "SCAN" {
+			cmdArgs = append([]string{cursor}, args...)
+		} else {
+			cmdArgs = append([]string{args[0], cursor}, args[1:]...)
+		}
+		reply, err := resp.Decode(executeCommand(cmd, cmdArgs))
+		if err != nil {
+			t.Fatalf("Failed to decode the %s reply: %v", cmd, err)
+		}
+		page := reply.([]any)
+		cursor = page[0].(string)
+		for _, element := range page[1].([]any) {
+			elements = append(elements, element.(string))
+		}
+		if cursor == "0" {
+			return elements
+		}
+	}
+}
+
+func TestExecuteScanCommands(t *testing.T) {
+	resetGlobalDict()
+	var expectedKeys, expectedMembers []string
+	for i := 0; i < 300; i++ {
+		key := "key:" + strconv.Itoa(i)
+		executeCommand("SET", []string{key, "v"})
+		expectedKeys = append(expectedKeys, key)
+
+		member := "m" + strconv.Itoa(i)
+		executeCommand("SADD", []string{"myset", member})
+		executeCommand("HSET", []string{"myhash", member, "v"})
+		executeCommand("ZADD", []string{"myzset", strconv.Itoa(i), member})
+		expectedMembers = append(expectedMembers, member)
+	}
+	executeCommand("SADD", []string{"ints", "1", "2", "3"})
+	expectedKeys = append(expectedKeys, "myset", "myhash", "myzset", "ints")
+
+	assertSameElements := func(t *testing.T, got, expected []string) {
+		t.Helper()
+		got = append([]string(nil), got...)
+		sort.Strings(got)
+		expected = append([]string(nil), expected...)
+		sort.Strings(expected)
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %d elements, got %d: %v", len(expected), len(got), got)
+		}
+	}
+
+	t.Run("SCAN returns every key once", func(t *testing.T) {
+		assertSameElements(t, scanAll(t, "SCAN", []string{"COUNT", "7"}), expectedKeys)
+	})
+
+	t.Run("SCAN with MATCH and TYPE", func(t *testing.T) {
+		assertSameElements(t, scanAll(t, "SCAN", []string{"MATCH", "my*", "TYPE", "set"}), []string{"myset"})
+	})
+
+	t.Run("SSCAN", func(t *testing.T) {
+		assertSameElements(t, scanAll(t, "SSCAN", []string{"myset", "COUNT", "20"}), expectedMembers)
+	})
+
+	t.Run("SSCAN of an intset in one call", func(t *testing.T) {
+		assertResponse(t, executeCommand("SSCAN", []string{"ints", "0"}), "*2\r\n$1\r\n0\r\n*3\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n")
+	})
+
+	t.Run("HSCAN NOVALUES", func(t *testing.T) {
+		assertSameElements(t, scanAll(t, "HSCAN", []string{"myhash", "NOVALUES"}), expectedMembers)
+	})
+
+	t.Run("ZSCAN replies scores", func(t *testing.T) {
+		elements := scanAll(t, "ZSCAN", []string{"myzset", "MATCH", "m1?"})
+		assertSameElements(t, elements, []string{"m10", "10", "m11", "11", "m12", "12", "m13", "13", "m14", "14", "m15", "15", "m16", "16", "m17", "17", "m18", "18", "m19", "19"})
+	})
+
+	t.Run("SCAN skips expired keys", func(t *testing.T) {
+		executeCommand("PEXPIRE", []string{"key:0", "1"})
+		time.Sleep(5 * time.Millisecond)
+		for _, key := range scanAll(t, "SCAN", []string{"MATCH", "key:0"}) {
+			t.Errorf("Expected no key, got %q", key)
+		}
+	})
+
+	t.Run("SCAN with an unknown TYPE", func(t *testing.T) {
+		assertResponse(t, executeCommand("SCAN", []string{"0", "TYPE", "foo"}), "-ERR unknown type name 'foo'\r\n")
+	})
+
+	t.Run("SSCAN against a string", func(t *testing.T) {
+		assertResponse(t, executeCommand("SSCAN", []string{"key:1", "0"}), constant.ErrWrongType)
+	})
+
+	t.Run("ZSCAN with an invalid cursor", func(t *testing.T) {
+		assertResponse(t, executeCommand("ZSCAN", []string{"myzset", "-1"}), "-ERR invalid cursor\r\n")
+	})
+}
+
+func TestExecuteKeysCommand(t *testing.T) {
+	resetGlobalDict()
+	for _, key := range []string{"hello", "hallo", "hxllo", "heeeello", "h*llo", "other"} {
+		executeCommand("SET", []string{key, "v"})
+	}
+	executeCommand("SET", []string{"hillo", "v", "PX", "1"})
+	time.Sleep(5 * time.Millisecond)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"*", []string{"h*llo", "hallo", "heeeello", "hello", "hxllo", "other"}},
+		{"h?llo", []string{"h*llo", "hallo", "hello", "hxllo"}},
+		{"h*llo", []string{"h*llo", "hallo", "heeeello", "hello", "hxllo"}},
+		{"h[ae]llo", []string{"hallo", "hello"}},
+		{"h[^e]llo", []string{"h*llo", "hallo", "hxllo"}},
+		{"h[a-e]llo", []string{"hallo", "hello"}},
+		{"h\\*llo", []string{"h*llo"}},
+		{"nomatch*", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			reply, err := resp.Decode(executeCommand("KEYS", []string{tt.pattern}))
+			if err != nil {
+				t.Fatalf("Failed to decode the KEYS reply: %v", err)
+			}
+			keys := []string{}
+			for _, key := range reply.([]any) {
+				keys = append(keys, key.(string))
+			}
+			sort.Strings(keys)
+			if strings.Join(keys, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %q, got %q", tt.expected, keys)
+			}
+		})
+	}
+}
+
+func TestExecuteDebugHTStats(t *testing.T) {
+	resetGlobalDict()
+	assertResponse(t, executeCommand("DEBUG", []string{"HTSTATS", "0"}), "$113\r\n[Dictionary HT]\nNo stats available for empty dictionaries\n[Expires HT]\nNo stats available for empty dictionaries\n\r\n")
+
+	executeCommand("SET", []string{"k1", "v"})
+	executeCommand("SET", []string{"k2", "v", "EX", "100"})
+	expected := "[Dictionary HT]\nHash table 0 stats (main hash table):\n table size: 4\n number of elements: 2\n" +
+		"[Expires HT]\nHash table 0 stats (main hash table):\n table size: 4\n number of elements: 1\n"
+	assertResponse(t, executeCommand("DEBUG", []string{"HTSTATS", "0"}), fmt.Sprintf("$%d\r\n%s\r\n", len(expected), expected))
+
+	reply := string(executeCommand("DEBUG", []string{"HTSTATS", "0", "FULL"}))
+	if !strings.Contains(reply, " Chain length distribution:\n") {
+		t.Errorf("Expected the chain lengths with FULL, got %q", reply)
+	}
+
+	assertResponse(t, executeCommand("DEBUG", []string{"HTSTATS", "16"}), "-ERR Out of range database\r\n")
+	assertResponse(t, executeCommand("DEBUG", []string{"HTSTATS", "x"}), constant.ErrNotInteger)
+	assertResponse(t, executeCommand("DEBUG", []string{"HTSTATS"}), "-ERR wrong number of arguments for 'DEBUG|HTSTATS' command\r\n")
+}
+
+// Test that the databases hold their own keys and that keys move and copy between them
+func TestExecuteDatabaseCommands(t *testing.T) {
+	// run executes a sequence of commands, each given as its name, arguments and expected reply
+	run := func(t *testing.T, steps [][]string) {
+		t.Helper()
+		resetGlobalDict()
+		for _, step := range steps {
+			assertResponse(t, executeCommand(step[0], step[1:len(step)-1]), step[len(step)-1])
+		}
+	}
+
+	t.Run("SELECT isolates the keys", func(t *testing.T) {
+		run(t, [][]string{
+			{"SELECT", "1", constant.RespOk},
+			{"SET", "k", "v1", constant.RespOk},
+			{"DBSIZE", ":1\r\n"},
+			{"SELECT", "0", constant.RespOk},
+			{"GET", "k", constant.RespNil},
+			{"DBSIZE", ":0\r\n"},
+			{"SELECT", "16", constant.ErrDBOutOfRange},
+			{"SELECT", "x", constant.ErrNotInteger},
+		})
+	})
+
+	t.Run("MOVE keeps the expiry", func(t *testing.T) {
+		run(t, [][]string{
+			{"SET", "k", "v", "EX", "100", constant.RespOk},
+			{"SET", "taken", "v", constant.RespOk},
+			{"MOVE", "k", "1", ":1\r\n"},
+			{"MOVE", "k", "1", ":0\r\n"},
+			{"MOVE", "taken", "0", constant.ErrSameObject},
+			{"SELECT", "1", constant.RespOk},
+			{"TTL", "k", ":100\r\n"},
+			{"SET", "taken", "other", constant.RespOk},
+			{"SELECT", "0", constant.RespOk},
+			{"MOVE", "taken", "1", ":0\r\n"},
+			{"GET", "taken", "$1\r\nv\r\n"},
+		})
+	})
+
+	t.Run("SWAPDB", func(t *testing.T) {
+		run(t, [][]string{
+			{"SET", "k", "zero", constant.RespOk},
+			{"SWAPDB", "0", "1", constant.RespOk},
+			{"GET", "k", constant.RespNil},
+			{"SELECT", "1", constant.RespOk},
+			{"GET", "k", "$4\r\nzero\r\n"},
+			{"SWAPDB", "x", "1", "-ERR invalid first DB index\r\n"},
+			{"SWAPDB", "0", "x", "-ERR invalid second DB index\r\n"},
+			{"SWAPDB", "0", "16", constant.ErrDBOutOfRange},
+		})
+	})
+
+	t.Run("COPY to another database", func(t *testing.T) {
+		run(t, [][]string{
+			{"RPUSH", "list", "a", "b", ":2\r\n"},
+			{"COPY", "list", "list", constant.ErrSameObject},
+			{"COPY", "list", "list", "DB", "2", ":1\r\n"},
+			{"COPY", "list", "list", "DB", "2", ":0\r\n"},
+			{"RPUSH", "list", "c", ":3\r\n"},
+			{"COPY", "list", "list", "DB", "2", "REPLACE", ":1\r\n"},
+			{"COPY", "list", "copy", ":1\r\n"},
+			{"RPUSH", "copy", "d", ":4\r\n"},
+			{"LLEN", "list", ":3\r\n"},
+			{"COPY", "missing", "copy", "REPLACE", ":0\r\n"},
+			{"COPY", "list", "copy", "DB", "16", constant.ErrDBOutOfRange},
+			{"COPY", "list", "copy", "FOO", constant.ErrSyntax},
+			{"SELECT", "2", constant.RespOk},
+			{"LLEN", "list", ":3\r\n"},
+		})
+	})
+
+	t.Run("COPY keeps the expiry and encoding", func(t *testing.T) {
+		run(t, [][]string{
+			{"SADD", "s", "1", "2", ":2\r\n"},
+			{"PEXPIRE", "s", "100000", ":1\r\n"},
+			{"COPY", "s", "s2", ":1\r\n"},
+			{"OBJECT", "ENCODING", "s2", "$6\r\nintset\r\n"},
+			{"SADD", "s2", "3", ":1\r\n"},
+			{"SCARD", "s", ":2\r\n"},
+			{"TTL", "s2", ":100\r\n"},
+		})
+	})
+
+	t.Run("FLUSHDB and FLUSHALL", func(t *testing.T) {
+		run(t, [][]string{
+			{"SET", "k", "v", constant.RespOk},
+			{"SELECT", "1", constant.RespOk},
+			{"SET", "k", "v", constant.RespOk},
+			{"FLUSHDB", "ASYNC", constant.RespOk},
+			{"DBSIZE", ":0\r\n"},
+			{"SELECT", "0", constant.RespOk},
+			{"DBSIZE", ":1\r\n"},
+			{"FLUSHALL", "FOO", constant.ErrSyntax},
+			{"FLUSHALL", constant.RespOk},
+			{"DBSIZE", ":0\r\n"},
+		})
+	})
+
+	t.Run("FLUSHALL ASYNC frees the keys in the background", func(t *testing.T) {
+		members := []string{"big"}
+		for i := 0; i < 1000; i++ {
+			members = append(members, "member"+strconv.Itoa(i))
+		}
+		executeCommand("SADD", members)
+		big := dict.Peek("big")
+		databases[1].Snapshot() // Read by a snapshot, the keys of database 1 are kept
+		executeCommand("SELECT", []string{"1"})
+		executeCommand("SADD", members)
+		shared := dict.Peek("big")
+		executeCommand("SELECT", []string{"0"})
+		previous := databases[1]
+
+		assertResponse(t, executeCommand("FLUSHALL", []string{"ASYNC"}), constant.RespOk)
+		assertResponse(t, executeCommand("DBSIZE", nil), ":0\r\n")
+		lazyfreeJobs.Wait()
+		previous.ReleaseSnapshot()
+		if big.Value != nil || shared.Value.(*data_structure.Set).Len() != 1000 {
+			t.Errorf("Expected the keys freed except the ones read by the snapshot")
+		}
+	})
+
+	t.Run("RANDOMKEY", func(t *testing.T) {
+		run(t, [][]string{
+			{"RANDOMKEY", constant.RespNil},
+			{"SET", "k", "v", constant.RespOk},
+			{"RANDOMKEY", "$1\r\nk\r\n"},
+			{"PEXPIRE", "k", "1", ":1\r\n"},
+		})
+		time.Sleep(5 * time.Millisecond)
+		assertResponse(t, executeCommand("RANDOMKEY", nil), constant.RespNil)
+	})
+	resetGlobalDict()
+}
+
+func TestExecuteKeyCommands(t *testing.T) {
+	setKeys := func() {
+		executeCommand("SET", []string{"k1", "v1", "EX", "100"})
+		executeCommand("SET", []string{"k2", "v2"})
+	}
+
+	tests := []struct {
+		name     string
+		setup    func()
+		cmd      string
+		args     []string
+		expected string
+		check    []string // Command run after the tested one
+		checkRes string
+	}{
+		{
+			name:     "RENAME keeps the expiry",
+			setup:    setKeys,
+			cmd:      "RENAME",
+			args:     []string{"k1", "k3"},
+			expected: constant.RespOk,
+			check:    []string{"TTL", "k3"},
+			checkRes: ":100\r\n",
+		},
+		{
+			name:     "RENAME overwrites the destination",
+			setup:    setKeys,
+			cmd:      "RENAME",
+			args:     []string{"k1", "k2"},
+			expected: constant.RespOk,
+			check:    []string{"GET", "k2"},
+			checkRes: "$2\r\nv1\r\n",
+		},
+		{
+			name:     "RENAME removes the source",
+			setup:    setKeys,
+			cmd:      "RENAME",
+			args:     []string{"k1", "k2"},
+			expected: constant.RespOk,
+			check:    []string{"EXISTS", "k1"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "RENAME to the same key",
+			setup:    setKeys,
+			cmd:      "RENAME",
+			args:     []string{"k1", "k1"},
+			expected: constant.RespOk,
+			check:    []string{"GET", "k1"},
+			checkRes: "$2\r\nv1\r\n",
+		},
+		{
+			name:     "RENAME a missing key",
+			setup:    func() {},
+			cmd:      "RENAME",
+			args:     []string{"k1", "k2"},
+			expected: constant.ErrNoSuchKey,
+		},
+		{
+			name:     "RENAMENX to an existing key",
+			setup:    setKeys,
+			cmd:      "RENAMENX",
+			args:     []string{"k1", "k2"},
+			expected: ":0\r\n",
+			check:    []string{"GET", "k2"},
+			checkRes: "$2\r\nv2\r\n",
+		},
+		{
+			name:     "RENAMENX",
+			setup:    setKeys,
+			cmd:      "RENAMENX",
+			args:     []string{"k1", "k3"},
+			expected: ":1\r\n",
+			check:    []string{"TTL", "k3"},
+			checkRes: ":100\r\n",
+		},
+		{
+			name:     "UNLINK",
+			setup:    setKeys,
+			cmd:      "UNLINK",
+			args:     []string{"k1", "k2", "missing"},
+			expected: ":2\r\n",
+			check:    []string{"DBSIZE"},
+			checkRes: ":0\r\n",
+		},
+		{
+			name:     "TOUCH",
+			setup:    setKeys,
+			cmd:      "TOUCH",
+			args:     []string{"k1", "k2", "missing"},
+			expected: ":2\r\n",
+		},
+		{
+			name:     "OBJECT IDLETIME of a new key",
+			setup:    setKeys,
+			cmd:      "OBJECT",
+			args:     []string{"IDLETIME", "k1"},
+			expected: ":0\r\n",
+		},
+		{
+			name:     "OBJECT FREQ of a new key",
+			setup:    setKeys,
+			cmd:      "OBJECT",
+			args:     []string{"FREQ", "k1"},
+			expected: ":5\r\n",
+		},
+		{
+			name:     "OBJECT REFCOUNT",
+			setup:    setKeys,
+			cmd:      "OBJECT",
+			args:     []string{"REFCOUNT", "k1"},
+			expected: ":1\r\n",
+		},
+		{
+			name:     "OBJECT of a missing key",
+			setup:    func() {},
+			cmd:      "OBJECT",
+			args:     []string{"IDLETIME", "missing"},
+			expected: constant.RespNil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGlobalDict()
+			tt.setup()
+			assertResponse(t, executeCommand(tt.cmd, tt.args), tt.expected)
+			if tt.check != nil {
+				assertResponse(t, executeCommand(tt.check[0], tt.check[1:]), tt.checkRes)
+			}
+		})
+	}
+
+	t.Run("OBJECT FREQ grows with the accesses", func(t *testing.T) {
+		resetGlobalDict()
+		setKeys()
+		for i := 0; i < 1000; i++ {
+			executeCommand("GET", []string{"k1"})
+		}
+		freq, err := resp.Decode(executeCommand("OBJECT", []string{"FREQ", "k1"}))
+		if err != nil || freq.(int64) <= 5 {
+			t.Errorf("Expected a frequency above 5, got %v", freq)
+		}
+		assertResponse(t, executeCommand("OBJECT", []string{"FREQ", "k2"}), ":5\r\n")
+	})
+
+	t.Run("OBJECT HELP", func(t *testing.T) {
+		result := string(executeCommand("OBJECT", []string{"HELP"}))
+		if !strings.HasPrefix(result, "*15\r\n+OBJECT <subcommand>") {
+			t.Errorf("Unexpected help %q", result)
+		}
+	})
+
+	t.Run("UNLINK frees a large collection in the background", func(t *testing.T) {
+		resetGlobalDict()
+		members := []string{"big"}
+		for i := 0; i < 1000; i++ {
+			members = append(members, "member"+strconv.Itoa(i))
+		}
+		executeCommand("SADD", append([]string{"shared"}, members[1:]...))
+		shared := dict.Peek("shared")
+
+		// A value read by a snapshot is left as it is
+		dict.Snapshot()
+		assertResponse(t, executeCommand("UNLINK", []string{"shared"}), ":1\r\n")
+		lazyfreeJobs.Wait()
+		dict.ReleaseSnapshot()
+		if shared.Value.(*data_structure.Set).Len() != 1000 {
+			t.Errorf("Expected the value read by the snapshot to be kept")
+		}
+
+		executeCommand("SADD", members)
+		big := dict.Peek("big")
+		assertResponse(t, executeCommand("UNLINK", []string{"big"}), ":1\r\n")
+		assertResponse(t, executeCommand("EXISTS", []string{"big"}), ":0\r\n")
+		lazyfreeJobs.Wait()
+		if big.Value != nil {
+			t.Errorf("Expected the set to be freed")
+		}
+	})
+}
+
+// setMaxMemory limits the memory for the duration of the test, starting with an empty pool
+func setMaxMemory(t *testing.T, limit int64, policy string) {
+	t.Helper()
+	previousLimit, previousPolicy := config.MaxMemory, config.MaxMemoryPolicy
+	t.Cleanup(func() {
+		config.MaxMemory, config.MaxMemoryPolicy = previousLimit, previousPolicy
+		evictionPool = evictionPool[:0]
+	})
+	config.MaxMemory, config.MaxMemoryPolicy = limit, policy
+	evictionPool = evictionPool[:0]
+}
+
+func TestMemoryAccounting(t *testing.T) {
+	// datasetMemory is the memory of the keys, without the hash tables that do not shrink at once
+	datasetMemory := func() int64 {
+		var used int64
+		for _, db := range databases {
+			main, expires := db.MemoryOverhead()
+			used += db.UsedMemory() - main - expires
+		}
+		return used
+	}
+	resetGlobalDict()
+	empty := datasetMemory()
+
+	executeCommand("SET", []string{"k", strings.Repeat("x", 1000)})
+	afterSet := usedMemory()
+	if afterSet < empty+1000 {
+		t.Errorf("Expected at least 1000 more bytes after SET, got %d", afterSet-empty)
+	}
+
+	// Collections modified in place are estimated again after the command
+	executeCommand("RPUSH", []string{"list", "a"})
+	afterPush := usedMemory()
+	executeCommand("RPUSH", []string{"list", strings.Repeat("y", 1000)})
+	if usedMemory() < afterPush+1000 {
+		t.Errorf("Expected at least 1000 more bytes after RPUSH, got %d", usedMemory()-afterPush)
+	}
+
+	executeCommand("SET", []string{"k2", "v", "EX", "100"})
+	executeCommand("MOVE", []string{"k2", "1"})
+	executeCommand("DEL", []string{"k", "list"})
+	executeCommand("SELECT", []string{"1"})
+	executeCommand("DEL", []string{"k2"})
+	executeCommand("SELECT", []string{"0"})
+	if used := datasetMemory(); used != empty {
+		t.Errorf("Expected %d bytes once the keys are deleted, got %d", empty, used)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	// fill stores count keys of 100 bytes in the selected database, with an expiry when ttl is set
+	fill := func(prefix string, count int, ttl bool) {
+		for i := 0; i < count; i++ {
+			args := []string{prefix + strconv.Itoa(i), strings.Repeat("v", 100)}
+			if ttl {
+				args = append(args, "EX", strconv.Itoa(100+i))
+			}
+			executeCommand("SET", args)
+		}
+	}
+	countKeys := func(prefix string, count int) int {
+		n := 0
+		for i := 0; i < count; i++ {
+			if dict.Get(prefix+strconv.Itoa(i)) != nil {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("noeviction refuses the commands using memory", func(t *testing.T) {
+		resetGlobalDict()
+		fill("k", 10, false)
+		setMaxMemory(t, usedMemory()-1, policyNoEviction)
+
+		assertResponse(t, executeCommand("SET", []string{"new", "v"}), constant.ErrOOM)
+		assertResponse(t, executeCommand("GET", []string{"k0"}), "$100\r\n"+strings.Repeat("v", 100)+"\r\n")
+		assertResponse(t, executeCommand("DEL", []string{"k0"}), ":1\r\n")
+		assertResponse(t, executeCommand("SET", []string{"new", "v"}), constant.RespOk)
+	})
+
+	for _, policy := range []string{policyAllKeysLRU, policyAllKeysLFU, policyAllKeysRandom} {
+		t.Run(policy+" evicts down to the limit", func(t *testing.T) {
+			resetGlobalDict()
+			fill("k", 50, false)
+			selectDB(3)
+			fill("k", 50, false)
+			setMaxMemory(t, usedMemory()/2, policy)
+
+			assertResponse(t, executeCommand("SET", []string{"new", "v"}), constant.RespOk)
+			if usedMemory() > config.MaxMemory+1000 {
+				t.Errorf("Expected at most %d bytes, got %d", config.MaxMemory, usedMemory())
+			}
+			if n := countKeys("k", 50); n == 0 || n == 50 {
+				t.Errorf("Expected some keys evicted from the selected database, %d left", n)
+			}
+		})
+	}
+
+	t.Run("allkeys-lfu keeps the frequently used keys", func(t *testing.T) {
+		resetGlobalDict()
+		fill("hot", 4, false)
+		fill("cold", 40, false)
+		for i := 0; i < 100; i++ {
+			for j := 0; j < 4; j++ {
+				executeCommand("GET", []string{"hot" + strconv.Itoa(j)})
+			}
+		}
+		setMaxMemory(t, usedMemory()/2, policyAllKeysLFU)
+
+		executeCommand("PING", nil)
+		if n := countKeys("hot", 4); n != 4 {
+			t.Errorf("Expected the 4 hot keys to be kept, got %d", n)
+		}
+	})
+
+	for _, policy := range []string{policyVolatileLRU, policyVolatileLFU, policyVolatileRandom, policyVolatileTTL} {
+		t.Run(policy+" only evicts keys with an expiry", func(t *testing.T) {
+			resetGlobalDict()
+			fill("persistent", 20, false)
+			fill("volatile", 20, true)
+			setMaxMemory(t, usedMemory()-1000, policy)
+
+			assertResponse(t, executeCommand("SET", []string{"new", "v"}), constant.RespOk)
+			if n := countKeys("persistent", 20); n != 20 {
+				t.Errorf("Expected the 20 keys without expiry to be kept, got %d", n)
+			}
+			if n := countKeys("volatile", 20); n == 20 {
+				t.Errorf("Expected keys with an expiry to be evicted")
+			}
+
+			// Once no key has an expiry, nothing can be evicted
+			config.MaxMemory = 1
+			assertResponse(t, executeCommand("SET", []string{"other", "v"}), constant.ErrOOM)
+			if n := countKeys("persistent", 20); n != 20 {
+				t.Errorf("Expected the 20 keys without expiry to be kept, got %d", n)
+			}
+		})
+	}
+
+	t.Run("volatile-ttl evicts the keys expiring first", func(t *testing.T) {
+		resetGlobalDict()
+		fill("persistent", 10, false)
+		fill("volatile", 2, true)
+		setMaxMemory(t, usedMemory()-1, policyVolatileTTL)
+		previousSamples := config.MaxMemorySamples
+		t.Cleanup(func() { config.MaxMemorySamples = previousSamples })
+		config.MaxMemorySamples = 64 // Both keys are sampled
+
+		executeCommand("PING", nil)
+		if dict.Get("volatile0") != nil || dict.Get("volatile1") == nil {
+			t.Errorf("Expected the key with the shortest TTL to be evicted first")
+		}
+	})
+}
+
+func TestExecuteConfigCommands(t *testing.T) {
+	setMaxMemory(t, 0, policyNoEviction)
+	previousSamples := config.MaxMemorySamples
+	t.Cleanup(func() { config.MaxMemorySamples = previousSamples })
+	useTempDir(t)
+	file := filepath.Join(config.Dir, "file")
+	os.WriteFile(file, nil, 0o644)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"SET with a unit", []string{"SET", "maxmemory", "2mb"}, constant.RespOk},
+		{"GET a parameter", []string{"GET", "maxmemory"}, "*2\r\n$9\r\nmaxmemory\r\n$7\r\n2097152\r\n"},
+		{"SET several parameters", []string{"SET", "maxmemory", "1k", "MAXMEMORY-POLICY", "AllKeys-LRU"}, constant.RespOk},
+		{"GET a pattern", []string{"GET", "maxmemory-p*", "maxmemory"}, "*4\r\n$9\r\nmaxmemory\r\n$4\r\n1000\r\n$16\r\nmaxmemory-policy\r\n$11\r\nallkeys-lru\r\n"},
+		{"GET no match", []string{"GET", "nothing"}, "*0\r\n"},
+		{
+			"SET an invalid memory",
+			[]string{"SET", "maxmemory", "1xb"},
+			"-ERR CONFIG SET failed (possibly related to argument 'maxmemory') - argument must be a memory value\r\n",
+		},
+		{
+			"SET an invalid policy",
+			[]string{"SET", "maxmemory-policy", "lru"},
+			"-ERR CONFIG SET failed (possibly related to argument 'maxmemory-policy') - argument(s) must be one of the following: volatile-lru, volatile-lfu, volatile-random, volatile-ttl, allkeys-lru, allkeys-lfu, allkeys-random, noeviction\r\n",
+		},
+		{
+			"SET out of range samples restores the other parameters",
+			[]string{"SET", "maxmemory", "5gb", "maxmemory-samples", "65"},
+			"-ERR CONFIG SET failed (possibly related to argument 'maxmemory-samples') - argument must be between 1 and 64 inclusive\r\n",
+		},
+		{"GET after a failed SET", []string{"GET", "maxmemory"}, "*2\r\n$9\r\nmaxmemory\r\n$4\r\n1000\r\n"},
+		{
+			"SET twice the same parameter",
+			[]string{"SET", "maxmemory", "1", "maxmemory", "2"},
+			"-ERR CONFIG SET failed (possibly related to argument 'maxmemory') - duplicate parameter\r\n",
+		},
+		{"SET an unknown parameter", []string{"SET", "foo", "1"}, "-ERR Unknown option or number of arguments for CONFIG SET - 'foo'\r\n"},
+		{"SET without a value", []string{"SET", "maxmemory", "1", "maxmemory-samples"}, "-ERR wrong number of arguments for 'CONFIG|SET' command\r\n"},
+		{"SET back to no limit", []string{"SET", "maxmemory", "0"}, constant.RespOk},
+		{"GET the save rules", []string{"GET", "save"}, "*2\r\n$4\r\nsave\r\n$23\r\n3600 1 300 100 60 10000\r\n"},
+		{"SET the save rules", []string{"SET", "save", " 900 1  60 0 "}, constant.RespOk},
+		{"GET the new save rules", []string{"GET", "save"}, "*2\r\n$4\r\nsave\r\n$10\r\n900 1 60 0\r\n"},
+		{"SET no save rule", []string{"SET", "save", ""}, constant.RespOk},
+		{"GET no save rule", []string{"GET", "save"}, "*2\r\n$4\r\nsave\r\n$0\r\n\r\n"},
+		{
+			"SET invalid save rules",
+			[]string{"SET", "save", "900 1 60"},
+			"-ERR CONFIG SET failed (possibly related to argument 'save') - Invalid save parameters\r\n",
+		},
+		{"SET the file name", []string{"SET", "dbfilename", "other.rdb"}, constant.RespOk},
+		{
+			"SET a path as file name",
+			[]string{"SET", "dbfilename", "dir/other.rdb"},
+			"-ERR CONFIG SET failed (possibly related to argument 'dbfilename') - dbfilename can't be a path, just a filename\r\n",
+		},
+		{
+			"SET a file as directory",
+			[]string{"SET", "dir", file},
+			"-ERR CONFIG SET failed (possibly related to argument 'dir') - " + file + " is not a directory\r\n",
+		},
+		{"SET the fsync policy", []string{"SET", "appendfsync", "ALWAYS"}, constant.RespOk},
+		{"GET the fsync policy", []string{"GET", "appendfsync"}, "*2\r\n$11\r\nappendfsync\r\n$6\r\nalways\r\n"},
+		{
+			"SET an invalid fsync policy",
+			[]string{"SET", "appendfsync", "never"},
+			"-ERR CONFIG SET failed (possibly related to argument 'appendfsync') - argument(s) must be one of the following: always, everysec, no\r\n",
+		},
+		{
+			"SET an invalid boolean",
+			[]string{"SET", "aof-load-truncated", "true"},
+			"-ERR CONFIG SET failed (possibly related to argument 'aof-load-truncated') - argument must be 'yes' or 'no'\r\n",
+		},
+		{"SET the rewrite size with a unit", []string{"SET", "auto-aof-rewrite-min-size", "1mb"}, constant.RespOk},
+		{"GET the rewrite parameters", []string{"GET", "auto-aof-rewrite-*"}, "*4\r\n$27\r\nauto-aof-rewrite-percentage\r\n$3\r\n100\r\n$25\r\nauto-aof-rewrite-min-size\r\n$7\r\n1048576\r\n"},
+		{
+			"SET a negative rewrite percentage",
+			[]string{"SET", "auto-aof-rewrite-percentage", "-1"},
+			"-ERR CONFIG SET failed (possibly related to argument 'auto-aof-rewrite-percentage') - argument must be between 0 and 2147483647 inclusive\r\n",
+		},
+		{
+			"SET an immutable parameter",
+			[]string{"SET", "appendfilename", "other.aof"},
+			"-ERR CONFIG SET failed (possibly related to argument 'appendfilename') - can't set immutable config\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResponse(t, executeCommand("CONFIG", tt.args), tt.expected)
+		})
+	}
+}
+
+func TestExecuteMemoryCommands(t *testing.T) {
+	resetGlobalDict()
+	executeCommand("SET", []string{"short", "v"})
+	executeCommand("SET", []string{"long", strings.Repeat("v", 1000)})
+
+	usage := func(args ...string) int64 {
+		n, err := strconv.ParseInt(strings.Trim(string(executeCommand("MEMORY", append([]string{"USAGE"}, args...))), ":\r\n"), 10, 64)
+		if err != nil {
+			t.Fatalf("Expected an integer reply: %v", err)
+		}
+		return n
+	}
+	if short, long := usage("short"), usage("long"); long-short != 998 {
+		t.Errorf("Expected the longer key and value to use 998 more bytes, got %d and %d", short, long)
+	}
+	if usage("long", "SAMPLES", "0") != usage("long") {
+		t.Errorf("Expected the size of a string not to depend on the samples")
+	}
+
+	assertResponse(t, executeCommand("MEMORY", []string{"USAGE", "missing"}), constant.RespNil)
+	assertResponse(t, executeCommand("MEMORY", []string{"USAGE", "long", "SAMPLES"}), constant.ErrSyntax)
+	assertResponse(t, executeCommand("MEMORY", []string{"USAGE", "long", "SAMPLES", "-1"}), constant.ErrSyntax)
+	assertResponse(t, executeCommand("MEMORY", []string{"USAGE", "long", "SAMPLES", "x"}), constant.ErrNotInteger)
+
+	stats := string(executeCommand("MEMORY", []string{"STATS"}))
+	total := fmt.Sprintf("$15\r\ntotal.allocated\r\n:%d\r\n", usedMemory())
+	if !strings.Contains(stats, total) || !strings.Contains(stats, "$4\r\ndb.0\r\n") || !strings.Contains(stats, "$10\r\nkeys.count\r\n:2\r\n") {
+		t.Errorf("Unexpected MEMORY STATS reply %q", stats)
+	}
+}
+
+// useTempDir saves the snapshots of the test in a temporary directory
+func useTempDir(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir() // Removed after the cleanup below, which waits for the background save
+	previousDir, previousFilename, previousParams := config.Dir, config.DBFilename, config.SaveParams
+	previousAppendOnly, previousFsync, previousTruncated := config.AppendOnly, config.AppendFsync, config.AOFLoadTruncated
+	previousPreamble, previousPercentage, previousMinSize := config.AOFUseRDBPreamble, config.AutoAOFRewritePercentage, config.AutoAOFRewriteMinSize
+	t.Cleanup(func() {
+		config.Dir, config.DBFilename, config.SaveParams = previousDir, previousFilename, previousParams
+		config.AppendOnly, config.AppendFsync, config.AOFLoadTruncated = previousAppendOnly, previousFsync, previousTruncated
+		config.AOFUseRDBPreamble, config.AutoAOFRewritePercentage, config.AutoAOFRewriteMinSize = previousPreamble, previousPercentage, previousMinSize
+		if aofFile != nil {
+			stopAppendOnly()
+		}
+		waitBackgroundJobs()
+		bgsaveScheduled, aofRewriteScheduled = false, false
+	})
+	config.Dir = dir
+}
+
+func TestExecuteSaveAndLoad(t *testing.T) {
+	useTempDir(t)
+	resetGlobalDict()
+	executeCommand("SET", []string{"string", "value"})
+	executeCommand("SET", []string{"expiring", "value", "EX", "100"})
+	executeCommand("SET", []string{"expired", "value", "PX", "1"})
+	executeCommand("RPUSH", []string{"list", "a", "b", "c"})
+	executeCommand("HSET", []string{"hash", "f1", "v1", "f2", "v2"})
+	executeCommand("HPEXPIRE", []string{"hash", "100000", "FIELDS", "1", "f2"})
+	executeCommand("SADD", []string{"set", "1", "2", "x"})
+	executeCommand("ZADD", []string{"zset", "1", "a", "2.5", "b"})
+	executeCommand("SELECT", []string{"3"})
+	executeCommand("SET", []string{"other", "db"})
+	executeCommand("SELECT", []string{"0"})
+	time.Sleep(5 * time.Millisecond)
+
+	before := time.Now().Unix()
+	assertResponse(t, executeCommand("SAVE", nil), constant.RespOk)
+	if dirty != 0 || lastSave < before {
+		t.Errorf("Expected no change since the save at %d, got %d changes and %d", before, dirty, lastSave)
+	}
+	assertResponse(t, executeCommand("LASTSAVE", nil), fmt.Sprintf(":%d\r\n", lastSave))
+
+	resetGlobalDict()
+	if err := loadRDB(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertResponse(t, executeCommand("GET", []string{"string"}), "$5\r\nvalue\r\n")
+	assertResponse(t, executeCommand("EXISTS", []string{"expired"}), ":0\r\n")
+	if ttl := string(executeCommand("TTL", []string{"expiring"})); ttl != ":100\r\n" && ttl != ":99\r\n" {
+		t.Errorf("Expected a TTL of 100 seconds, got %q", ttl)
+	}
+	assertResponse(t, executeCommand("LRANGE", []string{"list", "0", "-1"}), "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n")
+	assertResponse(t, executeCommand("HGET", []string{"hash", "f1"}), "$2\r\nv1\r\n")
+	assertResponse(t, executeCommand("HPERSIST", []string{"hash", "FIELDS", "2", "f1", "f2"}), "*2\r\n:-1\r\n:1\r\n")
+	assertResponse(t, executeCommand("SCARD", []string{"set"}), ":3\r\n")
+	assertResponse(t, executeCommand("ZSCORE", []string{"zset", "b"}), "$3\r\n2.5\r\n")
+	assertResponse(t, executeCommand("DBSIZE", nil), ":6\r\n")
+	executeCommand("SELECT", []string{"3"})
+	assertResponse(t, executeCommand("GET", []string{"other"}), "$2\r\ndb\r\n")
+	executeCommand("SELECT", []string{"0"})
+}
+
+func TestExecuteBackgroundSave(t *testing.T) {
+	useTempDir(t)
+	resetGlobalDict()
+	executeCommand("SET", []string{"string", "before"})
+	executeCommand("RPUSH", []string{"list", "a"})
+	executeCommand("SET", []string{"deleted", "value"})
+
+	assertResponse(t, executeCommand("BGSAVE", nil), "+Background saving started\r\n")
+	assertResponse(t, executeCommand("BGSAVE", nil), constant.ErrBgsaveInProgress)
+	assertResponse(t, executeCommand("SAVE", nil), constant.ErrBgsaveInProgress)
+
+	// The writes made while saving are not part of the snapshot, nor counted as saved
+	executeCommand("SET", []string{"string", "after"})
+	executeCommand("RPUSH", []string{"list", "b"})
+	executeCommand("DEL", []string{"deleted"})
+	executeCommand("SET", []string{"added", "value"})
+	waitBackgroundSave()
+	if dirty != 4 || !lastBgsaveOK {
+		t.Errorf("Expected 4 changes after a successful save, got %d", dirty)
+	}
+	assertResponse(t, executeCommand("GET", []string{"string"}), "$5\r\nafter\r\n")
+
+	resetGlobalDict()
+	if err := loadRDB(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertResponse(t, executeCommand("GET", []string{"string"}), "$6\r\nbefore\r\n")
+	assertResponse(t, executeCommand("LRANGE", []string{"list", "0", "-1"}), "*1\r\n$1\r\na\r\n")
+	assertResponse(t, executeCommand("EXISTS", []string{"deleted", "added"}), ":1\r\n")
+
+	assertResponse(t, executeCommand("BGSAVE", []string{"SCHEDULE"}), "+Background saving started\r\n")
+	assertResponse(t, executeCommand("BGSAVE", []string{"NOW"}), constant.ErrSyntax)
+}
+
+// Test that the writes made while a background save or rewrite encodes the collections
+// copy them without touching the values read by the encoder. Run with -race
+func TestWritesDuringBackgroundJobs(t *testing.T) {
+	useTempDir(t)
+	previousPreamble := config.AOFUseRDBPreamble
+	t.Cleanup(func() { config.AOFUseRDBPreamble = previousPreamble })
+
+	jobs := []struct {
+		name  string
+		start func()
+		wait  func()
+	}{
+		{"BGSAVE", func() { executeCommand("BGSAVE", nil) }, waitBackgroundSave},
+		{"BGREWRITEAOF", func() {
+			config.AOFUseRDBPreamble = false
+			executeCommand("BGREWRITEAOF", nil)
+		}, waitAppendOnlyRewrite},
+		{"BGREWRITEAOF with an RDB preamble", func() {
+			config.AOFUseRDBPreamble = true
+			executeCommand("BGREWRITEAOF", nil)
+		}, waitAppendOnlyRewrite},
+	}
+	for _, job := range jobs {
+		t.Run(job.name, func(t *testing.T) {
+			resetGlobalDict()
+			for i := 0; i < 200; i++ {
+				key := strconv.Itoa(i)
+				for j := 0; j < 300; j++ {
+					member := "m" + strconv.Itoa(j)
+					executeCommand("SADD", []string{"set" + key, member})
+					executeCommand("HSET", []string{"hash" + key, member, "v"})
+					executeCommand("ZADD", []string{"zset" + key, strconv.Itoa(j), member})
+				}
+			}
+
+			job.start()
+			for i := 0; i < 200; i++ {
+				key := strconv.Itoa(i)
+				executeCommand("SADD", []string{"set" + key, "new"})
+				executeCommand("HSET", []string{"hash" + key, "new", "v"})
+				executeCommand("ZADD", []string{"zset" + key, "0", "new"})
+			}
+			job.wait()
+			assertResponse(t, executeCommand("SCARD", []string{"set0"}), ":301\r\n")
+		})
+	}
+}
+
+func TestSaveRules(t *testing.T) {
+	useTempDir(t)
+	resetGlobalDict()
+	config.SaveParams = []config.SaveParam{{Seconds: 1, Changes: 2}}
+	dirty, lastSave = 0, time.Now().Unix()-2
+
+	executeCommand("SET", []string{"key", "value"})
+	executeCommand("GET", []string{"key"})
+	executeCommand("SET", []string{"key", "value", "XX", "GET", "KEEPTTL", "NX"}) // Rejected
+	CheckSnapshot()
+	if bgsaveInFlight != nil {
+		t.Fatalf("Expected no save after a single change")
+	}
+
+	executeCommand("DEL", []string{"key"})
+	CheckSnapshot()
+	if bgsaveInFlight == nil {
+		t.Fatalf("Expected a save after 2 changes in more than a second")
+	}
+	waitBackgroundSave()
+	if dirty != 0 {
+		t.Errorf("Expected no change left to save, got %d", dirty)
+	}
+	if _, err := os.Stat(filepath.Join(config.Dir, config.DBFilename)); err != nil {
+		t.Errorf("Expected the snapshot file: %v", err)
+	}
+}
+
+func TestLoadMissingOrCorruptedRDB(t *testing.T) {
+	useTempDir(t)
+	resetGlobalDict()
+	if err := loadRDB(); err != nil {
+		t.Errorf("Expected no error without a snapshot file, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(config.Dir, config.DBFilename), []byte("REDIS0011\xfa"), 0o644)
+	if err := loadRDB(); err == nil {
+		t.Errorf("Expected an error for a truncated snapshot file")
+	}
+}
+
+// readAppendOnlyCommands returns the commands of the incremental file being written
+func readAppendOnlyCommands(t *testing.T) [][]string {
+	t.Helper()
+	FlushAppendOnlyFile()
+	data, err := os.ReadFile(aofFile.incr.Name())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var commands [][]string
+	if _, err := aof.DecodeCommands(data, func(argv []string, _ int) error {
+		commands = append(commands, argv)
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return commands
+}
+
+func TestAppendOnlyFile(t *testing.T) {
+	useTempDir(t)
+	resetGlobalDict()
+	executeCommand("SET", []string{"before", "value"})
+	assertResponse(t, executeCommand("CONFIG", []string{"SET", "appendonly", "yes"}), constant.RespOk)
+
+	dir := filepath.Join(config.Dir, config.AppendDirname)
+	manifest, err := os.ReadFile(filepath.Join(dir, "appendonly.aof.manifest"))
+	expected := "file appendonly.aof.1.base.rdb seq 1 type b\nfile appendonly.aof.1.incr.aof seq 1 type i\n"
+	if err != nil || string(manifest) != expected {
+		t.Fatalf("Expected the manifest %q, got %q, %v", expected, manifest, err)
+	}
+
+	executeCommand("SET", []string{"string", "value", "EX", "100"})
+	executeCommand("SET", []string{"string", "other", "NX", "EX", "100"}) // Not set
+	executeCommand("GET", []string{"string"})
+	executeCommand("SADD", []string{"set", "a"})
+	executeCommand("SPOP", []string{"set"})
+	executeCommand("EXPIRE", []string{"missing", "100"})
+	executeCommand("SELECT", []string{"1"})
+	executeCommand("HSET", []string{"hash", "f1", "v1", "f2", "v2"})
+	executeCommand("HPEXPIRE", []string{"hash", "100000", "FIELDS", "2", "f1", "missing"})
+	executeCommand("HEXPIREAT", []string{"hash", "1", "FIELDS", "1", "f2"})
+	executeCommand("SELECT", []string{"0"})
+	executeCommand("DEL", []string{"before"})
+
+	expiry := func(key string) string {
+		expiryTime, _ := databases[0].GetExpiryTime(key)
+		return strconv.FormatUint(expiryTime, 10)
+	}
+	hash, _ := databases[1].Peek("hash").Value.(*data_structure.Hash)
+	fieldExpiry, _ := hash.GetExpiry("f1")
+	expectedCommands := [][]string{
+		{"SELECT", "0"},
+		{"SET", "string", "value", "PXAT", expiry("string")},
+		{"SADD", "set", "a"},
+		{"SREM", "set", "a"},
+		{"SELECT", "1"},
+		{"HSET", "hash", "f1", "v1", "f2", "v2"},
+		{"HPEXPIREAT", "hash", strconv.FormatUint(fieldExpiry, 10), "FIELDS", "1", "f1"},
+		{"HDEL", "hash", "f2"},
+		{"SELECT", "0"},
+		{"DEL", "before"},
+	}
+	if commands := readAppendOnlyCommands(t); !reflect.DeepEqual(commands, expectedCommands) {
+		t.Errorf("Expected the commands %q, got %q", expectedCommands, commands)
+	}
+
+	// The base file and the commands give the databases back
+	assertResponse(t, executeCommand("CONFIG", []string{"SET", "appendonly", "no"}), constant.RespOk)
+	resetGlobalDict()
+	config.AppendOnly = true
+	if err := LoadDataFromDisk(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertResponse(t, executeCommand("EXISTS", []string{"before", "set"}), ":0\r\n")
+	assertResponse(t, executeCommand("GET", []string{"string"}), "$5\r\nvalue\r\n")
+	if ttl := string(executeCommand("TTL", []string{"string"})); ttl != ":100\r\n" && ttl != ":99\r\n" {
+		t.Errorf("Expected a TTL of 100 seconds, got %q", ttl)
+	}
+	executeCommand("SELECT", []string{"1"})
+	assertResponse(t, executeCommand("HGETALL", []string{"hash"}), "*2\r\n$2\r\nf1\r\n$2\r\nv1\r\n")
+	executeCommand("SELECT", []string{"0"})
+
+	// The commands are appended to the last incremental file, selecting the database again
+	executeCommand("SET", []string{"after", "value"})
+	if commands := readAppendOnlyCommands(t); !reflect.DeepEqual(commands[len(commands)-2:], [][]string{{"SELECT", "0"}, {"SET", "after", "value"}}) {
+		t.Errorf("Expected the new commands at the end, got %q", commands)
+	}
+}
+
+func TestLoadTruncatedAppendOnlyFile(t *testing.T) {
+	useTempDir(t)
+	config.AppendOnly = true
+	dir := filepath.Join(config.Dir, config.AppendDirname)
+	os.Mkdir(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "appendonly.aof.manifest"), []byte("file appendonly.aof.1.incr.aof seq 1 type i\n"), 0o644)
+
+	var data []byte
+	data = aof.AppendCommand(data, []string{"SET", "key", "value"})
+	data = aof.AppendCommand(data, []string{"SET", "expired", "value", "PXAT", "1"})
+	valid := len(data)
+	data = aof.AppendCommand(data, []string{"MULTI"})
+	data = aof.AppendCommand(data, []string{"SET", "uncommitted", "value"})
+	incr := filepath.Join(dir, "appendonly.aof.1.incr.aof")
+
+	// An unfinished transaction is discarded, and a truncated command
+	for _, tail := range []string{"", "*2\r\n$3\r\nDEL"} {
+		os.WriteFile(incr, append(append([]byte(nil), data...), tail...), 0o644)
+		config.AOFLoadTruncated = false
+		resetGlobalDict()
+		if err := LoadDataFromDisk(); err == nil {
+			t.Errorf("Expected an error with aof-load-truncated off")
+		}
+
+		config.AOFLoadTruncated = true
+		resetGlobalDict()
+		if err := LoadDataFromDisk(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assertResponse(t, executeCommand("GET", []string{"key"}), "$5\r\nvalue\r\n")
+		assertResponse(t, executeCommand("EXISTS", []string{"expired", "uncommitted"}), ":0\r\n")
+		if info, _ := os.Stat(incr); info.Size() != int64(valid) {
+			t.Errorf("Expected the file to be truncated to %d bytes, got %d", valid, info.Size())
+		}
+		stopAppendOnly()
+	}
+
+	os.WriteFile(incr, append(append([]byte(nil), data...), "GET key\r\n"...), 0o644)
+	resetGlobalDict()
+	if err := LoadDataFromDisk(); err == nil {
+		t.Errorf("Expected an error for an invalid command")
+	}
+}
+
+func TestSetConfigArgs(t *testing.T) {
+	useTempDir(t)
+	err := SetConfigArgs([]string{"--appendonly", "yes", "--save", "900", "1", "300", "10", "--appendfilename", "other.aof"})
+	previousFilename := "appendonly.aof"
+	t.Cleanup(func() { config.AppendFilename = previousFilename })
+	if err != nil || !config.AppendOnly || formatSaveParams() != "900 1 300 10" || config.AppendFilename != "other.aof" {
+		t.Errorf("Unexpected parameters %v, %q, %q, %v", config.AppendOnly, formatSaveParams(), config.AppendFilename, err)
+	}
+	for _, invalid := range [][]string{{"appendonly", "yes"}, {"--unknown", "1"}, {"--appendonly", "maybe"}} {
+		if err := SetConfigArgs(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+// readManifestFiles returns the names of the files of the manifest, and checks that they
+// are the only files of the directory
+func readManifestFiles(t *testing.T) []string {
+	t.Helper()
+	dir := filepath.Join(config.Dir, config.AppendDirname)
+	m, err := readManifest(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var names []string
+	for _, f := range m.Files() {
+		names = append(names, f.Name)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != len(names)+1 {
+		t.Errorf("Expected only the files %q and the manifest, got %d files", names, len(entries))
+	}
+	return names
+}
+
+func TestRewriteAppendOnlyFile(t *testing.T) {
+	for _, preamble := range []bool{true, false} {
+		t.Run(fmt.Sprintf("preamble %v", preamble), func(t *testing.T) {
+			useTempDir(t)
+			resetGlobalDict()
+			config.AOFUseRDBPreamble = preamble
+			assertResponse(t, executeCommand("CONFIG", []string{"SET", "appendonly", "yes"}), constant.RespOk)
+			for i := range 100 {
+				executeCommand("INCR", []string{"counter"})
+				executeCommand("RPUSH", []string{"list", strconv.Itoa(i)})
+			}
+			executeCommand("SET", []string{"session", "abc", "EX", "100"})
+			executeCommand("SELECT", []string{"2"})
+			executeCommand("HSET", []string{"hash", "f1", "v1", "f2", "v2"})
+			executeCommand("HEXPIRE", []string{"hash", "100", "FIELDS", "1", "f1"})
+			executeCommand("SELECT", []string{"0"})
+			FlushAppendOnlyFile()
+			sizeBefore := aofFile.size
+
+			assertResponse(t, executeCommand("BGREWRITEAOF", nil), "+Background append only file rewriting started\r\n")
+			assertResponse(t, executeCommand("BGREWRITEAOF", nil), constant.ErrAOFRewriteActive)
+			assertResponse(t, executeCommand("BGSAVE", nil), constant.ErrBgsaveAOFActive)
+			assertResponse(t, executeCommand("BGSAVE", []string{"SCHEDULE"}), "+Background saving scheduled\r\n")
+			// Written to the new incremental file, while the snapshot keeps the previous values
+			executeCommand("SET", []string{"during", "rewrite"})
+			executeCommand("LPOP", []string{"list"})
+
+			waitAppendOnlyRewrite()
+			base := "appendonly.aof.2.base.aof"
+			if preamble {
+				base = "appendonly.aof.2.base.rdb"
+			}
+			if files := readManifestFiles(t); !reflect.DeepEqual(files, []string{base, "appendonly.aof.2.incr.aof"}) {
+				t.Errorf("Expected the new base and incremental files, got %q", files)
+			}
+			if aofFile.size >= sizeBefore {
+				t.Errorf("Expected the files to shrink from %d bytes, got %d", sizeBefore, aofFile.size)
+			}
+			CheckSnapshot()
+			if bgsaveInFlight == nil {
+				t.Errorf("Expected the scheduled save to start after the rewrite")
+			}
+
+			assertResponse(t, executeCommand("CONFIG", []string{"SET", "appendonly", "no"}), constant.RespOk)
+			resetGlobalDict()
+			config.AppendOnly = true
+			if err := LoadDataFromDisk(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			assertResponse(t, executeCommand("GET", []string{"counter"}), "$3\r\n100\r\n")
+			assertResponse(t, executeCommand("GET", []string{"during"}), "$7\r\nrewrite\r\n")
+			assertResponse(t, executeCommand("LLEN", []string{"list"}), ":99\r\n")
+			assertResponse(t, executeCommand("LINDEX", []string{"list", "0"}), "$1\r\n1\r\n")
+			if ttl := string(executeCommand("TTL", []string{"session"})); ttl != ":100\r\n" && ttl != ":99\r\n" {
+				t.Errorf("Expected a TTL of 100 seconds, got %q", ttl)
+			}
+			executeCommand("SELECT", []string{"2"})
+			assertResponse(t, executeCommand("HTTL", []string{"hash", "FIELDS", "2", "f1", "f2"}), "*2\r\n:100\r\n:-1\r\n")
+			executeCommand("SELECT", []string{"0"})
+		})
+	}
+}
+
+func TestRewriteAppendOnlyFileTriggers(t *testing.T) {
+	useTempDir(t)
+	resetGlobalDict()
+
+	// With appendonly off, only the base file is written
+	executeCommand("SET", []string{"key", "value"})
+	assertResponse(t, executeCommand("BGREWRITEAOF", nil), "+Background append only file rewriting started\r\n")
+	waitAppendOnlyRewrite()
+	if files := readManifestFiles(t); !reflect.DeepEqual(files, []string{"appendonly.aof.1.base.rdb"}) {
+		t.Errorf("Expected a base file, got %q", files)
+	}
+
+	// Scheduled during a background save
+	assertResponse(t, executeCommand("BGSAVE", nil), "+Background saving started\r\n")
+	assertResponse(t, executeCommand("BGREWRITEAOF", nil), "+Background append only file rewriting scheduled\r\n")
+	CheckAppendOnlyRewrite()
+	if aofRewriteInFlight != nil {
+		t.Fatalf("Expected the rewrite to wait for the save")
+	}
+	waitBackgroundSave()
+	CheckAppendOnlyRewrite()
+	if aofRewriteInFlight == nil {
+		t.Fatalf("Expected the scheduled rewrite to start")
+	}
+	waitAppendOnlyRewrite()
+
+	// Started once the files doubled
+	assertResponse(t, executeCommand("CONFIG", []string{"SET", "appendonly", "yes", "auto-aof-rewrite-min-size", "1kb"}), constant.RespOk)
+	executeCommand("SET", []string{"small", "value"})
+	FlushAppendOnlyFile()
+	CheckAppendOnlyRewrite()
+	if aofRewriteInFlight != nil {
+		t.Fatalf("Expected no rewrite below auto-aof-rewrite-min-size")
+	}
+	for aofFile.size < 2*aofFile.baseSize || aofFile.size < 1000 {
+		executeCommand("SET", []string{"key", strings.Repeat("v", 100)})
+		FlushAppendOnlyFile()
+	}
+	assertResponse(t, executeCommand("CONFIG", []string{"SET", "auto-aof-rewrite-percentage", "0"}), constant.RespOk)
+	CheckAppendOnlyRewrite()
+	if aofRewriteInFlight != nil {
+		t.Fatalf("Expected no rewrite with auto-aof-rewrite-percentage 0")
+	}
+	assertResponse(t, executeCommand("CONFIG", []string{"SET", "auto-aof-rewrite-percentage", "100"}), constant.RespOk)
+	CheckAppendOnlyRewrite()
+	if aofRewriteInFlight == nil {
+		t.Fatalf("Expected a rewrite once the files doubled")
+	}
+	waitAppendOnlyRewrite()
+	if aofFile.baseSize != aofFile.size {
+		t.Errorf("Expected the size after the rewrite as the new base size, got %d and %d", aofFile.baseSize, aofFile.size)
+	}
+}
+
+// useReplicationState restores the replication state of a master without replicas once the
+// test is done
+func useReplicationState(t *testing.T) {
+	t.Helper()
+	useTempDir(t)
+	previousReadOnly, previousBacklogSize := config.ReplicaReadOnly, config.ReplBacklogSize
+	t.Cleanup(func() {
+		waitBackgroundJobs()
+		dropMasterLink()
+		config.ReplicaReadOnly, config.ReplBacklogSize = previousReadOnly, previousBacklogSize
+		masterHost, masterPort, masterLinkState = "", 0, ""
+		replID, replID2, masterReplOffset, secondReplOffset = newReplID(), strings.Repeat("0", 40), 0, -1
+		backlog, replicas, replicaPorts, replSelectedDB = nil, nil, map[*connection.Connection]int{}, -1
+		droppedLinks = nil
+	})
+}
+
+// newTestClient returns a client whose replies are read from the other end of a socket pair
+func newTestClient(t *testing.T) (*connection.Connection, func() string) {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		syscall.Close(fds[0])
+		syscall.Close(fds[1])
+	})
+	syscall.SetNonblock(fds[1], true)
+	conn := connection.NewConnection(fds[0])
+	return conn, func() string {
+		if err := conn.WriteOutput(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var out []byte
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := syscall.Read(fds[1], buf)
+			if n <= 0 || err != nil {
+				return string(out)
+			}
+			out = append(out, buf[:n]...)
+		}
+	}
+}
+
+func executeClientCommand(conn *connection.Connection, name string, args ...string) {
+	ExecuteAndRespond(&command.Command{Cmd: name, Args: args}, conn)
+}
+
+func TestReplBacklog(t *testing.T) {
+	useReplicationState(t)
+	masterReplOffset = 100
+	b := newReplBacklog(8)
+	feed := func(data string) {
+		masterReplOffset += int64(len(data))
+		b.append([]byte(data))
+	}
+
+	feed("abc")
+	if b.offset != 101 || string(b.since(101)) != "abc" || string(b.since(103)) != "c" || len(b.since(104)) != 0 {
+		t.Errorf("Expected abc from offset 101, got %q from %d", b.since(b.offset), b.offset)
+	}
+	feed("defghij") // Wraps around, the oldest bytes are overwritten
+	if b.offset != 103 || string(b.since(103)) != "cdefghij" || string(b.since(108)) != "hij" {
+		t.Errorf("Expected cdefghij from offset 103, got %q from %d", b.since(b.offset), b.offset)
+	}
+	feed("0123456789abcdef") // Larger than the buffer
+	if b.offset != 119 || string(b.since(119)) != "89abcdef" {
+		t.Errorf("Expected 89abcdef from offset 119, got %q from %d", b.since(b.offset), b.offset)
+	}
+
+	b.resize(4)
+	if b.offset != 123 || string(b.since(123)) != "cdef" {
+		t.Errorf("Expected cdef from offset 123 once shrunk, got %q from %d", b.since(b.offset), b.offset)
+	}
+	b.resize(16)
+	feed("gh")
+	if b.offset != 123 || string(b.since(123)) != "cdefgh" {
+		t.Errorf("Expected cdefgh from offset 123 once grown, got %q from %d", b.since(b.offset), b.offset)
+	}
+}
+
+func TestReplicationMaster(t *testing.T) {
+	useReplicationState(t)
+	resetGlobalDict()
+	executeCommand("SET", []string{"before", "sync"})
+
+	// A full resynchronization sends the RDB file, then the commands run during its save
+	replica, readReplica := newTestClient(t)
+	executeClientCommand(replica, "REPLCONF", "listening-port", "6380")
+	executeClientCommand(replica, "PSYNC", "?", "-1")
+	client, readClient := newTestClient(t)
+	executeClientCommand(client, "SET", "during", "sync")
+	waitBackgroundSave()
+	out := readReplica()
+	header := fmt.Sprintf("+OK\r\n+FULLRESYNC %s 0\r\n$", replID)
+	if !strings.HasPrefix(out, header) {
+		t.Fatalf("Expected %q, got %q", header, out)
+	}
+	size, _ := strconv.Atoi(out[len(header):strings.Index(out, "\r\nREDIS")])
+	payload := out[strings.Index(out, "\r\nREDIS")+2:]
+	commands := "*2\r\n$6\r\nSELECT\r\n$1\r\n0\r\n*3\r\n$3\r\nSET\r\n$6\r\nduring\r\n$4\r\nsync\r\n"
+	if len(payload) != size+len(commands) || payload[size:] != commands {
+		t.Errorf("Expected an RDB file of %d bytes followed by %q, got %q", size, commands, payload)
+	}
+	assertResponse(t, []byte(readClient()), constant.RespOk)
+
+	// Then the writes are sent as they run, without their reply
+	executeClientCommand(client, "SELECT", "2")
+	executeClientCommand(client, "INCR", "counter")
+	executeClientCommand(client, "GET", "counter")
+	assertResponse(t, []byte(readReplica()), "*2\r\n$6\r\nSELECT\r\n$1\r\n2\r\n*2\r\n$4\r\nINCR\r\n$7\r\ncounter\r\n")
+	executeClientCommand(replica, "REPLCONF", "ACK", strconv.FormatInt(masterReplOffset, 10))
+	assertResponse(t, []byte(readReplica()), "")
+	expected := fmt.Sprintf("*3\r\n$6\r\nmaster\r\n:%d\r\n*1\r\n*3\r\n$1\r\n?\r\n$4\r\n6380\r\n$%d\r\n%d\r\n",
+		masterReplOffset, len(strconv.FormatInt(masterReplOffset, 10)), masterReplOffset)
+	assertResponse(t, executeCommand("ROLE", nil), expected)
+
+	// A partial resynchronization continues from the backlog
+	offset := masterReplOffset + 1
+	executeClientCommand(client, "DEL", "counter")
+	other, readOther := newTestClient(t)
+	executeClientCommand(other, "PSYNC", replID, strconv.FormatInt(offset, 10))
+	assertResponse(t, []byte(readOther()), "+CONTINUE "+replID+"\r\n*2\r\n$3\r\nDEL\r\n$7\r\ncounter\r\n")
+	executeClientCommand(other, "PSYNC", replID, strconv.FormatInt(offset, 10))
+	assertResponse(t, []byte(readOther()), constant.ErrReplicaConnection)
+
+	// Unless the history or the offset is unknown
+	for _, args := range [][]string{
+		{newReplID(), strconv.FormatInt(offset, 10)},
+		{replID, strconv.FormatInt(masterReplOffset+2, 10)},
+		{replID, "0"},
+	} {
+		conn, read := newTestClient(t)
+		executeClientCommand(conn, "PSYNC", args...)
+		waitBackgroundSave()
+		if out := read(); !strings.HasPrefix(out, "+FULLRESYNC "+replID+" ") {
+			t.Errorf("Expected a full resynchronization for PSYNC %q, got %q", args, out)
+		}
+	}
+
+	assertResponse(t, executeCommand("REPLCONF", []string{"listening-port", "x"}), constant.ErrNotInteger)
+	assertResponse(t, executeCommand("REPLCONF", []string{"rdb-only", "1"}), "-ERR Unrecognized REPLCONF option: rdb-only\r\n")
+	assertResponse(t, executeCommand("REPLCONF", []string{"capa"}), constant.ErrSyntax)
+
+	// A replica lost is forgotten
+	count := len(replicas)
+	ConnectionClosed(replica)
+	if len(replicas) != count-1 || findReplica(replica) != nil {
+		t.Errorf("Expected the replica to be removed, got %d replicas", len(replicas))
+	}
+}
+
+func TestReplicationReplica(t *testing.T) {
+	useReplicationState(t)
+	resetGlobalDict()
+	previousID := replID
+
+	assertResponse(t, executeCommand("REPLICAOF", []string{"127.0.0.1", "port"}), constant.ErrInvalidMasterPort)
+	assertResponse(t, executeCommand("REPLICAOF", []string{"127.0.0.1", "1"}), constant.RespOk)
+	assertResponse(t, executeCommand("REPLICAOF", []string{"127.0.0.1", "1"}), "+OK Already connected to specified master\r\n")
+	assertResponse(t, executeCommand("ROLE", nil), "*5\r\n$5\r\nslave\r\n$9\r\n127.0.0.1\r\n:1\r\n$7\r\nconnect\r\n:-1\r\n")
+	assertResponse(t, executeCommand("CONFIG", []string{"GET", "replicaof"}), "*2\r\n$9\r\nreplicaof\r\n$11\r\n127.0.0.1 1\r\n")
+	assertResponse(t, executeCommand("PSYNC", []string{replID, "1"}), constant.ErrReplicaConnection)
+	conn, read := newTestClient(t)
+	executeClientCommand(conn, "PSYNC", replID, "1")
+	assertResponse(t, []byte(read()), constant.ErrNoMasterLink)
+
+	// The writes only come from the master, which gets no reply
+	executeClientCommand(conn, "SET", "key", "value")
+	assertResponse(t, []byte(read()), constant.ErrReadOnly)
+	master, readMaster := newTestClient(t)
+	master.Master = true
+	masterLink, masterLinkState = master, linkConnected
+	executeClientCommand(master, "SET", "key", "master")
+	executeClientCommand(master, "REPLCONF", "GETACK", "*")
+	assertResponse(t, []byte(readMaster()), "*3\r\n$8\r\nREPLCONF\r\n$3\r\nACK\r\n$1\r\n0\r\n")
+	executeClientCommand(master, "REPLICAOF", "127.0.0.1", "2")
+	assertResponse(t, []byte(readMaster()), "")
+	assertResponse(t, executeCommand("GET", []string{"key"}), "$6\r\nmaster\r\n")
+	assertResponse(t, executeCommand("CONFIG", []string{"SET", "replica-read-only", "no"}), constant.RespOk)
+	assertResponse(t, executeCommand("SET", []string{"key", "local"}), constant.RespOk)
+
+	// The stream of the master is sent as received to the replicas of this replica
+	backlog = newReplBacklog(config.ReplBacklogSize)
+	FeedMasterStream([]byte("*1\r\n$4\r\nPING\r\n"))
+	if masterReplOffset != 14 || string(backlog.since(1)) != "*1\r\n$4\r\nPING\r\n" {
+		t.Errorf("Expected the PING at offset 1, got offset %d", masterReplOffset)
+	}
+
+	// A master has a new history, continuing the one of its previous master
+	assertResponse(t, executeCommand("REPLICAOF", []string{"NO", "ONE"}), constant.RespOk)
+	if master.Master || !master.CloseASAP || masterLink != nil {
+		t.Errorf("Expected the link to the master to be closed")
+	}
+	if replID == previousID || replID2 != previousID || secondReplOffset != 15 {
+		t.Errorf("Expected %s up to offset 15 as previous history, got %s up to %d", previousID, replID2, secondReplOffset)
+	}
+	assertResponse(t, executeCommand("ROLE", nil), "*3\r\n$6\r\nmaster\r\n:14\r\n*0\r\n")
+	other, readOther := newTestClient(t)
+	executeClientCommand(other, "PSYNC", previousID, "15")
+	assertResponse(t, []byte(readOther()), "+CONTINUE "+replID+"\r\n")
+}
+
+func TestSyncWithMaster(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for _, reply := range []string{"+PONG\r\n", "+OK\r\n", "+OK\r\n", "+FULLRESYNC " + strings.Repeat("a", 40) + " 42\r\n\n\n$3\r\nrdb*1\r\n$4\r\nPING\r\n"} {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			for range 2 * n {
+				r.ReadString('\n')
+			}
+			c.Write([]byte(reply))
+		}
+		io.Copy(io.Discard, c)
+	}()
+
+	results := make(chan *syncResult, 1)
+	syncWithMaster(results, 3, listener.Addr().String(), replID, 1, 6380, time.Second)
+	result := <-results
+	if result.err != nil {
+		t.Fatalf("Unexpected error: %v", result.err)
+	}
+	defer syscall.Close(result.fd)
+	if result.gen != 3 || !result.full || result.replID != strings.Repeat("a", 40) || result.offset != 42 ||
+		string(result.rdb) != "rdb" || string(result.rest) != "*1\r\n$4\r\nPING\r\n" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"redis-repo/internal/data_structure"
+	"sync"
+)
+
+// lazyfreeThreshold is Redis's LAZYFREE_THRESHOLD: the values taking more work than this
+// to free, see data_structure.ValueObject.FreeEffort, are freed by another goroutine
+const lazyfreeThreshold = 64
+
+// lazyfreeJobs tracks the goroutines freeing values, so the tests can wait for them
+var lazyfreeJobs sync.WaitGroup
+
+// freeObjectAsync frees a value deleted from the keyspace, as Redis's freeObjAsync. A large
+// one is freed by another goroutine, a small one is left to the garbage collector
+func freeObjectAsync(obj *data_structure.ValueObject) {
+	if obj == nil || obj.FreeEffort() <= lazyfreeThreshold {
+		return
+	}
+	lazyfreeJobs.Add(1)
+	go func() {
+		defer lazyfreeJobs.Done()
+		obj.Free()
+	}()
+}
+
+// emptyDBAsync frees databases replaced by empty ones, as Redis's emptyDbAsync. The ones
+// still read by a snapshot are left to the garbage collector once it is done
+func emptyDBAsync(dbs []*data_structure.Dict) {
+	var unused []*data_structure.Dict
+	for _, db := range dbs {
+		if !db.InSnapshot() {
+			unused = append(unused, db)
+		}
+	}
+	if len(unused) == 0 {
+		return
+	}
+	lazyfreeJobs.Add(1)
+	go func() {
+		defer lazyfreeJobs.Done()
+		for _, db := range unused {
+			db.Free()
+		}
+	}()
+}
@@ -0,0 +1,234 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"redis-repo/internal/config"
+	"redis-repo/internal/data_structure"
+	"redis-repo/internal/rdb"
+	"strconv"
+	"time"
+)
+
+// bgsaveRetryDelay is the number of seconds before the save rules try again after a failed
+// background save, as Redis's CONFIG_BGSAVE_RETRY_DELAY
+const bgsaveRetryDelay = 5
+
+// Snapshot state. dirty counts the writes since the last successful save, lastSave is the
+// Unix time of that save, or of the startup
+var (
+	dirty           int64
+	lastSave        = time.Now().Unix()
+	lastBgsaveTry   int64
+	lastBgsaveOK    = true
+	bgsaveInFlight  *backgroundSave
+	bgsaveScheduled bool // BGSAVE SCHEDULE was called during an append only file rewrite
+)
+
+// backgroundSave is a snapshot being written by another goroutine. The databases share
+// their values with it until it is done, see data_structure.Dict.Snapshot
+type backgroundSave struct {
+	dbs   []*data_structure.Dict
+	dirty int64  // Value of dirty when the snapshot was taken
+	path  string // File written, sent to the replicas waiting for it
+	done  chan error
+}
+
+// snapshotDatabases takes a snapshot of every database, to release once written
+func snapshotDatabases() ([]*data_structure.Dict, [][]data_structure.SnapshotEntry) {
+	dbs := make([]*data_structure.Dict, len(databases))
+	entries := make([][]data_structure.SnapshotEntry, len(databases))
+	for i, db := range databases {
+		dbs[i] = db
+		entries[i] = db.Snapshot()
+	}
+	return dbs, entries
+}
+
+func releaseSnapshot(dbs []*data_structure.Dict) {
+	for _, db := range dbs {
+		db.ReleaseSnapshot()
+	}
+}
+
+// rdbPath returns the path of the snapshot file
+func rdbPath() string {
+	return filepath.Join(config.Dir, config.DBFilename)
+}
+
+// writeRDB writes the snapshot to path. usedMem is the memory when the snapshot was taken,
+// as the databases are not read outside of the event loop. aofBase marks the base file of
+// an append only file
+func writeRDB(path string, snapshot [][]data_structure.SnapshotEntry, usedMem int64, aofBase bool) error {
+	return writeFileAtomically(path, func(w io.Writer) error {
+		e := rdb.NewEncoder(w)
+		e.WriteHeader(rdb.SnapshotVersion(snapshot))
+		e.WriteAux("redis-ver", config.RedisVersion)
+		e.WriteAux("redis-bits", "64")
+		e.WriteAux("ctime", strconv.FormatInt(time.Now().Unix(), 10))
+		e.WriteAux("used-mem", strconv.FormatInt(usedMem, 10))
+		if aofBase {
+			e.WriteAux("aof-base", "1")
+		} else {
+			e.WriteAux("aof-base", "0")
+		}
+		for id, entries := range snapshot {
+			e.WriteDB(id, entries)
+		}
+		return e.WriteEOF()
+	})
+}
+
+// writeFileAtomically writes a temporary file renamed to path once synced, so path always
+// holds a complete file
+func writeFileAtomically(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "temp-"+strconv.Itoa(os.Getpid())+"-*"+filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // No-op once renamed
+
+	err = write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	return err
+}
+
+// rdbSave saves the databases in the foreground, as SAVE
+func rdbSave() error {
+	waitAppendOnlyRewrite() // A database has one snapshot at a time
+	dbs, snapshot := snapshotDatabases()
+	defer releaseSnapshot(dbs)
+	if err := writeRDB(rdbPath(), snapshot, usedMemory(), false); err != nil {
+		log.Println("Failed saving the DB:", err)
+		return err
+	}
+	log.Println("DB saved on disk")
+	dirty = 0
+	lastSave = time.Now().Unix()
+	return nil
+}
+
+// rdbSaveBackground starts saving the databases in another goroutine. The event loop only
+// copies the index of the keys: the values are copied when modified, see
+// data_structure.Dict.Snapshot
+func rdbSaveBackground() error {
+	if bgsaveInFlight != nil || aofRewriteInFlight != nil {
+		return errors.New("background job already in progress")
+	}
+	lastBgsaveTry = time.Now().Unix()
+	bgsaveScheduled = false
+
+	dbs, snapshot := snapshotDatabases()
+	path, usedMem := rdbPath(), usedMemory()
+	bg := &backgroundSave{dbs: dbs, dirty: dirty, path: path, done: make(chan error, 1)}
+	replicationBgsaveStarted()
+	go func() {
+		bg.done <- writeRDB(path, snapshot, usedMem, false)
+	}()
+	bgsaveInFlight = bg
+	log.Println("Background saving started")
+	return nil
+}
+
+// waitBackgroundSave blocks until the background save in progress, if any, is done
+func waitBackgroundSave() {
+	if bgsaveInFlight != nil {
+		finishBackgroundSave(<-bgsaveInFlight.done)
+	}
+}
+
+func finishBackgroundSave(err error) {
+	bg := bgsaveInFlight
+	bgsaveInFlight = nil
+	releaseSnapshot(bg.dbs)
+	lastBgsaveOK = err == nil
+	replicationBgsaveDone(bg.path, err)
+	if err != nil {
+		log.Println("Background saving error:", err)
+		return
+	}
+	log.Println("Background saving terminated with success")
+	dirty -= bg.dirty
+	lastSave = time.Now().Unix()
+}
+
+// CheckSnapshot completes the background save once its goroutine is done, and starts one
+// when it was scheduled or when a save rule of config.SaveParams is met, as Redis's
+// serverCron
+func CheckSnapshot() {
+	if bgsaveInFlight != nil {
+		select {
+		case err := <-bgsaveInFlight.done:
+			finishBackgroundSave(err)
+		default:
+		}
+		return
+	}
+	if aofRewriteInFlight != nil {
+		return
+	}
+	if bgsaveScheduled {
+		rdbSaveBackground()
+		return
+	}
+
+	now := time.Now().Unix()
+	for _, param := range config.SaveParams {
+		if dirty >= param.Changes && now-lastSave > param.Seconds &&
+			(lastBgsaveOK || now-lastBgsaveTry > bgsaveRetryDelay) {
+			log.Printf("%d changes in %d seconds. Saving...", param.Changes, param.Seconds)
+			rdbSaveBackground()
+			return
+		}
+	}
+}
+
+// loadRDB loads the databases from the snapshot file, when it exists
+func loadRDB() error {
+	f, err := os.Open(rdbPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	d := rdb.NewDecoder(f)
+	if err := d.Decode(loadSnapshotEntry); err != nil {
+		return fmt.Errorf("%s at offset %d: %w", rdbPath(), d.Offset(), err)
+	}
+	log.Printf("DB loaded from disk: %.3f seconds", time.Since(start).Seconds())
+	return nil
+}
+
+// loadSnapshotEntry stores a key read from an RDB file, unless it has expired since
+func loadSnapshotEntry(db int, entry data_structure.SnapshotEntry) error {
+	if db >= len(databases) {
+		return fmt.Errorf("database %d is out of range", db)
+	}
+	now := uint64(time.Now().UnixMilli())
+	if entry.ExpiryTimeMs != 0 && entry.ExpiryTimeMs < now {
+		return nil
+	}
+	if hash, ok := entry.Value.Value.(*data_structure.Hash); ok {
+		if hash.DeleteExpired(now); hash.Len() == 0 {
+			return nil
+		}
+	}
+	storeObject(databases[db], entry.Key, entry.Value, entry.ExpiryTimeMs)
+	return nil
+}
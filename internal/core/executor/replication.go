@@ -0,0 +1,587 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"redis-repo/internal/aof"
+	"redis-repo/internal/config"
+	"redis-repo/internal/core/connection"
+	"redis-repo/internal/rdb"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// currentClient is the client whose command runs, nil outside of ExecuteAndRespond
+var currentClient *connection.Connection
+
+// Replication state of the master side, as Redis's replid, replid2, master_repl_offset and
+// second_replid_offset. Every byte sent to the replicas advances masterReplOffset. replID2
+// is the ID of the previous master of a promoted replica, valid up to secondReplOffset, so
+// the other replicas of that master can continue from it
+var (
+	replID           = newReplID()
+	replID2          = strings.Repeat("0", 40)
+	masterReplOffset int64
+	secondReplOffset int64 = -1
+
+	backlog          *replBacklog // Created with the first replica
+	replicas         []*replica
+	replicaPorts     = map[*connection.Connection]int{} // Ports sent with REPLCONF listening-port
+	replSelectedDB   = -1                               // Database of the last command sent, -1 to select it first
+	lastReplicasPing time.Time
+)
+
+// States of a replica, as Redis's SLAVE_STATE_*
+const (
+	replicaWaitBgsaveStart = iota // Waiting for a background save to start
+	replicaWaitBgsaveEnd          // Waiting for the background save to send it
+	replicaOnline                 // Receiving the commands
+)
+
+// replica is a client that sent PSYNC
+type replica struct {
+	conn      *connection.Connection
+	state     int
+	pending   []byte // Commands sent during the background save, after its RDB file
+	port      int    // Listening port, 0 when unknown
+	ackOffset int64  // Offset of the last REPLCONF ACK
+	ackTime   time.Time
+}
+
+// newReplID returns a random replication ID of 40 hexadecimal characters
+func newReplID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// replBacklog keeps the last bytes sent to the replicas in a circular buffer, as Redis's
+// repl_backlog, so a replica reconnecting can get what it missed
+type replBacklog struct {
+	buf     []byte
+	idx     int   // Position of the next byte written in buf
+	histlen int   // Bytes of buf holding data
+	offset  int64 // Replication offset of the first byte held
+}
+
+func newReplBacklog(size int64) *replBacklog {
+	return &replBacklog{buf: make([]byte, size), offset: masterReplOffset + 1}
+}
+
+// append adds the bytes sent to the replicas, once counted in masterReplOffset
+func (b *replBacklog) append(data []byte) {
+	for len(data) > 0 {
+		n := copy(b.buf[b.idx:], data)
+		b.idx = (b.idx + n) % len(b.buf)
+		b.histlen = min(b.histlen+n, len(b.buf))
+		data = data[n:]
+	}
+	b.offset = masterReplOffset - int64(b.histlen) + 1
+}
+
+// resize keeps the most recent bytes held that fit in a buffer of size bytes
+func (b *replBacklog) resize(size int64) {
+	data := b.since(b.offset)
+	if int64(len(data)) > size {
+		data = data[int64(len(data))-size:]
+	}
+	b.buf, b.idx, b.histlen = make([]byte, size), 0, 0
+	b.append(data)
+}
+
+// since returns the bytes held from offset on
+func (b *replBacklog) since(offset int64) []byte {
+	skip := int(offset - b.offset)
+	out := make([]byte, 0, b.histlen-skip)
+	start := (b.idx - b.histlen + skip + len(b.buf)) % len(b.buf)
+	if start+b.histlen-skip <= len(b.buf) {
+		return append(out, b.buf[start:start+b.histlen-skip]...)
+	}
+	out = append(out, b.buf[start:]...)
+	return append(out, b.buf[:b.idx]...)
+}
+
+// feedReplicas sends a write command run against database db to the replicas
+func feedReplicas(db int, argv []string) {
+	if backlog == nil && len(replicas) == 0 {
+		return
+	}
+	var buf []byte
+	if db != replSelectedDB {
+		buf = aof.AppendCommand(buf, []string{"SELECT", strconv.Itoa(db)})
+		replSelectedDB = db
+	}
+	feedReplicationStream(aof.AppendCommand(buf, argv))
+}
+
+// feedReplicationStream adds data to the backlog and to the output of the replicas. The
+// replicas waiting for a background save get it once the RDB file is sent
+func feedReplicationStream(data []byte) {
+	if backlog == nil && len(replicas) == 0 {
+		return
+	}
+	masterReplOffset += int64(len(data))
+	if backlog != nil {
+		backlog.append(data)
+	}
+	for _, r := range replicas {
+		switch r.state {
+		case replicaOnline:
+			r.conn.AddReply(data)
+		case replicaWaitBgsaveEnd:
+			r.pending = append(r.pending, data...)
+		}
+	}
+}
+
+// addReplica turns the current client into a replica
+func addReplica(state int) *replica {
+	conn := currentClient
+	conn.Class = connection.ClassReplica
+	r := &replica{conn: conn, state: state, port: replicaPorts[conn], ackTime: time.Now()}
+	replicas = append(replicas, r)
+	if backlog == nil {
+		backlog = newReplBacklog(config.ReplBacklogSize)
+	}
+	return r
+}
+
+func findReplica(conn *connection.Connection) *replica {
+	for _, r := range replicas {
+		if r.conn == conn {
+			return r
+		}
+	}
+	return nil
+}
+
+// replicationBgsaveStarted sends the offset of the background save just started to the
+// replicas waiting for one, they get the commands from there
+func replicationBgsaveStarted() {
+	for _, r := range replicas {
+		if r.state == replicaWaitBgsaveStart {
+			r.conn.AddReply([]byte(fmt.Sprintf("+FULLRESYNC %s %d\r\n", replID, masterReplOffset)))
+			r.state, r.pending = replicaWaitBgsaveEnd, nil
+			replSelectedDB = -1 // The replica loads the file with database 0 selected
+		}
+	}
+}
+
+// replicationBgsaveDone sends the RDB file written at path to the replicas waiting for
+// it, followed by the commands run since it was started. The file is streamed from the
+// disk, so only the commands count against the output buffer limit of the replicas
+func replicationBgsaveDone(path string, err error) {
+	for _, r := range replicas {
+		if r.state != replicaWaitBgsaveEnd {
+			continue
+		}
+		var f *os.File
+		var info os.FileInfo
+		if err == nil {
+			if f, err = os.Open(path); err == nil {
+				if info, err = f.Stat(); err != nil {
+					f.Close()
+				}
+			}
+		}
+		if err != nil {
+			log.Println("Full resynchronization of a replica failed:", err)
+			r.conn.CloseASAP = true
+			continue
+		}
+		r.conn.AddReply([]byte("$" + strconv.FormatInt(info.Size(), 10) + "\r\n"))
+		r.conn.AddFile(f, info.Size())
+		r.conn.AddReply(r.pending)
+		r.state, r.pending, r.ackTime = replicaOnline, nil, time.Now()
+		log.Println("Synchronization with a replica succeeded")
+	}
+}
+
+// ConnectionClosed forgets a client once disconnected, a replica or the link to the master
+func ConnectionClosed(conn *connection.Connection) {
+	delete(replicaPorts, conn)
+	for i, r := range replicas {
+		if r.conn == conn {
+			replicas = append(replicas[:i], replicas[i+1:]...)
+			log.Println("Connection with a replica lost")
+			return
+		}
+	}
+	if conn == masterLink {
+		masterLink = nil
+		if masterHost != "" {
+			masterLinkState = linkConnect
+			log.Println("Connection with master lost")
+		}
+	}
+}
+
+// ReplicationConnections returns the connections receiving data outside of their own
+// commands, the replicas and the master link, so their output is sent every iteration,
+// and the links to a previous master, to close
+func ReplicationConnections() []*connection.Connection {
+	conns := append([]*connection.Connection(nil), droppedLinks...)
+	droppedLinks = nil
+	for _, r := range replicas {
+		conns = append(conns, r.conn)
+	}
+	if masterLink != nil {
+		conns = append(conns, masterLink)
+	}
+	return conns
+}
+
+/*
+ * Replica side
+ */
+
+// States of the link to the master, as reported by ROLE
+const (
+	linkConnect    = "connect"    // Waiting to connect
+	linkConnecting = "connecting" // Handshake and transfer of the RDB file in progress
+	linkConnected  = "connected"  // Receiving the commands
+)
+
+// Replica state. masterHost is empty on a master. masterSync is the handshake in progress
+// in another goroutine, the ones of a previous master are ignored thanks to masterGen
+var (
+	masterHost         string
+	masterPort         int
+	masterLink         *connection.Connection
+	droppedLinks       []*connection.Connection // Links closed by the event loop once returned
+	masterLinkState    string
+	masterLastIO       time.Time
+	masterSync         chan *syncResult
+	masterGen          int
+	nextConnectAttempt time.Time
+	lastAckSent        time.Time
+)
+
+// syncResult is the outcome of a handshake with the master: the socket, non-blocking,
+// and for a full resynchronization the ID, offset and RDB file of the master
+type syncResult struct {
+	gen    int
+	fd     int
+	full   bool
+	replID string
+	offset int64
+	rdb    []byte
+	rest   []byte // Commands read after the RDB file
+	err    error
+}
+
+// replicationSetMaster replicates host:port from now on. The replicas are disconnected,
+// they resynchronize with the data of the new master
+func replicationSetMaster(host string, port int) {
+	dropMasterLink()
+	masterHost, masterPort = host, port
+	masterLinkState = linkConnect
+	nextConnectAttempt = time.Time{}
+	for _, r := range replicas {
+		r.conn.CloseASAP = true
+	}
+}
+
+// replicationUnsetMaster turns the replica into a master. Its replication ID becomes the
+// previous one, so the other replicas of its master continue from it
+func replicationUnsetMaster() {
+	dropMasterLink()
+	masterHost, masterPort, masterLinkState = "", 0, ""
+	replID2, secondReplOffset = replID, masterReplOffset+1
+	replID = newReplID()
+	replSelectedDB = -1
+}
+
+// dropMasterLink closes the link to the master and ignores the handshake in progress
+func dropMasterLink() {
+	if masterLink != nil {
+		masterLink.CloseASAP = true
+		masterLink.Master = false // The commands still buffered are not applied
+		droppedLinks = append(droppedLinks, masterLink)
+		masterLink = nil
+	}
+	masterSync = nil
+	masterGen++
+}
+
+// CheckReplication runs the periodic replication tasks, as Redis's replicationCron: a
+// master pings its replicas and starts the background save the new ones wait for, a
+// replica connects to its master and acknowledges the commands applied. It returns the
+// link to the master once the handshake is done, for the event loop to monitor
+func CheckReplication() *connection.Connection {
+	now := time.Now()
+	timeout := time.Duration(config.ReplTimeout) * time.Second
+	for _, r := range replicas {
+		if r.state == replicaOnline && now.Sub(r.ackTime) > timeout {
+			log.Println("Disconnecting timedout replica")
+			r.conn.CloseASAP = true
+		}
+	}
+	// A replica forwards the pings of its master, its replicas keep the offsets of the master
+	if masterHost == "" && len(replicas) > 0 && now.Sub(lastReplicasPing) >= time.Duration(config.ReplPingReplicaPeriod)*time.Second {
+		feedReplicationStream(aof.AppendCommand(nil, []string{"PING"}))
+		lastReplicasPing = now
+	}
+	for _, r := range replicas {
+		if r.state == replicaWaitBgsaveStart && bgsaveInFlight == nil && aofRewriteInFlight == nil {
+			rdbSaveBackground()
+			break
+		}
+	}
+
+	if masterHost == "" {
+		return nil
+	}
+	if masterLink != nil {
+		if now.Sub(masterLastIO) > timeout {
+			log.Println("MASTER timeout: no data nor PING received")
+			dropMasterLink()
+			masterLinkState = linkConnect
+		} else if now.Sub(lastAckSent) >= time.Second {
+			masterLink.AddReply(replconfAck())
+			lastAckSent = now
+		}
+		return nil
+	}
+	if masterSync == nil {
+		if now.Before(nextConnectAttempt) {
+			return nil
+		}
+		log.Printf("Connecting to MASTER %s:%d", masterHost, masterPort)
+		masterSync = make(chan *syncResult, 1)
+		masterLinkState = linkConnecting
+		go syncWithMaster(masterSync, masterGen, net.JoinHostPort(masterHost, strconv.Itoa(masterPort)),
+			replID, masterReplOffset+1, config.Port, timeout)
+		return nil
+	}
+
+	var result *syncResult
+	select {
+	case result = <-masterSync:
+	default:
+		return nil
+	}
+	masterSync = nil
+	if result.err != nil {
+		log.Println("Error syncing with MASTER:", result.err)
+		masterLinkState = linkConnect
+		nextConnectAttempt = now.Add(time.Second)
+		return nil
+	}
+	if result.gen != masterGen {
+		syscall.Close(result.fd)
+		return nil
+	}
+	if result.full {
+		if err := loadMasterRDB(result); err != nil {
+			log.Println("Failed trying to load the MASTER synchronization DB from socket:", err)
+			syscall.Close(result.fd)
+			masterLinkState = linkConnect
+			nextConnectAttempt = now.Add(time.Second)
+			return nil
+		}
+		log.Println("MASTER <-> REPLICA sync: Finished with success")
+	} else {
+		if result.replID != "" && result.replID != replID {
+			replID2, secondReplOffset = replID, masterReplOffset+1
+			replID = result.replID
+		}
+		log.Println("MASTER <-> REPLICA sync: Master accepted a Partial Resynchronization")
+	}
+
+	masterLink = connection.NewConnection(result.fd)
+	masterLink.Master = true
+	masterLink.QueryBuf = result.rest
+	masterLinkState, masterLastIO, lastAckSent = linkConnected, now, time.Time{}
+	if backlog == nil {
+		backlog = newReplBacklog(config.ReplBacklogSize)
+	}
+	return masterLink
+}
+
+// loadMasterRDB replaces the databases by the RDB file of the master and takes its
+// replication ID and offset. The append only file is written again from them
+func loadMasterRDB(result *syncResult) error {
+	waitBackgroundJobs() // A database has one snapshot at a time
+	for i := range databases {
+		databases[i] = newDatabase()
+	}
+	selectDB(0)
+	d := rdb.NewDecoder(bytes.NewReader(result.rdb))
+	if err := d.Decode(loadSnapshotEntry); err != nil {
+		return fmt.Errorf("offset %d: %w", d.Offset(), err)
+	}
+
+	replID, masterReplOffset = result.replID, result.offset
+	replID2, secondReplOffset = strings.Repeat("0", 40), -1
+	backlog = newReplBacklog(config.ReplBacklogSize)
+	for _, r := range replicas {
+		r.conn.CloseASAP = true // Their data is the one of the previous history
+	}
+	if aofFile != nil {
+		stopAppendOnly()
+		if err := startAppendOnly(); err != nil {
+			log.Println("Failed to restart the AOF after a full resynchronization:", err)
+		}
+	}
+	return nil
+}
+
+// FeedMasterStream accounts the bytes of the commands received from the master once
+// applied, and sends them to the replicas as received so their offsets match the ones of
+// the master
+func FeedMasterStream(data []byte) {
+	masterLastIO = time.Now()
+	if len(data) > 0 {
+		feedReplicationStream(data)
+		replSelectedDB = -1 // The database selected by the master is unknown
+	}
+}
+
+// replconfAck returns the REPLCONF ACK command sent to the master with the offset applied
+func replconfAck() []byte {
+	return aof.AppendCommand(nil, []string{"REPLCONF", "ACK", strconv.FormatInt(masterReplOffset, 10)})
+}
+
+// syncWithMaster runs the handshake with the master at addr in another goroutine: PING,
+// REPLCONF and PSYNC, asking to continue from offset of history id, then reads the RDB
+// file of a full resynchronization. The socket is then handed to the event loop
+func syncWithMaster(results chan<- *syncResult, gen int, addr, id string, offset int64, port int, timeout time.Duration) {
+	result := &syncResult{gen: gen}
+	defer func() { results <- result }()
+
+	c, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		result.err = err
+		return
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(timeout))
+	r := bufio.NewReader(c)
+
+	send := func(argv ...string) (string, error) {
+		if _, err := c.Write(aof.AppendCommand(nil, argv)); err != nil {
+			return "", err
+		}
+		return readLine(r)
+	}
+	if reply, err := send("PING"); err != nil || reply[0] == '-' {
+		result.err = handshakeError("PING", reply, err)
+		return
+	}
+	if reply, err := send("REPLCONF", "listening-port", strconv.Itoa(port)); err != nil || reply[0] == '-' {
+		result.err = handshakeError("REPLCONF listening-port", reply, err)
+		return
+	}
+	if _, err := send("REPLCONF", "capa", "psync2"); err != nil {
+		result.err = err
+		return
+	}
+	reply, err := send("PSYNC", id, strconv.FormatInt(offset, 10))
+	if err != nil || reply[0] == '-' {
+		result.err = handshakeError("PSYNC", reply, err)
+		return
+	}
+
+	fields := strings.Fields(reply)
+	switch {
+	case fields[0] == "+FULLRESYNC" && len(fields) == 3:
+		result.full, result.replID = true, fields[1]
+		if result.offset, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+			result.err = fmt.Errorf("invalid FULLRESYNC reply %q", reply)
+			return
+		}
+		if result.rdb, err = readPayload(c, r, timeout); err != nil {
+			result.err = err
+			return
+		}
+	case fields[0] == "+CONTINUE":
+		if len(fields) > 1 {
+			result.replID = fields[1]
+		}
+	default:
+		result.err = fmt.Errorf("unexpected reply to PSYNC %q", reply)
+		return
+	}
+
+	result.rest, _ = r.Peek(r.Buffered())
+	result.rest = append([]byte(nil), result.rest...)
+	result.fd, result.err = detachSocket(c)
+}
+
+// readLine reads a reply line without its CRLF, skipping the empty lines a master sends
+// to keep the link alive while it prepares the RDB file
+func readLine(r *bufio.Reader) (string, error) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			return line, nil
+		}
+	}
+}
+
+// readPayload reads the RDB file of a full resynchronization, sent as a bulk string
+// without its CRLF
+func readPayload(c net.Conn, r *bufio.Reader, timeout time.Duration) ([]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line[0] != '$' {
+		return nil, fmt.Errorf("bad protocol from MASTER, the first byte is not '$': %q", line)
+	}
+	size, err := strconv.Atoi(line[1:])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid RDB file size %q", line)
+	}
+	log.Printf("MASTER <-> REPLICA sync: receiving %d bytes from master", size)
+	c.SetDeadline(time.Now().Add(timeout + time.Duration(size/(1024*1024))*time.Second))
+	payload := make([]byte, size)
+	_, err = io.ReadFull(r, payload)
+	return payload, err
+}
+
+func handshakeError(step, reply string, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("error reply to %s: %s", step, reply)
+}
+
+// detachSocket returns a non-blocking duplicate of the socket of c, which can be closed
+func detachSocket(c net.Conn) (int, error) {
+	tcp, ok := c.(*net.TCPConn)
+	if !ok {
+		return 0, errors.New("not a TCP connection")
+	}
+	raw, err := tcp.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	fd := -1
+	var dupErr error
+	if err := raw.Control(func(s uintptr) { fd, dupErr = syscall.Dup(int(s)) }); err != nil {
+		return 0, err
+	}
+	if dupErr != nil {
+		return 0, dupErr
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return 0, err
+	}
+	return fd, nil
+}
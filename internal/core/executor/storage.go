@@ -1,35 +1,282 @@
 package executor
 
 import (
+	"redis-repo/internal/config"
 	"redis-repo/internal/constant"
 	"redis-repo/internal/data_structure"
+	"strconv"
 	"time"
 )
 
-var dict *data_structure.Dict
-var setStore map[string]data_structure.Set
+// databases are the logical databases, each one a keyspace holding every key whatever
+// the type of its value. dict is the database selected by the client whose command runs,
+// selectedDB its index
+var (
+	databases  []*data_structure.Dict
+	dict       *data_structure.Dict
+	selectedDB int
+)
 
 func init() {
-	dict = data_structure.NewDict()
-	setStore = make(map[string]data_structure.Set)
+	databases = make([]*data_structure.Dict, config.Databases)
+	for i := range databases {
+		databases[i] = newDatabase()
+	}
+	expireCursors = make([]uint64, config.Databases)
+	selectDB(0)
+}
+
+// newDatabase creates an empty database. The keys found expired by a lookup are deleted
+// from the AOF and the replicas too, like the ones deleted by the active expiry cycle
+func newDatabase() *data_structure.Dict {
+	db := data_structure.NewDict()
+	db.SetExpireHook(func(key string) {
+		for id := range databases {
+			if databases[id] == db {
+				propagateExpired(id, key)
+			}
+		}
+	})
+	return db
+}
+
+// selectDB makes database id the keyspace of the commands, it returns false when id is
+// out of range
+func selectDB(id int) bool {
+	if id < 0 || id >= len(databases) {
+		return false
+	}
+	selectedDB = id
+	dict = databases[id]
+	return true
+}
+
+// parseDBIndex parses the index of a database, replying an error when it is not an
+// integer or out of range
+func parseDBIndex(s string) (int, []byte) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, []byte(constant.ErrNotInteger)
+	}
+	if id < 0 || id >= len(databases) {
+		return 0, []byte(constant.ErrDBOutOfRange)
+	}
+	return id, nil
+}
+
+// storeObject stores obj at key in db with an expiry time, 0 for none, keeping track of
+// the fields with an expiry of a hash. Used to move or copy a key between databases
+func storeObject(db *data_structure.Dict, key string, obj *data_structure.ValueObject, expiryTimeMs uint64) {
+	db.Set(key, obj, expiryTimeMs)
+	if hash, ok := obj.Value.(*data_structure.Hash); ok && hash.HasFieldExpiry() {
+		db.TrackFieldExpiry(key)
+	}
+}
+
+// lookupKeyRead returns the object stored at key for a read, nil if the key does not exist.
+// The access is recorded in the statistics of the object
+func lookupKeyRead(key string) *data_structure.ValueObject {
+	obj := dict.Get(key)
+	if obj != nil {
+		obj.Touch()
+	}
+	return obj
+}
+
+// lookupKeyWrite returns the object stored at key before modifying it, nil if the key does not exist
+func lookupKeyWrite(key string) *data_structure.ValueObject {
+	return lookupKeyRead(key)
+}
+
+// lookupKeyNoTouch returns the object stored at key without recording an access, for
+// introspection commands such as OBJECT and TYPE
+func lookupKeyNoTouch(key string) *data_structure.ValueObject {
+	return dict.Get(key)
+}
+
+// getString returns the string stored at key, ok is false when the key holds another type
+func getString(key string) (value string, exists bool, ok bool) {
+	obj := lookupKeyRead(key)
+	if obj == nil {
+		return "", false, true
+	}
+	if obj.Type != data_structure.ObjString {
+		return "", true, false
+	}
+	return obj.StringValue(), true, true
+}
+
+// getList returns the list stored at key, nil if the key does not exist.
+// ok is false when the key holds another type
+func getList(key string) (list *data_structure.Quicklist, ok bool) {
+	return listFromObject(lookupKeyRead(key))
+}
+
+// getListForWrite is getList for commands about to modify the list
+func getListForWrite(key string) (list *data_structure.Quicklist, ok bool) {
+	return listFromObject(lookupKeyWrite(key))
+}
+
+func listFromObject(obj *data_structure.ValueObject) (*data_structure.Quicklist, bool) {
+	if obj == nil {
+		return nil, true
+	}
+	if obj.Type != data_structure.ObjList {
+		return nil, false
+	}
+	return obj.Value.(*data_structure.Quicklist), true
+}
+
+// newList creates an empty list with the configured node size
+func newList() *data_structure.Quicklist {
+	return data_structure.NewQuicklist(config.ListMaxListpackSize)
+}
+
+// deleteIfEmpty deletes key once the collection it holds has no elements left,
+// as Redis never keeps empty collections in the keyspace
+func deleteIfEmpty(key string, length int) {
+	if length == 0 {
+		dict.Delete(key)
+	}
+}
+
+// getZSet returns the sorted set stored at key, nil if the key does not exist.
+// ok is false when the key holds another type
+func getZSet(key string) (zset *data_structure.ZSet, ok bool) {
+	return zsetFromObject(lookupKeyRead(key))
+}
+
+// getZSetForWrite is getZSet for commands about to modify the sorted set
+func getZSetForWrite(key string) (zset *data_structure.ZSet, ok bool) {
+	return zsetFromObject(lookupKeyWrite(key))
+}
+
+func zsetFromObject(obj *data_structure.ValueObject) (*data_structure.ZSet, bool) {
+	if obj == nil {
+		return nil, true
+	}
+	if obj.Type != data_structure.ObjZSet {
+		return nil, false
+	}
+	return obj.Value.(*data_structure.ZSet), true
+}
+
+// getScoredMembers returns the sorted set or the plain set stored at key as an input of
+// the sorted set algebra, an empty sorted set if the key does not exist.
+// ok is false when the key holds another type
+func getScoredMembers(key string) (members data_structure.ScoredMembers, ok bool) {
+	obj := lookupKeyRead(key)
+	if obj == nil {
+		return data_structure.NewZSet(), true
+	}
+	switch obj.Type {
+	case data_structure.ObjZSet:
+		return obj.Value.(*data_structure.ZSet), true
+	case data_structure.ObjSet:
+		return data_structure.SetScores(obj.Value.(*data_structure.Set)), true
+	}
+	return nil, false
+}
+
+// getHash returns the hash stored at key, nil if the key does not exist.
+// ok is false when the key holds another type
+func getHash(key string) (hash *data_structure.Hash, ok bool) {
+	return hashFromObject(key, lookupKeyRead(key))
+}
+
+// getHashForWrite is getHash for commands about to modify the hash
+func getHashForWrite(key string) (hash *data_structure.Hash, ok bool) {
+	return hashFromObject(key, lookupKeyWrite(key))
 }
 
-// Clean some expired keys, follows Redis's solution
+// hashFromObject returns the hash of obj after removing its expired fields, which are
+// deleted from the AOF and the replicas too. The key is deleted when no field is left
+func hashFromObject(key string, obj *data_structure.ValueObject) (*data_structure.Hash, bool) {
+	if obj == nil {
+		return nil, true
+	}
+	if obj.Type != data_structure.ObjHash {
+		return nil, false
+	}
+
+	hash := obj.Value.(*data_structure.Hash)
+	if expired := hash.DeleteExpired(uint64(time.Now().UnixMilli())); len(expired) > 0 {
+		propagateExpiredFields(selectedDB, key, expired)
+		if hash.Len() == 0 {
+			dict.Delete(key)
+			return nil, true
+		}
+		dict.UpdateMemory(key)
+	}
+	return hash, true
+}
+
+// getSet returns the set stored at key, nil if the key does not exist.
+// ok is false when the key holds another type
+func getSet(key string) (set *data_structure.Set, ok bool) {
+	return setFromObject(lookupKeyRead(key))
+}
+
+// getSetForWrite is getSet for commands about to modify the set
+func getSetForWrite(key string) (set *data_structure.Set, ok bool) {
+	return setFromObject(lookupKeyWrite(key))
+}
+
+func setFromObject(obj *data_structure.ValueObject) (*data_structure.Set, bool) {
+	if obj == nil {
+		return nil, true
+	}
+	if obj.Type != data_structure.ObjSet {
+		return nil, false
+	}
+	return obj.Value.(*data_structure.Set), true
+}
+
+// expireCursors are where the next cleanup resumes scanning the keys with an expiry of
+// each database, expireDB the database it starts with
+var (
+	expireCursors []uint64
+	expireDB      int
+)
+
+// Clean some expired keys of every database, follows Redis's solution. The databases share
+// the time limit, so each cleanup starts with the database following the last one cleaned
 func CleanupExpiredKeys() {
-	deleted, total := 0, 0
+	current := selectedDB
+	defer selectDB(current)
+
 	startTime := time.Now().UnixMilli()
+	for range databases {
+		if time.Now().UnixMilli()-startTime > constant.ActiveCleanupTimeLimit {
+			break
+		}
+		selectDB(expireDB)
+		cleanupExpiredKeys(&expireCursors[expireDB], startTime)
+		expireDB = (expireDB + 1) % len(databases)
+	}
+}
 
-	dict.IterateExpiredKeys(func(key string, expiryTime uint64) bool {
-		if dict.HasExpired(key) {
+// cleanupExpiredKeys cleans the selected database, resuming the scan at cursor
+func cleanupExpiredKeys(cursor *uint64, startTime int64) {
+	deleted, total := 0, 0
+	for {
+		var expired []string
+		*cursor = dict.ScanExpiredKeys(*cursor, func(key string, expiryTime uint64) {
+			if dict.HasExpired(key) {
+				expired = append(expired, key)
+			}
+			total++
+		})
+		for _, key := range expired {
 			dict.Delete(key)
-			deleted++
+			propagateExpired(selectedDB, key)
 		}
-		total++
+		deleted += len(expired)
 
 		// Check batches using a sample size, and stop the cleanup once the ratio of expired keys is within the acceptable range
-		if total == constant.ActiveCleanupSampleSize {
-			if float64(deleted/total) < constant.ActiveCleanupAcceptedExpiredProportion {
-				return false // Stop iteration
+		if total >= constant.ActiveCleanupSampleSize {
+			if float64(deleted)/float64(total) < constant.ActiveCleanupAcceptedExpiredProportion {
+				break
 			}
 
 			// Reset variables to continue clean up
@@ -37,12 +284,29 @@ func CleanupExpiredKeys() {
 			deleted = 0
 		}
 
-		// Ensure the time for active clean up does not take a lot
-		now := time.Now().UnixMilli()
-		if now-startTime > constant.ActiveCleanupTimeLimit {
-			return false // Stop iteration
+		// Stop after a whole pass, and ensure the time for active clean up does not take a lot
+		if *cursor == 0 || time.Now().UnixMilli()-startTime > constant.ActiveCleanupTimeLimit {
+			break
+		}
+	}
+
+	// Then remove the expired fields of hashes, within the same time limit
+	dict.IterateFieldExpiryKeys(func(key string) bool {
+		hash, _ := hashFromObject(key, dict.Get(key))
+		if hash == nil || !hash.HasFieldExpiry() {
+			dict.UntrackFieldExpiry(key) // Deleted, overwritten or without expiring fields left
 		}
 
-		return true // Continue iteration
+		return time.Now().UnixMilli()-startTime <= constant.ActiveCleanupTimeLimit
 	})
 }
+
+// RehashKeyspace moves some buckets of a database being resized, so its rehashing
+// completes even when no command touches it. Like Redis, one database is rehashed per call
+func RehashKeyspace() {
+	for _, db := range databases {
+		if db.Rehash(constant.ActiveRehashTimeLimit * time.Millisecond) {
+			return
+		}
+	}
+}
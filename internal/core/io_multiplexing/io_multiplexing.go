@@ -28,12 +28,18 @@ func (ep *Epoll) Monitor(epEvent syscall.EpollEvent) error {
 	return syscall.EpollCtl(ep.fd, syscall.EPOLL_CTL_ADD, int(epEvent.Fd), &epEvent)
 }
 
+// Modify replaces the events monitored for an already registered file descriptor
+func (ep *Epoll) Modify(epEvent syscall.EpollEvent) error {
+	return syscall.EpollCtl(ep.fd, syscall.EPOLL_CTL_MOD, int(epEvent.Fd), &epEvent)
+}
+
 func (ep *Epoll) Remove(fd int) error {
 	return syscall.EpollCtl(ep.fd, syscall.EPOLL_CTL_DEL, fd, nil)
 }
 
-func (ep *Epoll) Wait() ([]syscall.EpollEvent, error) {
-	n, err := syscall.EpollWait(ep.fd, ep.epollEvents, -1)
+// Wait blocks until events are ready or timeoutMs milliseconds passed, -1 to wait forever
+func (ep *Epoll) Wait(timeoutMs int) ([]syscall.EpollEvent, error) {
+	n, err := syscall.EpollWait(ep.fd, ep.epollEvents, timeoutMs)
 	if err != nil {
 		return nil, err
 	}
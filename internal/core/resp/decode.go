@@ -6,6 +6,13 @@ import (
 	"log"
 )
 
+// ErrIncomplete reports that the data ends before a complete RESP value,
+// more bytes are needed before decoding can succeed
+var ErrIncomplete = errors.New("incomplete RESP data")
+
+// errorContext is the number of bytes of Data around Position shown by DecodingError
+const errorContext = 16
+
 // DecodingError represents an error that occurred during decoding
 type DecodingError struct {
 	Position int
@@ -13,8 +20,20 @@ type DecodingError struct {
 	Err      error
 }
 
+// Error quotes only the bytes around Position, Data may be a whole AOF file
 func (e *DecodingError) Error() string {
-	return fmt.Sprintf("decoding error at position %d: %v (data: %q)", e.Position, e.Err, e.Data)
+	start := max(0, min(e.Position, len(e.Data))-errorContext)
+	end := min(len(e.Data), max(0, e.Position)+errorContext)
+	return fmt.Sprintf("decoding error at position %d: %v (data: %q)", e.Position, e.Err, e.Data[start:end])
+}
+
+func (e *DecodingError) Unwrap() error {
+	return e.Err
+}
+
+// IsIncomplete reports whether err was caused by data ending in the middle of a value
+func IsIncomplete(err error) bool {
+	return errors.Is(err, ErrIncomplete)
 }
 
 // DecodeResult represents the result of decoding a RESP value
@@ -27,7 +46,7 @@ type DecodeResult struct {
 // Example: :-5\r\n => -5
 func readInteger(data []byte) (*DecodeResult, error) {
 	if len(data) < 3 {
-		return nil, &DecodingError{Position: 0, Data: data, Err: errors.New("insufficient data for integer")}
+		return nil, &DecodingError{Position: 0, Data: data, Err: fmt.Errorf("insufficient data for integer: %w", ErrIncomplete)}
 	}
 
 	pos := 1
@@ -58,7 +77,7 @@ func readInteger(data []byte) (*DecodeResult, error) {
 // Example: -Example Error\r\n => Example Error
 func readError(data []byte) (*DecodeResult, error) {
 	if len(data) < 3 {
-		return nil, &DecodingError{Position: 0, Data: data, Err: errors.New("insufficient data for error")}
+		return nil, &DecodingError{Position: 0, Data: data, Err: fmt.Errorf("insufficient data for error: %w", ErrIncomplete)}
 	}
 
 	pos := 1
@@ -66,7 +85,10 @@ func readError(data []byte) (*DecodeResult, error) {
 		pos++
 	}
 
-	if pos >= len(data) || pos+1 >= len(data) || data[pos+1] != LineFeedByte {
+	if pos >= len(data) || pos+1 >= len(data) {
+		return nil, &DecodingError{Position: pos, Data: data, Err: fmt.Errorf("missing CRLF terminator: %w", ErrIncomplete)}
+	}
+	if data[pos+1] != LineFeedByte {
 		return nil, &DecodingError{Position: pos, Data: data, Err: errors.New("missing CRLF terminator")}
 	}
 
@@ -80,7 +102,7 @@ func readError(data []byte) (*DecodeResult, error) {
 // Example: +Hello world\r\n => Hello world
 func readSimpleString(data []byte) (*DecodeResult, error) {
 	if len(data) < 3 {
-		return nil, &DecodingError{Position: 0, Data: data, Err: errors.New("insufficient data for simple string")}
+		return nil, &DecodingError{Position: 0, Data: data, Err: fmt.Errorf("insufficient data for simple string: %w", ErrIncomplete)}
 	}
 
 	pos := 1
@@ -88,7 +110,10 @@ func readSimpleString(data []byte) (*DecodeResult, error) {
 		pos++
 	}
 
-	if pos >= len(data) || pos+1 >= len(data) || data[pos+1] != LineFeedByte {
+	if pos >= len(data) || pos+1 >= len(data) {
+		return nil, &DecodingError{Position: pos, Data: data, Err: fmt.Errorf("missing CRLF terminator: %w", ErrIncomplete)}
+	}
+	if data[pos+1] != LineFeedByte {
 		return nil, &DecodingError{Position: pos, Data: data, Err: errors.New("missing CRLF terminator")}
 	}
 
@@ -102,7 +127,7 @@ func readSimpleString(data []byte) (*DecodeResult, error) {
 // Example: $9\r\nhello\r\n\r\n => hello\r\n
 func readBulkString(data []byte) (*DecodeResult, error) {
 	if len(data) < 5 {
-		return nil, &DecodingError{Position: 0, Data: data, Err: errors.New("insufficient data for bulk string")}
+		return nil, &DecodingError{Position: 0, Data: data, Err: fmt.Errorf("insufficient data for bulk string: %w", ErrIncomplete)}
 	}
 
 	pos := 1
@@ -119,10 +144,14 @@ func readBulkString(data []byte) (*DecodeResult, error) {
 			Length: pos,
 		}, nil
 	}
+	if length < 0 {
+		return nil, &DecodingError{Position: 1, Data: data, Err: fmt.Errorf("invalid bulk string length %d", length)}
+	}
 
-	// Check if we have enough data for the string
-	if pos+int(length)+2 > len(data) {
-		return nil, &DecodingError{Position: pos, Data: data, Err: errors.New("insufficient data for bulk string content")}
+	// Check if we have enough data for the string, without computing pos+length that a
+	// huge length overflows
+	if length > int64(len(data)-pos-2) {
+		return nil, &DecodingError{Position: pos, Data: data, Err: fmt.Errorf("insufficient data for bulk string content: %w", ErrIncomplete)}
 	}
 
 	// Verify CRLF terminator
@@ -140,7 +169,7 @@ func readBulkString(data []byte) (*DecodeResult, error) {
 // Example: *3\r\n$5\r\nhello\r\n$5\r\nworld\r\n:+25\r\n => ['hello', 'world', 25]
 func readArray(data []byte) (*DecodeResult, error) {
 	if len(data) < 4 {
-		return nil, &DecodingError{Position: 0, Data: data, Err: errors.New("insufficient data for array")}
+		return nil, &DecodingError{Position: 0, Data: data, Err: fmt.Errorf("insufficient data for array: %w", ErrIncomplete)}
 	}
 
 	pos := 1
@@ -157,14 +186,22 @@ func readArray(data []byte) (*DecodeResult, error) {
 			Length: pos,
 		}, nil
 	}
+	if length < 0 {
+		return nil, &DecodingError{Position: 1, Data: data, Err: fmt.Errorf("invalid array length %d", length)}
+	}
 
-	arrResult := make([]any, length)
-	for i := range arrResult {
+	// The declared length is untrusted until the elements arrive, so cap the preallocation
+	arrResult := make([]any, 0, min(length, maxArrayPrealloc))
+	for i := 0; i < int(length); i++ {
 		result, err := decode(data[pos:])
 		if err != nil {
+			if IsIncomplete(err) {
+				// Cheap error: partial frames are retried on every read
+				return nil, &DecodingError{Position: pos, Data: data, Err: fmt.Errorf("array element %d: %w", i, ErrIncomplete)}
+			}
 			return nil, &DecodingError{Position: pos, Data: data, Err: fmt.Errorf("failed to decode array element %d: %w, current result arrResult: %s", i, err, arrResult)}
 		}
-		arrResult[i] = result.Value
+		arrResult = append(arrResult, result.Value)
 		pos += result.Length
 	}
 
@@ -178,7 +215,7 @@ func readArray(data []byte) (*DecodeResult, error) {
 // Example: 5\r\n => (5, 3), -1\r\n => (-1, 4)
 func extractNumber(data []byte) (int64, int, error) {
 	if len(data) == 0 {
-		return 0, 0, errors.New("empty data")
+		return 0, 0, fmt.Errorf("empty data: %w", ErrIncomplete)
 	}
 
 	pos := 0
@@ -202,11 +239,18 @@ func extractNumber(data []byte) (int64, int, error) {
 		pos++
 	}
 
+	if pos >= len(data) {
+		return 0, 0, fmt.Errorf("missing CRLF terminator: %w", ErrIncomplete)
+	}
+
 	if !hasDigit {
 		return 0, 0, errors.New("no digits found in number")
 	}
 
-	if pos >= len(data) || pos+1 >= len(data) || data[pos+1] != LineFeedByte {
+	if pos >= len(data) || pos+1 >= len(data) {
+		return 0, 0, fmt.Errorf("missing CRLF terminator: %w", ErrIncomplete)
+	}
+	if data[pos+1] != LineFeedByte {
 		return 0, 0, errors.New("missing CRLF terminator")
 	}
 
@@ -216,7 +260,7 @@ func extractNumber(data []byte) (int64, int, error) {
 // decode decodes a single RESP value from the given data
 func decode(data []byte) (*DecodeResult, error) {
 	if len(data) == 0 {
-		return nil, &DecodingError{Position: 0, Data: data, Err: errors.New("empty data")}
+		return nil, &DecodingError{Position: 0, Data: data, Err: fmt.Errorf("empty data: %w", ErrIncomplete)}
 	}
 
 	sign := data[0]
@@ -260,3 +304,10 @@ func Decode(data []byte) (any, error) {
 	}
 	return result.Value, nil
 }
+
+// DecodeNext decodes the first RESP value in data and reports how many bytes it used,
+// so callers holding several pipelined values can advance to the next one.
+// When data ends before the value is complete the error satisfies IsIncomplete
+func DecodeNext(data []byte) (*DecodeResult, error) {
+	return decode(data)
+}
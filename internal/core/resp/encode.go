@@ -73,6 +73,10 @@ func encodeArray(arr []any) ([]byte, error) {
 
 func encode(data any) ([]byte, error) {
 	switch v := data.(type) {
+	case nil:
+		return RespNil, nil
+	case SimpleString:
+		return EncodeSimpleString(string(v)), nil
 	case int, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
 		return encodeInteger(convertToInt64(v))
 	case string:
@@ -93,6 +97,8 @@ func encode(data any) ([]byte, error) {
 // - string: encoded as bulk string (e.g., "hello" -> $5\r\nhello\r\n)
 // - error: encoded as error (e.g., errors.New("msg") -> -msg\r\n)
 // - []any: encoded as array (e.g., []any{"hello", 42} -> *2\r\n$5\r\nhello\r\n:42\r\n)
+// - SimpleString: encoded as simple string (e.g., SimpleString("OK") -> +OK\r\n)
+// - nil: encoded as nil bulk string ($-1\r\n)
 func Encode(data any) []byte {
 	result, err := encode(data)
 	if err != nil {
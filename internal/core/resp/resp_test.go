@@ -3,6 +3,7 @@ package resp
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -218,6 +219,7 @@ func TestDecodeBulkString(t *testing.T) {
 		{"string with CRLF", "$12\r\nhello\r\nworld\r\n", "hello\r\nworld", false},
 		{"invalid length", "$abc\r\nhello\r\n", nil, true},
 		{"insufficient data", "$10\r\nhello\r\n", nil, true},
+		{"huge length", "$9223372036854775806\r\nhello\r\n", nil, true},
 	}
 
 	for _, tt := range tests {
@@ -240,6 +242,20 @@ func TestDecodeBulkString(t *testing.T) {
 	}
 }
 
+func TestDecodingErrorMessage(t *testing.T) {
+	data := []byte(strings.Repeat("a", 100) + "$9223372036854775806\r\n" + strings.Repeat("b", 100))
+	_, err := Decode(data[100:])
+	if !IsIncomplete(err) {
+		t.Fatalf("Expected an incomplete bulk string, got %v", err)
+	}
+
+	err = &DecodingError{Position: 100, Data: data, Err: errors.New("invalid")}
+	expected := `decoding error at position 100: invalid (data: "aaaaaaaaaaaaaaaa$922337203685477")`
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
 func TestDecodeError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -410,3 +426,43 @@ func TestEncodeErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		length     int
+		incomplete bool
+		hasError   bool
+	}{
+		{"single array", "*1\r\n$4\r\nPING\r\n", 14, false, false},
+		{"pipelined arrays", "*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nPING\r\n", 14, false, false},
+		{"partial bulk string", "*2\r\n$3\r\nGET\r\n$5\r\nhel", 0, true, true},
+		{"partial length", "*2\r\n$3\r\nGET\r\n$1", 0, true, true},
+		{"missing line feed", "*1\r", 0, true, true},
+		{"empty data", "", 0, true, true},
+		{"invalid length", "*1\r\n$x\r\nPING\r\n", 0, false, true},
+		{"negative bulk length", "$-5\r\nhello\r\n", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DecodeNext([]byte(tt.input))
+			if tt.hasError {
+				if err == nil {
+					t.Fatalf("Expected error but got none")
+				}
+				if IsIncomplete(err) != tt.incomplete {
+					t.Errorf("Expected incomplete=%v, got error %v", tt.incomplete, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.Length != tt.length {
+				t.Errorf("Expected length %d, got %d", tt.length, result.Length)
+			}
+		})
+	}
+}
@@ -7,12 +7,19 @@ const (
 	CRLFString         = "\r\n"
 )
 
+// maxArrayPrealloc bounds the capacity reserved up front when decoding an array
+const maxArrayPrealloc = 1024
+
 // CRLFBytes represents the CRLF sequence as bytes
 var CRLFBytes = []byte{CarriageReturnByte, LineFeedByte}
 
 // Pre-encoded RESP nil value
 var RespNil = []byte("$-1\r\n")
 
+// SimpleString marks a string to be encoded as a simple string instead of a bulk string,
+// e.g. status values nested in an array
+type SimpleString string
+
 // RESP data types
 type DataType struct {
 	Name string
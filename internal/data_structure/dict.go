@@ -4,19 +4,31 @@ import (
 	"time"
 )
 
-type ValueObject struct {
-	Value any
+type Dict struct {
+	dictStore        *HashTable[*ValueObject]
+	expiredDictStore *HashTable[uint64]
+	fieldExpiryStore map[string]struct{} // Keys of hashes that may have fields with an expiry
+	usedMemory       int64               // Bytes of the keys and their values, see UsedMemory
+	snapshot         uint64              // Snapshot in progress, 0 for none, see Snapshot
+	onExpire         func(key string)    // Called when Get deletes an expired key, see SetExpireHook
 }
 
-type Dict struct {
-	dictStore        map[string]*ValueObject
-	expiredDictStore map[string]uint64
+// SnapshotEntry is a key as it was when the snapshot of its Dict was taken
+type SnapshotEntry struct {
+	Key          string
+	Value        *ValueObject
+	ExpiryTimeMs uint64 // 0 for none
 }
 
+// lastSnapshot numbers the snapshots of every Dict, so the objects of an old snapshot are
+// not mistaken for the ones of the current one
+var lastSnapshot uint64
+
 func NewDict() *Dict {
 	return &Dict{
-		dictStore:        make(map[string]*ValueObject),
-		expiredDictStore: make(map[string]uint64),
+		dictStore:        NewHashTable[*ValueObject](),
+		expiredDictStore: NewHashTable[uint64](),
+		fieldExpiryStore: make(map[string]struct{}),
 	}
 }
 
@@ -24,16 +36,39 @@ func NewDict() *Dict {
  * Dictionary implementation
  */
 
+// SetExpireHook sets a function called with the key that Get finds expired, once deleted
+func (d *Dict) SetExpireHook(fn func(key string)) {
+	d.onExpire = fn
+}
+
+// Get returns the object stored at key, nil when the key does not exist or has expired.
+// A value still read by a snapshot is copied first, so the caller may modify the object
 func (d *Dict) Get(key string) *ValueObject {
-	v := d.dictStore[key]
+	v, _ := d.dictStore.Get(key)
 	if v != nil && d.HasExpired(key) {
 		d.Delete(key)
+		if d.onExpire != nil {
+			d.onExpire(key)
+		}
 		return nil
 	}
 
+	if v != nil && d.isShared(v) {
+		v = v.cow()
+		d.dictStore.Set(key, v)
+	}
+	return v
+}
+
+// Peek returns the object stored at key without copying a value read by a snapshot,
+// so only the type and the access statistics of the object may be used
+func (d *Dict) Peek(key string) *ValueObject {
+	v, _ := d.dictStore.Get(key)
 	return v
 }
 
+// Set stores the value at key, replacing any previous value and expiry.
+// The value is either a *ValueObject or a Go value accepted by NewValueObject
 func (d *Dict) Set(key string, value any, expiryTimeMs uint64) {
 	d.SetDictStore(key, value)
 	if expiryTimeMs > 0 {
@@ -44,42 +79,201 @@ func (d *Dict) Set(key string, value any, expiryTimeMs uint64) {
 }
 
 func (d *Dict) Delete(key string) bool {
-	if _, exist := d.dictStore[key]; !exist {
+	obj, ok := d.dictStore.Get(key)
+	if !ok {
 		return false
 	}
-	delete(d.dictStore, key)
+	d.dictStore.Delete(key)
+	d.usedMemory -= obj.memory
 	d.DeleteExpiry(key)
+	d.UntrackFieldExpiry(key)
 	return true
 }
 
+// Unlink deletes key like Delete and returns its object for the caller to free, nil when
+// the value is still read by the snapshot in progress and must be left as it is
+func (d *Dict) Unlink(key string) (*ValueObject, bool) {
+	obj, ok := d.dictStore.Get(key)
+	if !ok {
+		return nil, false
+	}
+	d.Delete(key)
+	if d.isShared(obj) {
+		return nil, true
+	}
+	return obj, true
+}
+
+// InSnapshot reports whether a snapshot of the dictionary is in progress
+func (d *Dict) InSnapshot() bool {
+	return d.snapshot != 0
+}
+
+// Free releases every key and value of a dictionary no longer used, as Redis's
+// emptyDbAsync. It must not be called while a snapshot reads it, see InSnapshot
+func (d *Dict) Free() {
+	d.dictStore.Iterate(func(_ string, obj *ValueObject) bool {
+		obj.Free()
+		return true
+	})
+	d.dictStore.free()
+	d.expiredDictStore.free()
+	d.fieldExpiryStore = nil
+}
+
+// SetDictStore stores the value at key, keeping any expiry already set on it
 func (d *Dict) SetDictStore(key string, value any) {
-	d.dictStore[key] = &ValueObject{value}
+	obj := NewValueObject(value)
+	if old, _ := d.dictStore.Get(key); old != nil {
+		d.usedMemory -= old.memory
+	}
+	obj.memory = 0 // The object may come from another key or database, which accounted it
+	d.account(key, obj)
+	d.dictStore.Set(key, obj)
+}
+
+// Len returns the number of keys, including the expired ones not deleted yet
+func (d *Dict) Len() int {
+	return d.dictStore.Len()
+}
+
+// Scan calls fn on the keys of one bucket of the keyspace and returns the next cursor,
+// see HashTable.Scan. Expired keys are skipped
+func (d *Dict) Scan(cursor uint64, fn func(key string, value *ValueObject)) uint64 {
+	return d.dictStore.Scan(cursor, func(key string, value *ValueObject) {
+		if !d.HasExpired(key) {
+			fn(key, value)
+		}
+	})
+}
+
+// RandomKey returns a random key, maybe an expired one not deleted yet
+func (d *Dict) RandomKey() (string, bool) {
+	return d.dictStore.RandomKey()
+}
+
+// Iterate calls fn on every key until fn returns false, fn must not modify the keyspace.
+// Expired keys are skipped
+func (d *Dict) Iterate(fn func(key string, value *ValueObject) bool) {
+	d.dictStore.Iterate(func(key string, value *ValueObject) bool {
+		return d.HasExpired(key) || fn(key, value)
+	})
+}
+
+// Rehash spends up to duration moving the buckets of the keyspace or, once the keyspace
+// is not being resized, of the expires. It returns false when neither is being resized
+func (d *Dict) Rehash(duration time.Duration) bool {
+	switch {
+	case d.dictStore.IsRehashing():
+		d.dictStore.Rehash(duration)
+	case d.expiredDictStore.IsRehashing():
+		d.expiredDictStore.Rehash(duration)
+	default:
+		return false
+	}
+	return true
+}
+
+// Stats describes the hash tables of the keyspace and of the expires, see HashTable.Stats
+func (d *Dict) Stats(full bool) string {
+	return "[Dictionary HT]\n" + d.dictStore.Stats(full) + "[Expires HT]\n" + d.expiredDictStore.Stats(full)
+}
+
+/*
+ * Memory accounting
+ */
+
+// account estimates the size of key holding obj, replacing what was accounted for it before
+func (d *Dict) account(key string, obj *ValueObject) {
+	size := KeyMemoryUsage(key, obj, DefaultMemorySamples)
+	d.usedMemory += size - obj.memory
+	obj.memory = size
+}
+
+// UpdateMemory estimates again the size of key, after its value was modified in place.
+// A value read by a snapshot has not been modified, so it is not visited
+func (d *Dict) UpdateMemory(key string) {
+	if obj, _ := d.dictStore.Get(key); obj != nil && !d.isShared(obj) {
+		d.account(key, obj)
+	}
+}
+
+// UsedMemory returns the estimated bytes used by the dictionary, the sum of the estimated
+// size of every key when it was last stored or updated plus the overhead of the hash tables
+func (d *Dict) UsedMemory() int64 {
+	main, expires := d.MemoryOverhead()
+	return d.usedMemory + main + expires
+}
+
+// MemoryOverhead returns the bytes used by the hash table of the keyspace without its
+// entries, and by the hash table of the expires
+func (d *Dict) MemoryOverhead() (main int64, expires int64) {
+	expires = d.expiredDictStore.bucketsMemory() + int64(d.expiredDictStore.Len())*(hashTableEntryOverhead+stringOverhead+8)
+	return d.dictStore.bucketsMemory(), expires
+}
+
+/*
+ * Snapshots
+ */
+
+// Snapshot returns every key that has not expired with its value and expiry, for a
+// point-in-time copy of the dictionary saved in the background. Only the index of the
+// keys is copied: the values are shared with the snapshot until ReleaseSnapshot, and
+// Get copies a shared value before returning it so the snapshot never sees it change
+func (d *Dict) Snapshot() []SnapshotEntry {
+	lastSnapshot++
+	d.snapshot = lastSnapshot
+	entries := make([]SnapshotEntry, 0, d.Len())
+	d.Iterate(func(key string, value *ValueObject) bool {
+		value.snapshot = d.snapshot
+		expiryTime, _ := d.GetExpiryTime(key)
+		entries = append(entries, SnapshotEntry{Key: key, Value: value, ExpiryTimeMs: expiryTime})
+		return true
+	})
+	return entries
+}
+
+// ReleaseSnapshot ends the snapshot once its values are no longer read
+func (d *Dict) ReleaseSnapshot() {
+	d.snapshot = 0
+}
+
+// isShared reports whether the value of obj may be read by the snapshot in progress
+func (d *Dict) isShared(obj *ValueObject) bool {
+	return d.snapshot != 0 && obj.snapshot == d.snapshot
 }
 
 /*
  * Expired Dictionary store implementation
  */
 
-// IterateExpiredKeys iterates over expired keys without exposing internal state
-func (d *Dict) IterateExpiredKeys(fn func(key string, expiryTime uint64) bool) {
-	for key, expiryTime := range d.expiredDictStore {
-		if !fn(key, expiryTime) {
-			break
-		}
+// RandomExpiringKey returns a random key with an expiry and its expiry time, maybe an
+// expired key not deleted yet
+func (d *Dict) RandomExpiringKey() (string, uint64, bool) {
+	key, ok := d.expiredDictStore.RandomKey()
+	if !ok {
+		return "", 0, false
 	}
+	expiryTime, _ := d.expiredDictStore.Get(key)
+	return key, expiryTime, true
+}
+
+// ScanExpiredKeys calls fn on the keys with an expiry of one bucket and returns the next
+// cursor, see HashTable.Scan. fn must not modify the dictionary
+func (d *Dict) ScanExpiredKeys(cursor uint64, fn func(key string, expiryTime uint64)) uint64 {
+	return d.expiredDictStore.Scan(cursor, fn)
 }
 
 func (d *Dict) GetExpiryTime(key string) (uint64, bool) {
-	expiryTime, exist := d.expiredDictStore[key]
-	return expiryTime, exist
+	return d.expiredDictStore.Get(key)
 }
 
 func (d *Dict) SetExpiry(key string, expiryTimeMs uint64) {
-	d.expiredDictStore[key] = expiryTimeMs
+	d.expiredDictStore.Set(key, expiryTimeMs)
 }
 
 func (d *Dict) DeleteExpiry(key string) {
-	delete(d.expiredDictStore, key)
+	d.expiredDictStore.Delete(key)
 }
 
 func (d *Dict) HasExpired(key string) bool {
@@ -91,3 +285,26 @@ func (d *Dict) HasExpired(key string) bool {
 	now := uint64(time.Now().UnixMilli())
 	return expiryTime < now
 }
+
+/*
+ * Hash field expiry tracking
+ */
+
+// TrackFieldExpiry records that the hash at key has fields with an expiry,
+// so the active expiry cycle visits it
+func (d *Dict) TrackFieldExpiry(key string) {
+	d.fieldExpiryStore[key] = struct{}{}
+}
+
+func (d *Dict) UntrackFieldExpiry(key string) {
+	delete(d.fieldExpiryStore, key)
+}
+
+// IterateFieldExpiryKeys iterates over the tracked hash keys, fn may untrack the key it is given
+func (d *Dict) IterateFieldExpiryKeys(fn func(key string) bool) {
+	for key := range d.fieldExpiryStore {
+		if !fn(key) {
+			break
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package data_structure
+
+import "math"
+
+// noFieldExpiry is the nextExpiry of a hash without any field expiry
+const noFieldExpiry = math.MaxUint64
+
+// Hash represents a Redis hash: a map of fields to values, where fields may have
+// their own expiry time as with Redis 7.4's HEXPIRE
+type Hash struct {
+	fields     *HashTable[string]
+	expires    map[string]uint64 // Expiry time in milliseconds of the fields having one
+	nextExpiry uint64            // Lower bound of the expiry times, to skip scans of expires
+}
+
+// NewHash creates an empty hash
+func NewHash() *Hash {
+	return &Hash{
+		fields:     NewHashTable[string](),
+		expires:    make(map[string]uint64),
+		nextExpiry: noFieldExpiry,
+	}
+}
+
+// Dup returns a copy of the hash, with the expiry of its fields
+func (h *Hash) Dup() *Hash {
+	expires := make(map[string]uint64, len(h.expires))
+	for field, expiryTime := range h.expires {
+		expires[field] = expiryTime
+	}
+	return &Hash{fields: h.fields.Dup(), expires: expires, nextExpiry: h.nextExpiry}
+}
+
+// Len returns the number of fields
+func (h *Hash) Len() int {
+	return h.fields.Len()
+}
+
+// Get returns the value of a field
+func (h *Hash) Get(field string) (string, bool) {
+	value, ok := h.fields.Get(field)
+	return value, ok
+}
+
+// Set stores the value of a field, removing its expiry. It returns true when the field is new
+func (h *Hash) Set(field, value string) bool {
+	delete(h.expires, field)
+	return h.fields.Set(field, value)
+}
+
+// Update stores the value of an existing or new field, keeping its expiry
+func (h *Hash) Update(field, value string) {
+	h.fields.Set(field, value)
+}
+
+// Delete removes a field, returns false when it does not exist
+func (h *Hash) Delete(field string) bool {
+	if !h.fields.Delete(field) {
+		return false
+	}
+	delete(h.expires, field)
+	return true
+}
+
+// Iterate calls fn on every field until fn returns false
+func (h *Hash) Iterate(fn func(field, value string) bool) {
+	h.fields.Iterate(fn)
+}
+
+// SnapshotIterate is Iterate for a hash shared with a snapshot, see
+// HashTable.SnapshotIterate
+func (h *Hash) SnapshotIterate(fn func(field, value string) bool) {
+	h.fields.SnapshotIterate(fn)
+}
+
+// Scan calls fn on the fields of one bucket and returns the next cursor, see HashTable.Scan
+func (h *Hash) Scan(cursor uint64, fn func(field, value string)) uint64 {
+	return h.fields.Scan(cursor, fn)
+}
+
+// Fields returns the names of all the fields
+func (h *Hash) Fields() []string {
+	fields := make([]string, 0, h.fields.Len())
+	h.fields.Iterate(func(field, _ string) bool {
+		fields = append(fields, field)
+		return true
+	})
+	return fields
+}
+
+// RandomField returns a random field of a non empty hash with its value
+func (h *Hash) RandomField() (string, string) {
+	field, _ := h.fields.RandomKey()
+	value, _ := h.fields.Get(field)
+	return field, value
+}
+
+/*
+ * Field expiry
+ */
+
+// GetExpiry returns the expiry time in milliseconds of a field
+func (h *Hash) GetExpiry(field string) (uint64, bool) {
+	expiryTimeMs, ok := h.expires[field]
+	return expiryTimeMs, ok
+}
+
+// SetExpiry sets the expiry time in milliseconds of an existing field
+func (h *Hash) SetExpiry(field string, expiryTimeMs uint64) {
+	h.expires[field] = expiryTimeMs
+	h.nextExpiry = min(h.nextExpiry, expiryTimeMs)
+}
+
+// Persist removes the expiry of a field, returns false when it has none
+func (h *Hash) Persist(field string) bool {
+	if _, ok := h.expires[field]; !ok {
+		return false
+	}
+	delete(h.expires, field)
+	return true
+}
+
+// HasFieldExpiry reports whether some fields have an expiry
+func (h *Hash) HasFieldExpiry() bool {
+	return len(h.expires) > 0
+}
+
+// DeleteExpired removes the fields whose expiry time is before nowMs and returns them.
+// It only scans the expiring fields once one may have expired
+func (h *Hash) DeleteExpired(nowMs uint64) []string {
+	if h.nextExpiry >= nowMs {
+		return nil
+	}
+
+	var deleted []string
+	h.nextExpiry = noFieldExpiry
+	for field, expiryTimeMs := range h.expires {
+		if expiryTimeMs < nowMs {
+			h.fields.Delete(field)
+			delete(h.expires, field)
+			deleted = append(deleted, field)
+		} else {
+			h.nextExpiry = min(h.nextExpiry, expiryTimeMs)
+		}
+	}
+	return deleted
+}
@@ -0,0 +1,449 @@
+package data_structure
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math/bits"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	// hashTableInitialSize is the number of buckets of a table holding its first entry
+	hashTableInitialSize = 4
+
+	// hashTableMinFill is the inverse of the smallest fill ratio of a table: it shrinks
+	// once fewer than 1/hashTableMinFill of its buckets would be used
+	hashTableMinFill = 8
+
+	// hashTableStatsVectLen bounds the chain lengths detailed by Stats
+	hashTableStatsVectLen = 50
+)
+
+// HashTable is a chained hash table with a power of two number of buckets, following
+// Redis's dict. Unlike a Go map it can be walked with a cursor, see Scan, and it never
+// moves all its entries at once: resizing allocates a second table and the entries are
+// moved bucket by bucket, a step on each lookup or update and in batches by Rehash, so a
+// large table grows or shrinks without blocking the server
+type HashTable[V any] struct {
+	tables      [2][]*hashTableEntry[V] // tables[1] is only allocated while rehashing
+	used        [2]int
+	rehashIdx   int // Next bucket of tables[0] to move to tables[1], -1 when not rehashing
+	pauseRehash int // Rehash steps are skipped while Iterate or Scan walk the tables
+	seed        maphash.Seed
+}
+
+type hashTableEntry[V any] struct {
+	key   string
+	value V
+	next  *hashTableEntry[V]
+}
+
+// NewHashTable creates an empty hash table
+func NewHashTable[V any]() *HashTable[V] {
+	return &HashTable[V]{rehashIdx: -1, seed: maphash.MakeSeed()}
+}
+
+// Len returns the number of entries
+func (ht *HashTable[V]) Len() int {
+	return ht.used[0] + ht.used[1]
+}
+
+// Dup returns a copy of the table, the values are copied as by an assignment
+func (ht *HashTable[V]) Dup() *HashTable[V] {
+	dup := NewHashTable[V]()
+	ht.SnapshotIterate(func(key string, value V) bool {
+		dup.Set(key, value)
+		return true
+	})
+	return dup
+}
+
+// free drops every entry and unlinks the chains of the buckets, as Redis's dictRelease
+func (ht *HashTable[V]) free() {
+	for i, table := range ht.tables {
+		for j, e := range table {
+			for e != nil {
+				next := e.next
+				*e = hashTableEntry[V]{}
+				e = next
+			}
+			table[j] = nil
+		}
+		ht.tables[i], ht.used[i] = nil, 0
+	}
+	ht.rehashIdx = -1
+}
+
+// IsRehashing reports whether the entries are being moved to a resized table
+func (ht *HashTable[V]) IsRehashing() bool {
+	return ht.rehashIdx != -1
+}
+
+func (ht *HashTable[V]) hash(key string) uint64 {
+	return maphash.String(ht.seed, key)
+}
+
+func (ht *HashTable[V]) find(key string) *hashTableEntry[V] {
+	if ht.Len() == 0 {
+		return nil
+	}
+	ht.rehashStep()
+	h := ht.hash(key)
+	for t := 0; t <= 1; t++ {
+		table := ht.tables[t]
+		for e := table[h&uint64(len(table)-1)]; e != nil; e = e.next {
+			if e.key == key {
+				return e
+			}
+		}
+		if !ht.IsRehashing() {
+			break
+		}
+	}
+	return nil
+}
+
+// Get returns the value of a key
+func (ht *HashTable[V]) Get(key string) (V, bool) {
+	if e := ht.find(key); e != nil {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set stores the value of a key, returns true when the key is new
+func (ht *HashTable[V]) Set(key string, value V) bool {
+	if e := ht.find(key); e != nil {
+		e.value = value
+		return false
+	}
+
+	ht.expandIfNeeded()
+	t := 0
+	if ht.IsRehashing() {
+		t = 1 // New entries go to the new table, so tables[0] only empties
+	}
+	table := ht.tables[t]
+	i := ht.hash(key) & uint64(len(table)-1)
+	table[i] = &hashTableEntry[V]{key: key, value: value, next: table[i]}
+	ht.used[t]++
+	return true
+}
+
+// Delete removes a key, returns false when it does not exist
+func (ht *HashTable[V]) Delete(key string) bool {
+	if ht.Len() == 0 {
+		return false
+	}
+	ht.rehashStep()
+	h := ht.hash(key)
+	for t := 0; t <= 1; t++ {
+		table := ht.tables[t]
+		i := h & uint64(len(table)-1)
+		for prev, e := (*hashTableEntry[V])(nil), table[i]; e != nil; prev, e = e, e.next {
+			if e.key != key {
+				continue
+			}
+			if prev == nil {
+				table[i] = e.next
+			} else {
+				prev.next = e.next
+			}
+			ht.used[t]--
+			ht.shrinkIfNeeded()
+			return true
+		}
+		if !ht.IsRehashing() {
+			break
+		}
+	}
+	return false
+}
+
+// expandIfNeeded allocates the first table, or starts growing the table once it holds
+// as many entries as buckets
+func (ht *HashTable[V]) expandIfNeeded() {
+	if ht.IsRehashing() {
+		return
+	}
+	if len(ht.tables[0]) == 0 {
+		ht.resize(hashTableInitialSize)
+	} else if ht.used[0] >= len(ht.tables[0]) {
+		ht.resize(ht.used[0] + 1)
+	}
+}
+
+// shrinkIfNeeded starts shrinking the table once less than 1/hashTableMinFill of its
+// buckets are used
+func (ht *HashTable[V]) shrinkIfNeeded() {
+	if ht.IsRehashing() {
+		return
+	}
+	if size := len(ht.tables[0]); size > hashTableInitialSize && ht.used[0]*hashTableMinFill <= size {
+		ht.resize(ht.used[0])
+	}
+}
+
+// resize starts rehashing to a table of the smallest power of two buckets holding size
+// entries. An empty table is replaced at once, there is nothing to move
+func (ht *HashTable[V]) resize(size int) {
+	buckets := hashTableInitialSize
+	for buckets < size {
+		buckets *= 2
+	}
+	if buckets == len(ht.tables[0]) {
+		return
+	}
+
+	if ht.used[0] == 0 {
+		ht.tables[0] = make([]*hashTableEntry[V], buckets)
+		return
+	}
+	ht.tables[1] = make([]*hashTableEntry[V], buckets)
+	ht.rehashIdx = 0
+}
+
+// rehash moves n non-empty buckets of tables[0] to tables[1], visiting at most 10 * n
+// empty buckets so a sparse table does not block the caller. It returns whether there
+// are buckets left to move. Entries deleted while shrinking may leave the new table
+// sparse too, so a completed rehashing starts the next shrink when needed
+func (ht *HashTable[V]) rehash(n int) bool {
+	if !ht.IsRehashing() {
+		return false
+	}
+
+	emptyVisits := 10 * n
+	mask := uint64(len(ht.tables[1]) - 1)
+	for ; n > 0 && ht.used[0] > 0; n-- {
+		for ht.tables[0][ht.rehashIdx] == nil {
+			ht.rehashIdx++
+			emptyVisits--
+			if emptyVisits == 0 {
+				return true
+			}
+		}
+		for e := ht.tables[0][ht.rehashIdx]; e != nil; {
+			next := e.next
+			i := ht.hash(e.key) & mask
+			e.next = ht.tables[1][i]
+			ht.tables[1][i] = e
+			ht.used[0]--
+			ht.used[1]++
+			e = next
+		}
+		ht.tables[0][ht.rehashIdx] = nil
+		ht.rehashIdx++
+	}
+
+	if ht.used[0] > 0 {
+		return true
+	}
+	ht.tables[0], ht.tables[1] = ht.tables[1], nil
+	ht.used[0], ht.used[1] = ht.used[1], 0
+	ht.rehashIdx = -1
+	ht.shrinkIfNeeded()
+	return ht.IsRehashing()
+}
+
+// rehashStep moves one bucket, called on every lookup and update
+func (ht *HashTable[V]) rehashStep() {
+	if ht.pauseRehash == 0 {
+		ht.rehash(1)
+	}
+}
+
+// Rehash moves buckets in batches of 100 until the rehashing completes or the duration
+// elapses, returning whether there are buckets left to move. The server calls it from its
+// cron so an idle table completes its rehashing too
+func (ht *HashTable[V]) Rehash(duration time.Duration) bool {
+	if ht.pauseRehash > 0 {
+		return ht.IsRehashing()
+	}
+	start := time.Now()
+	for ht.rehash(100) {
+		if time.Since(start) >= duration {
+			return true
+		}
+	}
+	return false
+}
+
+// RandomKey returns a random key like Redis's dictGetRandomKey: it picks random buckets
+// until one is not empty, then a random entry of its chain
+func (ht *HashTable[V]) RandomKey() (string, bool) {
+	if ht.Len() == 0 {
+		return "", false
+	}
+	ht.rehashStep()
+
+	var e *hashTableEntry[V]
+	for e == nil {
+		if ht.IsRehashing() {
+			// The buckets of tables[0] below rehashIdx were already moved, so they are empty
+			size := len(ht.tables[0])
+			i := ht.rehashIdx + rand.Intn(size+len(ht.tables[1])-ht.rehashIdx)
+			if i < size {
+				e = ht.tables[0][i]
+			} else {
+				e = ht.tables[1][i-size]
+			}
+		} else {
+			e = ht.tables[0][rand.Intn(len(ht.tables[0]))]
+		}
+	}
+
+	length := 0
+	for x := e; x != nil; x = x.next {
+		length++
+	}
+	for i := rand.Intn(length); i > 0; i-- {
+		e = e.next
+	}
+	return e.key, true
+}
+
+// Iterate calls fn on every entry until fn returns false, fn must not modify the table.
+// While rehashing, the buckets of tables[0] below rehashIdx are empty and skipped, so
+// visiting the first entries costs the same as out of a rehashing
+func (ht *HashTable[V]) Iterate(fn func(key string, value V) bool) {
+	ht.pauseRehash++
+	defer func() { ht.pauseRehash-- }()
+	ht.SnapshotIterate(fn)
+}
+
+// SnapshotIterate is Iterate for the readers of a table shared with a snapshot, see
+// Dict.Snapshot. It only reads the table and does not pause the rehashing, so the event
+// loop copying the table and the goroutine saving it may walk it at once. fn must not
+// look up nor modify the table
+func (ht *HashTable[V]) SnapshotIterate(fn func(key string, value V) bool) {
+	for i, table := range ht.tables {
+		if i == 0 && ht.IsRehashing() {
+			table = table[ht.rehashIdx:]
+		}
+		for _, e := range table {
+			for ; e != nil; e = e.next {
+				if !fn(e.key, e.value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Scan calls fn on the entries of the bucket at cursor and returns the cursor of the
+// next bucket, 0 once the whole table was visited. Starting from 0 and calling Scan
+// until it returns 0 visits every entry present during the whole scan at least once,
+// even if the table is resized between calls.
+//
+// As in Redis's dictScan, the cursor is incremented from its most significant bit
+// down: when the table doubles, bucket i splits into i and i + size, both visited
+// after i, and when it halves the buckets i and i + size/2 merge into one not visited
+// yet or visited through both. While rehashing, the bucket of the smaller table and all
+// the buckets of the larger one it expands to are visited together. fn must not modify
+// the table
+func (ht *HashTable[V]) Scan(cursor uint64, fn func(key string, value V)) uint64 {
+	if ht.Len() == 0 {
+		return 0
+	}
+	ht.pauseRehash++
+	defer func() { ht.pauseRehash-- }()
+
+	emit := func(e *hashTableEntry[V]) {
+		for ; e != nil; e = e.next {
+			fn(e.key, e.value)
+		}
+	}
+
+	if !ht.IsRehashing() {
+		mask := uint64(len(ht.tables[0]) - 1)
+		emit(ht.tables[0][cursor&mask])
+		return nextScanCursor(cursor, mask)
+	}
+
+	small, large := ht.tables[0], ht.tables[1]
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	smallMask, largeMask := uint64(len(small)-1), uint64(len(large)-1)
+	emit(small[cursor&smallMask])
+	for {
+		emit(large[cursor&largeMask])
+		cursor = nextScanCursor(cursor, largeMask)
+		if cursor&(smallMask^largeMask) == 0 {
+			return cursor
+		}
+	}
+}
+
+// nextScanCursor increments the reversed bits of cursor covered by mask
+func nextScanCursor(cursor, mask uint64) uint64 {
+	// Set the bits above the mask so incrementing the reversed cursor carries over them
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
+
+// Stats describes the tables like Redis's dictGetStats: their sizes and number of
+// entries, and with full the chain lengths of their buckets
+func (ht *HashTable[V]) Stats(full bool) string {
+	var b strings.Builder
+	if ht.Len() == 0 {
+		b.WriteString("No stats available for empty dictionaries\n")
+		return b.String()
+	}
+	ht.tableStats(&b, 0, full)
+	if ht.IsRehashing() {
+		ht.tableStats(&b, 1, full)
+	}
+	return b.String()
+}
+
+func (ht *HashTable[V]) tableStats(b *strings.Builder, t int, full bool) {
+	name := "main hash table"
+	if t == 1 {
+		name = "rehashing target"
+	}
+	table := ht.tables[t]
+	fmt.Fprintf(b, "Hash table %d stats (%s):\n", t, name)
+	fmt.Fprintf(b, " table size: %d\n", len(table))
+	fmt.Fprintf(b, " number of elements: %d\n", ht.used[t])
+	if !full {
+		return
+	}
+	if ht.used[t] == 0 {
+		b.WriteString(" No stats available for empty dictionaries\n")
+		return
+	}
+
+	var chainLengths [hashTableStatsVectLen]int
+	slots, maxChainLength, totalChainLength := 0, 0, 0
+	for _, e := range table {
+		length := 0
+		for ; e != nil; e = e.next {
+			length++
+		}
+		chainLengths[min(length, hashTableStatsVectLen-1)]++
+		if length == 0 {
+			continue
+		}
+		slots++
+		maxChainLength = max(maxChainLength, length)
+		totalChainLength += length
+	}
+
+	fmt.Fprintf(b, " different slots: %d\n", slots)
+	fmt.Fprintf(b, " max chain length: %d\n", maxChainLength)
+	fmt.Fprintf(b, " avg chain length (counted): %.02f\n", float64(totalChainLength)/float64(slots))
+	fmt.Fprintf(b, " avg chain length (computed): %.02f\n", float64(ht.used[t])/float64(slots))
+	b.WriteString(" Chain length distribution:\n")
+	for length, count := range chainLengths {
+		if count == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "   %d: %d (%.02f%%)\n", length, count, 100*float64(count)/float64(len(table)))
+	}
+}
@@ -0,0 +1,208 @@
+package data_structure
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashTable(t *testing.T) {
+	ht := NewHashTable[int]()
+	for i := 0; i < 1000; i++ {
+		if !ht.Set(strconv.Itoa(i), i) {
+			t.Fatalf("Expected key %d to be new", i)
+		}
+	}
+	if ht.Set("7", 70) {
+		t.Errorf("Expected key 7 to exist")
+	}
+	if v, ok := ht.Get("7"); !ok || v != 70 {
+		t.Errorf("Expected 70, got %d", v)
+	}
+	for i := 0; i < 1000; i += 2 {
+		if !ht.Delete(strconv.Itoa(i)) {
+			t.Fatalf("Expected key %d to be deleted", i)
+		}
+	}
+	if ht.Len() != 500 {
+		t.Errorf("Expected 500 keys, got %d", ht.Len())
+	}
+	if _, ok := ht.Get("2"); ok {
+		t.Errorf("Expected key 2 to be deleted")
+	}
+}
+
+// Test that a scan returns every key present during the whole scan while the table grows
+func TestHashTableScanWhileGrowing(t *testing.T) {
+	ht := NewHashTable[int]()
+	for i := 0; i < 100; i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+
+	seen := make(map[string]int)
+	cursor, next := uint64(0), 100
+	for {
+		cursor = ht.Scan(cursor, func(key string, _ int) {
+			seen[key]++
+		})
+		if cursor == 0 {
+			break
+		}
+		// Grow the table several times during the scan
+		for i := 0; i < 20 && next < 2000; i++ {
+			ht.Set(strconv.Itoa(next), next)
+			next++
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		if seen[strconv.Itoa(i)] == 0 {
+			t.Errorf("Key %d was not returned by the scan", i)
+		}
+	}
+}
+
+// Test that the entries are moved a bucket at a time and stay reachable while rehashing
+func TestHashTableIncrementalRehash(t *testing.T) {
+	ht := NewHashTable[int]()
+	for i := 0; i < 64; i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+	for ht.IsRehashing() {
+		ht.Rehash(time.Millisecond)
+	}
+
+	ht.Set("64", 64) // The 65th entry of a 64 buckets table starts growing it
+	if !ht.IsRehashing() {
+		t.Fatalf("Expected the table to be rehashing")
+	}
+	for i := 0; i <= 64; i++ {
+		if v, ok := ht.Get(strconv.Itoa(i)); !ok || v != i {
+			t.Errorf("Expected key %d while rehashing, got %d, %v", i, v, ok)
+		}
+	}
+	ht.rehash(16)
+	visited := 0
+	ht.Iterate(func(string, int) bool {
+		visited++
+		return true
+	})
+	if visited != 65 {
+		t.Errorf("Expected to iterate over 65 keys while rehashing, got %d", visited)
+	}
+
+	if ht.Rehash(time.Second) {
+		t.Errorf("Expected the rehashing to complete")
+	}
+	if ht.IsRehashing() || len(ht.tables[0]) != 128 || ht.Len() != 65 {
+		t.Errorf("Expected 65 keys in 128 buckets, got %d in %d", ht.Len(), len(ht.tables[0]))
+	}
+}
+
+// Test that the table shrinks once most of its entries are deleted
+func TestHashTableShrink(t *testing.T) {
+	ht := NewHashTable[int]()
+	for i := 0; i < 1000; i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+	for i := 0; i < 990; i++ {
+		ht.Delete(strconv.Itoa(i))
+	}
+	ht.Rehash(time.Second)
+
+	if len(ht.tables[0]) != 16 {
+		t.Errorf("Expected 16 buckets, got %d", len(ht.tables[0]))
+	}
+	for i := 990; i < 1000; i++ {
+		if _, ok := ht.Get(strconv.Itoa(i)); !ok {
+			t.Errorf("Expected key %d after shrinking", i)
+		}
+	}
+}
+
+// Test that a scan returns every key exactly once across the tables of a rehashing table,
+// whether it grows or shrinks
+func TestHashTableScanWhileRehashing(t *testing.T) {
+	for _, shrink := range []bool{false, true} {
+		ht := NewHashTable[int]()
+		keys := 64
+		if shrink {
+			for i := 0; i < 1000; i++ {
+				ht.Set(strconv.Itoa(i), i)
+			}
+			ht.Rehash(time.Second)
+			for i := keys; i < 1000; i++ {
+				ht.Delete(strconv.Itoa(i))
+			}
+		} else {
+			for i := 0; i <= keys; i++ {
+				ht.Set(strconv.Itoa(i), i)
+			}
+			keys++
+		}
+		if !ht.IsRehashing() {
+			t.Fatalf("Expected the table to be rehashing")
+		}
+
+		seen := make(map[string]int)
+		cursor := uint64(0)
+		for {
+			cursor = ht.Scan(cursor, func(key string, _ int) {
+				seen[key]++
+			})
+			ht.rehash(1)
+			if cursor == 0 {
+				break
+			}
+		}
+		for i := 0; i < keys; i++ {
+			if seen[strconv.Itoa(i)] != 1 {
+				t.Errorf("Expected key %d once when shrink is %v, got %d times", i, shrink, seen[strconv.Itoa(i)])
+			}
+		}
+	}
+}
+
+func TestHashTableStats(t *testing.T) {
+	ht := NewHashTable[int]()
+	if stats := ht.Stats(true); stats != "No stats available for empty dictionaries\n" {
+		t.Errorf("Unexpected stats of an empty table: %q", stats)
+	}
+
+	for i := 0; i < 3; i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+	stats := ht.Stats(true)
+	for _, line := range []string{"Hash table 0 stats (main hash table):", " table size: 4", " number of elements: 3", " Chain length distribution:"} {
+		if !strings.Contains(stats, line+"\n") {
+			t.Errorf("Expected %q in the stats, got %q", line, stats)
+		}
+	}
+	if strings.Contains(ht.Stats(false), "chain") {
+		t.Errorf("Expected no chain lengths without full")
+	}
+}
+
+// Test that RandomKey returns every key eventually, also while rehashing
+func TestHashTableRandomKey(t *testing.T) {
+	ht := NewHashTable[int]()
+	if _, ok := ht.RandomKey(); ok {
+		t.Errorf("Expected no key in an empty table")
+	}
+	for i := 0; i <= 16; i++ {
+		ht.Set(strconv.Itoa(i), i)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 10000 && len(seen) < 17; i++ {
+		key, ok := ht.RandomKey()
+		if _, exists := ht.Get(key); !ok || !exists {
+			t.Fatalf("Expected an existing key, got %q", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) != 17 {
+		t.Errorf("Expected the 17 keys, got %d", len(seen))
+	}
+}
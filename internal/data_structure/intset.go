@@ -0,0 +1,139 @@
+package data_structure
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// Intset is a sorted array of distinct integers packed in a byte slice, following Redis's
+// intset. All the integers use the width of the largest one, 2, 4 or 8 bytes, and the
+// whole array is upgraded to a larger width when an integer does not fit
+type Intset struct {
+	width int // Bytes per integer
+	data  []byte
+}
+
+// NewIntset creates an empty intset
+func NewIntset() *Intset {
+	return &Intset{width: 2}
+}
+
+// Dup returns a copy of the intset
+func (is *Intset) Dup() *Intset {
+	return &Intset{width: is.width, data: append([]byte(nil), is.data...)}
+}
+
+// intsetWidth returns the smallest width able to hold v
+func intsetWidth(v int64) int {
+	switch {
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// Len returns the number of integers
+func (is *Intset) Len() int {
+	return len(is.data) / is.width
+}
+
+// Get returns integer i, which must be in [0, Len())
+func (is *Intset) Get(i int) int64 {
+	return intsetDecode(is.data[i*is.width:], is.width)
+}
+
+func intsetDecode(b []byte, width int) int64 {
+	switch width {
+	case 2:
+		return int64(int16(binary.LittleEndian.Uint16(b)))
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+	default:
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+}
+
+func intsetEncode(b []byte, width int, v int64) {
+	switch width {
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(b, uint64(v))
+	}
+}
+
+// search returns the position of v, or the position where it would be inserted
+func (is *Intset) search(v int64) (int, bool) {
+	i := sort.Search(is.Len(), func(i int) bool { return is.Get(i) >= v })
+	return i, i < is.Len() && is.Get(i) == v
+}
+
+// Contains reports whether v is in the intset
+func (is *Intset) Contains(v int64) bool {
+	_, found := is.search(v)
+	return found
+}
+
+// upgrade re-encodes every integer with a larger width
+func (is *Intset) upgrade(width int) {
+	n := is.Len()
+	data := make([]byte, n*width, (n+1)*width)
+	for i := 0; i < n; i++ {
+		intsetEncode(data[i*width:], width, is.Get(i))
+	}
+	is.width = width
+	is.data = data
+}
+
+// Add inserts v, returns false when it is already in the intset
+func (is *Intset) Add(v int64) bool {
+	if width := intsetWidth(v); width > is.width {
+		is.upgrade(width) // v is out of the current range, so it is not in the intset yet
+	}
+	i, found := is.search(v)
+	if found {
+		return false
+	}
+
+	offset := i * is.width
+	is.data = append(is.data, make([]byte, is.width)...) // Grow, the tail is moved right below
+	copy(is.data[offset+is.width:], is.data[offset:len(is.data)-is.width])
+	intsetEncode(is.data[offset:], is.width, v)
+	return true
+}
+
+// Remove deletes v, returns false when it is not in the intset
+func (is *Intset) Remove(v int64) bool {
+	i, found := is.search(v)
+	if !found {
+		return false
+	}
+	offset := i * is.width
+	is.data = append(is.data[:offset], is.data[offset+is.width:]...)
+	return true
+}
+
+// Min returns the smallest integer, the intset must not be empty
+func (is *Intset) Min() int64 {
+	return is.Get(0)
+}
+
+// Max returns the largest integer, the intset must not be empty
+func (is *Intset) Max() int64 {
+	return is.Get(is.Len() - 1)
+}
+
+// Iterate calls fn on every integer in ascending order until fn returns false
+func (is *Intset) Iterate(fn func(v int64) bool) {
+	for i := 0; i < is.Len(); i++ {
+		if !fn(is.Get(i)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package data_structure
+
+import "encoding/binary"
+
+// Listpack stores a sequence of strings in a single byte slice, each entry being its
+// length as a uvarint followed by its bytes. It trades O(n) access for a much smaller
+// memory footprint than one allocation per element, so it is only used for small
+// collections or as the nodes of a Quicklist
+type Listpack struct {
+	data  []byte
+	count int
+}
+
+// NewListpack creates an empty listpack
+func NewListpack() *Listpack {
+	return &Listpack{}
+}
+
+// Dup returns a copy of the listpack
+func (lp *Listpack) Dup() *Listpack {
+	return &Listpack{data: append([]byte(nil), lp.data...), count: lp.count}
+}
+
+// Len returns the number of entries
+func (lp *Listpack) Len() int {
+	return lp.count
+}
+
+// Bytes returns the encoded size of the entries
+func (lp *Listpack) Bytes() int {
+	return len(lp.data)
+}
+
+// entrySize returns the encoded size of a value
+func entrySize(value string) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], uint64(len(value))) + len(value)
+}
+
+// seek returns the byte offset of entry i, i may be Len() for the end of the listpack
+func (lp *Listpack) seek(i int) int {
+	offset := 0
+	for ; i > 0; i-- {
+		n, size := binary.Uvarint(lp.data[offset:])
+		offset += size + int(n)
+	}
+	return offset
+}
+
+// entryAt decodes the entry starting at offset, returning it and the offset of the next one
+func (lp *Listpack) entryAt(offset int) (string, int) {
+	n, size := binary.Uvarint(lp.data[offset:])
+	start := offset + size
+	end := start + int(n)
+	return string(lp.data[start:end]), end
+}
+
+// Get returns entry i, which must be in [0, Len())
+func (lp *Listpack) Get(i int) string {
+	value, _ := lp.entryAt(lp.seek(i))
+	return value
+}
+
+// Append adds a value after the last entry
+func (lp *Listpack) Append(value string) {
+	lp.data = binary.AppendUvarint(lp.data, uint64(len(value)))
+	lp.data = append(lp.data, value...)
+	lp.count++
+}
+
+// Insert adds a value so it becomes entry i, i must be in [0, Len()]
+func (lp *Listpack) Insert(i int, value string) {
+	if i == lp.count {
+		lp.Append(value)
+		return
+	}
+
+	offset := lp.seek(i)
+	entry := binary.AppendUvarint(make([]byte, 0, entrySize(value)), uint64(len(value)))
+	entry = append(entry, value...)
+
+	lp.data = append(lp.data, entry...) // Grow, the tail is moved right below
+	copy(lp.data[offset+len(entry):], lp.data[offset:len(lp.data)-len(entry)])
+	copy(lp.data[offset:], entry)
+	lp.count++
+}
+
+// Replace overwrites entry i
+func (lp *Listpack) Replace(i int, value string) {
+	lp.Delete(i)
+	lp.Insert(i, value)
+}
+
+// Delete removes entry i
+func (lp *Listpack) Delete(i int) {
+	lp.DeleteRange(i, 1)
+}
+
+// DeleteRange removes n entries starting at entry i
+func (lp *Listpack) DeleteRange(i, n int) {
+	if n <= 0 {
+		return
+	}
+	start := lp.seek(i)
+	end := start
+	for k := 0; k < n; k++ {
+		_, end = lp.entryAt(end)
+	}
+	lp.data = append(lp.data[:start], lp.data[end:]...)
+	lp.count -= n
+}
+
+// Split keeps the first i entries and returns a listpack holding the others
+func (lp *Listpack) Split(i int) *Listpack {
+	offset := lp.seek(i)
+	other := &Listpack{
+		data:  append([]byte(nil), lp.data[offset:]...),
+		count: lp.count - i,
+	}
+	lp.data = lp.data[:offset:offset]
+	lp.count = i
+	return other
+}
+
+// Merge appends all the entries of other
+func (lp *Listpack) Merge(other *Listpack) {
+	lp.data = append(lp.data, other.data...)
+	lp.count += other.count
+}
+
+// Iterate calls fn on every entry from the first one until fn returns false
+func (lp *Listpack) Iterate(fn func(i int, value string) bool) {
+	offset := 0
+	for i := 0; i < lp.count; i++ {
+		var value string
+		value, offset = lp.entryAt(offset)
+		if !fn(i, value) {
+			return
+		}
+	}
+}
+
+// Find returns the index of the first entry equal to value, -1 when there is none
+func (lp *Listpack) Find(value string) int {
+	found := -1
+	lp.Iterate(func(i int, entry string) bool {
+		if entry == value {
+			found = i
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// Entries returns all the entries in order
+func (lp *Listpack) Entries() []string {
+	entries := make([]string, 0, lp.count)
+	lp.Iterate(func(_ int, value string) bool {
+		entries = append(entries, value)
+		return true
+	})
+	return entries
+}
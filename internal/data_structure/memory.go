@@ -0,0 +1,109 @@
+package data_structure
+
+// Estimated sizes in bytes of the structures holding the values, on a 64 bit platform.
+// They account for the Go headers and pointers, not for the allocator's rounding
+const (
+	objectOverhead         = 48 // ValueObject, with the interface holding its value
+	stringOverhead         = 16 // String header
+	sliceOverhead          = 24 // Slice header
+	pointerSize            = 8
+	hashTableOverhead      = 72 // HashTable without its buckets
+	hashTableEntryOverhead = 40 // hashTableEntry without its key and value
+	quicklistOverhead      = 48
+	quicklistNodeOverhead  = 24 + 40 // quicklistNode and its Listpack
+	skiplistNodeOverhead   = 56 + 24 // skiplistNode and its average 1.33 levels
+	mapEntryOverhead       = 48      // Entry of a Go map, averaged over its buckets
+)
+
+// DefaultMemorySamples is the number of elements of a collection sampled to estimate
+// its size, as Redis's MEMORY USAGE
+const DefaultMemorySamples = 5
+
+// MemoryUsage estimates the bytes used by the object and its value. The elements of a
+// large collection are not all visited: the size of samples of them is extrapolated to the
+// whole collection like Redis's MEMORY USAGE, samples being 0 to visit every element
+func (o *ValueObject) MemoryUsage(samples int) int64 {
+	switch v := o.Value.(type) {
+	case int64:
+		return objectOverhead + 8
+	case string:
+		return objectOverhead + stringOverhead + int64(len(v))
+	case *Quicklist:
+		return objectOverhead + v.memoryUsage(samples)
+	case *Set:
+		return objectOverhead + v.memoryUsage(samples)
+	case *ZSet:
+		return objectOverhead + v.memoryUsage(samples)
+	case *Hash:
+		return objectOverhead + v.memoryUsage(samples)
+	default:
+		return objectOverhead
+	}
+}
+
+// KeyMemoryUsage estimates the bytes used by a key of the keyspace: its entry in the hash
+// table, its name and its value, as reported by MEMORY USAGE
+func KeyMemoryUsage(key string, obj *ValueObject, samples int) int64 {
+	return hashTableEntryOverhead + stringOverhead + int64(len(key)) + obj.MemoryUsage(samples)
+}
+
+// extrapolate returns the size of n elements from the total size of the sampled ones
+func extrapolate(sampledSize int64, sampled, n int) int64 {
+	if sampled == 0 {
+		return 0
+	}
+	return sampledSize * int64(n) / int64(sampled)
+}
+
+func (ql *Quicklist) memoryUsage(samples int) int64 {
+	var size int64
+	sampled := 0
+	for node := ql.head; node != nil && (samples == 0 || sampled < samples); node = node.next {
+		size += quicklistNodeOverhead + int64(node.lp.Bytes())
+		sampled++
+	}
+	return quicklistOverhead + extrapolate(size, sampled, ql.nodes)
+}
+
+func (s *Set) memoryUsage(samples int) int64 {
+	switch s.encoding {
+	case EncodingIntset:
+		return sliceOverhead + int64(len(s.intset.data))
+	case EncodingListpack:
+		return sliceOverhead + int64(s.lp.Bytes())
+	default:
+		return s.dict.memoryUsage(samples, func(struct{}) int64 { return 0 })
+	}
+}
+
+func (z *ZSet) memoryUsage(samples int) int64 {
+	// The members are shared by the dict and the skiplist, so the skiplist nodes only add
+	// their own overhead
+	size := z.dict.memoryUsage(samples, func(float64) int64 { return 8 })
+	return size + int64(z.Len())*skiplistNodeOverhead
+}
+
+func (h *Hash) memoryUsage(samples int) int64 {
+	size := h.fields.memoryUsage(samples, func(value string) int64 {
+		return stringOverhead + int64(len(value))
+	})
+	return size + int64(len(h.expires))*mapEntryOverhead
+}
+
+// memoryUsage estimates the bytes used by the table, its keys and, as measured by
+// valueSize, its values
+func (ht *HashTable[V]) memoryUsage(samples int, valueSize func(V) int64) int64 {
+	var size int64
+	sampled := 0
+	ht.Iterate(func(key string, value V) bool {
+		size += stringOverhead + int64(len(key)) + valueSize(value)
+		sampled++
+		return samples == 0 || sampled < samples
+	})
+	return ht.bucketsMemory() + int64(ht.Len())*hashTableEntryOverhead + extrapolate(size, sampled, ht.Len())
+}
+
+// bucketsMemory returns the bytes used by the table without its entries
+func (ht *HashTable[V]) bucketsMemory() int64 {
+	return hashTableOverhead + int64(len(ht.tables[0])+len(ht.tables[1]))*pointerSize
+}
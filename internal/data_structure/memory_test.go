@@ -0,0 +1,77 @@
+package data_structure
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Test that the estimate of every type grows with its content, and that sampling
+// extrapolates the size of collections of elements of the same size
+func TestMemoryUsage(t *testing.T) {
+	fill := func(n int) []*ValueObject {
+		list := NewQuicklist(128)
+		members := make([]string, n)
+		hash := NewHash()
+		zset := NewZSet()
+		for i := 0; i < n; i++ {
+			member := strings.Repeat("m", 20) + strconv.Itoa(1000000+i)
+			list.PushTail(member)
+			members[i] = member
+			hash.Set(member, member)
+			zset.Set(member, float64(i))
+		}
+		return []*ValueObject{
+			NewStringObject(strings.Repeat("s", n)),
+			NewListObject(list),
+			NewSetObject(NewSet(members)),
+			NewHashObject(hash),
+			NewZSetObject(zset),
+		}
+	}
+
+	small, large := fill(10), fill(1000)
+	for i := range small {
+		if small[i].MemoryUsage(DefaultMemorySamples) >= large[i].MemoryUsage(DefaultMemorySamples) {
+			t.Errorf("Expected a larger %v to use more memory", small[i].Type)
+		}
+
+		sampled, full := large[i].MemoryUsage(DefaultMemorySamples), large[i].MemoryUsage(0)
+		if sampled < full*9/10 || sampled > full*11/10 {
+			t.Errorf("Expected the sampled size %d of a %v to be close to %d", sampled, large[i].Type, full)
+		}
+	}
+}
+
+// Test that the dictionary accounts for the keys it stores, updates and deletes
+func TestDictUsedMemory(t *testing.T) {
+	d := NewDict()
+	if d.usedMemory != 0 {
+		t.Fatalf("Expected no memory used by an empty dictionary, got %d", d.usedMemory)
+	}
+
+	d.Set("k", "v", 0)
+	d.Set("k", strings.Repeat("v", 100), 0)
+	obj := d.Get("k")
+	if d.usedMemory != KeyMemoryUsage("k", obj, DefaultMemorySamples) {
+		t.Errorf("Expected the size of the last value only, got %d", d.usedMemory)
+	}
+
+	list := NewQuicklist(128)
+	d.Set("list", list, 0)
+	before := d.usedMemory
+	list.PushTail(strings.Repeat("e", 100))
+	d.UpdateMemory("list")
+	if d.usedMemory < before+100 {
+		t.Errorf("Expected the pushed element to be accounted, got %d more bytes", d.usedMemory-before)
+	}
+
+	// The object moves to another dictionary, which accounts for it
+	other := NewDict()
+	d.Delete("k")
+	other.Set("k", obj, 0)
+	d.Delete("list")
+	if d.usedMemory != 0 || other.usedMemory != KeyMemoryUsage("k", obj, DefaultMemorySamples) {
+		t.Errorf("Expected the memory to follow the key, got %d and %d", d.usedMemory, other.usedMemory)
+	}
+}
@@ -0,0 +1,309 @@
+package data_structure
+
+import (
+	"fmt"
+	"math/rand"
+	"redis-repo/internal/config"
+	"strconv"
+	"time"
+)
+
+// ObjectType is the Redis data type of a value, as reported by TYPE
+type ObjectType uint8
+
+const (
+	ObjString ObjectType = iota
+	ObjList
+	ObjSet
+	ObjZSet
+	ObjHash
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjString:
+		return "string"
+	case ObjList:
+		return "list"
+	case ObjSet:
+		return "set"
+	case ObjZSet:
+		return "zset"
+	case ObjHash:
+		return "hash"
+	default:
+		return "unknown"
+	}
+}
+
+// ObjectEncoding is the internal representation of a value, as reported by OBJECT ENCODING
+type ObjectEncoding uint8
+
+const (
+	EncodingRaw ObjectEncoding = iota
+	EncodingEmbstr
+	EncodingInt
+	EncodingHashtable
+	EncodingQuicklist
+	EncodingSkiplist
+	EncodingIntset
+	EncodingListpack
+)
+
+func (e ObjectEncoding) String() string {
+	switch e {
+	case EncodingRaw:
+		return "raw"
+	case EncodingEmbstr:
+		return "embstr"
+	case EncodingInt:
+		return "int"
+	case EncodingHashtable:
+		return "hashtable"
+	case EncodingQuicklist:
+		return "quicklist"
+	case EncodingSkiplist:
+		return "skiplist"
+	case EncodingIntset:
+		return "intset"
+	case EncodingListpack:
+		return "listpack"
+	default:
+		return "unknown"
+	}
+}
+
+// Strings up to this length are reported with the embstr encoding, as in Redis
+const embstrSizeLimit = 44
+
+// ValueObject is a value stored in the keyspace together with its type and encoding, and
+// the access statistics used by OBJECT IDLETIME and OBJECT FREQ
+type ValueObject struct {
+	Type     ObjectType
+	Encoding ObjectEncoding
+	Value    any
+
+	accessTime  uint32 // Unix time in seconds of the last access
+	lfuCounter  uint8  // Logarithmic access frequency, see Touch
+	lfuDecrTime uint16 // Unix time in minutes, modulo 2^16, of the last update of lfuCounter
+
+	memory   int64  // Bytes accounted for the key holding the object by its Dict, see Dict.UpdateMemory
+	snapshot uint64 // Snapshot of the Dict that may still read the value, see Dict.Snapshot
+}
+
+// newObject creates an object accessed now, with the initial frequency of a new key
+func newObject(t ObjectType, encoding ObjectEncoding, value any) *ValueObject {
+	o := &ValueObject{Type: t, Encoding: encoding, Value: value, lfuCounter: lfuInitVal}
+	now := time.Now()
+	o.accessTime = uint32(now.Unix())
+	o.lfuDecrTime = lfuTimeInMinutes(now)
+	return o
+}
+
+// CurrentEncoding returns the encoding of the value. Sets convert themselves to another
+// encoding as they grow, so their encoding is asked to the set
+func (o *ValueObject) CurrentEncoding() ObjectEncoding {
+	if set, ok := o.Value.(*Set); ok {
+		return set.Encoding()
+	}
+	return o.Encoding
+}
+
+// Dup returns a new object holding a copy of the value that can be modified
+// independently, as COPY stores. Strings are immutable, so their value is shared
+func (o *ValueObject) Dup() *ValueObject {
+	dup := newObject(o.Type, o.Encoding, o.Value)
+	switch v := o.Value.(type) {
+	case *Quicklist:
+		dup.Value = v.Dup()
+	case *Set:
+		dup.Value = v.Dup()
+	case *ZSet:
+		dup.Value = v.Dup()
+	case *Hash:
+		dup.Value = v.Dup()
+	}
+	return dup
+}
+
+// cow returns a copy of the object with its access statistics, whose value can be modified
+// while a snapshot reads the value of the original one
+func (o *ValueObject) cow() *ValueObject {
+	dup := *o
+	dup.Value = o.Dup().Value
+	dup.snapshot = 0
+	return &dup
+}
+
+// FreeEffort returns the work needed to free the value, as Redis's lazyfreeGetFreeEffort:
+// the number of allocations of a list, or of the members of a set, sorted set or hash
+// held in a hash table. Strings and packed sets take a single one
+func (o *ValueObject) FreeEffort() int {
+	switch v := o.Value.(type) {
+	case *Quicklist:
+		return v.NodeCount()
+	case *Set:
+		if v.encoding == EncodingHashtable {
+			return v.Len()
+		}
+	case *ZSet:
+		return v.Len()
+	case *Hash:
+		return v.Len()
+	}
+	return 1
+}
+
+// Free releases the value of an object deleted from the keyspace and not read by any
+// snapshot, walking it once so a large value can be freed outside of the event loop.
+// The object is empty afterwards
+func (o *ValueObject) Free() {
+	switch v := o.Value.(type) {
+	case *Quicklist:
+		v.free()
+	case *Set:
+		if v.dict != nil {
+			v.dict.free()
+		}
+	case *ZSet:
+		v.dict.free()
+		v.zsl.free()
+	case *Hash:
+		v.fields.free()
+		v.expires = nil
+	}
+	o.Value = nil
+}
+
+// NewStringObject creates a string value. Strings holding a canonical 64 bit integer
+// are stored as an int64 so counters do not parse them on every update
+func NewStringObject(value string) *ValueObject {
+	if n, ok := ParseCanonicalInt(value); ok {
+		return NewIntObject(n)
+	}
+
+	encoding := EncodingRaw
+	if len(value) <= embstrSizeLimit {
+		encoding = EncodingEmbstr
+	}
+	return newObject(ObjString, encoding, value)
+}
+
+// NewIntObject creates a string value with the integer encoding
+func NewIntObject(value int64) *ValueObject {
+	return newObject(ObjString, EncodingInt, value)
+}
+
+// StringValue returns the value of a string object whatever its encoding
+func (o *ValueObject) StringValue() string {
+	if o.Encoding == EncodingInt {
+		return strconv.FormatInt(o.Value.(int64), 10)
+	}
+	return o.Value.(string)
+}
+
+// ParseCanonicalInt parses s as a 64 bit integer only when formatting the integer
+// gives s back, so no sign, spaces or leading zeros are lost by the int encoding
+func ParseCanonicalInt(s string) (int64, bool) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != s {
+		return 0, false
+	}
+	return n, true
+}
+
+// NewListObject creates a list value
+func NewListObject(list *Quicklist) *ValueObject {
+	return newObject(ObjList, EncodingQuicklist, list)
+}
+
+// NewSetObject creates a set value
+func NewSetObject(set *Set) *ValueObject {
+	return newObject(ObjSet, set.Encoding(), set)
+}
+
+// NewZSetObject creates a sorted set value
+func NewZSetObject(zset *ZSet) *ValueObject {
+	return newObject(ObjZSet, EncodingSkiplist, zset)
+}
+
+// NewHashObject creates a hash value
+func NewHashObject(hash *Hash) *ValueObject {
+	return newObject(ObjHash, EncodingHashtable, hash)
+}
+
+// NewValueObject wraps a Go value, deriving its type and encoding from the Go type
+func NewValueObject(value any) *ValueObject {
+	switch v := value.(type) {
+	case *ValueObject:
+		return v
+	case string:
+		return NewStringObject(v)
+	case *Quicklist:
+		return NewListObject(v)
+	case *Set:
+		return NewSetObject(v)
+	case *ZSet:
+		return NewZSetObject(v)
+	case *Hash:
+		return NewHashObject(v)
+	default:
+		panic(fmt.Sprintf("unsupported value type %T", value))
+	}
+}
+
+/*
+ * Access statistics, following Redis's LRU clock and LFU counter
+ */
+
+// lfuInitVal is the frequency of a new object, so it is not evicted before having a
+// chance to be accessed again
+const lfuInitVal = 5
+
+func lfuTimeInMinutes(now time.Time) uint16 {
+	return uint16(now.Unix() / 60)
+}
+
+// Touch records an access to the object: its access time becomes now and its frequency
+// counter, once decayed, is incremented with a probability decreasing as it grows, so
+// the 8 bit counter covers millions of accesses
+func (o *ValueObject) Touch() {
+	now := time.Now()
+	o.accessTime = uint32(now.Unix())
+
+	counter := o.decayedFreq(now)
+	if counter < 255 {
+		base := max(float64(counter)-lfuInitVal, 0)
+		if rand.Float64() < 1/(base*config.LFULogFactor+1) {
+			counter++
+		}
+	}
+	o.lfuCounter = counter
+	o.lfuDecrTime = lfuTimeInMinutes(now)
+}
+
+// IdleTime returns the time since the last access, in seconds
+func (o *ValueObject) IdleTime() int64 {
+	return max(time.Now().Unix()-int64(o.accessTime), 0)
+}
+
+// Freq returns the frequency counter of the object, decremented by one for every
+// config.LFUDecayTime minutes elapsed since its last decrement
+func (o *ValueObject) Freq() int {
+	return int(o.decayedFreq(time.Now()))
+}
+
+func (o *ValueObject) decayedFreq(now time.Time) uint8 {
+	if config.LFUDecayTime == 0 {
+		return o.lfuCounter
+	}
+	elapsed := lfuTimeInMinutes(now) - o.lfuDecrTime // Wraps around like the stored time
+	periods := int(elapsed) / config.LFUDecayTime
+	if periods >= int(o.lfuCounter) {
+		return 0
+	}
+	return o.lfuCounter - uint8(periods)
+}
@@ -0,0 +1,76 @@
+package data_structure
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObjectAccessStatistics(t *testing.T) {
+	o := NewStringObject("v")
+	if o.Freq() != lfuInitVal || o.IdleTime() != 0 {
+		t.Fatalf("Expected a new object to have frequency %d and no idle time, got %d and %d", lfuInitVal, o.Freq(), o.IdleTime())
+	}
+
+	// Three minutes without access decrement the counter three times
+	o.lfuDecrTime -= 3
+	o.accessTime -= 180
+	if o.Freq() != lfuInitVal-3 {
+		t.Errorf("Expected frequency %d, got %d", lfuInitVal-3, o.Freq())
+	}
+	if idle := o.IdleTime(); idle < 180 || idle > 181 {
+		t.Errorf("Expected 180 seconds of idle time, got %d", o.IdleTime())
+	}
+
+	o.Touch()
+	if o.Freq() < lfuInitVal-3 || o.IdleTime() != 0 {
+		t.Errorf("Expected the access to be recorded, got frequency %d and idle time %d", o.Freq(), o.IdleTime())
+	}
+
+	// The counter grows logarithmically and saturates
+	o.lfuCounter = 255
+	o.lfuDecrTime = lfuTimeInMinutes(time.Now())
+	o.Touch()
+	if o.Freq() != 255 {
+		t.Errorf("Expected the counter to saturate at 255, got %d", o.Freq())
+	}
+}
+
+func TestSnapshotCopyOnWrite(t *testing.T) {
+	d := NewDict()
+	list := NewQuicklist(-2)
+	list.PushTail("a")
+	d.Set("list", list, 0)
+	d.Set("string", "value", 4102444800000)
+
+	entries := d.Snapshot()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 keys in the snapshot, got %d", len(entries))
+	}
+	var shared *ValueObject
+	for _, entry := range entries {
+		if entry.Key == "list" {
+			shared = entry.Value
+		} else if entry.ExpiryTimeMs != 4102444800000 {
+			t.Errorf("Expected the expiry of the key in the snapshot, got %d", entry.ExpiryTimeMs)
+		}
+	}
+
+	if d.Peek("list") != shared {
+		t.Errorf("Expected Peek not to copy the value")
+	}
+	obj := d.Get("list")
+	if obj == shared || d.Get("list") != obj {
+		t.Fatalf("Expected Get to copy the shared value once")
+	}
+	obj.Value.(*Quicklist).PushTail("b")
+	if n := shared.Value.(*Quicklist).Len(); n != 1 {
+		t.Errorf("Expected the snapshot to keep 1 element, got %d", n)
+	}
+
+	d.ReleaseSnapshot()
+	d.Snapshot()
+	d.ReleaseSnapshot()
+	if d.Get("string") != d.Peek("string") {
+		t.Errorf("Expected no copy once the snapshot is released")
+	}
+}
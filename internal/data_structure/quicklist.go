@@ -0,0 +1,388 @@
+package data_structure
+
+// Quicklist is the list type: a doubly linked list of listpack nodes, following Redis's
+// quicklist. Pushing and popping at both ends is O(1) while the elements stay packed
+// in a few large allocations instead of one node per element
+type Quicklist struct {
+	head  *quicklistNode
+	tail  *quicklistNode
+	count int // Number of elements in all nodes
+	nodes int
+	fill  int // Node size limit, see NewQuicklist
+}
+
+type quicklistNode struct {
+	prev *quicklistNode
+	next *quicklistNode
+	lp   *Listpack
+}
+
+// Byte limits of a node for the negative fill values -1 to -5
+var quicklistSizeLimits = [...]int{4096, 8192, 16384, 32768, 65536}
+
+// NewQuicklist creates an empty list. A positive fill is the maximum number of elements
+// per node, a negative one from -1 to -5 limits a node to 4, 8, 16, 32 or 64 KB,
+// as Redis's list-max-listpack-size
+func NewQuicklist(fill int) *Quicklist {
+	if fill == 0 {
+		fill = 1
+	}
+	if fill < -len(quicklistSizeLimits) {
+		fill = -len(quicklistSizeLimits)
+	}
+	return &Quicklist{fill: fill}
+}
+
+// free unlinks every node, as Redis's quicklistRelease
+func (ql *Quicklist) free() {
+	for node := ql.head; node != nil; {
+		next := node.next
+		*node = quicklistNode{}
+		node = next
+	}
+	ql.head, ql.tail, ql.count, ql.nodes = nil, nil, 0, 0
+}
+
+// Len returns the number of elements
+func (ql *Quicklist) Len() int {
+	return ql.count
+}
+
+// fits reports whether a node holding count elements encoded in bytes is within the fill limit
+func (ql *Quicklist) fits(count, bytes int) bool {
+	if ql.fill > 0 {
+		return count <= ql.fill
+	}
+	return bytes <= quicklistSizeLimits[-ql.fill-1]
+}
+
+// nodeAllowsInsert reports whether value can be added to node without exceeding the fill limit
+func (ql *Quicklist) nodeAllowsInsert(node *quicklistNode, value string) bool {
+	return node != nil && ql.fits(node.lp.Len()+1, node.lp.Bytes()+entrySize(value))
+}
+
+// Dup returns a copy of the list, node by node
+func (ql *Quicklist) Dup() *Quicklist {
+	dup := NewQuicklist(ql.fill)
+	for node := ql.head; node != nil; node = node.next {
+		dup.insertNodeAfter(dup.tail, &quicklistNode{lp: node.lp.Dup()})
+	}
+	dup.count = ql.count
+	return dup
+}
+
+// insertNodeAfter links node after prev, or as the head when prev is nil
+func (ql *Quicklist) insertNodeAfter(prev, node *quicklistNode) {
+	node.prev = prev
+	if prev == nil {
+		node.next = ql.head
+		ql.head = node
+	} else {
+		node.next = prev.next
+		prev.next = node
+	}
+	if node.next == nil {
+		ql.tail = node
+	} else {
+		node.next.prev = node
+	}
+	ql.nodes++
+}
+
+// unlinkNode removes node from the list, the elements it holds must be uncounted by the caller
+func (ql *Quicklist) unlinkNode(node *quicklistNode) {
+	if node.prev == nil {
+		ql.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next == nil {
+		ql.tail = node.prev
+	} else {
+		node.next.prev = node.prev
+	}
+	ql.nodes--
+}
+
+// mergeNext merges the node following node into it when both fit in one node
+func (ql *Quicklist) mergeNext(node *quicklistNode) {
+	next := node.next
+	if next == nil || !ql.fits(node.lp.Len()+next.lp.Len(), node.lp.Bytes()+next.lp.Bytes()) {
+		return
+	}
+	node.lp.Merge(next.lp)
+	ql.unlinkNode(next)
+}
+
+// locate returns the node holding element i and the position of the element in the node,
+// i must be in [0, Len())
+func (ql *Quicklist) locate(i int) (*quicklistNode, int) {
+	if i < ql.count/2 {
+		node := ql.head
+		for i >= node.lp.Len() {
+			i -= node.lp.Len()
+			node = node.next
+		}
+		return node, i
+	}
+
+	i = ql.count - 1 - i // Position from the tail
+	node := ql.tail
+	for i >= node.lp.Len() {
+		i -= node.lp.Len()
+		node = node.prev
+	}
+	return node, node.lp.Len() - 1 - i
+}
+
+// normalizeIndex turns a negative index into a position from the head, ok is false
+// when the index is out of range
+func (ql *Quicklist) normalizeIndex(i int) (int, bool) {
+	if i < 0 {
+		i += ql.count
+	}
+	return i, i >= 0 && i < ql.count
+}
+
+// PushHead adds a value before the first element
+func (ql *Quicklist) PushHead(value string) {
+	if ql.nodeAllowsInsert(ql.head, value) {
+		ql.head.lp.Insert(0, value)
+	} else {
+		node := &quicklistNode{lp: NewListpack()}
+		node.lp.Append(value)
+		ql.insertNodeAfter(nil, node)
+	}
+	ql.count++
+}
+
+// PushTail adds a value after the last element
+func (ql *Quicklist) PushTail(value string) {
+	if ql.nodeAllowsInsert(ql.tail, value) {
+		ql.tail.lp.Append(value)
+	} else {
+		node := &quicklistNode{lp: NewListpack()}
+		node.lp.Append(value)
+		ql.insertNodeAfter(ql.tail, node)
+	}
+	ql.count++
+}
+
+// PopHead removes and returns the first element, ok is false when the list is empty
+func (ql *Quicklist) PopHead() (value string, ok bool) {
+	if ql.count == 0 {
+		return "", false
+	}
+	value = ql.head.lp.Get(0)
+	ql.DeleteRange(0, 1)
+	return value, true
+}
+
+// PopTail removes and returns the last element, ok is false when the list is empty
+func (ql *Quicklist) PopTail() (value string, ok bool) {
+	if ql.count == 0 {
+		return "", false
+	}
+	value = ql.tail.lp.Get(ql.tail.lp.Len() - 1)
+	ql.DeleteRange(ql.count-1, 1)
+	return value, true
+}
+
+// Index returns element i, negative indexes count from the tail
+func (ql *Quicklist) Index(i int) (string, bool) {
+	i, ok := ql.normalizeIndex(i)
+	if !ok {
+		return "", false
+	}
+	node, offset := ql.locate(i)
+	return node.lp.Get(offset), true
+}
+
+// Replace overwrites element i, negative indexes count from the tail
+func (ql *Quicklist) Replace(i int, value string) bool {
+	i, ok := ql.normalizeIndex(i)
+	if !ok {
+		return false
+	}
+	node, offset := ql.locate(i)
+	node.lp.Replace(offset, value)
+	return true
+}
+
+// Insert adds a value so it becomes element i, i must be in [0, Len()]
+func (ql *Quicklist) Insert(i int, value string) {
+	if i == ql.count {
+		ql.PushTail(value)
+		return
+	}
+
+	node, offset := ql.locate(i)
+	if ql.nodeAllowsInsert(node, value) {
+		node.lp.Insert(offset, value)
+		ql.count++
+		return
+	}
+
+	// The node is full: split it at the insertion point, then append the value to the
+	// first half or to a new node between both halves
+	if offset > 0 {
+		rest := &quicklistNode{lp: node.lp.Split(offset)}
+		ql.insertNodeAfter(node, rest)
+	} else {
+		node = node.prev
+	}
+	if ql.nodeAllowsInsert(node, value) {
+		node.lp.Append(value)
+	} else {
+		newNode := &quicklistNode{lp: NewListpack()}
+		newNode.lp.Append(value)
+		ql.insertNodeAfter(node, newNode)
+	}
+	ql.count++
+}
+
+// DeleteRange removes n elements starting at element start, start must be in [0, Len())
+func (ql *Quicklist) DeleteRange(start, n int) {
+	n = min(n, ql.count-start)
+	for n > 0 {
+		node, offset := ql.locate(start)
+		deleted := min(n, node.lp.Len()-offset)
+		if deleted == node.lp.Len() {
+			ql.unlinkNode(node)
+		} else {
+			node.lp.DeleteRange(offset, deleted)
+		}
+		ql.count -= deleted
+		n -= deleted
+	}
+
+	// The nodes on both sides of the deleted range may now fit in one
+	if start > 0 && start < ql.count {
+		node, _ := ql.locate(start - 1)
+		ql.mergeNext(node)
+	}
+}
+
+// Iterate calls fn on the elements from element start towards the tail until fn
+// returns false, start must be in [0, Len()]
+func (ql *Quicklist) Iterate(start int, fn func(i int, value string) bool) {
+	if start >= ql.count {
+		return
+	}
+	node, offset := ql.locate(start)
+	i := start
+	for ; node != nil; node = node.next {
+		stop := false
+		node.lp.Iterate(func(k int, value string) bool {
+			if k < offset {
+				return true
+			}
+			if !fn(i, value) {
+				stop = true
+				return false
+			}
+			i++
+			return true
+		})
+		if stop {
+			return
+		}
+		offset = 0
+	}
+}
+
+// IterateReverse calls fn on the elements from element start towards the head until fn
+// returns false, start must be in [-1, Len())
+func (ql *Quicklist) IterateReverse(start int, fn func(i int, value string) bool) {
+	if start < 0 {
+		return
+	}
+	node, offset := ql.locate(start)
+	i := start
+	for ; node != nil; node = node.prev {
+		entries := node.lp.Entries()
+		for k := offset; k >= 0; k-- {
+			if !fn(i, entries[k]) {
+				return
+			}
+			i--
+		}
+		if node.prev != nil {
+			offset = node.prev.lp.Len() - 1
+		}
+	}
+}
+
+// Remove deletes the elements equal to value: the first count ones from the head when
+// count is positive, the last -count ones when count is negative, all of them when
+// count is 0. It returns the number of removed elements
+func (ql *Quicklist) Remove(value string, count int) int {
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+
+	removed := 0
+	node := ql.head
+	if count < 0 {
+		node = ql.tail
+	}
+	for node != nil && (limit == 0 || removed < limit) {
+		next := node.next
+		if count < 0 {
+			next = node.prev
+		}
+
+		// Rebuild the node without the removed elements
+		entries := node.lp.Entries()
+		keep := make([]bool, len(entries))
+		removedInNode := 0
+		for k := range entries {
+			pos := k
+			if count < 0 {
+				pos = len(entries) - 1 - k
+			}
+			if entries[pos] == value && (limit == 0 || removed+removedInNode < limit) {
+				removedInNode++
+			} else {
+				keep[pos] = true
+			}
+		}
+		removed += removedInNode
+		if removedInNode == 0 {
+			node = next
+			continue
+		}
+
+		kept := NewListpack()
+		for k, entry := range entries {
+			if keep[k] {
+				kept.Append(entry)
+			}
+		}
+		ql.count -= len(entries) - kept.Len()
+		if kept.Len() == 0 {
+			ql.unlinkNode(node)
+		} else {
+			node.lp = kept
+		}
+		node = next
+	}
+
+	ql.compact()
+	return removed
+}
+
+// compact merges the neighbour nodes that fit in one node
+func (ql *Quicklist) compact() {
+	for node := ql.head; node != nil; node = node.next {
+		for node.next != nil && ql.fits(node.lp.Len()+node.next.lp.Len(), node.lp.Bytes()+node.next.lp.Bytes()) {
+			ql.mergeNext(node)
+		}
+	}
+}
+
+// NodeCount returns the number of listpack nodes
+func (ql *Quicklist) NodeCount() int {
+	return ql.nodes
+}
@@ -0,0 +1,146 @@
+package data_structure
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// quicklistElements reads the whole list, checking the node invariants on the way
+func quicklistElements(t *testing.T, ql *Quicklist) []string {
+	t.Helper()
+	var elements []string
+	nodes := 0
+	var prev *quicklistNode
+	for node := ql.head; node != nil; node = node.next {
+		if node.prev != prev {
+			t.Fatalf("node %d has a wrong prev link", nodes)
+		}
+		if node.lp.Len() == 0 {
+			t.Fatalf("node %d is empty", nodes)
+		}
+		elements = append(elements, node.lp.Entries()...)
+		prev = node
+		nodes++
+	}
+	if ql.tail != prev {
+		t.Fatalf("tail is not the last node")
+	}
+	if nodes != ql.NodeCount() || len(elements) != ql.Len() {
+		t.Fatalf("got %d nodes and %d elements, counted %d and %d", ql.NodeCount(), ql.Len(), nodes, len(elements))
+	}
+	return elements
+}
+
+func TestQuicklistMatchesSlice(t *testing.T) {
+	for _, fill := range []int{1, 3, -1} {
+		t.Run("fill "+strconv.Itoa(fill), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			ql := NewQuicklist(fill)
+			var expected []string
+
+			for step := 0; step < 5000; step++ {
+				value := strconv.Itoa(rng.Intn(20))
+				if rng.Intn(10) == 0 {
+					value += string(make([]byte, rng.Intn(1000))) // Large elements exercise the byte limit
+				}
+
+				switch op := rng.Intn(9); {
+				case op == 0:
+					ql.PushHead(value)
+					expected = slices.Insert(expected, 0, value)
+				case op == 1:
+					ql.PushTail(value)
+					expected = append(expected, value)
+				case op == 2:
+					i := rng.Intn(len(expected) + 1)
+					ql.Insert(i, value)
+					expected = slices.Insert(expected, i, value)
+				case op == 3 && len(expected) > 0:
+					got, _ := ql.PopHead()
+					if got != expected[0] {
+						t.Fatalf("step %d: PopHead got %q, expected %q", step, got, expected[0])
+					}
+					expected = expected[1:]
+				case op == 4 && len(expected) > 0:
+					got, _ := ql.PopTail()
+					if got != expected[len(expected)-1] {
+						t.Fatalf("step %d: PopTail got %q, expected %q", step, got, expected[len(expected)-1])
+					}
+					expected = expected[:len(expected)-1]
+				case op == 5 && len(expected) > 0:
+					start := rng.Intn(len(expected))
+					n := rng.Intn(10)
+					ql.DeleteRange(start, n)
+					expected = slices.Delete(expected, start, min(start+n, len(expected)))
+				case op == 6 && len(expected) > 0:
+					i := rng.Intn(len(expected))
+					ql.Replace(i-len(expected), value)
+					expected[i] = value
+				case op == 7:
+					count := rng.Intn(5) - 2
+					removed := ql.Remove(value, count)
+					expected = removeFromSlice(expected, value, count)
+					if ql.Len() != len(expected) {
+						t.Fatalf("step %d: Remove removed %d elements, list has %d, expected %d", step, removed, ql.Len(), len(expected))
+					}
+				case op == 8 && len(expected) > 0:
+					i := rng.Intn(len(expected))
+					if got, _ := ql.Index(i); got != expected[i] {
+						t.Fatalf("step %d: Index(%d) got %q, expected %q", step, i, got, expected[i])
+					}
+				}
+
+				if got := quicklistElements(t, ql); !slices.Equal(got, expected) {
+					t.Fatalf("step %d: list differs from the expected elements", step)
+				}
+			}
+		})
+	}
+}
+
+// removeFromSlice removes value following the count argument of LREM
+func removeFromSlice(s []string, value string, count int) []string {
+	if count < 0 {
+		slices.Reverse(s)
+		s = removeFromSlice(s, value, -count)
+		slices.Reverse(s)
+		return s
+	}
+	res := make([]string, 0, len(s))
+	removed := 0
+	for _, v := range s {
+		if v == value && (count == 0 || removed < count) {
+			removed++
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+func TestQuicklistIterate(t *testing.T) {
+	ql := NewQuicklist(2)
+	for i := 0; i < 7; i++ {
+		ql.PushTail(strconv.Itoa(i))
+	}
+
+	var forward []string
+	ql.Iterate(3, func(i int, value string) bool {
+		forward = append(forward, value)
+		return i < 5
+	})
+	if !slices.Equal(forward, []string{"3", "4", "5"}) {
+		t.Errorf("Iterate got %v", forward)
+	}
+
+	var backward []string
+	ql.IterateReverse(4, func(i int, value string) bool {
+		backward = append(backward, value)
+		return true
+	})
+	if !slices.Equal(backward, []string{"4", "3", "2", "1", "0"}) {
+		t.Errorf("IterateReverse got %v", backward)
+	}
+}
@@ -1,59 +1,368 @@
 package data_structure
 
-// Set represents a Redis set (unique string collection)
-type Set map[string]struct{}
+import (
+	"math/rand"
+	"redis-repo/internal/config"
+	"strconv"
+)
 
-// NewSet creates a new set with initial members
-func NewSet(members []string) Set {
-	set := make(Set)
-	for _, member := range members {
-		set[member] = struct{}{}
+// Set represents a Redis set (unique string collection). Like in Redis, small sets are
+// packed: an intset while all the members are integers, a listpack while the set has
+// few short members, and they are converted to a hash table once they grow past the
+// config.SetMax* thresholds. A set never goes back to a packed encoding
+type Set struct {
+	encoding ObjectEncoding // EncodingIntset, EncodingListpack or EncodingHashtable
+	intset   *Intset
+	lp       *Listpack
+	dict     *HashTable[struct{}]
+}
+
+// NewSet creates a new set with initial members, its encoding is chosen from the first
+// member and the number of members as Redis's setTypeCreate
+func NewSet(members []string) *Set {
+	s := &Set{}
+	isInt := true // An empty set starts as an intset, like Redis's createIntsetObject
+	if len(members) > 0 {
+		_, isInt = ParseCanonicalInt(members[0])
+	}
+	switch {
+	case isInt && len(members) <= config.SetMaxIntsetEntries:
+		s.encoding = EncodingIntset
+		s.intset = NewIntset()
+	case len(members) <= config.SetMaxListpackEntries:
+		s.encoding = EncodingListpack
+		s.lp = NewListpack()
+	default:
+		s.encoding = EncodingHashtable
+		s.dict = NewHashTable[struct{}]()
+	}
+	s.Add(members)
+	return s
+}
+
+// Dup returns a copy of the set, keeping its encoding
+func (s *Set) Dup() *Set {
+	dup := &Set{encoding: s.encoding}
+	switch s.encoding {
+	case EncodingIntset:
+		dup.intset = s.intset.Dup()
+	case EncodingListpack:
+		dup.lp = s.lp.Dup()
+	default:
+		dup.dict = s.dict.Dup()
+	}
+	return dup
+}
+
+// Encoding returns the current encoding of the set
+func (s *Set) Encoding() ObjectEncoding {
+	return s.encoding
+}
+
+// Len returns the number of members
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	switch s.encoding {
+	case EncodingIntset:
+		return s.intset.Len()
+	case EncodingListpack:
+		return s.lp.Len()
+	default:
+		return s.dict.Len()
 	}
-	return set
 }
 
 // Add adds members to the set, returns number of new members added
-func (s Set) Add(members []string) int {
+func (s *Set) Add(members []string) int {
 	if s == nil {
 		return 0
 	}
 
 	added := 0
 	for _, member := range members {
-		if _, exists := s[member]; !exists {
-			s[member] = struct{}{}
+		if s.add(member) {
 			added++
 		}
 	}
 	return added
 }
 
+// add adds a member, converting the set to another encoding when the current one cannot
+// hold it. It returns false when the member already exists
+func (s *Set) add(member string) bool {
+	switch s.encoding {
+	case EncodingIntset:
+		if v, ok := ParseCanonicalInt(member); ok {
+			if !s.intset.Add(v) {
+				return false
+			}
+			if s.intset.Len() > config.SetMaxIntsetEntries {
+				if s.intset.Len() <= config.SetMaxListpackEntries && s.intsetFitsListpack() {
+					s.convertToListpack()
+				} else {
+					s.convertToHashtable()
+				}
+			}
+			return true
+		}
+		if s.intset.Len() < config.SetMaxListpackEntries && len(member) <= config.SetMaxListpackValue && s.intsetFitsListpack() {
+			s.convertToListpack()
+		} else {
+			s.convertToHashtable()
+		}
+		return s.add(member)
+
+	case EncodingListpack:
+		if s.lp.Find(member) >= 0 {
+			return false
+		}
+		if s.lp.Len() < config.SetMaxListpackEntries && len(member) <= config.SetMaxListpackValue {
+			s.lp.Append(member)
+			return true
+		}
+		s.convertToHashtable()
+		return s.add(member)
+
+	default:
+		return s.dict.Set(member, struct{}{})
+	}
+}
+
+// intsetFitsListpack reports whether the integers of the intset are short enough for the
+// listpack encoding, the longest being the smallest or the largest one
+func (s *Set) intsetFitsListpack() bool {
+	if s.intset.Len() == 0 {
+		return true
+	}
+	longest := max(len(strconv.FormatInt(s.intset.Min(), 10)), len(strconv.FormatInt(s.intset.Max(), 10)))
+	return longest <= config.SetMaxListpackValue
+}
+
+func (s *Set) convertToListpack() {
+	lp := NewListpack()
+	s.Iterate(func(member string) bool {
+		lp.Append(member)
+		return true
+	})
+	s.encoding = EncodingListpack
+	s.intset = nil
+	s.lp = lp
+}
+
+func (s *Set) convertToHashtable() {
+	dict := NewHashTable[struct{}]()
+	s.Iterate(func(member string) bool {
+		dict.Set(member, struct{}{})
+		return true
+	})
+	s.encoding = EncodingHashtable
+	s.intset = nil
+	s.lp = nil
+	s.dict = dict
+}
+
 // Remove removes members from the set, returns number of members removed
-func (s Set) Remove(members []string) int {
+func (s *Set) Remove(members []string) int {
 	if s == nil {
 		return 0
 	}
 
 	removed := 0
 	for _, member := range members {
-		if _, exists := s[member]; exists {
-			delete(s, member)
+		if s.remove(member) {
 			removed++
 		}
 	}
-
 	return removed
 }
 
+func (s *Set) remove(member string) bool {
+	switch s.encoding {
+	case EncodingIntset:
+		v, ok := ParseCanonicalInt(member)
+		return ok && s.intset.Remove(v)
+	case EncodingListpack:
+		i := s.lp.Find(member)
+		if i < 0 {
+			return false
+		}
+		s.lp.Delete(i)
+		return true
+	default:
+		return s.dict.Delete(member)
+	}
+}
+
 // IsMember checks if a member exists in the set
-func (s Set) IsMember(member string) int {
+func (s *Set) IsMember(member string) int {
 	if s == nil {
 		return 0
 	}
 
-	_, exist := s[member]
+	var exist bool
+	switch s.encoding {
+	case EncodingIntset:
+		v, ok := ParseCanonicalInt(member)
+		exist = ok && s.intset.Contains(v)
+	case EncodingListpack:
+		exist = s.lp.Find(member) >= 0
+	default:
+		_, exist = s.dict.Get(member)
+	}
 	if exist {
 		return 1
 	}
 	return 0
 }
+
+// Iterate calls fn on every member until fn returns false
+func (s *Set) Iterate(fn func(member string) bool) {
+	if s == nil {
+		return
+	}
+	switch s.encoding {
+	case EncodingIntset:
+		s.intset.Iterate(func(v int64) bool {
+			return fn(strconv.FormatInt(v, 10))
+		})
+	case EncodingListpack:
+		s.lp.Iterate(func(_ int, member string) bool {
+			return fn(member)
+		})
+	default:
+		s.dict.Iterate(func(member string, _ struct{}) bool {
+			return fn(member)
+		})
+	}
+}
+
+// SnapshotIterate is Iterate for a set shared with a snapshot, see
+// HashTable.SnapshotIterate
+func (s *Set) SnapshotIterate(fn func(member string) bool) {
+	if s == nil || s.encoding != EncodingHashtable {
+		s.Iterate(fn) // Walking a packed set only reads it
+		return
+	}
+	s.dict.SnapshotIterate(func(member string, _ struct{}) bool {
+		return fn(member)
+	})
+}
+
+// Scan calls fn on the members of one bucket of the hash table and returns the next
+// cursor, see HashTable.Scan. A packed set is small, so it is walked in one call
+// returning the cursor 0 as Redis does
+func (s *Set) Scan(cursor uint64, fn func(member string)) uint64 {
+	if s == nil {
+		return 0
+	}
+	if s.encoding == EncodingHashtable {
+		return s.dict.Scan(cursor, func(member string, _ struct{}) {
+			fn(member)
+		})
+	}
+	s.Iterate(func(member string) bool {
+		fn(member)
+		return true
+	})
+	return 0
+}
+
+// Members returns all the members of the set
+func (s *Set) Members() []string {
+	members := make([]string, 0, s.Len())
+	s.Iterate(func(member string) bool {
+		members = append(members, member)
+		return true
+	})
+	return members
+}
+
+// RandomMember returns a random member of a non empty set in constant time, as Redis's
+// setTypeRandomElement
+func (s *Set) RandomMember() string {
+	switch s.encoding {
+	case EncodingIntset:
+		return strconv.FormatInt(s.intset.Get(rand.Intn(s.intset.Len())), 10)
+	case EncodingListpack:
+		return s.lp.Get(rand.Intn(s.lp.Len()))
+	default:
+		member, _ := s.dict.RandomKey()
+		return member
+	}
+}
+
+// SetUnion returns the members found in any of the sets
+func SetUnion(sets []*Set) *Set {
+	result := NewSet(nil)
+	for _, set := range sets {
+		set.Iterate(func(member string) bool {
+			result.add(member)
+			return true
+		})
+	}
+	return result
+}
+
+// SetInter returns the members found in all the sets
+func SetInter(sets []*Set) *Set {
+	result := NewSet(nil)
+	iterateSetInter(sets, func(member string) bool {
+		result.add(member)
+		return true
+	})
+	return result
+}
+
+// SetInterCard returns the number of members found in all the sets, stopping at limit
+// unless limit is 0
+func SetInterCard(sets []*Set, limit int) int {
+	count := 0
+	iterateSetInter(sets, func(member string) bool {
+		count++
+		return limit == 0 || count < limit
+	})
+	return count
+}
+
+// iterateSetInter calls fn on the members of the intersection until fn returns false.
+// It walks the smallest set and looks its members up in the others
+func iterateSetInter(sets []*Set, fn func(member string) bool) {
+	if len(sets) == 0 {
+		return
+	}
+	smallest := 0
+	for i := 1; i < len(sets); i++ {
+		if sets[i].Len() < sets[smallest].Len() {
+			smallest = i
+		}
+	}
+
+	sets[smallest].Iterate(func(member string) bool {
+		for i, set := range sets {
+			if i != smallest && set.IsMember(member) == 0 {
+				return true
+			}
+		}
+		return fn(member)
+	})
+}
+
+// SetDiff returns the members of the first set found in no other set
+func SetDiff(sets []*Set) *Set {
+	result := NewSet(nil)
+	if len(sets) == 0 {
+		return result
+	}
+	sets[0].Iterate(func(member string) bool {
+		for _, set := range sets[1:] {
+			if set.IsMember(member) == 1 {
+				return true
+			}
+		}
+		result.add(member)
+		return true
+	})
+	return result
+}
@@ -0,0 +1,113 @@
+package data_structure
+
+import (
+	"redis-repo/internal/config"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestIntsetUpgrade(t *testing.T) {
+	is := NewIntset()
+	values := []int64{5, -3, 70000, 1 << 40, -(1 << 40), 0, 5}
+	for _, v := range values {
+		is.Add(v)
+	}
+	if is.width != 8 {
+		t.Errorf("Expected width 8 after adding a 64 bit integer, got %d", is.width)
+	}
+
+	var got []int64
+	is.Iterate(func(v int64) bool {
+		got = append(got, v)
+		return true
+	})
+	expected := []int64{-(1 << 40), -3, 0, 5, 70000, 1 << 40}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+
+	if !is.Remove(70000) || is.Remove(70000) || is.Contains(70000) {
+		t.Errorf("Expected 70000 to be removed once")
+	}
+}
+
+func TestSetEncodingConversions(t *testing.T) {
+	tests := []struct {
+		name     string
+		members  func() []string
+		expected ObjectEncoding
+	}{
+		{
+			name:     "integers",
+			members:  func() []string { return []string{"1", "-2", "300"} },
+			expected: EncodingIntset,
+		},
+		{
+			name:     "non canonical integer",
+			members:  func() []string { return []string{"1", "02"} },
+			expected: EncodingListpack,
+		},
+		{
+			name: "too many integers",
+			members: func() []string {
+				var members []string
+				for i := 0; i <= config.SetMaxIntsetEntries; i++ {
+					members = append(members, strconv.Itoa(i))
+				}
+				return members
+			},
+			expected: EncodingHashtable,
+		},
+		{
+			name: "too many strings",
+			members: func() []string {
+				var members []string
+				for i := 0; i <= config.SetMaxListpackEntries; i++ {
+					members = append(members, "m"+strconv.Itoa(i))
+				}
+				return members
+			},
+			expected: EncodingHashtable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := tt.members()
+
+			// Adding the members one by one must give the same encoding as creating the set at once
+			incremental := NewSet(nil)
+			for _, member := range members {
+				incremental.Add([]string{member})
+			}
+			for _, set := range []*Set{NewSet(members), incremental} {
+				if set.Encoding() != tt.expected {
+					t.Errorf("Expected encoding %s, got %s", tt.expected, set.Encoding())
+				}
+				got := set.Members()
+				sort.Strings(got)
+				want := append([]string(nil), members...)
+				sort.Strings(want)
+				if len(got) != len(want) {
+					t.Fatalf("Expected %d members, got %d", len(want), len(got))
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("Expected members %v, got %v", want, got)
+					}
+				}
+				for _, member := range members {
+					if set.IsMember(member) != 1 {
+						t.Errorf("Expected %q to be a member", member)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package data_structure
+
+import "math/rand"
+
+// Skiplist parameters, as in Redis: up to 32 levels, each level holding a quarter
+// of the nodes of the level below
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+// skiplist keeps the members of a sorted set ordered by score, then by member.
+// Every forward link stores its span, the number of nodes it skips, so the rank
+// of a node is the sum of the spans on the way to it
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	levels   []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+func newSkiplistNode(level int, score float64, member string) *skiplistNode {
+	return &skiplistNode{member: member, score: score, levels: make([]skiplistLevel, level)}
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: newSkiplistNode(skiplistMaxLevel, 0, ""),
+		level:  1,
+	}
+}
+
+// free unlinks every node, as Redis's zslFree
+func (sl *skiplist) free() {
+	for x := sl.header.levels[0].forward; x != nil; {
+		next := x.levels[0].forward
+		*x = skiplistNode{}
+		x = next
+	}
+	for i := range sl.header.levels {
+		sl.header.levels[i] = skiplistLevel{}
+	}
+	sl.tail, sl.length, sl.level = nil, 0, 1
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// less reports whether the node sorts before the given score and member
+func (n *skiplistNode) less(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert adds a member that is not in the skiplist yet
+func (sl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	// Find the last node before the new one at every level, and its rank
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		if i < sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.header
+			update[i].levels[i].span = sl.length
+		}
+		sl.level = level
+	}
+
+	x = newSkiplistNode(level, score, member)
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+
+	// The levels above the new node now skip one more node
+	for i := level; i < sl.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != sl.header {
+		x.backward = update[0]
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+	sl.length++
+	return x
+}
+
+// deleteNode unlinks x, update holds the last node before x at every level
+func (sl *skiplist) deleteNode(x *skiplistNode, update []*skiplistNode) {
+	for i := 0; i < sl.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+	for sl.level > 1 && sl.header.levels[sl.level-1].forward == nil {
+		sl.level--
+	}
+	sl.length--
+}
+
+// delete removes the node with the given score and member, returns false when there is none
+func (sl *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, member) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.levels[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	sl.deleteNode(x, update[:])
+	return true
+}
+
+// rank returns the 1-based rank of the node with the given score and member, 0 when there is none
+func (sl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !scoreMemberLess(score, member, x.levels[i].forward) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		// x may be the header, whose member is empty like a real one
+		if x != sl.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// scoreMemberLess reports whether the score and member sort before the node
+func scoreMemberLess(score float64, member string, n *skiplistNode) bool {
+	return score < n.score || (score == n.score && member < n.member)
+}
+
+// byRank returns the node with the given 1-based rank, nil when out of range
+func (sl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == rank {
+			if x == sl.header {
+				return nil
+			}
+			return x
+		}
+	}
+	return nil
+}
+
+// firstMatch returns the first node for which aboveMin is true, nil when there is none.
+// aboveMin must be false for a prefix of the nodes and true for the others
+func (sl *skiplist) firstMatch(aboveMin func(n *skiplistNode) bool) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !aboveMin(x.levels[i].forward) {
+			x = x.levels[i].forward
+		}
+	}
+	return x.levels[0].forward
+}
+
+// lastMatch returns the last node for which belowMax is true, nil when there is none.
+// belowMax must be true for a prefix of the nodes and false for the others
+func (sl *skiplist) lastMatch(belowMax func(n *skiplistNode) bool) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && belowMax(x.levels[i].forward) {
+			x = x.levels[i].forward
+		}
+	}
+	if x == sl.header {
+		return nil
+	}
+	return x
+}
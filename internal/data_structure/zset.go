@@ -0,0 +1,283 @@
+package data_structure
+
+// ZSet represents a Redis sorted set: a dict from member to score for O(1) score
+// lookups, and a skiplist ordering the members by score for rank and range queries
+type ZSet struct {
+	dict *HashTable[float64]
+	zsl  *skiplist
+}
+
+// ZSetMember is a member of a sorted set with its score
+type ZSetMember struct {
+	Member string
+	Score  float64
+}
+
+// ScoreRange is a range of scores, each end being inclusive unless marked exclusive
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+func (r ScoreRange) aboveMin(score float64) bool {
+	if r.MinEx {
+		return score > r.Min
+	}
+	return score >= r.Min
+}
+
+func (r ScoreRange) belowMax(score float64) bool {
+	if r.MaxEx {
+		return score < r.Max
+	}
+	return score <= r.Max
+}
+
+// IsEmpty reports whether no score can be in the range
+func (r ScoreRange) IsEmpty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinEx || r.MaxEx))
+}
+
+// LexBound is an end of a LexRange: a member, or an infinity
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int // -1 for "-", lower than every member, 1 for "+", greater than every member
+}
+
+// LexRange is a range of members of a sorted set whose members all have the same score
+type LexRange struct {
+	Min, Max LexBound
+}
+
+func (r LexRange) aboveMin(member string) bool {
+	switch {
+	case r.Min.Inf != 0:
+		return r.Min.Inf < 0
+	case r.Min.Exclusive:
+		return member > r.Min.Value
+	default:
+		return member >= r.Min.Value
+	}
+}
+
+func (r LexRange) belowMax(member string) bool {
+	switch {
+	case r.Max.Inf != 0:
+		return r.Max.Inf > 0
+	case r.Max.Exclusive:
+		return member < r.Max.Value
+	default:
+		return member <= r.Max.Value
+	}
+}
+
+// IsEmpty reports whether no member can be in the range
+func (r LexRange) IsEmpty() bool {
+	if r.Min.Inf > 0 || r.Max.Inf < 0 {
+		return true
+	}
+	if r.Min.Inf < 0 || r.Max.Inf > 0 {
+		return false
+	}
+	return r.Min.Value > r.Max.Value || (r.Min.Value == r.Max.Value && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+// NewZSet creates an empty sorted set
+func NewZSet() *ZSet {
+	return &ZSet{
+		dict: NewHashTable[float64](),
+		zsl:  newSkiplist(),
+	}
+}
+
+// Dup returns a copy of the sorted set
+func (z *ZSet) Dup() *ZSet {
+	dup := NewZSet()
+	z.Iterate(func(m ZSetMember) bool {
+		dup.Set(m.Member, m.Score)
+		return true
+	})
+	return dup
+}
+
+// Len returns the number of members
+func (z *ZSet) Len() int {
+	return z.dict.Len()
+}
+
+// Score returns the score of a member
+func (z *ZSet) Score(member string) (float64, bool) {
+	score, ok := z.dict.Get(member)
+	return score, ok
+}
+
+// RandomMember returns a random member of a non empty sorted set with its score
+func (z *ZSet) RandomMember() ZSetMember {
+	member, _ := z.dict.RandomKey()
+	score, _ := z.dict.Get(member)
+	return ZSetMember{Member: member, Score: score}
+}
+
+// Set adds a member or updates its score, returns true when the member is new
+func (z *ZSet) Set(member string, score float64) bool {
+	current, exists := z.dict.Get(member)
+	if exists {
+		if current != score {
+			z.zsl.delete(current, member)
+			z.zsl.insert(score, member)
+			z.dict.Set(member, score)
+		}
+		return false
+	}
+
+	z.zsl.insert(score, member)
+	z.dict.Set(member, score)
+	return true
+}
+
+// Remove deletes a member, returns false when it does not exist
+func (z *ZSet) Remove(member string) bool {
+	score, exists := z.dict.Get(member)
+	if !exists {
+		return false
+	}
+	z.zsl.delete(score, member)
+	z.dict.Delete(member)
+	return true
+}
+
+// Rank returns the 0-based rank of a member, in descending order when reverse is set
+func (z *ZSet) Rank(member string, reverse bool) (int, bool) {
+	score, exists := z.dict.Get(member)
+	if !exists {
+		return 0, false
+	}
+	rank := z.zsl.rank(score, member) - 1
+	if reverse {
+		rank = z.Len() - 1 - rank
+	}
+	return rank, true
+}
+
+// RangeByRank calls fn on the members from rank start to rank stop, both included and
+// in [0, Len()), until fn returns false. Ranks are in descending order when reverse is set
+func (z *ZSet) RangeByRank(start, stop int, reverse bool, fn func(m ZSetMember) bool) {
+	if start > stop || start >= z.Len() {
+		return
+	}
+	var x *skiplistNode
+	if reverse {
+		x = z.zsl.byRank(z.Len() - start)
+	} else {
+		x = z.zsl.byRank(start + 1)
+	}
+	for n := stop - start + 1; x != nil && n > 0; n-- {
+		if !fn(ZSetMember{Member: x.member, Score: x.score}) {
+			return
+		}
+		x = nextNode(x, reverse)
+	}
+}
+
+// RangeByScore calls fn on the members within the range, from the lowest score or from
+// the highest one when reverse is set, skipping offset members, until fn returns false
+func (z *ZSet) RangeByScore(r ScoreRange, reverse bool, offset int, fn func(m ZSetMember) bool) {
+	if r.IsEmpty() {
+		return
+	}
+	var x *skiplistNode
+	if reverse {
+		x = z.zsl.lastMatch(func(n *skiplistNode) bool { return r.belowMax(n.score) })
+	} else {
+		x = z.zsl.firstMatch(func(n *skiplistNode) bool { return r.aboveMin(n.score) })
+	}
+	for ; x != nil && r.aboveMin(x.score) && r.belowMax(x.score); x = nextNode(x, reverse) {
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if !fn(ZSetMember{Member: x.member, Score: x.score}) {
+			return
+		}
+	}
+}
+
+// RangeByLex is RangeByScore for a range of members, assuming they all have the same score
+func (z *ZSet) RangeByLex(r LexRange, reverse bool, offset int, fn func(m ZSetMember) bool) {
+	if r.IsEmpty() {
+		return
+	}
+	var x *skiplistNode
+	if reverse {
+		x = z.zsl.lastMatch(func(n *skiplistNode) bool { return r.belowMax(n.member) })
+	} else {
+		x = z.zsl.firstMatch(func(n *skiplistNode) bool { return r.aboveMin(n.member) })
+	}
+	for ; x != nil && r.aboveMin(x.member) && r.belowMax(x.member); x = nextNode(x, reverse) {
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if !fn(ZSetMember{Member: x.member, Score: x.score}) {
+			return
+		}
+	}
+}
+
+// CountInRange returns the number of members whose score is within the range
+func (z *ZSet) CountInRange(r ScoreRange) int {
+	if r.IsEmpty() {
+		return 0
+	}
+	first := z.zsl.firstMatch(func(n *skiplistNode) bool { return r.aboveMin(n.score) })
+	if first == nil || !r.belowMax(first.score) {
+		return 0
+	}
+	last := z.zsl.lastMatch(func(n *skiplistNode) bool { return r.belowMax(n.score) })
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// PopMin removes and returns the member with the lowest score, ok is false when the set is empty
+func (z *ZSet) PopMin() (m ZSetMember, ok bool) {
+	return z.pop(z.zsl.header.levels[0].forward)
+}
+
+// PopMax removes and returns the member with the highest score, ok is false when the set is empty
+func (z *ZSet) PopMax() (m ZSetMember, ok bool) {
+	return z.pop(z.zsl.tail)
+}
+
+func (z *ZSet) pop(x *skiplistNode) (ZSetMember, bool) {
+	if x == nil {
+		return ZSetMember{}, false
+	}
+	m := ZSetMember{Member: x.member, Score: x.score}
+	z.Remove(x.member)
+	return m, true
+}
+
+// Iterate calls fn on the members from the lowest score until fn returns false
+func (z *ZSet) Iterate(fn func(m ZSetMember) bool) {
+	for x := z.zsl.header.levels[0].forward; x != nil; x = x.levels[0].forward {
+		if !fn(ZSetMember{Member: x.member, Score: x.score}) {
+			return
+		}
+	}
+}
+
+// Scan calls fn on the members of one bucket of the dict and returns the next cursor,
+// see HashTable.Scan
+func (z *ZSet) Scan(cursor uint64, fn func(m ZSetMember)) uint64 {
+	return z.dict.Scan(cursor, func(member string, score float64) {
+		fn(ZSetMember{Member: member, Score: score})
+	})
+}
+
+// nextNode returns the following node in ascending order, or in descending order when reverse is set
+func nextNode(x *skiplistNode, reverse bool) *skiplistNode {
+	if reverse {
+		return x.backward
+	}
+	return x.levels[0].forward
+}
@@ -0,0 +1,174 @@
+package data_structure
+
+import (
+	"math"
+	"sort"
+)
+
+// Aggregate tells how the scores of a member found in several inputs are combined
+type Aggregate int
+
+const (
+	AggregateSum Aggregate = iota
+	AggregateMin
+	AggregateMax
+)
+
+// ScoredMembers is an input of the sorted set algebra, implemented by ZSet and by
+// plain sets through SetScores
+type ScoredMembers interface {
+	Len() int
+	Score(member string) (float64, bool)
+	Iterate(fn func(m ZSetMember) bool)
+}
+
+// ZSetInput is a weighted input of ZUnion and ZInter, every score is multiplied by the weight
+type ZSetInput struct {
+	Members ScoredMembers
+	Weight  float64
+}
+
+// setScores presents a plain set as a sorted set whose members all score 1
+type setScores struct {
+	set *Set
+}
+
+// SetScores wraps a plain set so it can be used as an input of the sorted set algebra
+func SetScores(s *Set) ScoredMembers {
+	return setScores{s}
+}
+
+func (s setScores) Len() int {
+	return s.set.Len()
+}
+
+func (s setScores) Score(member string) (float64, bool) {
+	if s.set.IsMember(member) == 0 {
+		return 0, false
+	}
+	return 1, true
+}
+
+func (s setScores) Iterate(fn func(m ZSetMember) bool) {
+	s.set.Iterate(func(member string) bool {
+		return fn(ZSetMember{Member: member, Score: 1})
+	})
+}
+
+// weighted multiplies a score by a weight, 0 * inf is 0 as in Redis
+func weighted(score, weight float64) float64 {
+	score *= weight
+	if math.IsNaN(score) {
+		return 0
+	}
+	return score
+}
+
+// combine aggregates the score of a member in another input into acc, inf + -inf is 0
+func (agg Aggregate) combine(acc, score float64) float64 {
+	switch agg {
+	case AggregateMin:
+		return min(acc, score)
+	case AggregateMax:
+		return max(acc, score)
+	default:
+		acc += score
+		if math.IsNaN(acc) {
+			return 0
+		}
+		return acc
+	}
+}
+
+// ZUnion returns the members found in any input, their scores aggregated over the inputs
+func ZUnion(inputs []ZSetInput, agg Aggregate) *ZSet {
+	scores := make(map[string]float64)
+	for _, input := range inputs {
+		input.Members.Iterate(func(m ZSetMember) bool {
+			score := weighted(m.Score, input.Weight)
+			if acc, ok := scores[m.Member]; ok {
+				score = agg.combine(acc, score)
+			}
+			scores[m.Member] = score
+			return true
+		})
+	}
+
+	result := NewZSet()
+	for member, score := range scores {
+		result.Set(member, score)
+	}
+	return result
+}
+
+// ZInter returns the members found in every input, their scores aggregated over the inputs
+func ZInter(inputs []ZSetInput, agg Aggregate) *ZSet {
+	result := NewZSet()
+	iterateInter(inputs, agg, func(m ZSetMember) bool {
+		result.Set(m.Member, m.Score)
+		return true
+	})
+	return result
+}
+
+// ZInterCard returns the number of members found in every input, stopping at limit
+// unless limit is 0
+func ZInterCard(inputs []ScoredMembers, limit int) int {
+	weightedInputs := make([]ZSetInput, len(inputs))
+	for i, input := range inputs {
+		weightedInputs[i] = ZSetInput{Members: input, Weight: 1}
+	}
+
+	count := 0
+	iterateInter(weightedInputs, AggregateSum, func(m ZSetMember) bool {
+		count++
+		return limit == 0 || count < limit
+	})
+	return count
+}
+
+// iterateInter calls fn on the members of the intersection until fn returns false.
+// Like SINTER, it walks the smallest input and looks its members up in the others
+func iterateInter(inputs []ZSetInput, agg Aggregate, fn func(m ZSetMember) bool) {
+	if len(inputs) == 0 {
+		return
+	}
+	sorted := append([]ZSetInput(nil), inputs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Members.Len() < sorted[j].Members.Len()
+	})
+	if sorted[0].Members.Len() == 0 {
+		return // Intersecting with an empty input is always empty
+	}
+
+	sorted[0].Members.Iterate(func(m ZSetMember) bool {
+		acc := weighted(m.Score, sorted[0].Weight)
+		for _, other := range sorted[1:] {
+			score, ok := other.Members.Score(m.Member)
+			if !ok {
+				return true
+			}
+			acc = agg.combine(acc, weighted(score, other.Weight))
+		}
+		return fn(ZSetMember{Member: m.Member, Score: acc})
+	})
+}
+
+// ZDiff returns the members of the first input found in no other input, with their
+// scores in the first input
+func ZDiff(inputs []ScoredMembers) *ZSet {
+	result := NewZSet()
+	if len(inputs) == 0 {
+		return result
+	}
+	inputs[0].Iterate(func(m ZSetMember) bool {
+		for _, other := range inputs[1:] {
+			if _, ok := other.Score(m.Member); ok {
+				return true
+			}
+		}
+		result.Set(m.Member, m.Score)
+		return true
+	})
+	return result
+}
@@ -0,0 +1,241 @@
+package data_structure
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// sortedMembers returns the members of a score map in sorted set order
+func sortedMembers(scores map[string]float64) []ZSetMember {
+	members := make([]ZSetMember, 0, len(scores))
+	for member, score := range scores {
+		members = append(members, ZSetMember{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score < members[j].Score
+		}
+		return members[i].Member < members[j].Member
+	})
+	return members
+}
+
+func TestZSetMatchesSortedSlice(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	z := NewZSet()
+	scores := make(map[string]float64)
+
+	for step := 0; step < 3000; step++ {
+		member := "m" + strconv.Itoa(rng.Intn(200))
+		switch rng.Intn(4) {
+		case 0, 1:
+			score := float64(rng.Intn(50))
+			_, exists := scores[member]
+			if added := z.Set(member, score); added == exists {
+				t.Fatalf("step %d: Set(%q) returned %v", step, member, added)
+			}
+			scores[member] = score
+		case 2:
+			_, exists := scores[member]
+			if removed := z.Remove(member); removed != exists {
+				t.Fatalf("step %d: Remove(%q) returned %v", step, member, removed)
+			}
+			delete(scores, member)
+		case 3:
+			if len(scores) > 0 {
+				m, _ := z.PopMin()
+				delete(scores, m.Member)
+			}
+		}
+
+		if step%50 != 0 {
+			continue
+		}
+
+		expected := sortedMembers(scores)
+		if z.Len() != len(expected) {
+			t.Fatalf("step %d: Len() = %d, expected %d", step, z.Len(), len(expected))
+		}
+		for i, m := range expected {
+			if rank, _ := z.Rank(m.Member, false); rank != i {
+				t.Fatalf("step %d: Rank(%q) = %d, expected %d", step, m.Member, rank, i)
+			}
+			if rank, _ := z.Rank(m.Member, true); rank != len(expected)-1-i {
+				t.Fatalf("step %d: reverse Rank(%q) = %d, expected %d", step, m.Member, rank, len(expected)-1-i)
+			}
+		}
+
+		var got []ZSetMember
+		z.RangeByRank(0, z.Len()-1, false, func(m ZSetMember) bool {
+			got = append(got, m)
+			return true
+		})
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("step %d: RangeByRank differs at %d: %v, expected %v", step, i, got[i], expected[i])
+			}
+		}
+
+		r := ScoreRange{Min: 10, Max: 20, MinEx: true}
+		inRange := 0
+		for _, m := range expected {
+			if m.Score > 10 && m.Score <= 20 {
+				inRange++
+			}
+		}
+		if count := z.CountInRange(r); count != inRange {
+			t.Fatalf("step %d: CountInRange = %d, expected %d", step, count, inRange)
+		}
+	}
+}
+
+func TestZSetRanges(t *testing.T) {
+	z := NewZSet()
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		z.Set(member, float64(i))
+	}
+
+	collect := func(ranger func(fn func(m ZSetMember) bool)) string {
+		s := ""
+		ranger(func(m ZSetMember) bool {
+			s += m.Member
+			return true
+		})
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		ranger   func(fn func(m ZSetMember) bool)
+		expected string
+	}{
+		{
+			name:     "by rank",
+			ranger:   func(fn func(m ZSetMember) bool) { z.RangeByRank(1, 3, false, fn) },
+			expected: "bcd",
+		},
+		{
+			name:     "by rank reverse",
+			ranger:   func(fn func(m ZSetMember) bool) { z.RangeByRank(0, 1, true, fn) },
+			expected: "ed",
+		},
+		{
+			name: "by score",
+			ranger: func(fn func(m ZSetMember) bool) {
+				z.RangeByScore(ScoreRange{Min: 1, Max: 3, MaxEx: true}, false, 0, fn)
+			},
+			expected: "bc",
+		},
+		{
+			name:     "by score reverse with offset",
+			ranger:   func(fn func(m ZSetMember) bool) { z.RangeByScore(ScoreRange{Min: 0, Max: 4}, true, 1, fn) },
+			expected: "dcba",
+		},
+		{
+			name:     "by score empty",
+			ranger:   func(fn func(m ZSetMember) bool) { z.RangeByScore(ScoreRange{Min: 3, Max: 1}, false, 0, fn) },
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collect(tt.ranger); got != tt.expected {
+				t.Errorf("got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+
+	// Lexicographic ranges assume equal scores
+	lex := NewZSet()
+	for _, member := range []string{"a", "b", "c", "d"} {
+		lex.Set(member, 0)
+	}
+	got := collect(func(fn func(m ZSetMember) bool) {
+		lex.RangeByLex(LexRange{Min: LexBound{Value: "b", Exclusive: true}, Max: LexBound{Inf: 1}}, false, 0, fn)
+	})
+	if got != "cd" {
+		t.Errorf("RangeByLex got %q, expected %q", got, "cd")
+	}
+	got = collect(func(fn func(m ZSetMember) bool) {
+		lex.RangeByLex(LexRange{Min: LexBound{Inf: -1}, Max: LexBound{Value: "c"}}, true, 0, fn)
+	})
+	if got != "cba" {
+		t.Errorf("reverse RangeByLex got %q, expected %q", got, "cba")
+	}
+}
+
+func TestZSetOps(t *testing.T) {
+	newZSet := func(scores map[string]float64) *ZSet {
+		z := NewZSet()
+		for member, score := range scores {
+			z.Set(member, score)
+		}
+		return z
+	}
+	format := func(z *ZSet) string {
+		s := ""
+		z.Iterate(func(m ZSetMember) bool {
+			s += m.Member + "=" + strconv.FormatFloat(m.Score, 'g', -1, 64) + " "
+			return true
+		})
+		return s
+	}
+
+	a := newZSet(map[string]float64{"x": 1, "y": 2, "z": 3})
+	b := newZSet(map[string]float64{"y": 10, "z": 20, "w": 30})
+	s := SetScores(NewSet([]string{"x", "w"}))
+
+	tests := []struct {
+		name     string
+		result   *ZSet
+		expected string
+	}{
+		{
+			name:     "union sums the scores",
+			result:   ZUnion([]ZSetInput{{a, 1}, {b, 1}}, AggregateSum),
+			expected: "x=1 y=12 z=23 w=30 ",
+		},
+		{
+			name:     "union of a set with weights and max",
+			result:   ZUnion([]ZSetInput{{a, 1}, {s, 5}}, AggregateMax),
+			expected: "y=2 z=3 w=5 x=5 ",
+		},
+		{
+			name:     "inter with min",
+			result:   ZInter([]ZSetInput{{b, 1}, {a, 2}}, AggregateMin),
+			expected: "y=4 z=6 ",
+		},
+		{
+			name:     "inter with an empty input",
+			result:   ZInter([]ZSetInput{{a, 1}, {NewZSet(), 1}}, AggregateSum),
+			expected: "",
+		},
+		{
+			name:     "diff keeps the scores of the first input",
+			result:   ZDiff([]ScoredMembers{a, s}),
+			expected: "y=2 z=3 ",
+		},
+		{
+			name:     "infinite sums are 0",
+			result:   ZUnion([]ZSetInput{{newZSet(map[string]float64{"m": math.Inf(1)}), 1}, {newZSet(map[string]float64{"m": math.Inf(-1)}), 1}}, AggregateSum),
+			expected: "m=0 ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(tt.result); got != tt.expected {
+				t.Errorf("got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+
+	if got := ZInterCard([]ScoredMembers{a, b}, 0); got != 2 {
+		t.Errorf("ZInterCard got %d, expected 2", got)
+	}
+	if got := ZInterCard([]ScoredMembers{a, b}, 1); got != 1 {
+		t.Errorf("ZInterCard with a limit got %d, expected 1", got)
+	}
+}
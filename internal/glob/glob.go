@@ -0,0 +1,136 @@
+// Package glob implements the glob-style patterns of Redis's stringmatchlen, used by
+// KEYS and the MATCH option of the SCAN family. '*' matches any sequence of characters,
+// '?' any single character, "[abc]" one of the characters, "[a-z]" a character in the
+// range and "[^a]" any other character. A backslash matches the next character literally.
+//
+// As in Redis, the empty string matches no pattern but the empty one, so callers that
+// accept every element on a lone "*" check for it before matching.
+package glob
+
+// maxNesting bounds the recursion on '*', so a pattern made of many stars cannot
+// exhaust the stack. Deeper patterns never match, as in Redis
+const maxNesting = 1000
+
+// Match reports whether s matches pattern
+func Match(pattern, s string) bool {
+	skipLongerMatches := false
+	return match(pattern, s, false, &skipLongerMatches, 0)
+}
+
+// MatchFold is Match ignoring the ASCII case
+func MatchFold(pattern, s string) bool {
+	skipLongerMatches := false
+	return match(pattern, s, true, &skipLongerMatches, 0)
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func equal(a, b byte, nocase bool) bool {
+	if nocase {
+		return lower(a) == lower(b)
+	}
+	return a == b
+}
+
+// match follows stringmatchlen_impl. When a '*' fails to match the rest of s at every
+// position, skipLongerMatches is set: the outer '*' would only try shorter suffixes of s,
+// which cannot match either, so the search stops instead of being exponential
+func match(pattern, s string, nocase bool, skipLongerMatches *bool, nesting int) bool {
+	if nesting > maxNesting {
+		return false
+	}
+
+	p, i := 0, 0
+	for p < len(pattern) && i < len(s) {
+		switch pattern[p] {
+		case '*':
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true // A trailing star matches the rest of s
+			}
+			for ; i < len(s); i++ {
+				if match(pattern[p+1:], s[i:], nocase, skipLongerMatches, nesting+1) {
+					return true
+				}
+				if *skipLongerMatches {
+					return false
+				}
+			}
+			*skipLongerMatches = true
+			return false
+
+		case '?':
+			i++
+
+		case '[':
+			p++
+			not := p < len(pattern) && pattern[p] == '^'
+			if not {
+				p++
+			}
+			matched := false
+			for {
+				if p < len(pattern) && pattern[p] == '\\' && len(pattern)-p >= 2 {
+					p++
+					if pattern[p] == s[i] {
+						matched = true
+					}
+				} else if p < len(pattern) && pattern[p] == ']' {
+					break
+				} else if p >= len(pattern) {
+					p-- // An unterminated class ends the pattern
+					break
+				} else if len(pattern)-p >= 3 && pattern[p+1] == '-' {
+					start, end, c := pattern[p], pattern[p+2], s[i]
+					if start > end {
+						start, end = end, start
+					}
+					if nocase {
+						start, end, c = lower(start), lower(end), lower(c)
+					}
+					p += 2
+					if c >= start && c <= end {
+						matched = true
+					}
+				} else if equal(pattern[p], s[i], nocase) {
+					matched = true
+				}
+				p++
+			}
+			if not {
+				matched = !matched
+			}
+			if !matched {
+				return false
+			}
+			i++
+
+		case '\\':
+			if len(pattern)-p >= 2 {
+				p++
+			}
+			fallthrough
+		default:
+			if !equal(pattern[p], s[i], nocase) {
+				return false
+			}
+			i++
+		}
+
+		p++
+		if i == len(s) {
+			for p < len(pattern) && pattern[p] == '*' {
+				p++
+			}
+			break
+		}
+	}
+	return p == len(pattern) && i == len(s)
+}
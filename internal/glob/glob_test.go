@@ -0,0 +1,57 @@
+package glob
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{"*", "", false}, // As in Redis, callers treat a lone "*" as matching everything
+		{"*", "anything", true},
+		{"", "", true},
+		{"", "a", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "hllo", true},
+		{"h*llo", "heeeello", true},
+		{"h*llo*", "hello world", true},
+		{"user:*:name", "user:42:name", true},
+		{"user:*:name", "user:42:email", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true}, // Reversed ranges are swapped
+		{"h[a-b]llo", "hcllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"h[\\]]llo", "h]llo", true},
+		{"*\\", "a\\", true},
+		{"h[ab", "ha", true}, // An unterminated class ends the pattern
+		{"a**b", "ab", true},
+		{"a*", "", false},
+		{"*a*", "bab", true},
+		{"Hello", "hello", false},
+		{strings.Repeat("a*", 50) + "b", strings.Repeat("a", 60), false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.s); got != tt.expected {
+			t.Errorf("Match(%q, %q) = %v, expected %v", tt.pattern, tt.s, got, tt.expected)
+		}
+	}
+}
+
+func TestMatchFold(t *testing.T) {
+	if !MatchFold("H[A-C]llo", "hbLLO") {
+		t.Errorf("Expected a case insensitive match")
+	}
+	if MatchFold("h[^B]llo", "hbllo") {
+		t.Errorf("Expected the negated class to ignore the case")
+	}
+}
@@ -1,55 +1,156 @@
 package client
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"redis-repo/internal/config"
+	"redis-repo/internal/constant"
 	"redis-repo/internal/core/command"
+	"redis-repo/internal/core/connection"
 	"redis-repo/internal/core/executor"
 	"redis-repo/internal/core/io_multiplexing"
-	"redis-repo/internal/core/resp"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 )
 
-// parseCmd parses RESP data into a Command struct
-func parseCmd(data []byte) (*command.Command, error) {
-	value, err := resp.Decode(data)
-	if err != nil {
-		return nil, err
+var errQueryBufferLimit = errors.New("query buffer limit exceeded")
+
+// connections holds the state of every open client, indexed by file descriptor
+var connections = make(map[int]*connection.Connection)
+
+// maxMultibulkLen is the largest number of arguments of a request, as in Redis
+const maxMultibulkLen = 1024 * 1024
+
+// parseCommands decodes every complete command in the query buffer of conn, resuming the
+// request left partial by the previous read where it stopped, so every byte is parsed once.
+// It returns the commands and the number of bytes they used; the bytes of a trailing
+// partial command stay in the buffer, its arguments already parsed in conn.Argv
+func parseCommands(conn *connection.Connection) ([]*command.Command, int, error) {
+	var cmds []*command.Command
+	consumed := 0
+	for conn.QueryPos < len(conn.QueryBuf) {
+		complete, err := parseMultibulk(conn)
+		if err != nil {
+			return cmds, consumed, err
+		}
+		if !complete {
+			break
+		}
+		consumed = conn.QueryPos
+
+		// Empty multibulk requests are ignored, as Redis does
+		if len(conn.Argv) > 0 {
+			cmds = append(cmds, &command.Command{
+				Cmd:  strings.ToUpper(conn.Argv[0]),
+				Args: conn.Argv[1:],
+			})
+		}
+		conn.Argv = nil
 	}
+	return cmds, consumed, nil
+}
 
-	array := value.([]any)
-	tokens := make([]string, len(array))
-	for i := range tokens {
-		tokens[i] = array[i].(string)
+// parseMultibulk parses the request at conn.QueryPos as Redis's processMultibulkBuffer,
+// and reports whether it is complete. A partial request keeps its state on conn
+func parseMultibulk(conn *connection.Connection) (bool, error) {
+	if conn.MultibulkLen == 0 {
+		line, size, err := readLine(conn.QueryBuf[conn.QueryPos:])
+		if size == 0 {
+			return false, err
+		}
+		if len(line) == 0 || line[0] != '*' {
+			return false, fmt.Errorf("expected '*', got %q", line)
+		}
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxMultibulkLen {
+			return false, fmt.Errorf("invalid multibulk length %q", line[1:])
+		}
+		conn.QueryPos += size
+		if n <= 0 {
+			return true, nil
+		}
+		conn.MultibulkLen = n
+		// The declared length is untrusted until the arguments arrive, so cap the preallocation
+		conn.Argv = make([]string, 0, min(n, 1024))
 	}
 
-	res := &command.Command{
-		Cmd:  strings.ToUpper(tokens[0]),
-		Args: tokens[1:],
+	for conn.MultibulkLen > 0 {
+		if conn.BulkLen == -1 {
+			line, size, err := readLine(conn.QueryBuf[conn.QueryPos:])
+			if size == 0 {
+				return false, err
+			}
+			if len(line) == 0 || line[0] != '$' {
+				return false, fmt.Errorf("expected '$', got %q", line)
+			}
+			n, err := strconv.Atoi(string(line[1:]))
+			if err != nil || n < 0 || n > config.ProtoMaxBulkLen {
+				return false, fmt.Errorf("invalid bulk length %q", line[1:])
+			}
+			conn.QueryPos += size
+			conn.BulkLen = n
+		}
+
+		end := conn.QueryPos + conn.BulkLen
+		if end+2 > len(conn.QueryBuf) {
+			return false, nil
+		}
+		if conn.QueryBuf[end] != '\r' || conn.QueryBuf[end+1] != '\n' {
+			return false, errors.New("missing CRLF after a bulk argument")
+		}
+		conn.Argv = append(conn.Argv, string(conn.QueryBuf[conn.QueryPos:end]))
+		conn.QueryPos = end + 2
+		conn.BulkLen = -1
+		conn.MultibulkLen--
 	}
-	return res, nil
+	return true, nil
 }
 
-// readCommand reads a command from a file descriptor
-func readCommand(fd int) (*command.Command, error) {
-	buf := make([]byte, 512)
-	n, err := syscall.Read(fd, buf)
-	if err != nil {
-		return nil, err
+// readLine returns the line at the start of data without its CRLF and the bytes it uses,
+// a size of 0 while the CRLF has not arrived
+func readLine(data []byte) ([]byte, int, error) {
+	i := bytes.IndexByte(data, '\r')
+	if i == -1 || i+1 == len(data) {
+		if len(data) > config.ProtoInlineMaxSize {
+			return nil, 0, errors.New("too big count string")
+		}
+		return nil, 0, nil
 	}
-	if n == 0 {
-		return nil, io.EOF
+	if data[i+1] != '\n' {
+		return nil, 0, errors.New("missing CRLF after a count")
 	}
+	return data[:i], i + 2, nil
+}
 
-	cmd, err := parseCmd(buf[:n])
+// readQuery appends the bytes available on the connection to its query buffer. Like Redis,
+// the rest of a big bulk argument is read in one go into a buffer sized for it
+func readQuery(conn *connection.Connection) error {
+	readLen := config.IOBufferSize
+	if conn.BulkLen >= config.ProtoMbulkBigArg {
+		readLen = max(readLen, conn.QueryPos+conn.BulkLen+2-len(conn.QueryBuf))
+	}
+	conn.QueryBuf = slices.Grow(conn.QueryBuf, readLen)
+
+	start := len(conn.QueryBuf)
+	n, err := syscall.Read(conn.Fd, conn.QueryBuf[start:start+readLen])
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if n == 0 {
+		return io.EOF
 	}
 
-	return cmd, nil
+	conn.QueryBuf = conn.QueryBuf[:start+n]
+	if len(conn.QueryBuf) > config.MaxQueryBufferBytes {
+		return errQueryBufferLimit
+	}
+	return nil
 }
 
 // HandleNewConnection accepts a new client connection and adds it to the IO multiplexer monitoring
@@ -61,6 +162,13 @@ func HandleNewConnection(serverFd int, ioMultiplexer *io_multiplexing.Epoll) {
 		return
 	}
 
+	// Replies are written without blocking; whatever the socket cannot take is kept in the output buffer
+	if err = syscall.SetNonblock(connFd, true); err != nil {
+		log.Println("Set non-blocking on connection", formattedAddress, "failed:", err)
+		syscall.Close(connFd)
+		return
+	}
+
 	log.Println("New connection from:", formattedAddress)
 	if err = ioMultiplexer.Monitor(syscall.EpollEvent{
 		Fd:     int32(connFd),
@@ -68,28 +176,162 @@ func HandleNewConnection(serverFd int, ioMultiplexer *io_multiplexing.Epoll) {
 	}); err != nil {
 		log.Println("Monitor connection", formattedAddress, "failed:", err)
 		syscall.Close(connFd)
+		return
 	}
+	connections[connFd] = connection.NewConnection(connFd)
 }
 
-// HandleClientData reads commands from a client connection and sends responses
+// HandleClientData reads the available bytes from a client connection, executes every
+// complete command they contain and sends the responses
 // Returns true if connection should be closed, false otherwise
-func HandleClientData(clientFd int) bool {
-	cmd, err := readCommand(clientFd)
-	if err != nil {
-		if err == io.EOF || err == syscall.ECONNRESET {
-			return true
+func HandleClientData(clientFd int, ioMultiplexer *io_multiplexing.Epoll) bool {
+	conn, ok := connections[clientFd]
+	if !ok {
+		conn = connection.NewConnection(clientFd)
+		connections[clientFd] = conn
+	}
+
+	if err := readQuery(conn); err != nil {
+		switch err {
+		case syscall.EAGAIN, syscall.EINTR:
+			return false
+		case io.EOF, syscall.ECONNRESET:
+		case errQueryBufferLimit:
+			log.Println("Closing client", clientFd, "that exceeded the query buffer limit")
+		default:
+			log.Println("Read Error:", err)
+		}
+		return closeConnection(clientFd)
+	}
+
+	cmds, consumed, parseErr := parseCommands(conn)
+	for _, cmd := range cmds {
+		if conn.CloseASAP {
+			break
 		}
-		log.Println("Read Error:", err)
+		executor.ExecuteAndRespond(cmd, conn)
+	}
+	if conn.Master {
+		executor.FeedMasterStream(conn.QueryBuf[:consumed]) // Sent as received to the replicas of this replica
+	}
+	conn.ConsumeQuery(consumed)
+	executor.FlushAppendOnlyFile() // The writes reach the AOF before their replies are sent
+
+	if parseErr != nil && conn.Master {
+		log.Println("Protocol error from master:", parseErr)
+		return closeConnection(clientFd)
+	}
+	if parseErr != nil {
+		// The stream cannot be resynchronized after a malformed request,
+		// send what is pending and the error, then drop the client
+		log.Printf("Protocol error from client %d: %.200s", clientFd, parseErr)
+		conn.AddReply([]byte(constant.ErrProtocol))
+		conn.WriteOutput()
+		return closeConnection(clientFd)
+	}
+
+	return sendPendingOutput(conn, ioMultiplexer)
+}
+
+// AttachMasterConnection monitors the link to the master once the replication handshake is
+// done, and executes the commands received with the RDB file
+// Returns true if connection should be closed, false otherwise
+func AttachMasterConnection(conn *connection.Connection, ioMultiplexer *io_multiplexing.Epoll) bool {
+	if err := ioMultiplexer.Monitor(syscall.EpollEvent{
+		Fd:     int32(conn.Fd),
+		Events: syscall.EPOLLIN,
+	}); err != nil {
+		log.Println("Monitor master connection failed:", err)
+		executor.ConnectionClosed(conn)
+		return true
+	}
+	connections[conn.Fd] = conn
+
+	cmds, consumed, parseErr := parseCommands(conn)
+	for _, cmd := range cmds {
+		executor.ExecuteAndRespond(cmd, conn)
+	}
+	executor.FeedMasterStream(conn.QueryBuf[:consumed])
+	conn.ConsumeQuery(consumed)
+	executor.FlushAppendOnlyFile()
+	if parseErr != nil {
+		log.Println("Protocol error from master:", parseErr)
+		return closeConnection(conn.Fd)
+	}
+	return sendPendingOutput(conn, ioMultiplexer)
+}
+
+// HandleReplicationOutput sends the output queued for the replicas and the master outside
+// of their own commands, and returns the file descriptors of the ones to close
+func HandleReplicationOutput(ioMultiplexer *io_multiplexing.Epoll) []int {
+	var closed []int
+	for _, conn := range executor.ReplicationConnections() {
+		if _, ok := connections[conn.Fd]; !ok || (conn.PendingOutput() == 0 && !conn.CloseASAP) {
+			continue
+		}
+		if conn.CloseASAP || sendPendingOutput(conn, ioMultiplexer) {
+			closeConnection(conn.Fd)
+			closed = append(closed, conn.Fd)
+		}
+	}
+	return closed
+}
+
+// HandleClientWritable continues sending the output buffer of a client whose socket became writable
+// Returns true if connection should be closed, false otherwise
+func HandleClientWritable(clientFd int, ioMultiplexer *io_multiplexing.Epoll) bool {
+	conn, ok := connections[clientFd]
+	if !ok {
 		return false
 	}
+	return sendPendingOutput(conn, ioMultiplexer)
+}
+
+// sendPendingOutput writes the client's output buffer and monitors EPOLLOUT only while part of it remains
+// Returns true if connection should be closed, false otherwise
+func sendPendingOutput(conn *connection.Connection, ioMultiplexer *io_multiplexing.Epoll) bool {
+	if conn.CloseASAP {
+		log.Println("Closing client", conn.Fd, "that exceeded its output buffer limit")
+		return closeConnection(conn.Fd)
+	}
+
+	if err := conn.WriteOutput(); err != nil {
+		if err != syscall.EPIPE && err != syscall.ECONNRESET {
+			log.Println("Write Error:", err)
+		}
+		return closeConnection(conn.Fd)
+	}
 
-	if err = executor.ExecuteAndRespond(cmd, clientFd); err != nil {
-		log.Println("Execute and respond failed:", err)
+	wantWrite := conn.PendingOutput() > 0
+	if wantWrite == conn.WantWrite {
+		return false
 	}
 
+	events := uint32(syscall.EPOLLIN)
+	if wantWrite {
+		events |= syscall.EPOLLOUT
+	}
+	if err := ioMultiplexer.Modify(syscall.EpollEvent{
+		Fd:     int32(conn.Fd),
+		Events: events,
+	}); err != nil {
+		log.Println("Modify monitored events for client", conn.Fd, "failed:", err)
+		return closeConnection(conn.Fd)
+	}
+	conn.WantWrite = wantWrite
 	return false
 }
 
+// closeConnection forgets the state of a client, the caller closes its file descriptor
+func closeConnection(clientFd int) bool {
+	if conn, ok := connections[clientFd]; ok {
+		conn.Release()
+		executor.ConnectionClosed(conn)
+	}
+	delete(connections, clientFd)
+	return true
+}
+
 func formatSockaddr(sa syscall.Sockaddr) string {
 	switch a := sa.(type) {
 	case *syscall.SockaddrInet4:
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"redis-repo/internal/core/connection"
+	"strings"
+	"testing"
+)
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		cmds     []string
+		consumed int
+		hasError bool
+	}{
+		{
+			name:     "single command",
+			input:    "*2\r\n$3\r\nget\r\n$1\r\nk\r\n",
+			cmds:     []string{"GET k"},
+			consumed: 20,
+		},
+		{
+			name:     "pipelined commands",
+			input:    "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n*3\r\n$3\r\nSET\r\n$1\r\nb\r\n$1\r\n2\r\n*1\r\n$4\r\nPING\r\n",
+			cmds:     []string{"SET a 1", "SET b 2", "PING"},
+			consumed: 68,
+		},
+		{
+			name:     "trailing partial command is kept",
+			input:    "*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n$5\r\nhel",
+			cmds:     []string{"PING"},
+			consumed: 14,
+		},
+		{
+			name:     "only a partial command",
+			input:    "*2\r\n$3\r\nGET",
+			consumed: 0,
+		},
+		{
+			name:     "empty multibulk is skipped",
+			input:    "*0\r\n*1\r\n$4\r\nPING\r\n",
+			cmds:     []string{"PING"},
+			consumed: 18,
+		},
+		{
+			name:     "non array request",
+			input:    "+PING\r\n",
+			consumed: 0,
+			hasError: true,
+		},
+		{
+			name:     "bulk without its CRLF",
+			input:    "*1\r\n$4\r\nPINGxx",
+			consumed: 0,
+			hasError: true,
+		},
+		{
+			name:     "malformed length after a valid command",
+			input:    "*1\r\n$4\r\nPING\r\n*1\r\n$x\r\nPING\r\n",
+			cmds:     []string{"PING"},
+			consumed: 14,
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := connection.NewConnection(0)
+			conn.QueryBuf = []byte(tt.input)
+			cmds, consumed, err := parseCommands(conn)
+			if tt.hasError != (err != nil) {
+				t.Fatalf("Expected error=%v, got %v", tt.hasError, err)
+			}
+			if consumed != tt.consumed {
+				t.Errorf("Expected %d bytes consumed, got %d", tt.consumed, consumed)
+			}
+			if len(cmds) != len(tt.cmds) {
+				t.Fatalf("Expected %d commands, got %d", len(tt.cmds), len(cmds))
+			}
+			for i, cmd := range cmds {
+				got := strings.Join(append([]string{cmd.Cmd}, cmd.Args...), " ")
+				if got != tt.cmds[i] {
+					t.Errorf("Expected command %q, got %q", tt.cmds[i], got)
+				}
+			}
+		})
+	}
+}
+
+// Test that a request received a few bytes at a time is parsed where the previous read
+// stopped, with the bytes of the partial request kept until it completes
+func TestParseCommandsResumes(t *testing.T) {
+	input := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$10\r\n0123456789\r\n*0\r\n*1\r\n$4\r\nPING\r\n"
+	conn := connection.NewConnection(0)
+	var got []string
+	for i := 0; i < len(input); i += 3 {
+		conn.QueryBuf = append(conn.QueryBuf, input[i:min(i+3, len(input))]...)
+		cmds, consumed, err := parseCommands(conn)
+		if err != nil {
+			t.Fatalf("Unexpected error after %d bytes: %v", i, err)
+		}
+		for _, cmd := range cmds {
+			got = append(got, strings.Join(append([]string{cmd.Cmd}, cmd.Args...), " "))
+		}
+		conn.ConsumeQuery(consumed)
+		if conn.QueryPos > len(conn.QueryBuf) {
+			t.Fatalf("Parsed %d bytes of a %d bytes buffer", conn.QueryPos, len(conn.QueryBuf))
+		}
+	}
+	if strings.Join(got, ",") != "SET key 0123456789,PING" || len(conn.QueryBuf) != 0 {
+		t.Errorf("Expected SET and PING with nothing left, got %q and %q", got, conn.QueryBuf)
+	}
+}
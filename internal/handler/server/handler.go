@@ -5,4 +5,8 @@ import "redis-repo/internal/core/executor"
 // HandleSystemCleanup handles system-level cleanup operations
 func HandleSystemCleanup() {
 	executor.CleanupExpiredKeys()
+	executor.RehashKeyspace()
+	executor.CheckSnapshot()
+	executor.CheckAppendOnlyRewrite()
+	executor.FlushAppendOnlyFile()
 }
@@ -0,0 +1,29 @@
+package rdb
+
+// crc64Table is the table of the Jones polynomial used by Redis, in its reflected form.
+// Unlike hash/crc64, Redis neither inverts the initial value nor the result
+var crc64Table = makeCRC64Table(0x95ac9329ac4bc9b5)
+
+func makeCRC64Table(poly uint64) *[256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		crc := uint64(i)
+		for j := 0; j < 8; j++ {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ poly
+			} else {
+				crc >>= 1
+			}
+		}
+		table[i] = crc
+	}
+	return &table
+}
+
+// CRC64 returns the checksum of p following crc, as Redis's crc64
+func CRC64(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crc64Table[byte(crc)^b] ^ crc>>8
+	}
+	return crc
+}
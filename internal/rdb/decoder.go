@@ -0,0 +1,485 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"redis-repo/internal/config"
+	"redis-repo/internal/data_structure"
+	"strconv"
+)
+
+// Decoder reads a snapshot in the RDB format
+type Decoder struct {
+	r      *bufio.Reader
+	crc    uint64
+	offset int64
+
+	Version int               // Version of the file, once its header is read
+	Aux     map[string]string // Auxiliary fields read so far
+}
+
+// NewDecoder creates a decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), Aux: make(map[string]string)}
+}
+
+// Offset returns the number of bytes read so far. After an error, it is the offset of
+// the data that could not be read
+func (d *Decoder) Offset() int64 {
+	return d.offset
+}
+
+func (d *Decoder) read(n int) ([]byte, error) {
+	// A length read from a corrupted file may be huge, so large reads grow their buffer
+	// with the data actually read
+	b, err := io.ReadAll(io.LimitReader(d.r, int64(n)))
+	d.crc = CRC64(d.crc, b)
+	d.offset += int64(len(b))
+	if err == nil && len(b) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	if len(b) < n {
+		b = append(b, make([]byte, n-len(b))...)
+	}
+	return b, err
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.read(1)
+	return b[0], err
+}
+
+// readLen reads a length. encoded is set when it is the special encoding of a string,
+// the length being then the encoding
+func (d *Decoder) readLen() (n uint64, encoded bool, err error) {
+	c, err := d.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch {
+	case c>>6 == len6Bit:
+		return uint64(c & 0x3f), false, nil
+	case c>>6 == len14Bit:
+		next, err := d.readByte()
+		return uint64(c&0x3f)<<8 | uint64(next), false, err
+	case c == len32Bit:
+		b, err := d.read(4)
+		return uint64(binary.BigEndian.Uint32(b)), false, err
+	case c == len64Bit:
+		b, err := d.read(8)
+		return binary.BigEndian.Uint64(b), false, err
+	case c>>6 == lenEnc:
+		return uint64(c & 0x3f), true, nil
+	}
+	return 0, false, fmt.Errorf("unknown length encoding %d", c)
+}
+
+// readCount reads a length that is not a string encoding
+func (d *Decoder) readCount() (int, error) {
+	n, encoded, err := d.readLen()
+	if err == nil && (encoded || n > math.MaxInt32) {
+		err = fmt.Errorf("invalid length %d", n)
+	}
+	return int(n), err
+}
+
+func (d *Decoder) readString() (string, error) {
+	n, encoded, err := d.readLen()
+	if err != nil {
+		return "", err
+	}
+	if !encoded {
+		if n > config.ProtoMaxBulkLen {
+			return "", fmt.Errorf("string of %d bytes is too long", n)
+		}
+		b, err := d.read(int(n))
+		return string(b), err
+	}
+
+	switch n {
+	case encInt8:
+		b, err := d.read(1)
+		return strconv.Itoa(int(int8(b[0]))), err
+	case encInt16:
+		b, err := d.read(2)
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b)))), err
+	case encInt32:
+		b, err := d.read(4)
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b)))), err
+	case encLZF:
+		clen, err := d.readCount()
+		if err != nil {
+			return "", err
+		}
+		ulen, err := d.readCount()
+		if err != nil {
+			return "", err
+		}
+		if ulen > config.ProtoMaxBulkLen {
+			return "", fmt.Errorf("string of %d bytes is too long", ulen)
+		}
+		compressed, err := d.read(clen)
+		if err != nil {
+			return "", err
+		}
+		b, err := lzfDecompress(compressed, ulen)
+		return string(b), err
+	}
+	return "", fmt.Errorf("unknown string encoding %d", n)
+}
+
+func (d *Decoder) readMillis() (uint64, error) {
+	b, err := d.read(8)
+	return binary.LittleEndian.Uint64(b), err
+}
+
+// readStrings reads n strings
+func (d *Decoder) readStrings(n int) ([]string, error) {
+	values := make([]string, 0, min(n, 1024))
+	for i := 0; i < n; i++ {
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// readListpack reads a string holding a listpack and returns its values
+func (d *Decoder) readListpack() ([]string, error) {
+	b, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	return decodeListpack([]byte(b))
+}
+
+// Decode reads the whole file, calling fn for every key in the order of the file, with
+// the database it belongs to. Every key is returned, even the ones that have expired.
+// Reading stops at the first error, returned with the offset of the data in Offset
+func (d *Decoder) Decode(fn func(db int, entry data_structure.SnapshotEntry) error) error {
+	header, err := d.read(9)
+	if err != nil {
+		return err
+	}
+	if string(header[:5]) != "REDIS" {
+		return errors.New("wrong signature trying to load DB from file")
+	}
+	d.Version, err = strconv.Atoi(string(header[5:]))
+	if err != nil || d.Version < 1 || d.Version > maxVersion {
+		return fmt.Errorf("can't handle RDB format version %s", header[5:])
+	}
+
+	db := 0
+	var expiryTime uint64
+	for {
+		opcode, err := d.readByte()
+		if err != nil {
+			return err
+		}
+
+		switch opcode {
+		case opEOF:
+			return d.readChecksum()
+		case opSelectDB:
+			if db, err = d.readCount(); err != nil {
+				return err
+			}
+		case opResizeDB:
+			if _, err = d.readCount(); err == nil {
+				_, err = d.readCount()
+			}
+		case opExpireTimeMs:
+			expiryTime, err = d.readMillis()
+		case opExpireTime:
+			var b []byte
+			b, err = d.read(4)
+			expiryTime = uint64(binary.LittleEndian.Uint32(b)) * 1000
+		case opAux:
+			var key, value string
+			if key, err = d.readString(); err == nil {
+				value, err = d.readString()
+				d.Aux[key] = value
+			}
+		case opIdle:
+			_, err = d.readCount()
+		case opFreq:
+			_, err = d.readByte()
+		case opSlotInfo:
+			for i := 0; i < 3 && err == nil; i++ {
+				_, err = d.readCount()
+			}
+		case opFunction2:
+			_, err = d.readString()
+		case opModuleAux:
+			return errors.New("modules are not supported")
+		default:
+			var entry data_structure.SnapshotEntry
+			if entry, err = d.readEntry(opcode); err != nil {
+				return err
+			}
+			entry.ExpiryTimeMs = expiryTime
+			expiryTime = 0
+			err = fn(db, entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readChecksum checks the CRC64 ending the file, a zero checksum meaning it was not computed
+func (d *Decoder) readChecksum() error {
+	if d.Version < 5 {
+		return nil
+	}
+	expected := d.crc
+	b, err := d.read(8)
+	if err != nil {
+		return err
+	}
+	if checksum := binary.LittleEndian.Uint64(b); checksum != 0 && checksum != expected {
+		d.offset -= 8
+		return ErrChecksum
+	}
+	return nil
+}
+
+// readEntry reads a key and its value of type valueType
+func (d *Decoder) readEntry(valueType byte) (data_structure.SnapshotEntry, error) {
+	key, err := d.readString()
+	if err != nil {
+		return data_structure.SnapshotEntry{}, err
+	}
+	value, err := d.readValue(valueType)
+	if err != nil {
+		return data_structure.SnapshotEntry{}, err
+	}
+	return data_structure.SnapshotEntry{Key: key, Value: data_structure.NewValueObject(value)}, nil
+}
+
+// readValue reads a value, returning the Go value accepted by data_structure.NewValueObject
+func (d *Decoder) readValue(valueType byte) (any, error) {
+	switch valueType {
+	case typeString:
+		return d.readString()
+	case typeList, typeListQuicklist2:
+		return d.readList(valueType)
+	case typeSet, typeSetIntset, typeSetListpack:
+		return d.readSet(valueType)
+	case typeZSet, typeZSet2, typeZSetListpack:
+		return d.readZSet(valueType)
+	case typeHash, typeHashListpack, typeHashMetadata, typeHashListpackEx:
+		return d.readHash(valueType)
+	}
+	return nil, fmt.Errorf("unsupported value type %d", valueType)
+}
+
+func (d *Decoder) readList(valueType byte) (*data_structure.Quicklist, error) {
+	list := data_structure.NewQuicklist(config.ListMaxListpackSize)
+	n, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		if valueType == typeList {
+			value, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			list.PushTail(value)
+			continue
+		}
+
+		container, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		switch container {
+		case containerPlain:
+			var value string
+			value, err = d.readString()
+			values = []string{value}
+		case containerPacked:
+			values, err = d.readListpack()
+		default:
+			err = fmt.Errorf("unknown quicklist container %d", container)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			list.PushTail(value)
+		}
+	}
+	if list.Len() == 0 {
+		return nil, errors.New("empty list")
+	}
+	return list, nil
+}
+
+func (d *Decoder) readSet(valueType byte) (*data_structure.Set, error) {
+	var members []string
+	var err error
+	switch valueType {
+	case typeSet:
+		var n int
+		if n, err = d.readCount(); err == nil {
+			members, err = d.readStrings(n)
+		}
+	case typeSetIntset:
+		var b string
+		if b, err = d.readString(); err == nil {
+			members, err = decodeIntset([]byte(b))
+		}
+	default:
+		members, err = d.readListpack()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, errors.New("empty set")
+	}
+	return data_structure.NewSet(members), nil
+}
+
+func (d *Decoder) readZSet(valueType byte) (*data_structure.ZSet, error) {
+	zset := data_structure.NewZSet()
+	if valueType == typeZSetListpack {
+		values, err := d.readListpack()
+		if err != nil {
+			return nil, err
+		}
+		if len(values)%2 != 0 {
+			return nil, errListpack
+		}
+		for i := 0; i < len(values); i += 2 {
+			score, err := strconv.ParseFloat(values[i+1], 64)
+			if err != nil {
+				return nil, err
+			}
+			zset.Set(values[i], score)
+		}
+	} else {
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			member, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			score, err := d.readScore(valueType)
+			if err != nil {
+				return nil, err
+			}
+			zset.Set(member, score)
+		}
+	}
+	if zset.Len() == 0 {
+		return nil, errors.New("empty sorted set")
+	}
+	return zset, nil
+}
+
+// readScore reads a score, in binary for typeZSet2 and as a string for the older typeZSet
+func (d *Decoder) readScore(valueType byte) (float64, error) {
+	if valueType == typeZSet2 {
+		b, err := d.read(8)
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), err
+	}
+
+	n, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	b, err := d.read(int(n))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(b), 64)
+}
+
+func (d *Decoder) readHash(valueType byte) (*data_structure.Hash, error) {
+	hash := data_structure.NewHash()
+	var minExpiry uint64
+	var err error
+	if valueType == typeHashMetadata || valueType == typeHashListpackEx {
+		if minExpiry, err = d.readMillis(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch valueType {
+	case typeHash, typeHashMetadata:
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < n; i++ {
+			var ttl uint64
+			if valueType == typeHashMetadata {
+				if ttl, _, err = d.readLen(); err != nil {
+					return nil, err
+				}
+			}
+			pair, err := d.readStrings(2)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(pair[0], pair[1])
+			if ttl != 0 {
+				hash.SetExpiry(pair[0], minExpiry+ttl-1)
+			}
+		}
+	case typeHashListpack:
+		values, err := d.readListpack()
+		if err != nil {
+			return nil, err
+		}
+		if len(values)%2 != 0 {
+			return nil, errListpack
+		}
+		for i := 0; i < len(values); i += 2 {
+			hash.Set(values[i], values[i+1])
+		}
+	default: // Triplets of a field, its value and its expiry time, 0 for none
+		values, err := d.readListpack()
+		if err != nil {
+			return nil, err
+		}
+		if len(values)%3 != 0 {
+			return nil, errListpack
+		}
+		for i := 0; i < len(values); i += 3 {
+			hash.Set(values[i], values[i+1])
+			expiryTime, err := strconv.ParseUint(values[i+2], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if expiryTime != 0 {
+				hash.SetExpiry(values[i], expiryTime)
+			}
+		}
+	}
+	if hash.Len() == 0 {
+		return nil, errors.New("empty hash")
+	}
+	return hash, nil
+}
@@ -0,0 +1,244 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"redis-repo/internal/data_structure"
+	"strconv"
+)
+
+// List nodes are written as listpacks of at most this many elements, or bytes
+const (
+	listpackMaxEntries = 128
+	listpackMaxBytes   = 8192
+)
+
+// Encoder writes a snapshot in the RDB format. Its methods are called in the order of the
+// file: WriteHeader, WriteAux, WriteDB for every database, then WriteEOF. The first error
+// is kept and returned by every later call
+type Encoder struct {
+	w   *bufio.Writer
+	crc uint64
+	err error
+}
+
+// NewEncoder creates an encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+func (e *Encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	e.crc = CRC64(e.crc, b)
+	_, e.err = e.w.Write(b)
+}
+
+func (e *Encoder) writeByte(b byte) {
+	e.write([]byte{b})
+}
+
+// writeLen writes a length with the shortest of the 6, 14, 32 and 64 bit encodings
+func (e *Encoder) writeLen(n uint64) {
+	switch {
+	case n < 1<<6:
+		e.writeByte(byte(n))
+	case n < 1<<14:
+		e.write([]byte{len14Bit<<6 | byte(n>>8), byte(n)})
+	case n <= math.MaxUint32:
+		e.write(binary.BigEndian.AppendUint32([]byte{len32Bit}, uint32(n)))
+	default:
+		e.write(binary.BigEndian.AppendUint64([]byte{len64Bit}, n))
+	}
+}
+
+// writeString writes a string, as an 8, 16 or 32 bit integer when it is one, like Redis's
+// rdbSaveRawString. Strings are not compressed
+func (e *Encoder) writeString(s string) {
+	if n, ok := data_structure.ParseCanonicalInt(s); ok && len(s) <= 11 {
+		switch {
+		case n >= math.MinInt8 && n <= math.MaxInt8:
+			e.write([]byte{lenEnc<<6 | encInt8, byte(n)})
+			return
+		case n >= math.MinInt16 && n <= math.MaxInt16:
+			e.write(binary.LittleEndian.AppendUint16([]byte{lenEnc<<6 | encInt16}, uint16(n)))
+			return
+		case n >= math.MinInt32 && n <= math.MaxInt32:
+			e.write(binary.LittleEndian.AppendUint32([]byte{lenEnc<<6 | encInt32}, uint32(n)))
+			return
+		}
+	}
+	e.writeLen(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *Encoder) writeMillis(ms uint64) {
+	e.write(binary.LittleEndian.AppendUint64(nil, ms))
+}
+
+// WriteHeader writes the magic string and the version, see SnapshotVersion
+func (e *Encoder) WriteHeader(version int) error {
+	e.write([]byte(fmt.Sprintf("REDIS%04d", version)))
+	return e.err
+}
+
+// WriteAux writes an auxiliary field, such as the version of the server that wrote the file
+func (e *Encoder) WriteAux(key, value string) error {
+	e.writeByte(opAux)
+	e.writeString(key)
+	e.writeString(value)
+	return e.err
+}
+
+// WriteDB writes the keys of database id, nothing when it has none
+func (e *Encoder) WriteDB(id int, entries []data_structure.SnapshotEntry) error {
+	if len(entries) == 0 {
+		return e.err
+	}
+
+	expires := 0
+	for _, entry := range entries {
+		if entry.ExpiryTimeMs != 0 {
+			expires++
+		}
+	}
+	e.writeByte(opSelectDB)
+	e.writeLen(uint64(id))
+	e.writeByte(opResizeDB)
+	e.writeLen(uint64(len(entries)))
+	e.writeLen(uint64(expires))
+
+	for _, entry := range entries {
+		if err := e.WriteEntry(entry); err != nil {
+			return err
+		}
+	}
+	return e.err
+}
+
+// WriteEntry writes a key with its expiry and value
+func (e *Encoder) WriteEntry(entry data_structure.SnapshotEntry) error {
+	if entry.ExpiryTimeMs != 0 {
+		e.writeByte(opExpireTimeMs)
+		e.writeMillis(entry.ExpiryTimeMs)
+	}
+
+	switch v := entry.Value.Value.(type) {
+	case int64:
+		e.writeByte(typeString)
+		e.writeString(entry.Key)
+		e.writeString(strconv.FormatInt(v, 10))
+	case string:
+		e.writeByte(typeString)
+		e.writeString(entry.Key)
+		e.writeString(v)
+	case *data_structure.Quicklist:
+		e.writeByte(typeListQuicklist2)
+		e.writeString(entry.Key)
+		e.writeList(v)
+	case *data_structure.Set:
+		e.writeByte(typeSet)
+		e.writeString(entry.Key)
+		e.writeLen(uint64(v.Len()))
+		v.SnapshotIterate(func(member string) bool {
+			e.writeString(member)
+			return e.err == nil
+		})
+	case *data_structure.ZSet:
+		e.writeByte(typeZSet2)
+		e.writeString(entry.Key)
+		e.writeLen(uint64(v.Len()))
+		v.Iterate(func(m data_structure.ZSetMember) bool {
+			e.writeString(m.Member)
+			e.write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(m.Score)))
+			return e.err == nil
+		})
+	case *data_structure.Hash:
+		e.writeHash(entry.Key, v)
+	default:
+		return fmt.Errorf("cannot save a value of type %T", v)
+	}
+	return e.err
+}
+
+// writeList writes a list as quicklist nodes, each one a listpack
+func (e *Encoder) writeList(list *data_structure.Quicklist) {
+	var nodes [][]string
+	var node []string
+	size := 0
+	list.Iterate(0, func(_ int, value string) bool {
+		if len(node) == listpackMaxEntries || (len(node) > 0 && size+len(value) > listpackMaxBytes) {
+			nodes = append(nodes, node)
+			node, size = nil, 0
+		}
+		node = append(node, value)
+		size += len(value)
+		return true
+	})
+	if len(node) > 0 {
+		nodes = append(nodes, node)
+	}
+
+	e.writeLen(uint64(len(nodes)))
+	for _, node := range nodes {
+		e.writeLen(containerPacked)
+		lp := encodeListpack(node)
+		e.writeLen(uint64(len(lp)))
+		e.write(lp)
+	}
+}
+
+// writeHash writes a hash. A hash with field expiry is written as Redis 7.4 does: the
+// earliest expiry time of its fields, then every field with its expiry time relative to it
+func (e *Encoder) writeHash(key string, hash *data_structure.Hash) {
+	if !hash.HasFieldExpiry() {
+		e.writeByte(typeHash)
+		e.writeString(key)
+		e.writeLen(uint64(hash.Len()))
+		hash.SnapshotIterate(func(field, value string) bool {
+			e.writeString(field)
+			e.writeString(value)
+			return e.err == nil
+		})
+		return
+	}
+
+	minExpiry := uint64(math.MaxUint64)
+	hash.SnapshotIterate(func(field, _ string) bool {
+		if expiryTime, ok := hash.GetExpiry(field); ok {
+			minExpiry = min(minExpiry, expiryTime)
+		}
+		return true
+	})
+	e.writeByte(typeHashMetadata)
+	e.writeString(key)
+	e.writeMillis(minExpiry)
+	e.writeLen(uint64(hash.Len()))
+	hash.SnapshotIterate(func(field, value string) bool {
+		ttl := uint64(0) // No expiry
+		if expiryTime, ok := hash.GetExpiry(field); ok {
+			ttl = expiryTime - minExpiry + 1
+		}
+		e.writeLen(ttl)
+		e.writeString(field)
+		e.writeString(value)
+		return e.err == nil
+	})
+}
+
+// WriteEOF ends the file with the EOF opcode and the checksum, and flushes it
+func (e *Encoder) WriteEOF() error {
+	e.writeByte(opEOF)
+	if e.err != nil {
+		return e.err
+	}
+	_, e.err = e.w.Write(binary.LittleEndian.AppendUint64(nil, e.crc))
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
@@ -0,0 +1,230 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"redis-repo/internal/data_structure"
+	"strconv"
+)
+
+// Redis packs small collections and the nodes of lists in listpacks: a 4 bytes total size
+// and a 2 bytes number of entries, the entries, then a 0xFF terminator. Each entry is its
+// encoding, with the value, then the length of both as a backward varint so the listpack
+// can be walked from its end. They differ from data_structure.Listpack, so they are
+// converted when read and written
+
+const (
+	lpHeaderSize = 6
+	lpEOF        = 0xff
+)
+
+var errListpack = errors.New("invalid listpack")
+
+// lpBacklenSize returns the size of the backward length of an entry of l bytes
+func lpBacklenSize(l int) int {
+	switch {
+	case l <= 127:
+		return 1
+	case l < 16383:
+		return 2
+	case l < 2097151:
+		return 3
+	case l < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// lpAppendBacklen appends the backward length of an entry of l bytes, its most
+// significant 7 bits first and every byte but the first one flagged with 128
+func lpAppendBacklen(b []byte, l int) []byte {
+	size := lpBacklenSize(l)
+	for i := size - 1; i >= 0; i-- {
+		v := byte(l>>(7*i)) & 127
+		if i != size-1 {
+			v |= 128
+		}
+		b = append(b, v)
+	}
+	return b
+}
+
+// lpAppendEntry appends value with the smallest encoding, integers being stored as such
+func lpAppendEntry(b []byte, value string) []byte {
+	start := len(b)
+	if n, ok := data_structure.ParseCanonicalInt(value); ok {
+		switch {
+		case n >= 0 && n <= 127:
+			b = append(b, byte(n))
+		case n >= -4096 && n <= 4095:
+			u := uint64(n) & 0x1fff
+			b = append(b, 0xc0|byte(u>>8), byte(u))
+		case n >= -32768 && n <= 32767:
+			b = append(b, 0xf1, byte(n), byte(n>>8))
+		case n >= -8388608 && n <= 8388607:
+			b = append(b, 0xf2, byte(n), byte(n>>8), byte(n>>16))
+		case n >= -2147483648 && n <= 2147483647:
+			b = append(b, 0xf3)
+			b = binary.LittleEndian.AppendUint32(b, uint32(n))
+		default:
+			b = append(b, 0xf4)
+			b = binary.LittleEndian.AppendUint64(b, uint64(n))
+		}
+	} else {
+		switch l := len(value); {
+		case l < 64:
+			b = append(b, 0x80|byte(l))
+		case l < 4096:
+			b = append(b, 0xe0|byte(l>>8), byte(l))
+		default:
+			b = append(b, 0xf0)
+			b = binary.LittleEndian.AppendUint32(b, uint32(l))
+		}
+		b = append(b, value...)
+	}
+	return lpAppendBacklen(b, len(b)-start)
+}
+
+// encodeListpack packs values in a listpack
+func encodeListpack(values []string) []byte {
+	b := make([]byte, lpHeaderSize, lpHeaderSize+1+len(values)*2)
+	for _, value := range values {
+		b = lpAppendEntry(b, value)
+	}
+	b = append(b, lpEOF)
+
+	binary.LittleEndian.PutUint32(b, uint32(len(b)))
+	count := min(len(values), 65535) // 65535 means the entries have to be counted
+	binary.LittleEndian.PutUint16(b[4:], uint16(count))
+	return b
+}
+
+// decodeListpack returns the values packed in a listpack, the integers formatted in base 10
+func decodeListpack(b []byte) ([]string, error) {
+	if len(b) < lpHeaderSize+1 || int(binary.LittleEndian.Uint32(b)) != len(b) || b[len(b)-1] != lpEOF {
+		return nil, errListpack
+	}
+	count := int(binary.LittleEndian.Uint16(b[4:]))
+
+	var values []string
+	p := lpHeaderSize
+	for b[p] != lpEOF {
+		value, size, err := lpDecodeEntry(b[p : len(b)-1])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		p += size + lpBacklenSize(size)
+		if p >= len(b) {
+			return nil, errListpack
+		}
+	}
+	if count != 65535 && count != len(values) {
+		return nil, errListpack
+	}
+	return values, nil
+}
+
+// lpDecodeEntry decodes the entry at the start of b, returning its value and the size of
+// its encoding and value
+func lpDecodeEntry(b []byte) (string, int, error) {
+	need := func(n int) error {
+		if n > len(b) {
+			return errListpack
+		}
+		return nil
+	}
+	c := b[0]
+	var n int64
+	var size int
+	switch {
+	case c&0x80 == 0: // 7 bit unsigned integer
+		n, size = int64(c), 1
+	case c&0xc0 == 0x80: // String of up to 63 bytes
+		l := int(c & 0x3f)
+		if err := need(1 + l); err != nil {
+			return "", 0, err
+		}
+		return string(b[1 : 1+l]), 1 + l, nil
+	case c&0xe0 == 0xc0: // 13 bit signed integer
+		if err := need(2); err != nil {
+			return "", 0, err
+		}
+		u := int64(c&0x1f)<<8 | int64(b[1])
+		if u >= 1<<12 {
+			u -= 1 << 13
+		}
+		n, size = u, 2
+	case c&0xf0 == 0xe0: // String of up to 4095 bytes
+		if err := need(2); err != nil {
+			return "", 0, err
+		}
+		l := int(c&0x0f)<<8 | int(b[1])
+		if err := need(2 + l); err != nil {
+			return "", 0, err
+		}
+		return string(b[2 : 2+l]), 2 + l, nil
+	case c == 0xf0: // String with a 32 bit length
+		if err := need(5); err != nil {
+			return "", 0, err
+		}
+		l := int(binary.LittleEndian.Uint32(b[1:]))
+		if err := need(5 + l); err != nil {
+			return "", 0, err
+		}
+		return string(b[5 : 5+l]), 5 + l, nil
+	case c == 0xf1:
+		if err := need(3); err != nil {
+			return "", 0, err
+		}
+		n, size = int64(int16(binary.LittleEndian.Uint16(b[1:]))), 3
+	case c == 0xf2:
+		if err := need(4); err != nil {
+			return "", 0, err
+		}
+		n, size = int64(int32(uint32(b[1])<<8|uint32(b[2])<<16|uint32(b[3])<<24)>>8), 4
+	case c == 0xf3:
+		if err := need(5); err != nil {
+			return "", 0, err
+		}
+		n, size = int64(int32(binary.LittleEndian.Uint32(b[1:]))), 5
+	case c == 0xf4:
+		if err := need(9); err != nil {
+			return "", 0, err
+		}
+		n, size = int64(binary.LittleEndian.Uint64(b[1:])), 9
+	default:
+		return "", 0, errListpack
+	}
+	return strconv.FormatInt(n, 10), size, nil
+}
+
+// decodeIntset returns the members of an intset: its width in bytes, the number of
+// members, both as 32 bit integers, then the sorted members, all in little endian
+func decodeIntset(b []byte) ([]string, error) {
+	if len(b) < 8 {
+		return nil, errors.New("invalid intset")
+	}
+	width := int(binary.LittleEndian.Uint32(b))
+	count := int(binary.LittleEndian.Uint32(b[4:]))
+	if (width != 2 && width != 4 && width != 8) || len(b) != 8+width*count {
+		return nil, errors.New("invalid intset")
+	}
+
+	members := make([]string, count)
+	for i := range members {
+		p := b[8+i*width:]
+		var n int64
+		switch width {
+		case 2:
+			n = int64(int16(binary.LittleEndian.Uint16(p)))
+		case 4:
+			n = int64(int32(binary.LittleEndian.Uint32(p)))
+		default:
+			n = int64(binary.LittleEndian.Uint64(p))
+		}
+		members[i] = strconv.FormatInt(n, 10)
+	}
+	return members, nil
+}
@@ -0,0 +1,51 @@
+package rdb
+
+import "errors"
+
+var errLZF = errors.New("invalid LZF compressed string")
+
+// lzfDecompress expands the LZF compressed in into a string of length n. Redis compresses
+// the strings longer than 20 bytes; the strings written here are never compressed
+func lzfDecompress(in []byte, n int) ([]byte, error) {
+	out := make([]byte, 0, n)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 { // A run of ctrl+1 literal bytes
+			run := ctrl + 1
+			if i+run > len(in) || len(out)+run > n {
+				return nil, errLZF
+			}
+			out = append(out, in[i:i+run]...)
+			i += run
+			continue
+		}
+
+		// A back reference of length ctrl>>5, extended by the next byte when 7
+		length := ctrl >> 5
+		if length == 7 {
+			if i >= len(in) {
+				return nil, errLZF
+			}
+			length += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errLZF
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		length += 2
+		if ref < 0 || len(out)+length > n {
+			return nil, errLZF
+		}
+		for ; length > 0; length-- { // The reference may overlap the bytes being copied
+			out = append(out, out[ref])
+			ref++
+		}
+	}
+	if len(out) != n {
+		return nil, errLZF
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+// Package rdb reads and writes snapshots of the databases in the RDB format of Redis,
+// version 11 as written by Redis 7.2, so dumps can be moved between this server and Redis.
+// A snapshot holding hashes with field expiry is written as version 12, like Redis 7.4.
+//
+// A file is the "REDIS" magic and a 4 digit version, auxiliary fields, then for every
+// database a SELECTDB opcode followed by its keys, each one an optional expiry opcode,
+// the type of the value, the key and the value. It ends with the EOF opcode and the
+// CRC64 of all the bytes before it.
+package rdb
+
+import (
+	"errors"
+	"redis-repo/internal/data_structure"
+)
+
+// Version is the RDB version written. Files up to version 12, written by Redis 7.4, are read
+const Version = 11
+
+// VersionFieldExpiry is the RDB version written when a hash has field expiry, whose type
+// only exists from Redis 7.4. Redis 7.2 then refuses the version rather than the file
+const VersionFieldExpiry = 12
+
+const maxVersion = 12
+
+// SnapshotVersion returns the RDB version needed to write the snapshot of the databases
+func SnapshotVersion(dbs [][]data_structure.SnapshotEntry) int {
+	for _, entries := range dbs {
+		for _, entry := range entries {
+			if hash, ok := entry.Value.Value.(*data_structure.Hash); ok && hash.HasFieldExpiry() {
+				return VersionFieldExpiry
+			}
+		}
+	}
+	return Version
+}
+
+// Opcodes
+const (
+	opSlotInfo     = 244 // Cluster slot sizes, ignored
+	opFunction2    = 245 // Function library, ignored
+	opModuleAux    = 247
+	opIdle         = 248 // LRU idle time of the next key, ignored
+	opFreq         = 249 // LFU frequency of the next key, ignored
+	opAux          = 250
+	opResizeDB     = 251
+	opExpireTimeMs = 252
+	opExpireTime   = 253
+	opSelectDB     = 254
+	opEOF          = 255
+)
+
+// Value types
+const (
+	typeString         = 0
+	typeList           = 1
+	typeSet            = 2
+	typeZSet           = 3
+	typeHash           = 4
+	typeZSet2          = 5
+	typeSetIntset      = 11
+	typeHashListpack   = 16
+	typeZSetListpack   = 17
+	typeListQuicklist2 = 18
+	typeSetListpack    = 20
+	typeHashMetadata   = 24 // Hash with field expiry, from Redis 7.4
+	typeHashListpackEx = 25 // Packed hash with field expiry, from Redis 7.4
+)
+
+// Quicklist node containers of typeListQuicklist2
+const (
+	containerPlain  = 1
+	containerPacked = 2
+)
+
+// Special string encodings, flagged by the two high bits of a length
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+// Length prefixes
+const (
+	len6Bit  = 0
+	len14Bit = 1
+	len32Bit = 0x80
+	len64Bit = 0x81
+	lenEnc   = 3
+)
+
+// ErrChecksum is returned when the CRC64 at the end of a file does not match its content
+var ErrChecksum = errors.New("wrong RDB checksum")
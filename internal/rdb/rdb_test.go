@@ -0,0 +1,262 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"redis-repo/internal/data_structure"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCRC64(t *testing.T) {
+	// The check value of Redis's crc64 test
+	if crc := CRC64(0, []byte("123456789")); crc != 0xe9c6d914c4b8d9ca {
+		t.Errorf("Expected 0xe9c6d914c4b8d9ca, got %#x", crc)
+	}
+}
+
+func TestLZFDecompress(t *testing.T) {
+	// A literal "a", then a back reference copying it 9 times
+	out, err := lzfDecompress([]byte{0x00, 'a', 0xe0, 0x00, 0x00}, 10)
+	if err != nil || string(out) != "aaaaaaaaaa" {
+		t.Errorf("Expected 10 a, got %q, %v", out, err)
+	}
+	if _, err := lzfDecompress([]byte{0x00, 'a', 0xe0, 0x00, 0x05}, 10); err == nil {
+		t.Errorf("Expected an error for a reference before the start")
+	}
+}
+
+func TestListpack(t *testing.T) {
+	values := []string{
+		"0", "127", "128", "-1", "-4096", "4095", "4096", "-32768", "32767", "-8388608", "8388607",
+		"2147483647", "-2147483648", "9223372036854775807", "-9223372036854775808",
+		"", "a", "007", strings.Repeat("b", 63), strings.Repeat("c", 64), strings.Repeat("d", 5000),
+	}
+	b := encodeListpack(values)
+	got, err := decodeListpack(b)
+	if err != nil || !reflect.DeepEqual(got, values) {
+		t.Fatalf("Expected %q, got %q, %v", values, got, err)
+	}
+
+	// The 7 bit encoding of 5, the 6 bit string "ab" and their backward lengths
+	if !bytes.Equal(encodeListpack([]string{"5", "ab"}), []byte{13, 0, 0, 0, 2, 0, 5, 1, 0x82, 'a', 'b', 3, 0xff}) {
+		t.Errorf("Unexpected listpack %v", encodeListpack([]string{"5", "ab"}))
+	}
+	if _, err := decodeListpack(b[:len(b)-1]); err == nil {
+		t.Errorf("Expected an error for a truncated listpack")
+	}
+}
+
+// snapshot builds one value of every type, with an expiry on the key or the fields
+func snapshot() [][]data_structure.SnapshotEntry {
+	list := data_structure.NewQuicklist(-2)
+	for i := 0; i < 300; i++ {
+		list.PushTail("element" + strconv.Itoa(i))
+	}
+	hash := data_structure.NewHash()
+	hash.Set("field", "value")
+	hash.Set("expiring", "value")
+	hash.SetExpiry("expiring", 4102444800000)
+	zset := data_structure.NewZSet()
+	zset.Set("one", 1)
+	zset.Set("inf", math.Inf(1))
+	zset.Set("neg", -2.5)
+
+	db0 := []data_structure.SnapshotEntry{
+		{Key: "string", Value: data_structure.NewValueObject(strings.Repeat("s", 100))},
+		{Key: "int", Value: data_structure.NewValueObject("-123456"), ExpiryTimeMs: 4102444800000},
+		{Key: "bigint", Value: data_structure.NewValueObject("12345678901234")},
+		{Key: "list", Value: data_structure.NewValueObject(list)},
+		{Key: "intset", Value: data_structure.NewValueObject(data_structure.NewSet([]string{"1", "2", "-3"}))},
+		{Key: "set", Value: data_structure.NewValueObject(data_structure.NewSet([]string{"a", "b"}))},
+		{Key: "hash", Value: data_structure.NewValueObject(hash)},
+	}
+	db5 := []data_structure.SnapshotEntry{
+		{Key: "zset", Value: data_structure.NewValueObject(zset)},
+	}
+	return [][]data_structure.SnapshotEntry{db0, nil, nil, nil, nil, db5}
+}
+
+func encode(t *testing.T, dbs [][]data_structure.SnapshotEntry) []byte {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.WriteHeader(SnapshotVersion(dbs))
+	e.WriteAux("redis-ver", "7.2.0")
+	for id, entries := range dbs {
+		e.WriteDB(id, entries)
+	}
+	if err := e.WriteEOF(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// describe returns a comparable description of a value
+func describe(obj *data_structure.ValueObject) string {
+	switch v := obj.Value.(type) {
+	case *data_structure.Quicklist:
+		var values []string
+		v.Iterate(0, func(_ int, value string) bool {
+			values = append(values, value)
+			return true
+		})
+		return "list " + strings.Join(values, ",")
+	case *data_structure.Set:
+		return "set " + v.Encoding().String() + " " + strconv.Itoa(v.Len())
+	case *data_structure.ZSet:
+		var members []string
+		v.Iterate(func(m data_structure.ZSetMember) bool {
+			members = append(members, m.Member+"="+strconv.FormatFloat(m.Score, 'g', -1, 64))
+			return true
+		})
+		return "zset " + strings.Join(members, ",")
+	case *data_structure.Hash:
+		var fields []string
+		for _, field := range v.Fields() {
+			value, _ := v.Get(field)
+			expiryTime, _ := v.GetExpiry(field)
+			fields = append(fields, field+"="+value+"@"+strconv.FormatUint(expiryTime, 10))
+		}
+		sort.Strings(fields)
+		return "hash " + strings.Join(fields, ",")
+	default:
+		return obj.CurrentEncoding().String() + " " + obj.StringValue()
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	dbs := snapshot()
+	b := encode(t, dbs)
+	if !bytes.HasPrefix(b, []byte("REDIS0012")) {
+		t.Fatalf("Expected the version 12 magic for a hash with field expiry, got %q", b[:9])
+	}
+	if v := SnapshotVersion([][]data_structure.SnapshotEntry{dbs[5]}); v != Version {
+		t.Errorf("Expected version %d without field expiry, got %d", Version, v)
+	}
+
+	d := NewDecoder(bytes.NewReader(b))
+	i := 0
+	err := d.Decode(func(db int, entry data_structure.SnapshotEntry) error {
+		expected := dbs[db][0]
+		dbs[db] = dbs[db][1:]
+		if entry.Key != expected.Key || entry.ExpiryTimeMs != expected.ExpiryTimeMs || describe(entry.Value) != describe(expected.Value) {
+			t.Errorf("Expected %s %q expiring at %d in db %d, got %s %q expiring at %d",
+				expected.Key, describe(expected.Value), expected.ExpiryTimeMs, db, entry.Key, describe(entry.Value), entry.ExpiryTimeMs)
+		}
+		i++
+		return nil
+	})
+	if err != nil || i != 8 {
+		t.Errorf("Expected 8 keys, got %d, %v", i, err)
+	}
+	if d.Aux["redis-ver"] != "7.2.0" || d.Version != 12 || d.Offset() != int64(len(b)) {
+		t.Errorf("Unexpected aux %v, version %d or offset %d", d.Aux, d.Version, d.Offset())
+	}
+}
+
+// Test the encodings written by Redis but not by the encoder: compressed strings, packed
+// sets, sorted sets and hashes, old expiry and score formats, and the ignored opcodes
+func TestDecodeRedisEncodings(t *testing.T) {
+	var b []byte
+	str := func(s string) {
+		b = append(b, byte(len(s)))
+		b = append(b, s...)
+	}
+	listpack := func(values ...string) {
+		lp := encodeListpack(values)
+		b = append(b, len14Bit<<6|byte(len(lp)>>8), byte(len(lp)))
+		b = append(b, lp...)
+	}
+
+	b = append(b, "REDIS0011"...)
+	b = append(b, opFunction2)
+	str("#!lua name=lib")
+	b = append(b, opSelectDB, 2, opIdle, 10, opFreq, 5)
+	b = append(b, typeString)
+	str("lzf")
+	b = append(b, lenEnc<<6|encLZF, 5, 10, 0x00, 'a', 0xe0, 0x00, 0x00)
+	b = append(b, opExpireTime)
+	b = binary.LittleEndian.AppendUint32(b, 2000000000)
+	b = append(b, typeSetIntset)
+	str("intset")
+	b = append(b, 8+2*2)
+	b = binary.LittleEndian.AppendUint32(b, 2)
+	b = binary.LittleEndian.AppendUint32(b, 2)
+	b = binary.LittleEndian.AppendUint16(b, uint16(0xffff)) // -1
+	b = binary.LittleEndian.AppendUint16(b, 7)
+	b = append(b, typeZSetListpack)
+	str("zset")
+	listpack("a", "1.5", "b", "2")
+	b = append(b, typeZSet)
+	str("oldzset")
+	b = append(b, 1)
+	str("m")
+	b = append(b, 254)
+	b = append(b, typeHashListpackEx)
+	str("hash")
+	b = binary.LittleEndian.AppendUint64(b, 4102444800000)
+	listpack("f1", "v1", "0", "f2", "v2", "4102444800000")
+	b = append(b, typeListQuicklist2)
+	str("list")
+	b = append(b, 2, containerPlain)
+	str("plain")
+	b = append(b, containerPacked)
+	listpack("x", "y")
+	b = append(b, opEOF)
+	b = binary.LittleEndian.AppendUint64(b, CRC64(0, b))
+
+	got := make(map[string]string)
+	err := NewDecoder(bytes.NewReader(b)).Decode(func(db int, entry data_structure.SnapshotEntry) error {
+		if db != 2 {
+			t.Errorf("Expected the keys in db 2, got %d", db)
+		}
+		got[entry.Key] = describe(entry.Value) + " " + strconv.FormatUint(entry.ExpiryTimeMs, 10)
+		return nil
+	})
+	expected := map[string]string{
+		"lzf":     "embstr aaaaaaaaaa 0",
+		"intset":  "set intset 2 2000000000000",
+		"zset":    "zset a=1.5,b=2 0",
+		"oldzset": "zset m=+Inf 0",
+		"hash":    "hash f1=v1@0,f2=v2@4102444800000 0",
+		"list":    "list plain,x,y 0",
+	}
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, got, err)
+	}
+}
+
+func TestDecodeCorrupted(t *testing.T) {
+	b := encode(t, snapshot())
+
+	corrupted := append([]byte(nil), b...)
+	corrupted[len(corrupted)-20] ^= 0xff
+	d := NewDecoder(bytes.NewReader(corrupted))
+	err := d.Decode(func(int, data_structure.SnapshotEntry) error { return nil })
+	if err == nil {
+		t.Errorf("Expected an error for a corrupted file")
+	}
+
+	d = NewDecoder(bytes.NewReader(b[:len(b)-100]))
+	err = d.Decode(func(int, data_structure.SnapshotEntry) error { return nil })
+	if !errors.Is(err, io.ErrUnexpectedEOF) || d.Offset() != int64(len(b)-100) {
+		t.Errorf("Expected an unexpected EOF at offset %d, got %v at %d", len(b)-100, err, d.Offset())
+	}
+
+	checksum := append([]byte(nil), b...)
+	checksum[len(checksum)-1] ^= 0xff
+	d = NewDecoder(bytes.NewReader(checksum))
+	if err := d.Decode(func(int, data_structure.SnapshotEntry) error { return nil }); err != ErrChecksum {
+		t.Errorf("Expected a checksum error, got %v", err)
+	}
+
+	if err := NewDecoder(strings.NewReader("REDIS0099")).Decode(nil); err == nil {
+		t.Errorf("Expected an error for an unknown version")
+	}
+}
@@ -7,16 +7,22 @@ import (
 	"os"
 	"redis-repo/internal/config"
 	"redis-repo/internal/constant"
+	"redis-repo/internal/core/executor"
 	"redis-repo/internal/core/io_multiplexing"
 	"redis-repo/internal/handler/client"
 	"redis-repo/internal/handler/server"
+	"strconv"
 	"syscall"
 	"time"
 )
 
 // Main
 func RunRedisServer() {
-	log.Println("Starting an I/O Multiplexing TCP server on", config.Port)
+	log.Println("Starting an I/O Multiplexing TCP server on port", config.Port)
+
+	if err := executor.LoadDataFromDisk(); err != nil {
+		log.Fatal("Failed loading the data: ", err)
+	}
 
 	listener, listenerFile, serverFd, err := setupServer()
 	if err != nil {
@@ -37,7 +43,7 @@ func RunRedisServer() {
 // setupServer creates and configures the TCP listener, returning the listener,
 // file descriptor, and server file descriptor for epoll monitoring
 func setupServer() (net.Listener, *os.File, int, error) {
-	listener, err := net.Listen(config.Protocol, config.Port)
+	listener, err := net.Listen(config.Protocol, ":"+strconv.Itoa(config.Port))
 	if err != nil {
 		return nil, nil, 0, fmt.Errorf("failed to start listener: %w", err)
 	}
@@ -81,7 +87,8 @@ func setupIOMultiplexer(serverFd int) (*io_multiplexing.Epoll, error) {
 func runEventLoop(ioMultiplexer *io_multiplexing.Epoll, serverFd int) {
 	cleanupLastTime := time.Now().UnixMilli()
 	for {
-		events, err := ioMultiplexer.Wait()
+		// Wake up for the cron even when no client is active
+		events, err := ioMultiplexer.Wait(constant.ActiveCleanupFrequency)
 		if err != nil {
 			if err != syscall.EINTR {
 				// EINTR is expected when the system call is interrupted by a signal
@@ -95,6 +102,12 @@ func runEventLoop(ioMultiplexer *io_multiplexing.Epoll, serverFd int) {
 		now := time.Now().UnixMilli()
 		if now-cleanupLastTime >= constant.ActiveCleanupFrequency {
 			server.HandleSystemCleanup()
+			if master := executor.CheckReplication(); master != nil {
+				if client.AttachMasterConnection(master, ioMultiplexer) {
+					ioMultiplexer.Remove(master.Fd)
+					syscall.Close(master.Fd)
+				}
+			}
 			cleanupLastTime = now
 		}
 
@@ -103,7 +116,13 @@ func runEventLoop(ioMultiplexer *io_multiplexing.Epoll, serverFd int) {
 				client.HandleNewConnection(serverFd, ioMultiplexer)
 			} else {
 				clientFd := int(event.Fd)
-				shouldClose := client.HandleClientData(clientFd)
+				shouldClose := false
+				if event.Events&(syscall.EPOLLIN|syscall.EPOLLHUP|syscall.EPOLLERR) != 0 {
+					shouldClose = client.HandleClientData(clientFd, ioMultiplexer)
+				}
+				if !shouldClose && event.Events&syscall.EPOLLOUT != 0 {
+					shouldClose = client.HandleClientWritable(clientFd, ioMultiplexer)
+				}
 				if shouldClose {
 					// Server manages I/O multiplexer cleanup
 					ioMultiplexer.Remove(clientFd)
@@ -111,5 +130,11 @@ func runEventLoop(ioMultiplexer *io_multiplexing.Epoll, serverFd int) {
 				}
 			}
 		}
+
+		// The writes of this iteration are sent to the replicas
+		for _, fd := range client.HandleReplicationOutput(ioMultiplexer) {
+			ioMultiplexer.Remove(fd)
+			syscall.Close(fd)
+		}
 	}
 }